@@ -0,0 +1,416 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package batcher
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// Defaults, and bounds.
+const (
+	// DefaultMaxBufferedFactor sets - times `Config.FlushItems` - the
+	// default `Config.MaxBuffered`.
+	DefaultMaxBufferedFactor = 10
+
+	// DefaultMinBackoff is the default `Config.MinBackoff`.
+	DefaultMinBackoff = 100 * time.Millisecond
+
+	// DefaultMaxBackoff is the default `Config.MaxBackoff`.
+	DefaultMaxBackoff = 5 * time.Second
+)
+
+var (
+	// ErrClosed is returned when adding to a closed batcher.
+	ErrClosed = errors.New("batcher is closed")
+
+	// ErrBufferFull is returned when adding to a batcher holding
+	// `Config.MaxBuffered` items - the item is dropped.
+	ErrBufferFull = errors.New("batcher buffer is full")
+)
+
+// retryableError marks a failure worth a retry - see `Retryable`.
+type retryableError struct {
+	err error
+}
+
+// Error interface implementation.
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e retryableError) Unwrap() error {
+	return e.err
+}
+
+// Config configures a batcher. `Send` is required. Zero values mean
+// defaults.
+type Config[T any] struct {
+	// Send sends a batch - once. Wrap a failure worth a retry (e.g. a
+	// transport error, a 429, or a 5xx status) with `Retryable`.
+	Send func(batch []T) error
+
+	// Size returns an item's size - for `FlushBytes`. Defaults to 0: only
+	// `FlushItems` applies.
+	Size func(item T) int
+
+	// FlushItems is the maximum number of items per batch. Defaults to 500.
+	FlushItems int
+
+	// FlushBytes is the batch size threshold, in bytes - see `Size`.
+	// Defaults to 1MiB.
+	FlushBytes int
+
+	// FlushInterval is the periodic flush interval. Defaults to 5s.
+	FlushInterval time.Duration
+
+	// MaxBuffered is the maximum number of pending items - e.g. while the
+	// sink is down. Adding beyond it drops the item, and returns
+	// `ErrBufferFull`. Defaults to `DefaultMaxBufferedFactor` batches.
+	MaxBuffered int
+
+	// MaxRetries of retryable failures of a batch - 0 disables retries.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry - doubled after each
+	// one, up to `MaxBackoff`. Defaults to `DefaultMinBackoff`, and
+	// `DefaultMaxBackoff` - never zero, so retries don't busy-loop.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError receives background - periodic, or size-triggered - send
+	// failures. It may be called concurrently with `Flush`.
+	OnError func(error)
+}
+
+// Batcher accumulates items, and sends them in batches - when a batch is
+// full, periodically, and on `Flush`/`Close`.
+//
+// Delivery is ORDERED: a single attempt is in flight at a time, and a batch
+// failing with a retryable error is put back in FRONT of the pending items -
+// retried, possibly with newer items, before anything else. No lock is held
+// while backing off: adding, and flushing proceed meanwhile. A batch still
+// failing after `Config.MaxRetries` retries is dropped, and reported.
+type Batcher[T any] struct {
+	cfg Config[T]
+
+	// mu guards the pending items, the closed state, and the retry count.
+	// `failures` counts the failed attempts of the items in front.
+	mu           sync.Mutex
+	pending      []T
+	pendingBytes int
+	failures     int
+	closed       bool
+	closeErr     error
+
+	// sendMu serializes attempts - held during ONE attempt, never while
+	// backing off.
+	sendMu sync.Mutex
+
+	// flushCh signals a full batch to the worker.
+	flushCh chan struct{}
+
+	// done stops the worker, and cuts backoffs short. wg waits for the
+	// worker.
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+//////
+// Methods.
+//////
+
+// Add enqueues an item. A full batch is sent in the background. After
+// Close, it returns `ErrClosed`; when `Config.MaxBuffered` items are
+// pending, `ErrBufferFull` - the item is dropped.
+func (b *Batcher[T]) Add(item T) error {
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+
+		return ErrClosed
+	}
+
+	if len(b.pending) >= b.cfg.MaxBuffered {
+		b.mu.Unlock()
+
+		return ErrBufferFull
+	}
+
+	b.pending = append(b.pending, item)
+	b.pendingBytes += b.size(item)
+
+	full := len(b.pending) >= b.cfg.FlushItems || b.pendingBytes >= b.cfg.FlushBytes
+
+	b.mu.Unlock()
+
+	if full {
+		// Non-blocking: a pending signal already covers this batch.
+		select {
+		case b.flushCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Flush synchronously sends the pending items - retrying per
+// `Config.MaxRetries` - returning the failures, if any. Close cuts its
+// backoffs short. After Close it's a no-op.
+func (b *Batcher[T]) Flush() error {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+
+	if closed {
+		return nil
+	}
+
+	return b.drain(b.done)
+}
+
+// Close stops the periodic flush, and sends the pending items - retrying
+// per `Config.MaxRetries`. It's idempotent: subsequent calls return the
+// FIRST call's outcome.
+func (b *Batcher[T]) Close() error {
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+
+		return b.closeErr
+	}
+
+	b.closed = true
+
+	b.mu.Unlock()
+
+	close(b.done)
+
+	b.wg.Wait()
+
+	err := b.drain(nil)
+
+	b.mu.Lock()
+	b.closeErr = err
+	b.mu.Unlock()
+
+	return err
+}
+
+//////
+// Helpers.
+//////
+
+// size returns the size of `item` - see `Config.Size`.
+func (b *Batcher[T]) size(item T) int {
+	if b.cfg.Size == nil {
+		return 0
+	}
+
+	return b.cfg.Size(item)
+}
+
+// worker flushes periodically, and on full batches, until Close.
+func (b *Batcher[T]) worker() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		case <-b.flushCh:
+		}
+
+		if err := b.drain(b.done); err != nil && b.cfg.OnError != nil {
+			b.cfg.OnError(err)
+		}
+	}
+}
+
+// take removes the next batch - up to `FlushItems` items, or `FlushBytes`
+// bytes, at least one item - from the pending ones. Call it holding `mu`.
+func (b *Batcher[T]) take() []T {
+	n, total := 0, 0
+
+	for n < len(b.pending) && n < b.cfg.FlushItems && (n == 0 || total < b.cfg.FlushBytes) {
+		total += b.size(b.pending[n])
+
+		n++
+	}
+
+	batch := make([]T, n)
+
+	copy(batch, b.pending)
+
+	// Cleared, so the backing array doesn't retain the sent items.
+	clear(b.pending[:n])
+
+	b.pending = b.pending[n:]
+	b.pendingBytes -= total
+
+	if len(b.pending) == 0 {
+		b.pending = nil
+		b.pendingBytes = 0
+	}
+
+	return batch
+}
+
+// sendNext sends the next batch - once. A retryable failure within the
+// retry budget puts it back in front, and is reported as `retry`. `sent` is
+// false when there was nothing to send.
+func (b *Batcher[T]) sendNext() (sent, retry bool, err error) {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return false, false, nil
+	}
+
+	err = b.cfg.Send(batch)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+
+		return true, false, nil
+	}
+
+	var r retryableError
+
+	if errors.As(err, &r) && b.failures < b.cfg.MaxRetries {
+		b.failures++
+
+		b.pending = append(batch, b.pending...)
+
+		for _, item := range batch {
+			b.pendingBytes += b.size(item)
+		}
+
+		return true, true, err
+	}
+
+	b.failures = 0
+
+	return true, false, fmt.Errorf("%w (%d items dropped)", err, len(batch))
+}
+
+// drain sends batches until none is pending, backing off between retries -
+// without holding any lock. Closing `stop` cuts it short: a batch awaiting
+// its retry stays pending. It returns the failures, joined.
+func (b *Batcher[T]) drain(stop <-chan struct{}) error {
+	var errs []error
+
+	backoff := b.cfg.MinBackoff
+
+	for {
+		sent, retry, err := b.sendNext()
+
+		switch {
+		case !sent:
+			return errors.Join(errs...)
+		case retry:
+			timer := time.NewTimer(backoff)
+
+			select {
+			case <-stop:
+				timer.Stop()
+
+				return errors.Join(append(errs, err)...)
+			case <-timer.C:
+			}
+
+			backoff = min(backoff*2, b.cfg.MaxBackoff)
+
+			continue
+		case err != nil:
+			errs = append(errs, err)
+		}
+
+		backoff = b.cfg.MinBackoff
+
+		select {
+		case <-stop:
+			return errors.Join(errs...)
+		default:
+		}
+	}
+}
+
+//////
+// Factory.
+//////
+
+// Retryable marks `err` as worth a retry - see `Config.Send`. A nil `err`
+// stays nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return retryableError{err: err}
+}
+
+// New returns a started batcher - see `Config`. `Close` it to stop it.
+func New[T any](cfg Config[T]) *Batcher[T] {
+	if cfg.FlushItems <= 0 {
+		cfg.FlushItems = 500
+	}
+
+	if cfg.FlushBytes <= 0 {
+		cfg.FlushBytes = 1 << 20
+	}
+
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+
+	if cfg.MaxBuffered <= 0 {
+		cfg.MaxBuffered = DefaultMaxBufferedFactor * cfg.FlushItems
+	}
+
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+
+	cfg.MaxBackoff = max(cfg.MaxBackoff, cfg.MinBackoff)
+	cfg.MaxRetries = max(cfg.MaxRetries, 0)
+
+	b := &Batcher[T]{
+		cfg:     cfg,
+		done:    make(chan struct{}),
+		flushCh: make(chan struct{}, 1),
+	}
+
+	b.wg.Add(1)
+
+	go b.worker()
+
+	return b
+}
@@ -0,0 +1,292 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package batcher
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errSinkDown = errors.New("sink down")
+
+// sink records the batches it receives, failing the first `failures`
+// attempts with `err`.
+type sink struct {
+	mu       sync.Mutex
+	attempts int
+	batches  [][]int
+	err      error
+	failures int
+}
+
+// send is a `Config.Send`.
+func (s *sink) send(batch []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+
+	if s.failures > 0 {
+		s.failures--
+
+		return s.err
+	}
+
+	s.batches = append(s.batches, slices.Clone(batch))
+
+	return nil
+}
+
+// received returns the items received, in order, and the attempts count.
+func (s *sink) received() ([]int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []int
+
+	for _, b := range s.batches {
+		items = append(items, b...)
+	}
+
+	return items, s.attempts
+}
+
+func TestBatcher_FlushChunksInOrder(t *testing.T) {
+	s := &sink{}
+
+	b := New(Config[int]{Send: s.send, FlushItems: 3, FlushInterval: time.Hour})
+
+	defer b.Close()
+
+	for i := range 7 {
+		if err := b.Add(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	items, _ := s.received()
+
+	if !slices.Equal(items, []int{0, 1, 2, 3, 4, 5, 6}) {
+		t.Errorf("items = %v", items)
+	}
+
+	for _, batch := range s.batches {
+		if len(batch) > 3 {
+			t.Errorf("batch of %d items, want at most 3", len(batch))
+		}
+	}
+}
+
+func TestBatcher_FlushBytes(t *testing.T) {
+	s := &sink{}
+
+	b := New(Config[int]{
+		Send:          s.send,
+		Size:          func(int) int { return 10 },
+		FlushBytes:    20,
+		FlushInterval: time.Hour,
+	})
+
+	defer b.Close()
+
+	for i := range 4 {
+		_ = b.Add(i)
+	}
+
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.batches) != 2 {
+		t.Errorf("batches = %v, want 2 of 20 bytes", s.batches)
+	}
+}
+
+func TestBatcher_RetryKeepsOrder(t *testing.T) {
+	s := &sink{err: Retryable(errSinkDown), failures: 2}
+
+	b := New(Config[int]{Send: s.send, MaxRetries: 3, FlushItems: 2, FlushInterval: time.Hour})
+
+	defer b.Close()
+
+	for i := range 5 {
+		_ = b.Add(i)
+	}
+
+	if err := b.Flush(); err != nil {
+		t.Fatalf("Flush = %v, want success after retries", err)
+	}
+
+	items, attempts := s.received()
+
+	if !slices.Equal(items, []int{0, 1, 2, 3, 4}) || attempts != 5 {
+		t.Errorf("items = %v, attempts = %d", items, attempts)
+	}
+}
+
+func TestBatcher_RetriesExhausted(t *testing.T) {
+	s := &sink{err: Retryable(errSinkDown), failures: 10}
+
+	b := New(Config[int]{Send: s.send, MaxRetries: 2, FlushInterval: time.Hour})
+
+	defer b.Close()
+
+	_ = b.Add(1)
+
+	if err := b.Flush(); !errors.Is(err, errSinkDown) {
+		t.Errorf("Flush = %v, want errSinkDown", err)
+	}
+
+	if _, attempts := s.received(); attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+
+	// Dropped: nothing left to send.
+	if err := b.Flush(); err != nil {
+		t.Errorf("second Flush = %v", err)
+	}
+}
+
+func TestBatcher_NonRetryableDropped(t *testing.T) {
+	s := &sink{err: errSinkDown, failures: 1}
+
+	b := New(Config[int]{Send: s.send, MaxRetries: 5, FlushInterval: time.Hour})
+
+	defer b.Close()
+
+	_ = b.Add(1)
+
+	if err := b.Flush(); !errors.Is(err, errSinkDown) {
+		t.Errorf("Flush = %v, want errSinkDown", err)
+	}
+
+	if _, attempts := s.received(); attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+// A zero backoff means the default one - retries don't busy-loop.
+func TestBatcher_ZeroBackoff(t *testing.T) {
+	s := &sink{err: Retryable(errSinkDown), failures: 2}
+
+	b := New(Config[int]{Send: s.send, MaxRetries: 2, FlushInterval: time.Hour})
+
+	defer b.Close()
+
+	_ = b.Add(1)
+
+	start := time.Now()
+
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 3*DefaultMinBackoff {
+		t.Errorf("2 retries took %s - want at least %s", elapsed, 3*DefaultMinBackoff)
+	}
+}
+
+// Items beyond `MaxBuffered` are dropped.
+func TestBatcher_MaxBuffered(t *testing.T) {
+	s := &sink{}
+
+	b := New(Config[int]{Send: s.send, MaxBuffered: 2, FlushInterval: time.Hour})
+
+	defer b.Close()
+
+	_ = b.Add(1)
+	_ = b.Add(2)
+
+	if err := b.Add(3); !errors.Is(err, ErrBufferFull) {
+		t.Errorf("Add = %v, want ErrBufferFull", err)
+	}
+
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Add(3); err != nil {
+		t.Errorf("Add after Flush = %v", err)
+	}
+}
+
+// No lock is held while backing off: a Flush sends the batch awaiting its
+// retry right away - still in order.
+func TestBatcher_FlushDuringBackoff(t *testing.T) {
+	s := &sink{err: Retryable(errSinkDown), failures: 1}
+
+	b := New(Config[int]{
+		Send:          s.send,
+		FlushItems:    1,
+		FlushInterval: time.Hour,
+		MaxRetries:    3,
+		MinBackoff:    time.Hour,
+	})
+
+	defer b.Close()
+
+	// Full: the worker's attempt fails, and it backs off for an hour.
+	_ = b.Add(1)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for _, attempts := s.received(); attempts == 0 && time.Now().Before(deadline); _, attempts = s.received() {
+		time.Sleep(time.Millisecond)
+	}
+
+	_ = b.Add(2)
+
+	done := make(chan error, 1)
+
+	go func() { done <- b.Flush() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush waited for the backoff")
+	}
+
+	if items, _ := s.received(); !slices.Equal(items, []int{1, 2}) {
+		t.Errorf("items = %v, want [1 2]", items)
+	}
+}
+
+func TestBatcher_Close(t *testing.T) {
+	s := &sink{}
+
+	b := New(Config[int]{Send: s.send, FlushInterval: time.Hour})
+
+	_ = b.Add(1)
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if items, _ := s.received(); !slices.Equal(items, []int{1}) {
+		t.Errorf("items = %v - Close drains", items)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	if err := b.Flush(); err != nil {
+		t.Errorf("Flush after Close = %v, want a no-op", err)
+	}
+
+	if err := b.Add(2); !errors.Is(err, ErrClosed) {
+		t.Errorf("Add after Close = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package batcher is the batching engine shared by sypl's network outputs -
+// e.g. the HTTP output, and the Loki client: items are accumulated, and sent
+// in ordered batches - when a batch is full, periodically, and on
+// Flush/Close - with bounded buffering, and retries with exponential
+// backoff.
+//
+// Outputs provide the sending - see `Config.Send` - and mark the failures
+// worth a retry with `Retryable`.
+package batcher
@@ -0,0 +1,102 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl
+
+import (
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+//////
+// Clock, and ID generator.
+//
+// Messages created by the logger take their timestamp from the logger's
+// clock, and their ID from its generator - defaults: `time.Now`, and UUIDv4.
+// Inject others for deterministic tests, and replays, or time-sortable IDs
+// (see `message.NewULIDGenerator`, `message.NewUUIDv7Generator`, and
+// `message.NewCounterGenerator`). The time-sortable generators take a clock:
+// pass the injected one, so the IDs' timestamps follow it - e.g.:
+//
+//	l.SetClock(clock).SetIDGenerator(message.NewULIDGenerator(clock))
+//
+// An injected clock also flows - carried by the messages - to the
+// time-dependent processors - `Dedup`, `RateLimit`, and `Sample` - which
+// measure their windows on it. Otherwise they use `time.Now`: never the
+// messages' timestamps, which may be back-dated, or out of order.
+//
+// Messages built by the caller, and printed via `PrintMessage`, keep their
+// own timestamp, and ID.
+//////
+
+// SetClock sets the clock providing messages' timestamps. `nil` restores
+// `time.Now`. Child, and derived loggers inherit it.
+func (sypl *Sypl) SetClock(clock func() time.Time) *Sypl {
+	sypl.lock()
+	defer sypl.unlock()
+
+	sypl.clock = clock
+
+	return sypl
+}
+
+// SetIDGenerator sets the generator of messages' IDs. `nil` restores UUIDv4.
+// Child, and derived loggers inherit it.
+//
+// NOTE: Unlike the default - computed lazily, on the first `GetID` call - a
+// custom generator runs when the message is created, so time-sortable IDs
+// sort by creation, and sequential ones follow the logging order.
+func (sypl *Sypl) SetIDGenerator(idGenerator func() string) *Sypl {
+	sypl.lock()
+	defer sypl.unlock()
+
+	sypl.idGenerator = idGenerator
+
+	return sypl
+}
+
+// NewMessage creates a message with the logger's clock, and ID generator -
+// see `SetClock`, and `SetIDGenerator`. Use it to build messages printed
+// via `PrintMessage`.
+//
+// NOTE: A nil logger falls back to the defaults - processing then fails
+// fast, reporting it isn't initialized.
+func (sypl *Sypl) NewMessage(l level.Level, ct string) message.IMessage {
+	return sypl.buildMessage(message.New, l, ct)
+}
+
+//////
+// Helpers.
+//////
+
+// buildMessage creates a message via `factory` - `message.New`, or
+// `message.Acquire` - with the logger's clock, and ID generator.
+func (sypl *Sypl) buildMessage(
+	factory func(l level.Level, ct string, opts ...message.Option) message.IMessage,
+	l level.Level,
+	ct string,
+) message.IMessage {
+	if sypl == nil {
+		return factory(l, ct)
+	}
+
+	sypl.rLock()
+	clock, idGenerator := sypl.clock, sypl.idGenerator
+	sypl.rUnlock()
+
+	// The common case allocates no options.
+	if clock == nil && idGenerator == nil {
+		return factory(l, ct)
+	}
+
+	m := factory(l, ct, message.WithClock(clock), message.WithIDGenerator(idGenerator))
+
+	if idGenerator != nil {
+		m.GetID()
+	}
+
+	return m
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+// idsProcessor returns a processor collecting messages' IDs.
+func idsProcessor(ids *[]string, mu *sync.Mutex) processor.IProcessor {
+	return processor.New("IDs", func(m message.IMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		*ids = append(*ids, m.GetID())
+
+		return nil
+	})
+}
+
+// Messages take their timestamp from the logger's clock, and their ID from
+// its generator - child, and derived loggers inherit both.
+func TestSetClockAndIDGenerator(t *testing.T) {
+	frozen := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var mu sync.Mutex
+
+	ids := []string{}
+
+	rec, o := output.Recorder(level.Trace, idsProcessor(&ids, &mu))
+
+	l := sypl.New("clock", o)
+
+	if got := l.SetClock(func() time.Time { return frozen }); got != l {
+		t.Fatal("SetClock must return the same *Sypl for chaining")
+	}
+
+	if got := l.SetIDGenerator(message.NewCounterGenerator()); got != l {
+		t.Fatal("SetIDGenerator must return the same *Sypl for chaining")
+	}
+
+	l.Infoln("parent")
+	l.New("child").Infoln("child")
+	l.With(fields.Fields{"k": "v"}).Infoln("derived")
+	l.PrintlnWithOptions(level.Warn, "options")
+
+	records := rec.Messages()
+
+	if len(records) != 4 {
+		t.Fatalf("records = %d, want 4", len(records))
+	}
+
+	for _, r := range records {
+		if !r.Timestamp.Equal(frozen) {
+			t.Errorf("%q timestamp = %v, want %v", r.OriginalContent, r.Timestamp, frozen)
+		}
+	}
+
+	// The generator is shared - IDs follow the logging order.
+	if want := []string{
+		"00000000000000000001",
+		"00000000000000000002",
+		"00000000000000000003",
+		"00000000000000000004",
+	}; !equalStrings(ids, want) {
+		t.Errorf("IDs = %q, want %q", ids, want)
+	}
+
+	// Negative control: reset to the defaults.
+	l.SetClock(nil).SetIDGenerator(nil)
+
+	ids = ids[:0]
+
+	before := time.Now()
+
+	l.Infoln("defaults")
+
+	if r := rec.Messages()[4]; r.Timestamp.Before(before) {
+		t.Errorf("timestamp = %v, want time.Now", r.Timestamp)
+	}
+
+	if len(ids) != 1 || len(ids[0]) != 36 {
+		t.Errorf("IDs = %q, want a UUIDv4", ids)
+	}
+}
+
+// The logger's clock drives time-dependent processors' windows.
+func TestSetClock_DrivesProcessors(t *testing.T) {
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	rec, o := output.Recorder(level.Trace, processor.RateLimit(1, time.Minute))
+
+	l := sypl.New("clock", o).SetClock(func() time.Time { return now })
+
+	l.Info("first")
+	l.Info("limited")
+
+	// The window elapses on the logger's clock - not the wall clock.
+	now = now.Add(time.Minute)
+
+	l.Info("next window")
+
+	records := rec.Messages()
+
+	if len(records) != 2 || records[0].OriginalContent != "first" || records[1].OriginalContent != "next window" {
+		t.Errorf("records = %+v, want [first, next window]", records)
+	}
+}
+
+// Regression: without `SetClock`, time-dependent processors keep measuring
+// their windows on the wall clock - back-dated, or out-of-order timestamps
+// (e.g. replays) don't open new windows, so a burst of them is still
+// limited.
+func TestNoClock_ProcessorsUseWallClock(t *testing.T) {
+	rec, o := output.Recorder(level.Trace, processor.RateLimit(1, time.Hour))
+
+	l := sypl.New("clock", o)
+
+	base := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, offset := range []time.Duration{0, 2 * time.Hour, -time.Hour, 48 * time.Hour} {
+		m := message.New(level.Info, "replayed")
+		m.SetTimestamp(base.Add(offset))
+
+		l.PrintMessage(m)
+	}
+
+	l.Info("live")
+
+	if records := rec.Messages(); len(records) != 1 || records[0].OriginalContent != "replayed" {
+		t.Errorf("records = %+v, want only the first message - one per wall-clock hour", records)
+	}
+}
+
+// NewMessage builds messages with the logger's clock, and ID generator.
+func TestNewMessage(t *testing.T) {
+	frozen := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	l := sypl.New("clock").
+		SetClock(func() time.Time { return frozen }).
+		SetIDGenerator(func() string { return "fixed" })
+
+	m := l.NewMessage(level.Info, "content")
+
+	if !m.GetTimestamp().Equal(frozen) || m.GetID() != "fixed" {
+		t.Errorf("NewMessage() = %v, %q, want %v, fixed", m.GetTimestamp(), m.GetID(), frozen)
+	}
+}
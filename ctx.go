@@ -7,6 +7,7 @@ package sypl
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/thalesfsp/sypl/v2/fields"
 	"github.com/thalesfsp/sypl/v2/level"
@@ -17,8 +18,8 @@ import (
 //
 // Two independent capabilities:
 //
-//  1. Carrying a logger THROUGH a context: `NewContext`/`FromContext`/
-//     `FromContextOrDefault`.
+//  1. Carrying a logger THROUGH a context: `NewContext`/`NewContextWith`/
+//     `FromContext`/`FromContextOrDefault`.
 //  2. Extracting fields FROM a context: `SetContextExtractor` +
 //     `PrintWithContext` (and the leveled `*WithContext` variants).
 //
@@ -37,6 +38,39 @@ func NewContext(ctx context.Context, l *Sypl) context.Context {
 	return context.WithValue(ctx, contextKey{}, l)
 }
 
+// lazyLogger is a context-carried derived logger - see `NewContextWith`.
+type lazyLogger struct {
+	once sync.Once
+
+	parent *Sypl
+	fields fields.Fields
+
+	// derived is `parent.With(fields)`, once derived.
+	derived *Sypl
+}
+
+// get returns the derived logger - deriving it on the first call.
+func (ll *lazyLogger) get() *Sypl {
+	ll.once.Do(func() {
+		ll.derived = ll.parent.With(ll.fields)
+	})
+
+	return ll.derived
+}
+
+// NewContextWith returns a copy of `ctx` carrying `l.With(f)` - derived
+// LAZILY, on the first `FromContext`. Suited to per-request loggers: requests
+// never retrieving it don't pay for the derivation.
+//
+// NOTE: `f` is read at derivation time - don't modify it afterwards.
+func NewContextWith(ctx context.Context, l *Sypl, f fields.Fields) context.Context {
+	if l == nil {
+		return NewContext(ctx, nil)
+	}
+
+	return context.WithValue(ctx, contextKey{}, &lazyLogger{parent: l, fields: f})
+}
+
 // FromContext returns the `*Sypl` carried by `ctx`, and whether one was
 // found. Tolerates a nil `ctx`, and a stored nil logger: (nil, false).
 func FromContext(ctx context.Context) (*Sypl, bool) {
@@ -44,8 +78,16 @@ func FromContext(ctx context.Context) (*Sypl, bool) {
 		return nil, false
 	}
 
-	l, ok := ctx.Value(contextKey{}).(*Sypl)
-	if !ok || l == nil {
+	var l *Sypl
+
+	switch v := ctx.Value(contextKey{}).(type) {
+	case *Sypl:
+		l = v
+	case *lazyLogger:
+		l = v.get()
+	}
+
+	if l == nil {
 		return nil, false
 	}
 
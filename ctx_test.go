@@ -41,6 +41,37 @@ func TestContext_RoundTrip(t *testing.T) {
 	}
 }
 
+// NewContextWith derives the logger on first retrieval - once.
+func TestContext_NewContextWith(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	l := sypl.New("ctx-with", o)
+
+	ctx := sypl.NewContextWith(context.Background(), l, fields.Fields{"request_id": "r1"})
+
+	got, ok := sypl.FromContext(ctx)
+	if !ok || got == l {
+		t.Fatalf("FromContext = (%v, %v), want a derived logger, true", got, ok)
+	}
+
+	if again, _ := sypl.FromContext(ctx); again != got {
+		t.Error("FromContext derived the logger twice")
+	}
+
+	got.Infoln("derived")
+
+	if r := rec.Messages(); len(r) != 1 || r[0].Fields["request_id"] != "r1" {
+		t.Errorf("records = %v, want one carrying request_id", r)
+	}
+
+	// A nil logger does not count as found.
+	ctx = sypl.NewContextWith(context.Background(), nil, nil)
+
+	if got, ok := sypl.FromContext(ctx); ok || got != nil {
+		t.Fatalf("FromContext(ctx with nil logger) = (%v, %v), want (nil, false)", got, ok)
+	}
+}
+
 // Missing, nil-ctx, and nil-logger lookups: (nil, false) - never a panic.
 func TestContext_MissingAndNil(t *testing.T) {
 	if got, ok := sypl.FromContext(context.Background()); ok || got != nil {
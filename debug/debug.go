@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -200,7 +201,9 @@ func newMatchers(componentName, outputName string) *matchers {
 
 // New is the Debug factory.
 func New(componentName, outputName string) *Debug {
-	key := componentName + "\x00" + outputName
+	// Keyed also by the level registry's generation: registering a level
+	// changes the regexes.
+	key := componentName + "\x00" + outputName + "\x00" + strconv.FormatUint(level.Generation(), 10)
 
 	cached, ok := matchersCache.Load(key)
 	if !ok {
@@ -0,0 +1,282 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/output"
+)
+
+//////
+// Dispatch modes.
+//
+// How messages reach outputs - see `SetDispatchMode`:
+//   - `DispatchConcurrent` (default): each message is processed in its own
+//     goroutine, and written concurrently to the outputs receiving it. Calls
+//     made concurrently may reach an output in any order.
+//   - `DispatchOrdered`: messages are processed, and written - output after
+//     output, in registration order - on the caller's goroutine. No
+//     goroutine is spawned: calls made by one goroutine reach every output
+//     in order.
+//   - `DispatchWorkerPool`: a fixed pool of workers - one per output, with a
+//     bounded FIFO queue - writes the messages. Outputs are written
+//     concurrently, each one in its queue's order: messages from concurrent
+//     calls reach every output in the SAME order (per-output FIFO). Workers
+//     are started on the output's first message, shared by the child, and
+//     derived loggers, and stopped by the `Close` of the logger which
+//     created the pool - closing a child, or derived logger leaves them
+//     running. The pool itself is created by `SetDispatchMode` - loggers in
+//     other modes carry none.
+//
+// In all modes, Print-family calls return once every output wrote the
+// message.
+//
+// NOTE: In `DispatchWorkerPool` mode, outputs, and processors MUST NOT log
+// through the logger - they run on the output's worker, which would wait
+// for itself. The error handler (see `SetErrorHandler`) runs on the caller's
+// goroutine, so it may.
+//////
+
+// DispatchMode determines how messages are dispatched to outputs.
+type DispatchMode int
+
+// Available dispatch modes.
+const (
+	// DispatchConcurrent processes messages, and writes them to outputs
+	// concurrently. It's the default mode.
+	DispatchConcurrent DispatchMode = iota
+
+	// DispatchOrdered processes messages, and writes them to outputs
+	// sequentially, on the caller's goroutine.
+	DispatchOrdered
+
+	// DispatchWorkerPool writes messages through one worker per output, with
+	// per-output FIFO ordering.
+	DispatchWorkerPool
+)
+
+// dispatchQueueSize is the capacity of an output worker's queue. A full
+// queue blocks the caller.
+const dispatchQueueSize = 256
+
+// String interface implementation.
+func (d DispatchMode) String() string {
+	switch d {
+	case DispatchConcurrent:
+		return "Concurrent"
+	case DispatchOrdered:
+		return "Ordered"
+	case DispatchWorkerPool:
+		return "WorkerPool"
+	default:
+		return "Unknown"
+	}
+}
+
+// dispatchJob is a write queued to an output's worker.
+type dispatchJob struct {
+	msg message.IMessage
+	err *error
+	wg  *sync.WaitGroup
+}
+
+// dispatcher owns the output workers of the `DispatchWorkerPool` mode.
+type dispatcher struct {
+	// enqueue serializes the queueing of a message's writes, so messages
+	// from concurrent calls are queued - thus written - in the same order on
+	// every output.
+	enqueue sync.Mutex
+
+	// mu guards `workers`. Jobs are queued holding the READ lock, so `stop`
+	// never closes a queue being sent to.
+	mu sync.RWMutex
+
+	// owner is the logger which created the dispatcher - the only one
+	// stopping its workers, see `Close`.
+	owner *Sypl
+
+	// workers are keyed by `workerKey`. running tracks the started workers'
+	// goroutines - swapped by `stop`, so it only waits for the workers it
+	// stopped.
+	workers map[any]chan dispatchJob
+	running *sync.WaitGroup
+}
+
+// outputName keys the workers of non-comparable outputs - see `workerKey`.
+type outputName string
+
+//////
+// Helpers.
+//////
+
+// work writes the queued jobs to `o`, in order, until `jobs` is closed.
+func work(o output.IOutput, jobs <-chan dispatchJob, running *sync.WaitGroup) {
+	defer running.Done()
+
+	for job := range jobs {
+		*job.err = o.Write(job.msg)
+
+		job.wg.Done()
+	}
+}
+
+// workerKey returns the key of `o`'s worker: `o` itself - the pointer
+// identity of the usual pointer outputs - if comparable, otherwise its name.
+// Keying a map by a non-comparable value (e.g. a struct output holding a
+// slice) panics.
+func workerKey(o output.IOutput) any {
+	if reflect.ValueOf(o).Comparable() {
+		return o
+	}
+
+	return outputName(o.GetName())
+}
+
+// missing returns if any of the `writes` outputs has no worker. Call it
+// holding the lock.
+func (d *dispatcher) missing(writes []outputWrite) bool {
+	for _, w := range writes {
+		if _, ok := d.workers[workerKey(w.o)]; !ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// start starts the missing workers of the `writes` outputs.
+func (d *dispatcher) start(writes []outputWrite) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, w := range writes {
+		key := workerKey(w.o)
+
+		if _, ok := d.workers[key]; ok {
+			continue
+		}
+
+		jobs := make(chan dispatchJob, dispatchQueueSize)
+
+		d.workers[key] = jobs
+
+		d.running.Add(1)
+
+		go work(w.o, jobs, d.running)
+	}
+}
+
+// dispatch queues each write to its output's worker - starting it if
+// needed - and waits for all of them. It returns the write errors, indexed
+// as `writes`.
+func (d *dispatcher) dispatch(writes []outputWrite) []error {
+	errs := make([]error, len(writes))
+
+	var wg sync.WaitGroup
+
+	wg.Add(len(writes))
+
+	d.enqueue.Lock()
+
+	d.mu.RLock()
+
+	// `stop` may run in between `start`, and `RLock` - loop until every
+	// worker is there.
+	for d.missing(writes) {
+		d.mu.RUnlock()
+
+		d.start(writes)
+
+		d.mu.RLock()
+	}
+
+	for i, w := range writes {
+		d.workers[workerKey(w.o)] <- dispatchJob{msg: w.msg, err: &errs[i], wg: &wg}
+	}
+
+	d.mu.RUnlock()
+
+	d.enqueue.Unlock()
+
+	wg.Wait()
+
+	return errs
+}
+
+// stop stops the workers, and waits until they drained their queues - so no
+// write is in flight afterwards. Workers are started again on demand.
+func (d *dispatcher) stop() {
+	d.mu.Lock()
+
+	for key, jobs := range d.workers {
+		close(jobs)
+
+		delete(d.workers, key)
+	}
+
+	running := d.running
+
+	d.running = &sync.WaitGroup{}
+
+	d.mu.Unlock()
+
+	running.Wait()
+}
+
+// getDispatch returns the dispatch mode, and the dispatcher.
+func (sypl *Sypl) getDispatch() (DispatchMode, *dispatcher) {
+	sypl.rLock()
+	defer sypl.rUnlock()
+
+	return sypl.dispatchMode, sypl.dispatcher
+}
+
+//////
+// Methods.
+//////
+
+// SetDispatchMode sets how messages are dispatched to outputs - see the
+// dispatch modes notes. Default: `DispatchConcurrent`. Child, and derived
+// loggers inherit it - and share the output workers.
+//
+// NOTE: The `DispatchWorkerPool` workers pool is created on the first switch
+// to that mode, and kept. Loggers derived BEFORE it don't share it: set the
+// mode on the root logger first. Only this logger's `Close` stops the
+// workers.
+func (sypl *Sypl) SetDispatchMode(mode DispatchMode) *Sypl {
+	sypl.lock()
+	defer sypl.unlock()
+
+	sypl.dispatchMode = mode
+
+	if mode == DispatchWorkerPool && sypl.dispatcher == nil {
+		sypl.dispatcher = newDispatcher(sypl)
+	}
+
+	return sypl
+}
+
+// GetDispatchMode returns the dispatch mode - see `SetDispatchMode`.
+func (sypl *Sypl) GetDispatchMode() DispatchMode {
+	mode, _ := sypl.getDispatch()
+
+	return mode
+}
+
+//////
+// Factory.
+//////
+
+// newDispatcher returns a dispatcher with no workers, owned by `owner`.
+func newDispatcher(owner *Sypl) *dispatcher {
+	return &dispatcher{
+		owner:   owner,
+		running: &sync.WaitGroup{},
+		workers: map[any]chan dispatchJob{},
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/safebuffer"
+)
+
+// sequenceWriter records, in a log shared by several outputs, each write
+// prefixed by the output's name.
+type sequenceWriter struct {
+	name string
+	mu   *sync.Mutex
+	log  *[]string
+}
+
+// Write conforms to the `io.Writer` interface.
+func (w sequenceWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	*w.log = append(*w.log, w.name+":"+strings.TrimSuffix(string(p), "\n"))
+
+	return len(p), nil
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+// Write conforms to the `io.Writer` interface.
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+// gatedWriter blocks writes until `gate` is closed, signaling the first one
+// on `started`, and counts the writes made after it was closed - see
+// `closerOutput`.
+type gatedWriter struct {
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+
+	mu          sync.Mutex
+	closed      bool
+	writes      int
+	afterClosed int
+}
+
+// Write conforms to the `io.Writer` interface.
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	g.once.Do(func() { close(g.started) })
+
+	<-g.gate
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.writes++
+
+	if g.closed {
+		g.afterClosed++
+	}
+
+	return len(p), nil
+}
+
+// closerOutput is an output closing its `gatedWriter`.
+type closerOutput struct {
+	output.IOutput
+
+	w *gatedWriter
+}
+
+// Close conforms to the `io.Closer` interface.
+func (o *closerOutput) Close() error {
+	o.w.mu.Lock()
+	defer o.w.mu.Unlock()
+
+	o.w.closed = true
+
+	return nil
+}
+
+// valueOutput is a NON-comparable value-type output - its slice field makes
+// it unusable as a map key.
+type valueOutput struct {
+	output.IOutput
+
+	tags []string
+}
+
+// messages returns `n` message contents, in order.
+func messages(n int) []string {
+	contents := make([]string, 0, n)
+
+	for i := range n {
+		contents = append(contents, fmt.Sprintf("m%03d", i))
+	}
+
+	return contents
+}
+
+func TestDispatchMode_String(t *testing.T) {
+	for mode, want := range map[sypl.DispatchMode]string{
+		sypl.DispatchConcurrent: "Concurrent",
+		sypl.DispatchOrdered:    "Ordered",
+		sypl.DispatchWorkerPool: "WorkerPool",
+		sypl.DispatchMode(42):   "Unknown",
+	} {
+		if got := mode.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSetDispatchMode_Inherited(t *testing.T) {
+	l := sypl.New("dispatch")
+
+	if l.GetDispatchMode() != sypl.DispatchConcurrent {
+		t.Fatalf("GetDispatchMode() = %s by default", l.GetDispatchMode())
+	}
+
+	l.SetDispatchMode(sypl.DispatchWorkerPool)
+
+	if got := l.New("child").GetDispatchMode(); got != sypl.DispatchWorkerPool {
+		t.Errorf("child logger: GetDispatchMode() = %s", got)
+	}
+
+	if got := l.With(nil).GetDispatchMode(); got != sypl.DispatchWorkerPool {
+		t.Errorf("derived logger: GetDispatchMode() = %s", got)
+	}
+}
+
+func TestDispatchOrdered(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+
+	l := sypl.New(
+		"dispatch",
+		output.New("A", level.Trace, sequenceWriter{name: "A", mu: &mu, log: &log}),
+		output.New("B", level.Trace, sequenceWriter{name: "B", mu: &mu, log: &log}),
+	).SetDispatchMode(sypl.DispatchOrdered)
+
+	contents := messages(50)
+
+	// Several messages in a single call are processed in order too.
+	batch := []message.IMessage{}
+
+	for _, ct := range contents {
+		batch = append(batch, l.NewMessage(level.Info, ct))
+	}
+
+	l.PrintMessage(batch...)
+
+	// Outputs are written one after the other, in registration order.
+	want := []string{}
+
+	for _, ct := range contents {
+		want = append(want, "A:"+ct, "B:"+ct)
+	}
+
+	if !equalStrings(log, want) {
+		t.Errorf("got %v, want %v", log, want)
+	}
+}
+
+func TestDispatchWorkerPool_PerOutputFIFO(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	bufA, bufB := &safebuffer.Buffer{}, &safebuffer.Buffer{}
+
+	l := sypl.New(
+		"dispatch",
+		output.New("A", level.Trace, bufA),
+		output.New("B", level.Trace, bufB),
+	).SetDispatchMode(sypl.DispatchWorkerPool)
+
+	defer l.Close()
+
+	// Messages in a single call keep their order.
+	ordered := messages(50)
+
+	batch := []message.IMessage{}
+
+	for _, ct := range ordered {
+		batch = append(batch, l.NewMessage(level.Info, ct+"\n"))
+	}
+
+	l.PrintMessage(batch...)
+
+	for name, buf := range map[string]*safebuffer.Buffer{"A": bufA, "B": bufB} {
+		if got := contents(buf); !equalStrings(got, ordered) {
+			t.Errorf("%s: got %v, want %v", name, got, ordered)
+		}
+	}
+
+	bufA.Reset()
+	bufB.Reset()
+
+	// Concurrent calls - also from a child logger, sharing the workers -
+	// reach both outputs in the SAME order.
+	child := l.New("child")
+
+	var wg sync.WaitGroup
+
+	for g := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			logger := l
+			if g%2 == 1 {
+				logger = child
+			}
+
+			for i := range 100 {
+				logger.Infoln(fmt.Sprintf("g%d-%03d", g, i))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	gotA, gotB := contents(bufA), contents(bufB)
+
+	if len(gotA) != 800 || !equalStrings(gotA, gotB) {
+		t.Fatalf("outputs disagree on the order: %d, and %d lines", len(gotA), len(gotB))
+	}
+
+	// Each goroutine's messages keep their order.
+	last := map[string]string{}
+
+	for _, line := range gotA {
+		g, _, _ := strings.Cut(line, "-")
+
+		if line < last[g] {
+			t.Errorf("%q written after %q", line, last[g])
+		}
+
+		last[g] = line
+	}
+}
+
+func TestDispatchWorkerPool_CloseRestartsWorkers(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	buf := &safebuffer.Buffer{}
+
+	l := sypl.New("dispatch", output.New("Buffer", level.Trace, buf)).
+		SetDispatchMode(sypl.DispatchWorkerPool)
+
+	l.Infoln("before")
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Workers are started again on demand.
+	l.Infoln("after")
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != "before\nafter\n" {
+		t.Errorf("got %q, want %q", got, "before\nafter\n")
+	}
+}
+
+// Close waits for the queued writes before closing the outputs.
+func TestDispatchWorkerPool_CloseDrainsQueues(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	g := &gatedWriter{gate: make(chan struct{}), started: make(chan struct{})}
+
+	o := &closerOutput{IOutput: output.New("Gated", level.Trace, g), w: g}
+
+	l := sypl.New("dispatch", o).SetDispatchMode(sypl.DispatchWorkerPool)
+
+	var wg sync.WaitGroup
+
+	for i := range 3 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			l.Infoln(fmt.Sprint(i))
+		}()
+	}
+
+	// The first write is in flight, the others queued.
+	<-g.started
+
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error)
+
+	go func() { closed <- l.Close() }()
+
+	time.Sleep(20 * time.Millisecond)
+
+	close(g.gate)
+
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.writes != 3 || g.afterClosed != 0 {
+		t.Errorf("writes = %d, after Close = %d, want 3, and 0", g.writes, g.afterClosed)
+	}
+}
+
+func TestDispatchWorkerPool_ErrorHandlerMayLog(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	buf := &safebuffer.Buffer{}
+
+	failing := output.New("Failing", level.Trace, failingWriter{})
+
+	l := sypl.New("dispatch", failing, output.New("Buffer", level.Trace, buf)).
+		SetDispatchMode(sypl.DispatchWorkerPool)
+
+	defer l.Close()
+
+	// The handler runs on the caller's goroutine: logging through the
+	// logger mustn't wait for the failing output's worker.
+	l.SetErrorHandler(func(err error) {
+		l.PrintlnWithOptions(level.Error, "handled", sypl.WithOutputsNames("Buffer"))
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		l.Infoln("content")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging from the error handler deadlocked")
+	}
+
+	if got := buf.String(); got != "content\nhandled\n" {
+		t.Errorf("got %q, want %q", got, "content\nhandled\n")
+	}
+}
+
+// Non-comparable value-type outputs get a worker too - keyed by name.
+func TestDispatchWorkerPool_NonComparableOutput(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	buf := &safebuffer.Buffer{}
+
+	o := valueOutput{IOutput: output.New("Value", level.Trace, buf), tags: []string{"t"}}
+
+	l := sypl.New("dispatch", o).SetDispatchMode(sypl.DispatchWorkerPool)
+
+	l.Infoln("first")
+	l.With(nil).Infoln("second")
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != "first\nsecond\n" {
+		t.Errorf("got %q, want %q", got, "first\nsecond\n")
+	}
+}
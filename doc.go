@@ -97,4 +97,7 @@
 //     aggregate all errors via `errors.Join`. Fatal flushes (best-effort,
 //     time-bounded - a hung sink can't keep the process alive) before
 //     exiting.
+//   - `RecoverAndLog`, `Go`, and `RecoverHandler` replace ad-hoc
+//     `defer recover()` blocks: the panic value, and stack are logged
+//     through the normal pipeline - flushing before an optional re-panic.
 package sypl
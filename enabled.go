@@ -0,0 +1,164 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl
+
+import (
+	"os"
+
+	"github.com/thalesfsp/sypl/v2/debug"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/shared"
+	"github.com/thalesfsp/sypl/v2/status"
+)
+
+//////
+// Level checks.
+//
+// Ask before doing expensive work:
+//   - `Enabled(level)`: would any output write a message at that level?
+//   - `V(n)`: glog-style verbosity - `l.V(2).Infoln(...)` prints only if the
+//     logger's verbosity (see `SetVerbosity`) is at least 2, and Info is
+//     enabled.
+//////
+
+// Verbose prints @ the Info level, if enabled - see `Sypl.V`. Disabled, it's
+// a no-op.
+type Verbose struct {
+	sypl    *Sypl
+	enabled bool
+}
+
+// Enabled returns if `v` prints. Guard expensive arguments with it:
+//
+//	if v := l.V(2); v.Enabled() {
+//		v.Infoln(expensiveDump())
+//	}
+func (v Verbose) Enabled() bool {
+	return v.enabled
+}
+
+// Info prints @ the Info level, if enabled.
+func (v Verbose) Info(args ...any) {
+	if v.enabled {
+		v.sypl.Info(args...)
+	}
+}
+
+// Infof prints @ the Info level, according with the format, if enabled.
+func (v Verbose) Infof(format string, args ...any) {
+	if v.enabled {
+		v.sypl.Infof(format, args...)
+	}
+}
+
+// Infoln prints @ the Info level, also adding a new line to the end, if
+// enabled.
+func (v Verbose) Infoln(args ...any) {
+	if v.enabled {
+		v.sypl.Infoln(args...)
+	}
+}
+
+// Infow prints @ the Info level - key-value pairs become fields - if
+// enabled.
+func (v Verbose) Infow(msg string, keysAndValues ...any) {
+	if v.enabled {
+		v.sypl.Infow(msg, keysAndValues...)
+	}
+}
+
+//////
+// Helpers.
+//////
+
+// outputAdmits returns if `o`'s max level - or its `SYPL_LEVEL` override -
+// admits `l`.
+func outputAdmits(o output.IOutput, componentName string, l level.Level) bool {
+	maxLevel := o.GetMaxLevel()
+
+	if os.Getenv(shared.LevelEnvVar) != "" {
+		if debugLevel, _, ok := debug.New(componentName, o.GetName()).Level(); ok {
+			maxLevel = debugLevel
+		}
+	}
+
+	return maxLevel.Admits(l)
+}
+
+//////
+// Methods.
+//////
+
+// Enabled returns if at least one output would write a message at level
+// `l`: the component isn't filtered out by `SYPL_FILTER`, and an enabled
+// output's max level - or its `SYPL_LEVEL` override - admits `l`. Fatal is
+// always enabled - it exits the process. `None` never is.
+//
+// NOTE: It's conservative: processors, flags (e.g. `flag.Force`), and
+// routing rules depend on the message, so aren't considered.
+func (sypl *Sypl) Enabled(l level.Level) bool {
+	if sypl == nil || l == level.None {
+		return false
+	}
+
+	if l == level.Fatal {
+		return true
+	}
+
+	componentName := sypl.GetName()
+
+	if filter := os.Getenv(shared.FilterEnvVar); filter != "" && !filterMatch(filter, componentName) {
+		return false
+	}
+
+	for _, o := range sypl.GetOutputs() {
+		if o.GetStatus() == status.Enabled && outputAdmits(o, componentName, l) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetVerbosity sets the verbosity `V` checks against. Default: 0 - only
+// `V(0)` prints. Child, and derived loggers inherit it.
+func (sypl *Sypl) SetVerbosity(verbosity int) *Sypl {
+	sypl.lock()
+	defer sypl.unlock()
+
+	sypl.verbosity = verbosity
+
+	return sypl
+}
+
+// GetVerbosity returns the verbosity - see `SetVerbosity`.
+func (sypl *Sypl) GetVerbosity() int {
+	sypl.rLock()
+	defer sypl.rUnlock()
+
+	return sypl.verbosity
+}
+
+// V returns a glog-style verbose printer: it prints @ the Info level if `n`
+// is at most the logger's verbosity - see `SetVerbosity` -, and Info is
+// enabled - see `Enabled`.
+//
+// Example:
+//
+//	l.SetVerbosity(2)
+//
+//	l.V(1).Infoln("shown")
+//	l.V(3).Infoln("hidden")
+func (sypl *Sypl) V(n int) Verbose {
+	if sypl == nil {
+		return Verbose{}
+	}
+
+	return Verbose{
+		sypl:    sypl,
+		enabled: n <= sypl.GetVerbosity() && sypl.Enabled(level.Info),
+	}
+}
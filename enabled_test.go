@@ -0,0 +1,103 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/shared"
+	"github.com/thalesfsp/sypl/v2/status"
+)
+
+func TestEnabled(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	_, o := output.SafeBuffer(level.Info)
+
+	l := sypl.New("enabled", o)
+
+	tests := []struct {
+		name  string
+		setup func()
+		level level.Level
+		want  bool
+	}{
+		{name: "admitted", level: level.Warn, want: true},
+		{name: "at max level", level: level.Info, want: true},
+		{name: "beyond max level", level: level.Debug, want: false},
+		{name: "none", level: level.None, want: false},
+		{name: "SYPL_LEVEL raises", setup: func() { t.Setenv(shared.LevelEnvVar, "trace") }, level: level.Debug, want: true},
+		{name: "SYPL_FILTER filters out", setup: func() { t.Setenv(shared.FilterEnvVar, "other") }, level: level.Error, want: false},
+		{name: "fatal always", level: level.Fatal, want: true},
+		{name: "disabled output", setup: func() { o.SetStatus(status.Disabled) }, level: level.Error, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+
+			if got := l.Enabled(tt.level); got != tt.want {
+				t.Errorf("Enabled(%s) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+
+	var nilLogger *sypl.Sypl
+
+	if nilLogger.Enabled(level.Error) || nilLogger.V(0).Enabled() {
+		t.Error("a nil logger must enable nothing")
+	}
+}
+
+func TestV(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	buf, o := output.SafeBuffer(level.Info)
+
+	l := sypl.New("verbose", o)
+
+	if l.GetVerbosity() != 0 || !l.V(0).Enabled() || l.V(1).Enabled() {
+		t.Fatal("the default verbosity must be 0")
+	}
+
+	if got := l.SetVerbosity(2); got != l {
+		t.Fatal("SetVerbosity must return the same *Sypl for chaining")
+	}
+
+	l.V(1).Infoln("v1")
+	l.V(2).Infof("v%d\n", 2)
+	l.V(3).Infoln("v3")
+
+	// Derived loggers inherit the verbosity.
+	l.With(nil).V(2).Info("derived\n")
+	l.New("child").V(3).Infow("child")
+
+	got := buf.String()
+
+	for _, s := range []string{"v1", "v2", "derived"} {
+		if !strings.Contains(got, s) {
+			t.Errorf("V must print %q, got: %q", s, got)
+		}
+	}
+
+	for _, s := range []string{"v3", "child"} {
+		if strings.Contains(got, s) {
+			t.Errorf("V must not print %q, got: %q", s, got)
+		}
+	}
+
+	// Info itself gated out: V prints nothing.
+	o.SetMaxLevel(level.Warn)
+
+	if l.V(0).Enabled() {
+		t.Error("V must be disabled when Info isn't enabled")
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package es
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// defaultTemplatePriority is above the built-in `logs-*-*` template's
+// (100), so a bootstrapped template wins for the data streams it matches.
+const defaultTemplatePriority = 200
+
+// ErrBootstrapName is returned when `BootstrapConfig.Name` is empty.
+var ErrBootstrapName = errors.New("elasticsearch bootstrap: name is required")
+
+// BootstrapConfig configures `Bootstrap`. Zero values mean defaults.
+type BootstrapConfig struct {
+	// Name of the index template, and - when installed - of the ILM policy.
+	// Required.
+	Name string
+
+	// IndexPatterns the template applies to. Defaults to `Name*` - e.g. the
+	// data stream named `Name`.
+	IndexPatterns []string
+
+	// DataStream makes the template create data streams - see
+	// `DataStreamOutput`, and `BulkDataStreamOutput`.
+	DataStream bool
+
+	// Priority of the template. Defaults to 200 - above the built-in
+	// `logs-*-*` template.
+	Priority int
+
+	// Shards, and Replicas set the number of primary shards, and replicas.
+	// Zero means the server's default.
+	Shards   int
+	Replicas int
+
+	// RolloverMaxAge, and RolloverMaxPrimaryShardSize are the ILM hot-phase
+	// rollover conditions - e.g. "1d", and "50gb".
+	RolloverMaxAge              string
+	RolloverMaxPrimaryShardSize string
+
+	// DeleteAfter is the ILM delete-phase age - e.g. "30d".
+	DeleteAfter string
+
+	// ILMPolicy references an EXISTING ILM policy, instead of installing
+	// one. When empty, a policy named `Name` is installed if any of the
+	// rollover, or delete settings is set.
+	//
+	// NOTE: Rollover works out-of-the-box with data streams; plain indices
+	// also need a rollover alias - see `Settings`.
+	ILMPolicy string
+
+	// Settings are extra index settings, e.g.
+	// {"index.lifecycle.rollover_alias": "logs"}.
+	Settings map[string]any
+
+	// Properties are extra mapping properties, merged into - and overriding
+	// - `ECSMappings`' ones.
+	Properties map[string]any
+}
+
+//////
+// Helpers.
+//////
+
+// ECSMappings returns the index mappings of sypl's ECS documents - see
+// `formatter.ECS`: typed ECS core fields, and string fields - e.g. the
+// message fields - mapped as keywords.
+func ECSMappings() map[string]any {
+	keyword := map[string]any{"type": "keyword"}
+
+	return map[string]any{
+		"dynamic_templates": []any{
+			map[string]any{
+				"strings_as_keyword": map[string]any{
+					"match_mapping_type": "string",
+					"mapping":            map[string]any{"type": "keyword", "ignore_above": 1024},
+				},
+			},
+		},
+		"properties": map[string]any{
+			"@timestamp": map[string]any{"type": "date"},
+			"message":    map[string]any{"type": "text"},
+			"tags":       keyword,
+			"ecs": map[string]any{
+				"properties": map[string]any{"version": keyword},
+			},
+			"log": map[string]any{
+				"properties": map[string]any{"level": keyword, "logger": keyword},
+			},
+		},
+	}
+}
+
+// ilmPolicyBody returns the ILM policy of `cfg` - nil if there's nothing to
+// install.
+func ilmPolicyBody(cfg BootstrapConfig) map[string]any {
+	phases := map[string]any{}
+
+	rollover := map[string]any{}
+
+	if cfg.RolloverMaxAge != "" {
+		rollover["max_age"] = cfg.RolloverMaxAge
+	}
+
+	if cfg.RolloverMaxPrimaryShardSize != "" {
+		rollover["max_primary_shard_size"] = cfg.RolloverMaxPrimaryShardSize
+	}
+
+	if len(rollover) > 0 {
+		phases["hot"] = map[string]any{
+			"actions": map[string]any{"rollover": rollover},
+		}
+	}
+
+	if cfg.DeleteAfter != "" {
+		phases["delete"] = map[string]any{
+			"min_age": cfg.DeleteAfter,
+			"actions": map[string]any{"delete": map[string]any{}},
+		}
+	}
+
+	if len(phases) == 0 {
+		return nil
+	}
+
+	return map[string]any{
+		"policy": map[string]any{
+			"phases": phases,
+			"_meta":  map[string]any{"managed_by": "sypl"},
+		},
+	}
+}
+
+// indexTemplateBody returns the index template of `cfg`, referencing the
+// `policy` ILM policy - if any.
+func indexTemplateBody(cfg BootstrapConfig, policy string) map[string]any {
+	patterns := cfg.IndexPatterns
+	if len(patterns) == 0 {
+		patterns = []string{cfg.Name + "*"}
+	}
+
+	priority := cfg.Priority
+	if priority == 0 {
+		priority = defaultTemplatePriority
+	}
+
+	settings := map[string]any{}
+
+	if cfg.Shards > 0 {
+		settings["index.number_of_shards"] = cfg.Shards
+	}
+
+	if cfg.Replicas > 0 {
+		settings["index.number_of_replicas"] = cfg.Replicas
+	}
+
+	if policy != "" {
+		settings["index.lifecycle.name"] = policy
+	}
+
+	maps.Copy(settings, cfg.Settings)
+
+	mappings := ECSMappings()
+
+	maps.Copy(mappings["properties"].(map[string]any), cfg.Properties)
+
+	body := map[string]any{
+		"index_patterns": patterns,
+		"priority":       priority,
+		"template": map[string]any{
+			"settings": settings,
+			"mappings": mappings,
+		},
+		"_meta": map[string]any{"managed_by": "sypl"},
+	}
+
+	if cfg.DataStream {
+		body["data_stream"] = map[string]any{}
+	}
+
+	return body
+}
+
+// doBootstrapRequest performs `req`, turning an error response into an
+// error.
+func doBootstrapRequest(ctx context.Context, client *elasticsearch.Client, what string, req esapi.Request) error {
+	res, err := req.Do(ctx, client)
+	if err != nil {
+		return fmt.Errorf("elasticsearch bootstrap: failed installing the %s: %w", what, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		errMsg, err := parseResponseBodyError(res)
+		if err != nil {
+			return fmt.Errorf("elasticsearch bootstrap: failed installing the %s: %s", what, res.Status())
+		}
+
+		return fmt.Errorf("elasticsearch bootstrap: failed installing the %s: %s", what, errMsg)
+	}
+
+	return nil
+}
+
+//////
+// Bootstrap.
+//////
+
+// Bootstrap installs - or updates: it's idempotent - the ILM policy, and
+// the index template with the ECS mappings (see `ECSMappings`) sypl's
+// documents need. Call it once at startup, before writing - e.g. before
+// `DataStreamOutput`, or `BulkDataStreamOutput`.
+//
+// The ILM policy is only installed when a rollover, or delete setting is
+// set - clusters without ILM (e.g. serverless) just leave them empty.
+//
+// NOTE: Unlike the output factories, it returns errors - it never calls
+// log.Fatalf.
+func Bootstrap(ctx context.Context, esConfig Config, cfg BootstrapConfig) error {
+	if cfg.Name == "" {
+		return ErrBootstrapName
+	}
+
+	client, err := elasticsearch.NewClient(esConfig)
+	if err != nil {
+		return fmt.Errorf("elasticsearch bootstrap: failed creating the client: %w", err)
+	}
+
+	policy := cfg.ILMPolicy
+
+	if policyBody := ilmPolicyBody(cfg); policy == "" && policyBody != nil {
+		policy = cfg.Name
+
+		encoded, err := json.Marshal(policyBody)
+		if err != nil {
+			return fmt.Errorf("elasticsearch bootstrap: failed encoding the ILM policy: %w", err)
+		}
+
+		if err := doBootstrapRequest(ctx, client, "ILM policy", esapi.ILMPutLifecycleRequest{
+			Policy: policy,
+			Body:   bytes.NewReader(encoded),
+		}); err != nil {
+			return err
+		}
+	}
+
+	encoded, err := json.Marshal(indexTemplateBody(cfg, policy))
+	if err != nil {
+		return fmt.Errorf("elasticsearch bootstrap: failed encoding the index template: %w", err)
+	}
+
+	return doBootstrapRequest(ctx, client, "index template", esapi.IndicesPutIndexTemplateRequest{
+		Name: cfg.Name,
+		Body: bytes.NewReader(encoded),
+	})
+}
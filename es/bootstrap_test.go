@@ -0,0 +1,202 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+//////
+// Test helpers.
+//////
+
+// newBootstrapES starts a fake Elasticsearch recording bootstrap requests,
+// answering `status` - with an error body when it isn't 200.
+func newBootstrapES(t *testing.T, status int) (Config, *requestRecorder) {
+	t.Helper()
+
+	recorder := &requestRecorder{}
+
+	srv := newFakeES(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		recorder.add(capturedRequest{Method: r.Method, Path: r.URL.Path, Body: string(body)})
+
+		w.WriteHeader(status)
+
+		if status != http.StatusOK {
+			fmt.Fprint(w, `{"error":{"type":"illegal_argument_exception","reason":"bad template"},"status":400}`)
+
+			return
+		}
+
+		fmt.Fprint(w, `{"acknowledged":true}`)
+	})
+
+	return Config{Addresses: []string{srv.URL}}, recorder
+}
+
+// decodeBody decodes a captured JSON request body.
+func decodeBody(t *testing.T, r capturedRequest) map[string]any {
+	t.Helper()
+
+	var body map[string]any
+
+	if err := json.Unmarshal([]byte(r.Body), &body); err != nil {
+		t.Fatalf("%s %s: invalid JSON body: %v: %s", r.Method, r.Path, err, r.Body)
+	}
+
+	return body
+}
+
+//////
+// Bootstrap.
+//////
+
+func TestBootstrap_ILMPolicyAndDataStreamTemplate(t *testing.T) {
+	cfg, recorder := newBootstrapES(t, http.StatusOK)
+
+	if err := Bootstrap(context.Background(), cfg, BootstrapConfig{
+		Name:                        "logs-app",
+		DataStream:                  true,
+		Replicas:                    1,
+		RolloverMaxAge:              "1d",
+		RolloverMaxPrimaryShardSize: "50gb",
+		DeleteAfter:                 "30d",
+		Properties:                  map[string]any{"tenant": map[string]any{"type": "keyword"}},
+	}); err != nil {
+		t.Fatalf("Bootstrap() error = %v, want nil", err)
+	}
+
+	requests := recorder.all()
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %+v", requests)
+	}
+
+	// The ILM policy first - the template references it.
+	if requests[0].Method != http.MethodPut || requests[0].Path != "/_ilm/policy/logs-app" {
+		t.Errorf("First request = %s %s, want PUT /_ilm/policy/logs-app", requests[0].Method, requests[0].Path)
+	}
+
+	policy := decodeBody(t, requests[0])["policy"].(map[string]any)["phases"].(map[string]any)
+
+	rollover := policy["hot"].(map[string]any)["actions"].(map[string]any)["rollover"].(map[string]any)
+
+	if rollover["max_age"] != "1d" || rollover["max_primary_shard_size"] != "50gb" {
+		t.Errorf("rollover = %v", rollover)
+	}
+
+	if policy["delete"].(map[string]any)["min_age"] != "30d" {
+		t.Errorf("delete phase = %v", policy["delete"])
+	}
+
+	if requests[1].Method != http.MethodPut || requests[1].Path != "/_index_template/logs-app" {
+		t.Errorf("Second request = %s %s, want PUT /_index_template/logs-app", requests[1].Method, requests[1].Path)
+	}
+
+	template := decodeBody(t, requests[1])
+
+	if _, ok := template["data_stream"]; !ok {
+		t.Error("Template lacks data_stream")
+	}
+
+	if patterns := template["index_patterns"].([]any); len(patterns) != 1 || patterns[0] != "logs-app*" {
+		t.Errorf("index_patterns = %v, want [logs-app*]", patterns)
+	}
+
+	if template["priority"] != float64(defaultTemplatePriority) {
+		t.Errorf("priority = %v, want %d", template["priority"], defaultTemplatePriority)
+	}
+
+	inner := template["template"].(map[string]any)
+
+	settings := inner["settings"].(map[string]any)
+
+	if settings["index.lifecycle.name"] != "logs-app" || settings["index.number_of_replicas"] != float64(1) {
+		t.Errorf("settings = %v", settings)
+	}
+
+	properties := inner["mappings"].(map[string]any)["properties"].(map[string]any)
+
+	for _, key := range []string{"@timestamp", "message", "log", "ecs", "tags", "tenant"} {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("Mappings lack %q: %v", key, properties)
+		}
+	}
+}
+
+func TestBootstrap_NoILMSettingsSkipsThePolicy(t *testing.T) {
+	cfg, recorder := newBootstrapES(t, http.StatusOK)
+
+	if err := Bootstrap(context.Background(), cfg, BootstrapConfig{
+		Name:          "sypl",
+		IndexPatterns: []string{"sypl-*"},
+	}); err != nil {
+		t.Fatalf("Bootstrap() error = %v, want nil", err)
+	}
+
+	requests := recorder.all()
+
+	if len(requests) != 1 || requests[0].Path != "/_index_template/sypl" {
+		t.Fatalf("requests = %+v, want only the index template", requests)
+	}
+
+	template := decodeBody(t, requests[0])
+
+	if _, ok := template["data_stream"]; ok {
+		t.Error("Template shouldn't create data streams")
+	}
+
+	settings := template["template"].(map[string]any)["settings"].(map[string]any)
+
+	if _, ok := settings["index.lifecycle.name"]; ok {
+		t.Errorf("settings = %v, want no ILM policy", settings)
+	}
+}
+
+func TestBootstrap_ExistingILMPolicy(t *testing.T) {
+	cfg, recorder := newBootstrapES(t, http.StatusOK)
+
+	if err := Bootstrap(context.Background(), cfg, BootstrapConfig{
+		Name:        "logs-app",
+		ILMPolicy:   "logs",
+		DeleteAfter: "30d",
+	}); err != nil {
+		t.Fatalf("Bootstrap() error = %v, want nil", err)
+	}
+
+	requests := recorder.all()
+
+	if len(requests) != 1 {
+		t.Fatalf("requests = %+v, want only the index template", requests)
+	}
+
+	settings := decodeBody(t, requests[0])["template"].(map[string]any)["settings"].(map[string]any)
+
+	if settings["index.lifecycle.name"] != "logs" {
+		t.Errorf("settings = %v, want the existing policy referenced", settings)
+	}
+}
+
+func TestBootstrap_Errors(t *testing.T) {
+	if err := Bootstrap(context.Background(), Config{}, BootstrapConfig{}); !errors.Is(err, ErrBootstrapName) {
+		t.Errorf("Bootstrap() error = %v, want ErrBootstrapName", err)
+	}
+
+	cfg, _ := newBootstrapES(t, http.StatusBadRequest)
+
+	err := Bootstrap(context.Background(), cfg, BootstrapConfig{Name: "logs-app"})
+	if err == nil || !strings.Contains(err.Error(), "index template: bad template") {
+		t.Errorf("Bootstrap() error = %v, want the server's reason", err)
+	}
+}
@@ -76,6 +76,14 @@ func BulkWithCloseTimeout(d time.Duration) BulkOption {
 	}
 }
 
+// BulkWithDataStream makes the client append to data streams: items use the
+// "create" action - data streams reject "index". See `BulkDataStreamOutput`.
+func BulkWithDataStream() BulkOption {
+	return func(es *ElasticSearchBulk) {
+		es.action = OpTypeCreate
+	}
+}
+
 // ElasticSearchBulk `Output` definition: it batches documents through
 // esutil's BulkIndexer instead of one request per document.
 type ElasticSearchBulk struct {
@@ -89,6 +97,9 @@ type ElasticSearchBulk struct {
 	// evaluated at the index time.
 	DynamicIndex DynamicIndexFunc
 
+	// action is the _bulk item action - "index" when empty.
+	action string
+
 	// Indexer configuration.
 	closeTimeout  time.Duration
 	flushBytes    int
@@ -96,6 +107,26 @@ type ElasticSearchBulk struct {
 	numWorkers    int
 	onError       func(error)
 
+	// Dead-letter queue, and retries - see `BulkWithDeadLetterFunc`,
+	// `BulkWithDeadLetterFile`, and `BulkWithRetry`.
+	deadLetter      func(DeadLetter) error
+	deadLetterClose func() error
+	deadLetterPath  string
+	retryBackoff    time.Duration
+	retryMax        int
+
+	// counters are the per-item counters - see `Stats`.
+	counters bulkCounters
+
+	// retries tracks the pending retries. stopRetries - closed by Close -
+	// cuts their backoffs short.
+	retries     sync.WaitGroup
+	stopRetries chan struct{}
+
+	// closeMu serializes Close - it waits for the pending retries, which
+	// need `mu`, so `mu` can't be held throughout.
+	closeMu sync.Mutex
+
 	// mu guards the indexer - swapped on Flush - and the closed flag.
 	// closeErr records the first Close outcome - making Close idempotent:
 	// subsequent calls return it without re-closing.
@@ -119,76 +150,13 @@ type ElasticSearchBulk struct {
 // through the error callback, and returned as the write error - so one bad
 // payload can't corrupt the whole batch.
 func (es *ElasticSearchBulk) Write(data []byte) (int, error) {
-	parsedData, err := parseResponseBody(bytes.NewReader(data))
+	item, err := es.newItem(data)
 	if err != nil {
 		return 0, err
 	}
 
-	// Trailing linebreaks - e.g. restored by Sypl's pipeline after
-	// formatting - would inject blank lines into the NDJSON body,
-	// malformed for the _bulk protocol.
-	//
-	// CLONED: the indexer retains, and reads the payload AFTER this call
-	// returns, but the `io.Writer` contract forbids retaining `p` - the
-	// builtin logger reuses its write buffer, so aliasing it is a data
-	// race, and corrupts in-flight documents.
-	doc := bytes.Clone(bytes.TrimRight(data, "\r\n"))
-
-	// _bulk is NDJSON: each item's source must be a SINGLE line - interior
-	// linebreaks (e.g. a pretty-printed document) corrupt the WHOLE stream.
-	// Multi-line payloads are compacted; non-compactable ones are rejected
-	// through the error callback - enqueuing them would poison every item
-	// in the batch. Single-line payloads skip this entirely: no extra
-	// allocation on the fast path.
-	if bytes.ContainsAny(doc, "\r\n") {
-		var compacted bytes.Buffer
-
-		if err := json.Compact(&compacted, doc); err != nil {
-			err = fmt.Errorf(
-				"refusing to enqueue a multi-line, non-compactable document - it would corrupt the NDJSON _bulk stream: %w",
-				err,
-			)
-
-			if es.onError != nil {
-				es.onError(err)
-			}
-
-			return 0, err
-		}
-
-		doc = compacted.Bytes()
-	}
-
-	item := esutil.BulkIndexerItem{
-		Action:    "index",
-		Body:      bytes.NewReader(doc),
-		Index:     es.DynamicIndex(),
-		OnFailure: es.reportItemFailure,
-	}
-
-	// Check if parsedData has an id.
-	//
-	// NOTE: A non-string id is skipped - not an error. A logging library
-	// must never panic the host application on an odd payload.
-	if id, ok := parsedData["id"].(string); ok {
-		item.DocumentID = id
-	}
-
-	es.mu.Lock()
-	defer es.mu.Unlock()
-
-	if es.closed {
-		return 0, ErrBulkClosed
-	}
-
-	// Guard against an uninitialized indexer - never panic the host
-	// application.
-	if es.indexer == nil {
-		return 0, errors.New("elasticsearch bulk indexer isn't initialized")
-	}
-
-	if err := es.indexer.Add(context.Background(), item); err != nil {
-		return 0, fmt.Errorf("failed adding document to the bulk indexer: %w", err)
+	if err := es.add(item); err != nil {
+		return 0, err
 	}
 
 	return len(data), nil
@@ -243,33 +211,59 @@ func (es *ElasticSearchBulk) Flush() error {
 }
 
 // Close drains the bulk indexer - waiting, bounded by the close timeout
-// (default: 30s, see `BulkWithCloseTimeout`) - and shuts it down. It's
-// idempotent: subsequent calls return the FIRST call's outcome without
-// re-closing - parity with the async output. Writes after Close return
-// `ErrBulkClosed` - never panic.
+// (default: 30s, see `BulkWithCloseTimeout`) - and shuts it down. Pending
+// retries are cut short, and dead-lettered - see `BulkWithRetry` - then the
+// dead-letter file, if any, is closed. It's idempotent: subsequent calls
+// return the FIRST call's outcome without re-closing - parity with the
+// async output. Writes after Close return `ErrBulkClosed` - never panic.
 func (es *ElasticSearchBulk) Close() error {
+	es.closeMu.Lock()
+	defer es.closeMu.Unlock()
+
 	es.mu.Lock()
-	defer es.mu.Unlock()
 
 	if es.closed {
+		es.mu.Unlock()
+
 		return es.closeErr
 	}
 
 	es.closed = true
 
+	if es.stopRetries != nil {
+		close(es.stopRetries)
+	}
+
+	errs := []error{}
+
 	// The indexer may be nil - a previous rebuild failed - nothing to
 	// drain then.
-	if es.indexer == nil {
-		return nil
+	if es.indexer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), es.closeTimeout)
+
+		if err := es.indexer.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed closing the bulk indexer: %w", err))
+		}
+
+		cancel()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), es.closeTimeout)
-	defer cancel()
+	es.mu.Unlock()
+
+	// Retries - including the ones scheduled by the drain - need `mu`: they
+	// see the closed flag, and dead-letter their document.
+	es.retries.Wait()
 
-	if err := es.indexer.Close(ctx); err != nil {
-		es.closeErr = fmt.Errorf("failed closing the bulk indexer: %w", err)
+	if es.deadLetterClose != nil {
+		if err := es.deadLetterClose(); err != nil {
+			errs = append(errs, fmt.Errorf("failed closing the dead-letter queue: %w", err))
+		}
 	}
 
+	es.mu.Lock()
+	es.closeErr = errors.Join(errs...)
+	es.mu.Unlock()
+
 	return es.closeErr
 }
 
@@ -277,6 +271,126 @@ func (es *ElasticSearchBulk) Close() error {
 // Helpers.
 //////
 
+// writeBatch enqueues the documents, in order - each one like `Write` does -
+// holding the lock once. Errors are aggregated via `errors.Join`: one bad
+// document doesn't prevent its siblings.
+func (es *ElasticSearchBulk) writeBatch(docs [][]byte) error {
+	errs := []error{}
+
+	items := make([]esutil.BulkIndexerItem, 0, len(docs))
+
+	for _, doc := range docs {
+		item, err := es.newItem(doc)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) > 0 {
+		errs = append(errs, es.add(items...))
+	}
+
+	return errors.Join(errs...)
+}
+
+// newItem builds the bulk indexer item of a document - see `Write`.
+func (es *ElasticSearchBulk) newItem(data []byte) (esutil.BulkIndexerItem, error) {
+	parsedData, err := parseResponseBody(bytes.NewReader(data))
+	if err != nil {
+		return esutil.BulkIndexerItem{}, err
+	}
+
+	// Trailing linebreaks - e.g. restored by Sypl's pipeline after
+	// formatting - would inject blank lines into the NDJSON body,
+	// malformed for the _bulk protocol.
+	//
+	// CLONED: the indexer retains, and reads the payload AFTER this call
+	// returns, but the `io.Writer` contract forbids retaining `p` - the
+	// builtin logger reuses its write buffer, so aliasing it is a data
+	// race, and corrupts in-flight documents.
+	doc := bytes.Clone(bytes.TrimRight(data, "\r\n"))
+
+	// _bulk is NDJSON: each item's source must be a SINGLE line - interior
+	// linebreaks (e.g. a pretty-printed document) corrupt the WHOLE stream.
+	// Multi-line payloads are compacted; non-compactable ones are rejected
+	// through the error callback - enqueuing them would poison every item
+	// in the batch. Single-line payloads skip this entirely: no extra
+	// allocation on the fast path.
+	if bytes.ContainsAny(doc, "\r\n") {
+		var compacted bytes.Buffer
+
+		if err := json.Compact(&compacted, doc); err != nil {
+			err = fmt.Errorf(
+				"refusing to enqueue a multi-line, non-compactable document - it would corrupt the NDJSON _bulk stream: %w",
+				err,
+			)
+
+			if es.onError != nil {
+				es.onError(err)
+			}
+
+			return esutil.BulkIndexerItem{}, err
+		}
+
+		doc = compacted.Bytes()
+	}
+
+	// Data streams only accept "create" - see `BulkWithDataStream`.
+	action := es.action
+	if action == "" {
+		action = "index"
+	}
+
+	item := esutil.BulkIndexerItem{
+		Action:    action,
+		Body:      bytes.NewReader(doc),
+		Index:     es.DynamicIndex(),
+		OnFailure: es.itemFailureHandler(doc, 0),
+		OnSuccess: es.onItemSuccess,
+	}
+
+	// Check if parsedData has an id.
+	//
+	// NOTE: A non-string id is skipped - not an error. A logging library
+	// must never panic the host application on an odd payload.
+	if id, ok := parsedData["id"].(string); ok {
+		item.DocumentID = id
+	}
+
+	return item, nil
+}
+
+// add adds the items to the bulk indexer, in order, holding the lock once.
+// After Close, it returns `ErrBulkClosed`.
+func (es *ElasticSearchBulk) add(items ...esutil.BulkIndexerItem) error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if es.closed {
+		return ErrBulkClosed
+	}
+
+	// Guard against an uninitialized indexer - never panic the host
+	// application.
+	if es.indexer == nil {
+		return errors.New("elasticsearch bulk indexer isn't initialized")
+	}
+
+	errs := []error{}
+
+	for _, item := range items {
+		if err := es.indexer.Add(context.Background(), item); err != nil {
+			errs = append(errs, fmt.Errorf("failed adding document to the bulk indexer: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // reportItemFailure delivers a per-item indexing failure to the error
 // callback, if any.
 func (es *ElasticSearchBulk) reportItemFailure(
@@ -315,8 +429,20 @@ func (es *ElasticSearchBulk) reportItemFailure(
 // RUNTIME through Flush's indexer swap, where a kill-switch is
 // unacceptable.
 func (es *ElasticSearchBulk) newIndexer() (esutil.BulkIndexer, error) {
+	client := es.Client
+
+	// With a dead-letter queue, or retries, request failures become item
+	// failures - see `bulkTransport`. It wraps `es.Client`, so its product
+	// check, and node retries still apply. Otherwise, a request failure is
+	// reported once - per flush - as it always was.
+	if client != nil && (es.deadLetter != nil || es.retryMax > 0) {
+		client = &elasticsearch.Client{
+			BaseClient: elasticsearch.BaseClient{Transport: bulkTransport{Transport: es.Client}},
+		}
+	}
+
 	return newBulkIndexer(esutil.BulkIndexerConfig{
-		Client:        es.Client,
+		Client:        client,
 		FlushBytes:    es.flushBytes,
 		FlushInterval: es.flushInterval,
 		NumWorkers:    es.numWorkers,
@@ -382,12 +508,21 @@ func NewBulkWithDynamicIndex(
 		DynamicIndex: base.DynamicIndex,
 
 		closeTimeout: defaultBulkCloseTimeout,
+		stopRetries:  make(chan struct{}),
 	}
 
 	for _, opt := range opts {
 		opt(es)
 	}
 
+	// Opened per client - see `BulkWithDeadLetterFile`.
+	if es.deadLetterPath != "" {
+		f := &deadLetterFile{path: es.deadLetterPath}
+
+		es.deadLetter = f.write
+		es.deadLetterClose = f.close
+	}
+
 	es.indexer = es.mustNewIndexer()
 
 	return es
@@ -5,10 +5,14 @@
 package es
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
+	"sync"
 
 	"github.com/thalesfsp/sypl/v2/formatter"
 	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
 	"github.com/thalesfsp/sypl/v2/output"
 	"github.com/thalesfsp/sypl/v2/processor"
 )
@@ -18,11 +22,46 @@ import (
 //////
 
 // bulkOutput is an ElasticSearch-bulk-backed `output.IOutput` carrying the
-// Flush, and Close capabilities.
+// Flush, Close, and `output.IBatchOutput` capabilities.
 type bulkOutput struct {
 	*output.Proxy
 
 	es *ElasticSearchBulk
+
+	// mu serializes writes, so only `WriteBatch`'s documents are collected
+	// by the writer.
+	mu     sync.Mutex
+	writer *docCollector
+}
+
+// docCollector is the bulk output's writer: it enqueues each document into
+// the bulk indexer - or, while `collecting`, collects them for a single
+// enqueue. See `bulkOutput.WriteBatch`.
+type docCollector struct {
+	es *ElasticSearchBulk
+
+	collecting bool
+	docs       [][]byte
+}
+
+// Write conforms to the `io.Writer` interface.
+func (c *docCollector) Write(p []byte) (int, error) {
+	if !c.collecting {
+		return c.es.Write(p)
+	}
+
+	// CLONED: the builtin logger reuses its write buffer.
+	c.docs = append(c.docs, bytes.Clone(p))
+
+	return len(p), nil
+}
+
+// Write processes, formats, and enqueues the message - see `output.IOutput`.
+func (o *bulkOutput) Write(m message.IMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.Proxy.Write(m)
 }
 
 // Flush drains the bulk indexer - see `ElasticSearchBulk.Flush`. After
@@ -31,12 +70,44 @@ func (o *bulkOutput) Flush() error {
 	return o.es.Flush()
 }
 
+// Stats returns the per-item counters - see `ElasticSearchBulk.Stats`.
+func (o *bulkOutput) Stats() BulkStats {
+	return o.es.Stats()
+}
+
 // Close drains, and shuts the bulk indexer down. It's idempotent. Writes
 // after Close return `ErrBulkClosed`.
 func (o *bulkOutput) Close() error {
 	return o.es.Close()
 }
 
+// WriteBatch processes each message like `Write` does, then enqueues the
+// documents, in order, in one pass - holding the indexer's lock once. The
+// indexer batches them into _bulk requests. Errors are aggregated via
+// `errors.Join`.
+func (o *bulkOutput) WriteBatch(messages []message.IMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.writer.collecting = true
+
+	for _, m := range messages {
+		// Writes to the collector never fail.
+		_ = o.Proxy.Write(m)
+	}
+
+	docs := o.writer.docs
+
+	o.writer.collecting = false
+	o.writer.docs = nil
+
+	if len(docs) == 0 {
+		return nil
+	}
+
+	return o.es.writeBatch(docs)
+}
+
 //////
 // Factory.
 //////
@@ -58,9 +129,11 @@ func bulkOutputFactory(
 	// NOTE: INLINE JSON - not JSONPretty like the sync factory: the _bulk
 	// NDJSON protocol requires each document on a single line; an indented
 	// document would corrupt the batched payload.
-	inner := output.New(outputName, maxLevel, es, processors...).SetFormatter(formatter.JSON())
+	writer := &docCollector{es: es}
+
+	inner := output.New(outputName, maxLevel, writer, processors...).SetFormatter(formatter.JSON())
 
-	o := &bulkOutput{es: es}
+	o := &bulkOutput{es: es, writer: writer}
 
 	o.Proxy = output.NewProxy(inner, o)
 
@@ -75,9 +148,9 @@ func bulkOutputFactory(
 // that writes to ElasticSearch batching documents into _bulk requests -
 // the high-throughput sibling of `Output`.
 //
-// Capabilities: `Flush() error` (drains the indexer), and idempotent
-// `Close() error`. Indexing is asynchronous: per-item failures are
-// delivered through `BulkWithOnError`.
+// Capabilities: `Flush() error` (drains the indexer), idempotent
+// `Close() error`, and `output.IBatchOutput`. Indexing is asynchronous:
+// per-item failures are delivered through `BulkWithOnError`.
 //
 // NOTE: Formerly `output.ElasticSearchBulk`.
 // NOTE: By default, data is JSON-formatted.
@@ -100,14 +173,49 @@ func BulkOutput(
 	)
 }
 
+// BulkDataStreamOutput is a built-in `output` - named
+// `ElasticSearchBulkDataStream`, that appends to the `dataStream`
+// ElasticSearch data stream batching documents into _bulk requests - the
+// high-throughput sibling of `DataStreamOutput`. `BulkWithDataStream` is
+// implied.
+//
+// Capabilities: `Flush() error` (drains the indexer), idempotent
+// `Close() error`, and `output.IBatchOutput`. Indexing is asynchronous:
+// per-item failures are delivered through `BulkWithOnError`.
+//
+// NOTE: Data is ECS-formatted (see `formatter.ECS`) - data streams require
+// the `@timestamp` field.
+// NOTE: The data stream needs a matching index template - install one with
+// `Bootstrap`, or rely on the cluster's built-in `logs-*-*` template.
+func BulkDataStreamOutput(
+	dataStream string,
+	esConfig Config,
+	maxLevel level.Level,
+	bulkOpts []BulkOption,
+	processors ...processor.IProcessor,
+) output.IOutput {
+	o := bulkOutputFactory(
+		"ElasticSearchBulkDataStream",
+		func() string { return dataStream },
+		esConfig,
+		maxLevel,
+		append(slices.Clone(bulkOpts), BulkWithDataStream()),
+		processors...,
+	)
+
+	o.SetFormatter(formatter.ECS())
+
+	return o
+}
+
 // BulkOutputWithDynamicIndex is a built-in `output` - named
 // `ElasticSearchBulkWithDynamicIndex-{index}` that writes to ElasticSearch
 // batching documents into _bulk requests. It allows to define a function
 // that returns the index name to be used, evaluated at the index time.
 //
-// Capabilities: `Flush() error` (drains the indexer), and idempotent
-// `Close() error`. Indexing is asynchronous: per-item failures are
-// delivered through `BulkWithOnError`.
+// Capabilities: `Flush() error` (drains the indexer), idempotent
+// `Close() error`, and `output.IBatchOutput`. Indexing is asynchronous:
+// per-item failures are delivered through `BulkWithOnError`.
 //
 // NOTE: Formerly `output.ElasticSearchBulkWithDynamicIndex`.
 // NOTE: By default, data is JSON-formatted.
@@ -216,6 +216,50 @@ func TestElasticSearchBulkOutput_LevelGating(t *testing.T) {
 	}
 }
 
+func TestElasticSearchBulkOutput_WriteBatch(t *testing.T) {
+	srv, recorder := newFakeBulkESServer(t)
+
+	o := BulkOutput("idx-bulk", Config{
+		Addresses: []string{srv.URL},
+	}, level.Info, singleWorker())
+
+	b, ok := o.(output.IBatchOutput)
+	if !ok {
+		t.Fatal("The bulk output should implement output.IBatchOutput")
+	}
+
+	// Each message is a document - level gating applies per message.
+	if err := b.WriteBatch([]message.IMessage{
+		message.New(level.Info, "first"),
+		message.New(level.Debug, "muted"),
+		message.New(level.Info, "second"),
+	}); err != nil {
+		t.Fatalf("WriteBatch() error = %v, want nil", err)
+	}
+
+	if err := closeOutput(t, o); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	items := recorder.items()
+
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 indexed items, got %d", len(items))
+	}
+
+	for i, want := range []string{"first", "second"} {
+		parsed := map[string]interface{}{}
+
+		if err := json.Unmarshal([]byte(items[i][1]), &parsed); err != nil {
+			t.Fatalf("Indexed body isn't valid JSON: %v", err)
+		}
+
+		if parsed["message"] != want {
+			t.Errorf("Item %d message = %v, want %q", i, parsed["message"], want)
+		}
+	}
+}
+
 //////
 // ElasticSearchBulkWithDynamicIndex.
 //////
@@ -311,3 +355,60 @@ func TestElasticSearchBulkOutput_NoProcessorsAliasing(t *testing.T) {
 		t.Error("The output aliased the caller's backing array - the sentinel was overwritten")
 	}
 }
+
+func TestElasticSearchBulkDataStreamOutput(t *testing.T) {
+	srv, recorder := newFakeBulkESServer(t)
+
+	o := BulkDataStreamOutput("logs-app-default", Config{
+		Addresses: []string{srv.URL},
+	}, level.Trace, singleWorker())
+
+	if o.GetName() != "ElasticSearchBulkDataStream" {
+		t.Errorf("GetName() = %q, want %q", o.GetName(), "ElasticSearchBulkDataStream")
+	}
+
+	if o.GetFormatter() == nil || o.GetFormatter().GetName() != "ECS" {
+		t.Errorf("GetFormatter() = %v, want the ECS formatter", o.GetFormatter())
+	}
+
+	m := message.New(level.Warn, "stream message")
+
+	m.SetComponentName("api")
+
+	if err := o.Write(m); err != nil {
+		t.Fatalf("Write() error = %v, want nil", err)
+	}
+
+	if err := flushOutput(t, o); err != nil {
+		t.Fatalf("Flush() error = %v, want nil", err)
+	}
+
+	items := recorder.items()
+
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(items))
+	}
+
+	// Data streams only accept the "create" action.
+	if !strings.HasPrefix(items[0][0], `{"create":`) || !strings.Contains(items[0][0], `"_index":"logs-app-default"`) {
+		t.Errorf("Action line = %q, want a create into logs-app-default", items[0][0])
+	}
+
+	parsed := map[string]interface{}{}
+
+	if err := json.Unmarshal([]byte(items[0][1]), &parsed); err != nil {
+		t.Fatalf("Indexed body isn't valid JSON: %v", err)
+	}
+
+	if parsed["message"] != "stream message" || parsed["log.level"] != "warn" || parsed["log.logger"] != "api" {
+		t.Errorf("Indexed body = %v, want an ECS document", parsed)
+	}
+
+	if _, ok := parsed["@timestamp"]; !ok {
+		t.Error("Indexed body lacks @timestamp - required by data streams")
+	}
+
+	if err := closeOutput(t, o); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+}
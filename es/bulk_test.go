@@ -555,6 +555,42 @@ func TestElasticSearchBulk_Write_RejectsNonCompactableMultiLine(t *testing.T) {
 	}
 }
 
+// A batch is enqueued in one pass: a rejected document is reported, but
+// doesn't prevent its siblings.
+func TestElasticSearchBulk_WriteBatch(t *testing.T) {
+	es, recorder := newTestBulk(t, nil)
+
+	err := es.writeBatch([][]byte{
+		[]byte(`{"message":"first"}`),
+		[]byte("{\"message\":\"bad\"}\ngarbage trailing line"),
+		[]byte(`{"message":"second"}` + "\n"),
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "corrupt the NDJSON") {
+		t.Fatalf("writeBatch() error = %v, want the NDJSON-safety rejection", err)
+	}
+
+	if err := es.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	requests := recorder.all()
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 _bulk request, got %d", len(requests))
+	}
+
+	lines := strings.Split(strings.TrimSuffix(requests[0].Body, "\n"), "\n")
+
+	if len(lines) != 4 || lines[1] != `{"message":"first"}` || lines[3] != `{"message":"second"}` {
+		t.Errorf("Body = %q, want the first, and second documents", requests[0].Body)
+	}
+
+	if err := es.writeBatch([][]byte{[]byte(`{"message":"late"}`)}); !errors.Is(err, ErrBulkClosed) {
+		t.Errorf("writeBatch() after Close = %v, want ErrBulkClosed", err)
+	}
+}
+
 func TestElasticSearchBulk_Write_SingleLineFastPathNotCompacted(t *testing.T) {
 	es, recorder := newTestBulk(t, nil)
 
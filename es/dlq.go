@@ -0,0 +1,469 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package es
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/output"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// DeadLetterTag tags the messages `BulkWithDeadLetterOutput` writes.
+const DeadLetterTag = "dead-letter"
+
+// BulkRequestErrorType is the `DeadLetter.ErrorType` of documents whose
+// whole _bulk request failed - a transport error (`DeadLetter.Status` 0), a
+// 429, or a 5xx.
+const BulkRequestErrorType = "bulk_request_failure"
+
+// Dead-letter message field names - see `BulkWithDeadLetterOutput`.
+const (
+	DeadLetterFieldDocumentID = "dlq.documentID"
+	DeadLetterFieldError      = "dlq.error"
+	DeadLetterFieldErrorType  = "dlq.errorType"
+	DeadLetterFieldIndex      = "dlq.index"
+	DeadLetterFieldRetries    = "dlq.retries"
+	DeadLetterFieldStatus     = "dlq.status"
+)
+
+// DeadLetter is a document the bulk client gave up on - rejected by
+// ElasticSearch (e.g. a mapping conflict), or still failing transiently
+// after the retries - with the failure details.
+type DeadLetter struct {
+	// Timestamp of the failure.
+	Timestamp time.Time `json:"@timestamp"`
+
+	// Index the document was bound to.
+	Index string `json:"index"`
+
+	// DocumentID of the document - if any.
+	DocumentID string `json:"documentID,omitempty"`
+
+	// Status is the item's HTTP status - 0 for transport failures.
+	Status int `json:"status,omitempty"`
+
+	// ErrorType is ElasticSearch's error type, e.g.
+	// "mapper_parsing_exception".
+	ErrorType string `json:"errorType,omitempty"`
+
+	// Error is ElasticSearch's error reason, or the transport error.
+	Error string `json:"error"`
+
+	// Retries is the number of retries performed.
+	Retries int `json:"retries"`
+
+	// Document is the rejected document - as sent.
+	Document json.RawMessage `json:"document"`
+}
+
+// BulkStats are the bulk client's per-item counters - see
+// `ElasticSearchBulk.Stats`.
+type BulkStats struct {
+	// Indexed documents.
+	Indexed uint64
+
+	// Failed documents - permanently: rejected, or out of retries.
+	Failed uint64
+
+	// Retried is the number of retries of transiently failing - transport
+	// error, 429, or 5xx - documents.
+	Retried uint64
+
+	// DeadLettered is the number of failed documents written to the
+	// dead-letter queue.
+	DeadLettered uint64
+
+	// DeadLetterErrors is the number of failed documents the dead-letter
+	// queue couldn't take - those are lost.
+	DeadLetterErrors uint64
+}
+
+// bulkCounters are the concurrency-safe `BulkStats`.
+type bulkCounters struct {
+	indexed          atomic.Uint64
+	failed           atomic.Uint64
+	retried          atomic.Uint64
+	deadLettered     atomic.Uint64
+	deadLetterErrors atomic.Uint64
+}
+
+// deadLetterFile is an append-only NDJSON dead-letter file - opened on the
+// first dead letter.
+type deadLetterFile struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// write appends `dl` as a JSON line.
+func (f *deadLetterFile) write(dl DeadLetter) error {
+	line, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+
+		f.file = file
+	}
+
+	_, err = f.file.Write(append(line, '\n'))
+
+	return err
+}
+
+// close closes the file - if opened.
+func (f *deadLetterFile) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+
+	f.file = nil
+
+	return err
+}
+
+//////
+// Options.
+//////
+
+// BulkWithDeadLetterFunc routes documents the client gives up on - see
+// `DeadLetter` - to `fn`, instead of losing them. A failing `fn` is
+// reported through `BulkWithOnError`, and counted in
+// `BulkStats.DeadLetterErrors`. It may be called concurrently.
+func BulkWithDeadLetterFunc(fn func(DeadLetter) error) BulkOption {
+	return func(es *ElasticSearchBulk) {
+		es.deadLetter = fn
+		es.deadLetterPath = ""
+	}
+}
+
+// BulkWithDeadLetterOutput routes documents the client gives up on to `o` -
+// e.g. a file, or another cluster's output. Each is written as an Error
+// message, tagged `DeadLetterTag`, whose content is the document, and whose
+// `dlq.*` fields carry the failure details.
+//
+// NOTE: `o` is written to directly - not through a Sypl logger - so it's
+// the caller's responsibility to flush, and close it.
+func BulkWithDeadLetterOutput(o output.IOutput) BulkOption {
+	return BulkWithDeadLetterFunc(func(dl DeadLetter) error {
+		m := message.New(level.Error, string(dl.Document))
+
+		m.AddTags(DeadLetterTag)
+		m.SetFields(fields.Fields{
+			DeadLetterFieldDocumentID: dl.DocumentID,
+			DeadLetterFieldError:      dl.Error,
+			DeadLetterFieldErrorType:  dl.ErrorType,
+			DeadLetterFieldIndex:      dl.Index,
+			DeadLetterFieldRetries:    dl.Retries,
+			DeadLetterFieldStatus:     dl.Status,
+		})
+
+		return o.Write(m)
+	})
+}
+
+// BulkWithDeadLetterFile appends documents the client gives up on to the
+// `path` NDJSON file - one `DeadLetter` per line, ready to be replayed. The
+// file is created on the first dead letter, and closed by Close.
+//
+// NOTE: Each client opens the file on its own - reusing the option across
+// clients shares no state.
+func BulkWithDeadLetterFile(path string) BulkOption {
+	return func(es *ElasticSearchBulk) {
+		es.deadLetter = nil
+		es.deadLetterPath = path
+	}
+}
+
+// BulkWithRetry retries documents failing transiently - on a transport
+// error (e.g. a connection reset during a node restart), throttled by
+// ElasticSearch (429, e.g. es_rejected_execution_exception), or a 5xx -
+// up to `maxRetries` times, waiting `backoff`, doubled after each retry.
+// Other failures are permanent: they go straight to the dead-letter queue,
+// if any. Defaults to no retries.
+//
+// NOTE: Retries are re-enqueued after the backoff: a Flush may return
+// before they land - the next one delivers them. Close cuts the backoffs
+// short, dead-lettering the pending retries.
+func BulkWithRetry(maxRetries int, backoff time.Duration) BulkOption {
+	return func(es *ElasticSearchBulk) {
+		es.retryMax = maxRetries
+		es.retryBackoff = backoff
+	}
+}
+
+//////
+// Methods.
+//////
+
+// Stats returns the per-item counters.
+func (es *ElasticSearchBulk) Stats() BulkStats {
+	return BulkStats{
+		Indexed:          es.counters.indexed.Load(),
+		Failed:           es.counters.failed.Load(),
+		Retried:          es.counters.retried.Load(),
+		DeadLettered:     es.counters.deadLettered.Load(),
+		DeadLetterErrors: es.counters.deadLetterErrors.Load(),
+	}
+}
+
+//////
+// Helpers.
+//////
+
+// onItemSuccess counts indexed documents.
+func (es *ElasticSearchBulk) onItemSuccess(
+	context.Context,
+	esutil.BulkIndexerItem,
+	esutil.BulkIndexerResponseItem,
+) {
+	es.counters.indexed.Add(1)
+}
+
+// bulkTransport turns _bulk request failures - transport errors, and
+// 429/5xx statuses - into per-item failures: esutil only reports those to
+// the indexer's error callback, losing the items. This way, they go
+// through the retries, and the dead-letter queue. Only installed when one
+// of those is configured - see `newIndexer`.
+type bulkTransport struct {
+	esapi.Transport
+}
+
+// Perform implements `esapi.Transport`.
+func (t bulkTransport) Perform(req *http.Request) (*http.Response, error) {
+	// Set by the wrapped client - not this empty one.
+	req.Header.Del(elasticsearch.HeaderClientMeta)
+
+	if req.Body == nil || !strings.HasSuffix(req.URL.Path, "/_bulk") {
+		return t.Transport.Perform(req)
+	}
+
+	// Buffered: the items count is needed on failure.
+	body, err := io.ReadAll(req.Body)
+
+	req.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	res, err := t.Transport.Perform(req)
+
+	status := 0
+
+	var reason string
+
+	switch {
+	case err != nil:
+		reason = err.Error()
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+		status = res.StatusCode
+
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+
+		res.Body.Close()
+
+		reason = fmt.Sprintf("status: %d: %s", status, bytes.TrimSpace(msg))
+	default:
+		return res, nil
+	}
+
+	// NDJSON: one action line + one source line per item.
+	return failedBulkResponse(req, bytes.Count(body, []byte{'\n'})/2, status, reason), nil
+}
+
+// failedBulkResponse returns a _bulk response failing each of the `items`
+// with `status`, and `reason` - see `BulkRequestErrorType`.
+func failedBulkResponse(req *http.Request, items, status int, reason string) *http.Response {
+	//nolint:errchkjson // Marshaling maps of strings, and ints never fails.
+	item, _ := json.Marshal(map[string]any{
+		"index": map[string]any{
+			"status": status,
+			"error":  map[string]any{"type": BulkRequestErrorType, "reason": reason},
+		},
+	})
+
+	body := fmt.Sprintf(
+		`{"took":0,"errors":true,"items":[%s]}`,
+		strings.TrimSuffix(strings.Repeat(string(item)+",", items), ","),
+	)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type":      []string{"application/json"},
+			"X-Elastic-Product": []string{"Elasticsearch"},
+		},
+		Body:    io.NopCloser(strings.NewReader(body)),
+		Request: req,
+	}
+}
+
+// isTransientFailure determines whether an item failure is worth a retry:
+// a transport error, throttling (429), or a server error (5xx) - of the
+// item, or of its whole _bulk request.
+func isTransientFailure(res esutil.BulkIndexerResponseItem, err error) bool {
+	return err != nil ||
+		res.Error.Type == BulkRequestErrorType ||
+		res.Status == http.StatusTooManyRequests ||
+		res.Status >= http.StatusInternalServerError
+}
+
+// itemFailureHandler returns the failure callback of the `doc` item, at its
+// `attempt`-th retry: transient failures are retried, others - and those
+// out of retries - reported, and dead-lettered.
+func (es *ElasticSearchBulk) itemFailureHandler(
+	doc []byte,
+	attempt int,
+) func(context.Context, esutil.BulkIndexerItem, esutil.BulkIndexerResponseItem, error) {
+	return func(
+		ctx context.Context,
+		item esutil.BulkIndexerItem,
+		res esutil.BulkIndexerResponseItem,
+		err error,
+	) {
+		if isTransientFailure(res, err) && attempt < es.retryMax {
+			es.scheduleRetry(item, doc, attempt, res.Status)
+
+			return
+		}
+
+		es.counters.failed.Add(1)
+
+		es.reportItemFailure(ctx, item, res, err)
+
+		dl := DeadLetter{
+			Timestamp:  time.Now(),
+			Index:      item.Index,
+			DocumentID: item.DocumentID,
+			Status:     res.Status,
+			ErrorType:  res.Error.Type,
+			Error:      res.Error.Reason,
+			Retries:    attempt,
+			Document:   doc,
+		}
+
+		if err != nil {
+			dl.Error = err.Error()
+		}
+
+		es.writeDeadLetter(dl)
+	}
+}
+
+// scheduleRetry re-enqueues the `doc` item - which failed with `status`, 0
+// for transport errors - after the backoff.
+func (es *ElasticSearchBulk) scheduleRetry(item esutil.BulkIndexerItem, doc []byte, attempt, status int) {
+	es.counters.retried.Add(1)
+
+	es.retries.Add(1)
+
+	go func() {
+		defer es.retries.Done()
+
+		select {
+		case <-time.After(es.retryBackoff << attempt):
+		case <-es.stopRetries:
+		}
+
+		// A FRESH item: esutil caches the action line in unexported fields
+		// - re-adding the failed item would duplicate it.
+		item = esutil.BulkIndexerItem{
+			Action:     item.Action,
+			Body:       bytes.NewReader(doc),
+			DocumentID: item.DocumentID,
+			Index:      item.Index,
+			OnFailure:  es.itemFailureHandler(doc, attempt+1),
+			OnSuccess:  es.onItemSuccess,
+		}
+
+		es.mu.Lock()
+
+		err := ErrBulkClosed
+
+		if !es.closed && es.indexer != nil {
+			err = es.indexer.Add(context.Background(), item)
+		}
+
+		es.mu.Unlock()
+
+		if err != nil {
+			es.counters.failed.Add(1)
+
+			es.writeDeadLetter(DeadLetter{
+				Timestamp:  time.Now(),
+				Index:      item.Index,
+				DocumentID: item.DocumentID,
+				Status:     status,
+				Error:      fmt.Sprintf("failed retrying: %s", err),
+				Retries:    attempt,
+				Document:   doc,
+			})
+		}
+	}()
+}
+
+// writeDeadLetter hands `dl` to the dead-letter queue - if any.
+func (es *ElasticSearchBulk) writeDeadLetter(dl DeadLetter) {
+	if es.deadLetter == nil {
+		return
+	}
+
+	if err := es.deadLetter(dl); err != nil {
+		es.counters.deadLetterErrors.Add(1)
+
+		if es.onError != nil {
+			es.onError(fmt.Errorf(
+				`failed dead-lettering document (index: "%s", id: "%s"): %w`,
+				dl.Index,
+				dl.DocumentID,
+				err,
+			))
+		}
+
+		return
+	}
+
+	es.counters.deadLettered.Add(1)
+}
@@ -0,0 +1,438 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package es
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+)
+
+//////
+// Test helpers.
+//////
+
+// rejectingBulkHandler answers every _bulk request reporting each item with
+// `status` - 201 is a success, anything else a failure.
+func rejectingBulkHandler(status func() int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		// NDJSON: one action line + one source line per item.
+		lines := strings.Count(string(body), "\n")
+
+		items := []string{}
+
+		for range lines / 2 {
+			s := status()
+
+			if s == http.StatusCreated {
+				items = append(items, `{"index":{"_index":"idx","status":201,"result":"created"}}`)
+
+				continue
+			}
+
+			items = append(items, fmt.Sprintf(
+				`{"index":{"_index":"idx","status":%d,"error":{"type":"some_exception","reason":"status %d"}}}`,
+				s, s,
+			))
+		}
+
+		fmt.Fprintf(w, `{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	}
+}
+
+// deadLetterCollector concurrency-safely accumulates dead letters.
+type deadLetterCollector struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+func (c *deadLetterCollector) add(dl DeadLetter) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.letters = append(c.letters, dl)
+
+	return nil
+}
+
+func (c *deadLetterCollector) all() []DeadLetter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]DeadLetter, len(c.letters))
+	copy(out, c.letters)
+
+	return out
+}
+
+// waitFor polls `cond` for up to 5s.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+//////
+// Dead-letter queue.
+//////
+
+func TestElasticSearchBulk_DeadLetter_PermanentFailure(t *testing.T) {
+	collector := &deadLetterCollector{}
+
+	calls := atomic.Int32{}
+
+	// First item created, second rejected.
+	es, _ := newTestBulk(t, rejectingBulkHandler(func() int {
+		if calls.Add(1) == 1 {
+			return http.StatusCreated
+		}
+
+		return http.StatusBadRequest
+	}), BulkWithDeadLetterFunc(collector.add), BulkWithRetry(3, time.Millisecond))
+
+	_, _ = es.Write([]byte(`{"message":"good"}`))
+	_, _ = es.Write([]byte(`{"id":"doc-2","message":"bad"}`))
+
+	if err := es.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	letters := collector.all()
+
+	if len(letters) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %+v", letters)
+	}
+
+	dl := letters[0]
+
+	// Permanent failures aren't retried.
+	if dl.Status != http.StatusBadRequest || dl.Retries != 0 || dl.ErrorType != "some_exception" ||
+		dl.Error != "status 400" || dl.DocumentID != "doc-2" || dl.Index != bulkTestIndex {
+		t.Errorf("dead letter = %+v", dl)
+	}
+
+	if string(dl.Document) != `{"id":"doc-2","message":"bad"}` {
+		t.Errorf("dead letter document = %s", dl.Document)
+	}
+
+	want := BulkStats{Indexed: 1, Failed: 1, DeadLettered: 1}
+
+	if got := es.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestElasticSearchBulk_Retry_ThrottledThenIndexed(t *testing.T) {
+	collector := &deadLetterCollector{}
+
+	calls := atomic.Int32{}
+
+	es, _ := newTestBulk(t, rejectingBulkHandler(func() int {
+		if calls.Add(1) == 1 {
+			return http.StatusTooManyRequests
+		}
+
+		return http.StatusCreated
+	}), BulkWithDeadLetterFunc(collector.add), BulkWithRetry(3, time.Millisecond))
+
+	defer es.Close()
+
+	_, _ = es.Write([]byte(`{"message":"throttled"}`))
+
+	if err := es.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v, want nil", err)
+	}
+
+	// The retry lands after the backoff - the next Flush delivers it.
+	waitFor(t, func() bool {
+		_ = es.Flush()
+
+		return es.Stats().Indexed == 1
+	})
+
+	if got := es.Stats(); got.Retried != 1 || got.Failed != 0 {
+		t.Errorf("Stats() = %+v, want 1 retry, no failures", got)
+	}
+
+	if letters := collector.all(); len(letters) != 0 {
+		t.Errorf("dead letters = %+v, want none", letters)
+	}
+}
+
+func TestElasticSearchBulk_Retry_ServerErrorThenIndexed(t *testing.T) {
+	collector := &deadLetterCollector{}
+
+	calls := atomic.Int32{}
+
+	es, _ := newTestBulk(t, rejectingBulkHandler(func() int {
+		if calls.Add(1) == 1 {
+			return http.StatusServiceUnavailable
+		}
+
+		return http.StatusCreated
+	}), BulkWithDeadLetterFunc(collector.add), BulkWithRetry(3, time.Millisecond))
+
+	defer es.Close()
+
+	_, _ = es.Write([]byte(`{"message":"unavailable"}`))
+
+	waitFor(t, func() bool {
+		_ = es.Flush()
+
+		return es.Stats().Indexed == 1
+	})
+
+	if got := es.Stats(); got.Retried != 1 || got.Failed != 0 || len(collector.all()) != 0 {
+		t.Errorf("Stats() = %+v, dead letters = %+v - want 1 retry, no failures", got, collector.all())
+	}
+}
+
+// Transport errors - e.g. a connection reset during a node restart - are
+// retried, not dead-lettered right away.
+func TestElasticSearchBulk_Retry_TransportErrorThenIndexed(t *testing.T) {
+	collector := &deadLetterCollector{}
+
+	calls := atomic.Int32{}
+
+	ok := rejectingBulkHandler(func() int { return http.StatusCreated })
+
+	// Outlasts the ElasticSearch client's own transport retries.
+	es, _ := newTestBulk(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 8 {
+			panic(http.ErrAbortHandler)
+		}
+
+		ok(w, r)
+	}, BulkWithDeadLetterFunc(collector.add), BulkWithRetry(10, time.Millisecond))
+
+	defer es.Close()
+
+	_, _ = es.Write([]byte(`{"message":"reset"}`))
+
+	waitFor(t, func() bool {
+		_ = es.Flush()
+
+		return es.Stats().Indexed == 1
+	})
+
+	if got := es.Stats(); got.Retried == 0 || got.Failed != 0 || len(collector.all()) != 0 {
+		t.Errorf("Stats() = %+v, dead letters = %+v - want retries, no failures", got, collector.all())
+	}
+}
+
+func TestElasticSearchBulk_Retry_Exhausted(t *testing.T) {
+	collector := &deadLetterCollector{}
+
+	es, _ := newTestBulk(t, rejectingBulkHandler(func() int {
+		return http.StatusTooManyRequests
+	}), BulkWithDeadLetterFunc(collector.add), BulkWithRetry(2, time.Millisecond))
+
+	defer es.Close()
+
+	_, _ = es.Write([]byte(`{"message":"throttled"}`))
+
+	waitFor(t, func() bool {
+		_ = es.Flush()
+
+		return len(collector.all()) == 1
+	})
+
+	if dl := collector.all()[0]; dl.Status != http.StatusTooManyRequests || dl.Retries != 2 {
+		t.Errorf("dead letter = %+v, want a 429 after 2 retries", dl)
+	}
+
+	if got := es.Stats(); got.Retried != 2 || got.Failed != 1 || got.DeadLettered != 1 {
+		t.Errorf("Stats() = %+v", got)
+	}
+}
+
+func TestElasticSearchBulk_Retry_CloseCutsBackoffShort(t *testing.T) {
+	collector := &deadLetterCollector{}
+
+	es, _ := newTestBulk(t, rejectingBulkHandler(func() int {
+		return http.StatusTooManyRequests
+	}), BulkWithDeadLetterFunc(collector.add), BulkWithRetry(1, time.Hour))
+
+	_, _ = es.Write([]byte(`{"message":"throttled"}`))
+
+	done := make(chan error, 1)
+
+	go func() { done <- es.Close() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Close() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() waited for the retry backoff")
+	}
+
+	letters := collector.all()
+
+	if len(letters) != 1 || !strings.Contains(letters[0].Error, ErrBulkClosed.Error()) {
+		t.Errorf("dead letters = %+v, want the pending retry", letters)
+	}
+}
+
+func TestElasticSearchBulk_DeadLetterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dlq.ndjson")
+
+	es, _ := newTestBulk(t, rejectingBulkHandler(func() int {
+		return http.StatusBadRequest
+	}), BulkWithDeadLetterFile(path))
+
+	_, _ = es.Write([]byte(`{"message":"one"}`))
+	_, _ = es.Write([]byte(`{"message":"two"}`))
+
+	if err := es.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("dead-letter file = %q, want 2 lines", content)
+	}
+
+	var dl DeadLetter
+
+	if err := json.Unmarshal([]byte(lines[1]), &dl); err != nil {
+		t.Fatalf("invalid dead-letter line: %v: %s", err, lines[1])
+	}
+
+	if string(dl.Document) != `{"message":"two"}` || dl.Error != "status 400" {
+		t.Errorf("dead letter = %+v", dl)
+	}
+}
+
+// The option is reusable: each client opens, and closes the file on its
+// own.
+func TestElasticSearchBulk_DeadLetterFile_ReusedOption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dlq.ndjson")
+
+	opt := BulkWithDeadLetterFile(path)
+
+	rejecting := rejectingBulkHandler(func() int { return http.StatusBadRequest })
+
+	first, _ := newTestBulk(t, rejecting, opt)
+	second, _ := newTestBulk(t, rejecting, opt)
+
+	if first.deadLetterClose == nil || second.deadLetterClose == nil {
+		t.Fatal("dead-letter file not set up")
+	}
+
+	_, _ = first.Write([]byte(`{"message":"one"}`))
+	_, _ = second.Write([]byte(`{"message":"two"}`))
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = second.Write([]byte(`{"message":"three"}`))
+
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(string(content), "\n"); n != 3 {
+		t.Errorf("dead-letter file = %q, want 3 lines", content)
+	}
+}
+
+func TestElasticSearchBulk_DeadLetterOutput(t *testing.T) {
+	rec, dlq := output.Recorder(level.Trace)
+
+	es, _ := newTestBulk(t, rejectingBulkHandler(func() int {
+		return http.StatusBadRequest
+	}), BulkWithDeadLetterOutput(dlq))
+
+	_, _ = es.Write([]byte(`{"message":"lost?"}`))
+
+	if err := es.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	records := rec.Messages()
+
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 dead-letter message, got %d", len(records))
+	}
+
+	r := records[0]
+
+	if r.Level != level.Error || r.OriginalContent != `{"message":"lost?"}` ||
+		r.Fields[DeadLetterFieldStatus] != http.StatusBadRequest || r.Fields[DeadLetterFieldError] != "status 400" {
+		t.Errorf("dead-letter message = %+v", r)
+	}
+
+	if len(r.Tags) != 1 || r.Tags[0] != DeadLetterTag {
+		t.Errorf("tags = %v, want [%s]", r.Tags, DeadLetterTag)
+	}
+}
+
+func TestElasticSearchBulk_DeadLetterFailureIsCounted(t *testing.T) {
+	collector := &bulkErrorCollector{}
+
+	es, _ := newTestBulk(t, rejectingBulkHandler(func() int {
+		return http.StatusBadRequest
+	}),
+		BulkWithOnError(collector.callback()),
+		BulkWithDeadLetterFunc(func(DeadLetter) error { return fmt.Errorf("disk full") }),
+	)
+
+	_, _ = es.Write([]byte(`{"message":"lost"}`))
+
+	if err := es.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	if got := es.Stats(); got.DeadLetterErrors != 1 || got.DeadLettered != 0 {
+		t.Errorf("Stats() = %+v", got)
+	}
+
+	found := false
+
+	for _, err := range collector.all() {
+		if strings.Contains(err.Error(), "disk full") {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("callback errors = %v, want the dead-letter failure", collector.all())
+	}
+}
@@ -13,6 +13,16 @@
 // esutil's BulkIndexer - see `NewBulk`, and `NewBulkWithDynamicIndex`).
 // The bulk client indexes asynchronously: failures are delivered through
 // `BulkWithOnError`, and `Flush`/`Close` drain it.
+// - Dead-letter queue: documents the bulk client gives up on - permanently
+// rejected, or still failing transiently (transport error, 429, or 5xx -
+// of the document, or of its whole _bulk request) after `BulkWithRetry` -
+// are routed to a fallback output, NDJSON file, or func (see
+// `BulkWithDeadLetterOutput`, `BulkWithDeadLetterFile`,
+// `BulkWithDeadLetterFunc`), and counted - see `ElasticSearchBulk.Stats`.
+// - Data streams: `NewDataStream`, and `BulkWithDataStream` append documents
+// with op_type create - the ILM-friendly alternative to plain indices.
+// - `Bootstrap` installs - idempotently - an ILM policy, and an index
+// template with the ECS mappings (`ECSMappings`) of sypl's documents.
 //
 // Output factories (formerly in the `output` package):
 // - `Output` (was `output.ElasticSearch`)
@@ -21,6 +31,10 @@
 // - `BulkOutput` (was `output.ElasticSearchBulk`)
 // - `BulkOutputWithDynamicIndex` (was `output.ElasticSearchBulkWithDynamicIndex`)
 //
+// Data stream output factories - ECS-formatted, see `formatter.ECS`:
+// - `DataStreamOutput`
+// - `BulkDataStreamOutput`
+//
 // NOTE: It's the caller's responsibility to create the index, define its
-// mapping, and settings.
+// mapping, and settings - or to call `Bootstrap`.
 package es
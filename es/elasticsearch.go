@@ -19,6 +19,10 @@ import (
 
 var contextTimeout = 5 * time.Second
 
+// OpTypeCreate is the op_type data streams require: documents are
+// append-only - created, never updated.
+const OpTypeCreate = "create"
+
 // DynamicIndexFunc is a function which defines the name of the index, and
 // evaluated at the index time.
 type DynamicIndexFunc func() string
@@ -37,6 +41,10 @@ type ElasticSearch struct {
 	// DynamicIndex is a function which defines the name of the index, and
 	// evaluated at the index time.
 	DynamicIndex DynamicIndexFunc
+
+	// OpType is the index request op_type. Empty means the server's default
+	// ("index"). Data streams require `OpTypeCreate` - see `NewDataStream`.
+	OpType string
 }
 
 //////
@@ -52,8 +60,9 @@ func (es *ElasticSearch) Write(data []byte) (int, error) {
 
 	// Set up the request object.
 	req := esapi.IndexRequest{
-		Body:  bytes.NewReader(data),
-		Index: es.DynamicIndex(),
+		Body:   bytes.NewReader(data),
+		Index:  es.DynamicIndex(),
+		OpType: es.OpType,
 	}
 
 	// Check if parsedData as an id.
@@ -121,6 +130,23 @@ func New(
 	return NewWithDynamicIndex(func() string { return indexName }, esConfig)
 }
 
+// NewDataStream returns a new `ElasticSearch` client appending to the
+// `dataStream` data stream - documents are created (`OpTypeCreate`), never
+// updated.
+//
+// NOTE: Data streams require an `@timestamp` field - e.g. the ECS formatter,
+// see `DataStreamOutput` - and a matching index template - see `Bootstrap`.
+func NewDataStream(
+	dataStream string,
+	esConfig Config,
+) *ElasticSearch {
+	es := New(dataStream, esConfig)
+
+	es.OpType = OpTypeCreate
+
+	return es
+}
+
 // NewWithDynamicIndex returns a new `ElasticSearch` client. It allows to define
 // is a function which defines the name of the index, and evaluated at the index
 // time.
@@ -516,3 +516,38 @@ func TestElasticSearch_Write_UnexpectedSuccessResponses(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDataStream_WritesWithOpTypeCreate(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+		ops   []string
+	)
+
+	srv := newFakeES(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		paths = append(paths, r.URL.Path)
+		ops = append(ops, r.URL.Query().Get("op_type"))
+
+		fmt.Fprint(w, `{"result":"created"}`)
+	})
+
+	es := NewDataStream("logs-app-default", Config{Addresses: []string{srv.URL}})
+
+	if es.OpType != OpTypeCreate {
+		t.Errorf("OpType = %q, want %q", es.OpType, OpTypeCreate)
+	}
+
+	if _, err := es.Write([]byte(`{"@timestamp":"2026-07-13T00:00:00.000Z","message":"hello"}`)); err != nil {
+		t.Fatalf("Write() error = %v, want nil", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(paths) != 1 || paths[0] != "/logs-app-default/_doc" || ops[0] != OpTypeCreate {
+		t.Errorf("requests = %v (op_type %v), want one create into logs-app-default", paths, ops)
+	}
+}
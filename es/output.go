@@ -95,6 +95,28 @@ func Output(
 	)
 }
 
+// DataStreamOutput is a built-in `output` - named `ElasticSearchDataStream`,
+// that appends to the `dataStream` ElasticSearch data stream (op_type
+// create) - the modern, ILM-friendly alternative to plain indices.
+//
+// NOTE: Data is ECS-formatted (see `formatter.ECS`) - data streams require
+// the `@timestamp` field.
+// NOTE: The data stream needs a matching index template - install one with
+// `Bootstrap`, or rely on the cluster's built-in `logs-*-*` template.
+func DataStreamOutput(
+	dataStream string,
+	esConfig Config,
+	maxLevel level.Level,
+	processors ...processor.IProcessor,
+) output.IOutput {
+	return output.New(
+		"ElasticSearchDataStream",
+		maxLevel,
+		NewDataStream(dataStream, esConfig),
+		processors...,
+	).SetFormatter(formatter.ECS())
+}
+
 // OutputWithTagMap is a built-in `output` - named
 // `ElasticSearchWithTagMap-{tag}` that writes to ElasticSearch. It allows to
 // define a map of tags and indexes. The index name is a function which defines
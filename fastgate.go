@@ -84,10 +84,10 @@ func (sypl *Sypl) fastGated(l level.Level) bool {
 			continue
 		}
 
-		if ml := o.GetMaxLevel(); ml > maxEnabledLevel {
+		if ml := o.GetMaxLevel(); ml.Verbosity() > maxEnabledLevel.Verbosity() {
 			maxEnabledLevel = ml
 		}
 	}
 
-	return l > maxEnabledLevel
+	return !maxEnabledLevel.Admits(l)
 }
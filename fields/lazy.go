@@ -0,0 +1,122 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package fields
+
+import (
+	"fmt"
+	"sync"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// maxResolveDepth bounds the resolution of a `Valuer` returning `Valuer`s.
+const maxResolveDepth = 100
+
+// Valuer is a deferred field value - like slog's `LogValuer`. Sypl resolves
+// it only when an output actually writes the message - so expensive values
+// cost nothing for filtered-out levels.
+//
+// NOTE: Processors run before the resolution - they see the `Valuer`.
+type Valuer interface {
+	// FieldValue returns the resolved value. It may return another
+	// `Valuer`.
+	FieldValue() any
+}
+
+// lazyValue is a memoized `Valuer` computed by a function - see `Lazy`.
+type lazyValue struct {
+	once  sync.Once
+	fn    func() any
+	value any
+}
+
+// FieldValue implements the `Valuer` interface. `fn` runs once - all outputs
+// writing the message share the value.
+func (l *lazyValue) FieldValue() any {
+	l.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				l.value = fmt.Errorf("fields: Lazy value panicked: %v", r)
+			}
+		}()
+
+		l.value = l.fn()
+	})
+
+	return l.value
+}
+
+//////
+// Helpers.
+//////
+
+// resolve resolves `v` - recovering from a panicking `Valuer`, which yields
+// an error describing the panic.
+func resolve(v Valuer) (resolved any) {
+	defer func() {
+		if r := recover(); r != nil {
+			resolved = fmt.Errorf("fields: Valuer panicked: %v", r)
+		}
+	}()
+
+	var value any = v
+
+	for i := 0; i < maxResolveDepth; i++ {
+		valuer, ok := value.(Valuer)
+		if !ok {
+			return value
+		}
+
+		value = valuer.FieldValue()
+	}
+
+	return value
+}
+
+// Lazy returns a deferred field value computed by `fn` - see `Valuer`. `fn`
+// runs at most once, even if several outputs write the message.
+//
+// Example:
+//
+//	l.PrintWithOptions(level.Debug, "state\n", sypl.WithField(
+//		"dump", fields.Lazy(func() any { return expensiveDump() }),
+//	))
+func Lazy(fn func() any) Valuer {
+	return &lazyValue{fn: fn}
+}
+
+// Resolve returns `f` with its `Valuer`s resolved. `f` is returned as is if
+// it has none, otherwise a resolved copy - `f` isn't modified.
+func Resolve(f Fields) Fields {
+	hasValuer := false
+
+	for _, v := range f {
+		if _, ok := v.(Valuer); ok {
+			hasValuer = true
+
+			break
+		}
+	}
+
+	if !hasValuer {
+		return f
+	}
+
+	resolved := make(Fields, len(f))
+
+	for k, v := range f {
+		if valuer, ok := v.(Valuer); ok {
+			resolved[k] = resolve(valuer)
+
+			continue
+		}
+
+		resolved[k] = v
+	}
+
+	return resolved
+}
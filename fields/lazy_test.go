@@ -0,0 +1,65 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package fields
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// valuerFunc is a non-memoized `Valuer`.
+type valuerFunc func() any
+
+func (f valuerFunc) FieldValue() any { return f() }
+
+func TestResolve(t *testing.T) {
+	plain := Fields{"a": 1}
+
+	// Without Valuers, the same map is returned.
+	if got := Resolve(plain); reflect.ValueOf(got).Pointer() != reflect.ValueOf(plain).Pointer() {
+		t.Error("Resolve() must return the fields as is without Valuers")
+	}
+
+	f := Fields{
+		"a":      1,
+		"lazy":   Lazy(func() any { return "v" }),
+		"nested": valuerFunc(func() any { return Lazy(func() any { return 2 }) }),
+		"panics": Lazy(func() any { panic("boom") }),
+	}
+
+	got := Resolve(f)
+
+	if got["a"] != 1 || got["lazy"] != "v" || got["nested"] != 2 {
+		t.Errorf("Resolve() = %v", got)
+	}
+
+	if err, ok := got["panics"].(error); !ok || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Resolve() panics = %v, want an error describing the panic", got["panics"])
+	}
+
+	// The source isn't modified.
+	if _, ok := f["lazy"].(Valuer); !ok {
+		t.Error("Resolve() must not modify its input")
+	}
+}
+
+func TestLazy_Memoized(t *testing.T) {
+	calls := 0
+
+	v := Lazy(func() any {
+		calls++
+
+		return errors.New("once")
+	})
+
+	Resolve(Fields{"v": v})
+	Resolve(Fields{"v": v})
+
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
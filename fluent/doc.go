@@ -0,0 +1,36 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package fluent provides Sypl's Fluentd/Fluent Bit support: a batching
+// Forward protocol client, and the ready-to-use `output.IOutput` factory. It
+// lives in its own Go module (github.com/thalesfsp/sypl/fluent/v2) so the
+// core sypl module carries no MessagePack dependency - import this module
+// only if you ship to a `forward` input.
+//
+// Features:
+//   - Three event modes: Forward (default), PackedForward, and
+//     CompressedPackedForward (gzip) - see `WithMode`.
+//   - Tags from the component name, or the message's tags, with an optional
+//     prefix - see `WithTag`, `WithTagFromTags`, and `WithTagPrefix`.
+//   - Records with the message fields as keys, plus "message", "level",
+//     "component", and "tags". Times are EventTime - nanosecond precision.
+//   - At-least-once delivery with chunk acks - see `WithAck`.
+//   - Reconnection, and retries - with exponential backoff, in order - see
+//     `WithRetry`, plus write deadlines, so a stalled server never blocks
+//     the logger, and bounded buffering while it's down - see
+//     `WithMaxBuffered`. Batching is the core's `batcher` package, shared
+//     with `loki`, and `output.HTTP`.
+//   - TLS - see `WithTLS`.
+//   - `Flush`/`Close` capabilities, like `loki.Output`: Sypl's
+//     `Flush`/`Close` - and the pre-exit flush on Fatal - drain it.
+//
+// Usage:
+//
+//	o, err := fluent.Output(
+//		"tcp",
+//		"fluent-bit:24224",
+//		level.Info,
+//		[]fluent.Option{fluent.WithTagPrefix("k8s."), fluent.WithAck(0)},
+//	)
+package fluent
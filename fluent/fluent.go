@@ -0,0 +1,676 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package fluent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/batcher"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// Defaults.
+const (
+	defaultAckTimeout    = 5 * time.Second
+	defaultBatchSize     = 1000
+	defaultDialTimeout   = 5 * time.Second
+	defaultFlushInterval = time.Second
+	defaultMaxBackoff    = 5 * time.Second
+	defaultMaxBuffered   = 10000
+	defaultMaxRetries    = 5
+	defaultMinBackoff    = 100 * time.Millisecond
+	defaultWriteTimeout  = 5 * time.Second
+)
+
+// DefaultTag is the tag of messages without a component name - see
+// `WithTag`.
+const DefaultTag = "sypl"
+
+// eventTimeExtID is the Forward protocol's EventTime MessagePack extension
+// type.
+const eventTimeExtID = 0
+
+var (
+	// ErrClosed is returned when writing to a closed Fluent client.
+	ErrClosed = errors.New("fluent output is closed")
+
+	// ErrAckMismatch is returned when the server acknowledges a chunk other
+	// than the one sent.
+	ErrAckMismatch = errors.New("fluent ack mismatch")
+)
+
+// Mode is the Forward protocol event mode.
+type Mode int
+
+// Available modes.
+const (
+	// ModeForward sends `[tag, [[time, record], ...], option]` - one
+	// MessagePack array per tag. It's the default.
+	ModeForward Mode = iota
+
+	// ModePackedForward sends `[tag, entries, option]` - entries being the
+	// concatenated MessagePack `[time, record]` events, as a binary. Cheaper
+	// for the server to forward, as-is, to another node.
+	ModePackedForward
+
+	// ModeCompressedPackedForward is `ModePackedForward`, gzip-compressed.
+	ModeCompressedPackedForward
+)
+
+// String interface implementation.
+func (m Mode) String() string {
+	switch m {
+	case ModeForward:
+		return "Forward"
+	case ModePackedForward:
+		return "PackedForward"
+	case ModeCompressedPackedForward:
+		return "CompressedPackedForward"
+	default:
+		return "Unknown"
+	}
+}
+
+// Entry is an event, and its tag.
+type Entry struct {
+	// Tag routes the event in Fluentd/Fluent Bit - e.g. "api.http".
+	Tag string
+
+	// Time of the event. Sent with nanosecond precision.
+	Time time.Time
+
+	// Record is the event's body.
+	Record map[string]interface{}
+}
+
+// Option configures the Fluent client.
+type Option func(*Client)
+
+// WithMode sets the event mode. Defaults to `ModeForward`.
+func WithMode(m Mode) Option {
+	return func(c *Client) {
+		c.mode = m
+	}
+}
+
+// WithBatchSize sets the maximum number of entries per send. Defaults to
+// 1000.
+func WithBatchSize(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithFlushInterval sets the periodic send interval. Defaults to 1s.
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.flushInterval = d
+		}
+	}
+}
+
+// WithDialTimeout sets the connection timeout. Defaults to 5s.
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.dialTimeout = d
+		}
+	}
+}
+
+// WithWriteTimeout sets the per-send write deadline, so a stalled server
+// fails the send - and retries kick in - instead of blocking. Defaults to
+// 5s.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.writeTimeout = d
+		}
+	}
+}
+
+// WithTLS enables TLS - e.g. Fluentd's `<transport tls>`, or Fluent Bit's
+// `tls on`.
+func WithTLS(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = cfg
+	}
+}
+
+// WithAck enables at-least-once delivery: every send carries a `chunk` id,
+// and waits - up to `timeout`, defaulting to 5s - for the server's `ack`
+// response. Unacknowledged sends are retried per `WithRetry`, on a new
+// connection. Chunk ids derive from the chunk's content, so a retried chunk
+// keeps its id - and the server can de-duplicate.
+//
+// NOTE: Requires `require_ack_response` on Fluentd's forward output side,
+// or `Require_ack_response` on Fluent Bit's - the forward INPUT always
+// answers chunks.
+func WithAck(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.ack = true
+
+		if timeout > 0 {
+			c.ackTimeout = timeout
+		}
+	}
+}
+
+// WithRetry sets the maximum number of retries of failing sends - dial,
+// write, or ack errors - and the backoff bounds: the delay starts at
+// `minBackoff`, doubling up to `maxBackoff`. Every retry reconnects.
+// Defaults to 5 retries, from 100ms up to 5s - non-positive bounds keep the
+// defaults, so retries never busy-loop. `maxRetries <= 0` disables retries.
+func WithRetry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+
+		if minBackoff > 0 {
+			c.minBackoff = minBackoff
+		}
+
+		if maxBackoff > 0 {
+			c.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithMaxBuffered sets the maximum number of pending entries - e.g. while
+// the server is down. Beyond it, entries are dropped: `Add` returns
+// `batcher.ErrBufferFull`. Defaults to 10000.
+func WithMaxBuffered(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxBuffered = n
+		}
+	}
+}
+
+// WithOnError sets the callback receiving background - periodic, or
+// size-triggered - send failures. Entries of a send still failing after the
+// retries are dropped.
+func WithOnError(cb func(error)) Option {
+	return func(c *Client) {
+		c.onError = cb
+	}
+}
+
+// WithTag sets the tag of messages without a component name. Defaults to
+// `DefaultTag`.
+func WithTag(tag string) Option {
+	return func(c *Client) {
+		if tag != "" {
+			c.tagger.fallback = tag
+		}
+	}
+}
+
+// WithTagPrefix prefixes every tag - e.g. "k8s." routes the "api"
+// component's messages as "k8s.api".
+func WithTagPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.tagger.prefix = prefix
+	}
+}
+
+// WithTagFromTags derives the tag from the message's tags - the first, in
+// sorted order - falling back to the component name for untagged messages.
+func WithTagFromTags(enabled bool) Option {
+	return func(c *Client) {
+		c.tagger.fromTags = enabled
+	}
+}
+
+// Client sends entries to a Fluentd/Fluent Bit `forward` input, batched -
+// see the `batcher` package. Entries are accumulated, and sent - one send at
+// a time, over a single connection, so events stay ordered, retries included
+// - when the batch reaches its size, periodically, and on `Flush`/`Close`.
+// At most `WithMaxBuffered` entries are pending.
+type Client struct {
+	// Connection configuration.
+	addr         string
+	dialTimeout  time.Duration
+	network      string
+	tlsConfig    *tls.Config
+	writeTimeout time.Duration
+
+	// Send configuration.
+	ack           bool
+	ackTimeout    time.Duration
+	batchSize     int
+	flushInterval time.Duration
+	maxBackoff    time.Duration
+	maxBuffered   int
+	maxRetries    int
+	minBackoff    time.Duration
+	mode          Mode
+	onError       func(error)
+
+	// chunkSalt makes the content-derived chunk ids - see `WithAck` -
+	// unique per client.
+	chunkSalt []byte
+
+	// tagger derives tags from messages.
+	tagger *tagger
+
+	// batcher accumulates, and sends the entries.
+	batcher *batcher.Batcher[Entry]
+
+	// connMu guards the connection - held during ONE send attempt, never
+	// while backing off. Once `disconnected` by Close, sends fail.
+	connMu       sync.Mutex
+	conn         net.Conn
+	dec          *msgpack.Decoder
+	disconnected bool
+}
+
+//////
+// Methods.
+//////
+
+// Add enqueues an entry. A full batch is sent in the background. After
+// Close, it returns `ErrClosed`; with `WithMaxBuffered` entries pending,
+// `batcher.ErrBufferFull` - the entry is dropped.
+func (c *Client) Add(e Entry) error {
+	err := c.batcher.Add(e)
+
+	if errors.Is(err, batcher.ErrClosed) {
+		return ErrClosed
+	}
+
+	return err
+}
+
+// Flush synchronously sends the pending entries - retrying per `WithRetry`
+// - returning the send error, if any. After Close, it's a no-op.
+func (c *Client) Flush() error {
+	return c.batcher.Flush()
+}
+
+// Close stops the periodic send, sends the pending entries, and closes the
+// connection. It's idempotent: subsequent calls return the FIRST call's
+// outcome. Adds after Close return `ErrClosed`.
+func (c *Client) Close() error {
+	err := c.batcher.Close()
+
+	c.connMu.Lock()
+	c.disconnect()
+	c.disconnected = true
+	c.connMu.Unlock()
+
+	return err
+}
+
+//////
+// Helpers.
+//////
+
+// push sends a batch - once: one Forward message per tag, in order of first
+// appearance, in a single write. See `batcher.Config.Send`.
+func (c *Client) push(batch []Entry) error {
+	groups := groupByTag(batch)
+
+	tags := make([]string, 0, len(groups))
+
+	payload := []byte{}
+
+	chunks := make([]string, 0, len(groups))
+
+	for _, group := range groups {
+		tags = append(tags, group[0].Tag)
+
+		encoded, chunk, err := c.encodeGroup(group)
+		if err != nil {
+			return fmt.Errorf("failed encoding fluent entries (tag: %q): %w", group[0].Tag, err)
+		}
+
+		payload = append(payload, encoded...)
+
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	if c.disconnected {
+		return ErrClosed
+	}
+
+	if err := c.sendOnce(payload, chunks); err != nil {
+		// The connection state is unknown - e.g. half-written payload, or
+		// a late ack - so it's never reused.
+		c.disconnect()
+
+		return batcher.Retryable(fmt.Errorf("failed forwarding to fluent (tags: %q): %w", tags, err))
+	}
+
+	return nil
+}
+
+// encodeGroup encodes the entries of a single tag - with acks enabled,
+// requesting the ack of the returned chunk id.
+func (c *Client) encodeGroup(group []Entry) ([]byte, string, error) {
+	payload, err := encode(group, c.mode, "")
+	if err != nil || !c.ack {
+		return payload, "", err
+	}
+
+	chunk := c.chunkID(payload)
+
+	payload, err = encode(group, c.mode, chunk)
+
+	return payload, chunk, err
+}
+
+// chunkID returns the chunk id of `payload` - the base64-encoded, salted
+// hash of its content - so retries of a chunk keep its id.
+func (c *Client) chunkID(payload []byte) string {
+	h := sha256.New()
+
+	h.Write(c.chunkSalt)
+	h.Write(payload)
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)[:16])
+}
+
+// sendOnce writes the payload once - connecting, if needed - and waits for
+// the `chunks` acks, in order.
+func (c *Client) sendOnce(payload []byte, chunks []string) error {
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := c.conn.Write(payload); err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.ackTimeout)); err != nil {
+			return err
+		}
+
+		var res struct {
+			Ack string `msgpack:"ack"`
+		}
+
+		if err := c.dec.Decode(&res); err != nil {
+			return fmt.Errorf("failed reading ack: %w", err)
+		}
+
+		if res.Ack != chunk {
+			return fmt.Errorf("%w: sent %q, got %q", ErrAckMismatch, chunk, res.Ack)
+		}
+	}
+
+	return nil
+}
+
+// connect dials the server.
+func (c *Client) connect() error {
+	dialer := &net.Dialer{Timeout: c.dialTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if c.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, c.network, c.addr, c.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(c.network, c.addr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.dec = msgpack.NewDecoder(conn)
+
+	return nil
+}
+
+// disconnect closes the connection, if any.
+func (c *Client) disconnect() {
+	if c.conn == nil {
+		return
+	}
+
+	_ = c.conn.Close()
+
+	c.conn = nil
+	c.dec = nil
+}
+
+// groupByTag splits entries per tag, in order of first appearance, keeping
+// each tag's entries ordered.
+func groupByTag(entries []Entry) [][]Entry {
+	index := map[string]int{}
+
+	groups := [][]Entry{}
+
+	for _, e := range entries {
+		i, ok := index[e.Tag]
+		if !ok {
+			i = len(groups)
+
+			index[e.Tag] = i
+
+			groups = append(groups, nil)
+		}
+
+		groups[i] = append(groups[i], e)
+	}
+
+	return groups
+}
+
+// encodeEventTime encodes `t` as an EventTime: the extension type 0, with
+// big-endian 32-bit seconds, and nanoseconds.
+func encodeEventTime(enc *msgpack.Encoder, t time.Time) error {
+	if err := enc.EncodeExtHeader(eventTimeExtID, 8); err != nil {
+		return err
+	}
+
+	var b [8]byte
+
+	binary.BigEndian.PutUint32(b[:4], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+
+	_, err := enc.Writer().Write(b[:])
+
+	return err
+}
+
+// encodeEvents encodes the `[time, record]` events of `entries`.
+func encodeEvents(enc *msgpack.Encoder, entries []Entry) error {
+	for _, e := range entries {
+		if err := enc.EncodeArrayLen(2); err != nil {
+			return err
+		}
+
+		if err := encodeEventTime(enc, e.Time); err != nil {
+			return err
+		}
+
+		if err := enc.Encode(e.Record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newEncoder returns an encoder with sorted map keys, so records are
+// deterministic.
+func newEncoder(buf *bytes.Buffer) *msgpack.Encoder {
+	enc := msgpack.NewEncoder(buf)
+
+	enc.SetSortMapKeys(true)
+
+	return enc
+}
+
+// encode encodes the entries - all of the same tag - as a Forward protocol
+// message of the given mode. A non-empty `chunk` requests an ack.
+func encode(entries []Entry, mode Mode, chunk string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := newEncoder(buf)
+
+	if err := enc.EncodeArrayLen(3); err != nil {
+		return nil, err
+	}
+
+	if err := enc.EncodeString(entries[0].Tag); err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case ModePackedForward, ModeCompressedPackedForward:
+		packed := &bytes.Buffer{}
+
+		if err := encodeEvents(newEncoder(packed), entries); err != nil {
+			return nil, err
+		}
+
+		events := packed.Bytes()
+
+		if mode == ModeCompressedPackedForward {
+			compressed := &bytes.Buffer{}
+
+			zw := gzip.NewWriter(compressed)
+
+			if _, err := zw.Write(events); err != nil {
+				return nil, err
+			}
+
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+
+			events = compressed.Bytes()
+		}
+
+		if err := enc.EncodeBytes(events); err != nil {
+			return nil, err
+		}
+	case ModeForward:
+		fallthrough
+	default:
+		if err := enc.EncodeArrayLen(len(entries)); err != nil {
+			return nil, err
+		}
+
+		if err := encodeEvents(enc, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	option := map[string]interface{}{"size": len(entries)}
+
+	if chunk != "" {
+		option["chunk"] = chunk
+	}
+
+	if mode == ModeCompressedPackedForward {
+		option["compressed"] = "gzip"
+	}
+
+	if err := enc.Encode(option); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+//////
+// Factory.
+//////
+
+// New returns a new Fluent client sending to the `forward` input at `addr`
+// over `network` - "tcp", or "unix". See the `With*` options for modes,
+// batching, acks, TLS, retries, and tags.
+//
+// NOTE: Sends are asynchronous, and batched - deliver failures through
+// `WithOnError`, and drain with `Flush`, or `Close`. The connection is
+// established on the first send, and re-established on failures.
+func New(network, addr string, opts ...Option) (*Client, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return nil, fmt.Errorf("invalid fluent network %q: must be tcp, or unix", network)
+	}
+
+	if addr == "" {
+		return nil, errors.New("invalid fluent address: empty")
+	}
+
+	c := &Client{
+		ackTimeout:    defaultAckTimeout,
+		addr:          addr,
+		batchSize:     defaultBatchSize,
+		dialTimeout:   defaultDialTimeout,
+		flushInterval: defaultFlushInterval,
+		maxBackoff:    defaultMaxBackoff,
+		maxBuffered:   defaultMaxBuffered,
+		maxRetries:    defaultMaxRetries,
+		minBackoff:    defaultMinBackoff,
+		mode:          ModeForward,
+		network:       network,
+		tagger:        newTagger(),
+		writeTimeout:  defaultWriteTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.ack {
+		c.chunkSalt = make([]byte, 16)
+
+		if _, err := rand.Read(c.chunkSalt); err != nil {
+			return nil, fmt.Errorf("failed generating the chunk id salt: %w", err)
+		}
+	}
+
+	c.batcher = batcher.New(batcher.Config[Entry]{
+		Send:          c.push,
+		FlushItems:    c.batchSize,
+		FlushInterval: c.flushInterval,
+		MaxBuffered:   c.maxBuffered,
+		MaxRetries:    c.maxRetries,
+		MinBackoff:    c.minBackoff,
+		MaxBackoff:    c.maxBackoff,
+		OnError:       c.onError,
+	})
+
+	return c, nil
+}
@@ -0,0 +1,584 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package fluent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// event is a decoded `[time, record]` event.
+type event struct {
+	Time   time.Time
+	Record map[string]interface{}
+}
+
+// forwardMessage is a decoded Forward protocol message.
+type forwardMessage struct {
+	Tag    string
+	Packed bool
+	Events []event
+	Option map[string]interface{}
+}
+
+// fakeFluent is a local `forward` input: it decodes the messages sent to it,
+// and answers chunks - unless told to drop acks.
+type fakeFluent struct {
+	net.Listener
+
+	mu       sync.Mutex
+	messages []forwardMessage
+	conns    int
+	open     []net.Conn
+
+	// dropAcks is the number of chunks to NOT acknowledge - closing the
+	// connection instead.
+	dropAcks int
+}
+
+func newFakeFluent(t *testing.T) *fakeFluent {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeFluent{Listener: ln}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			f.mu.Lock()
+			f.conns++
+			f.open = append(f.open, conn)
+			f.mu.Unlock()
+
+			go f.serve(t, conn)
+		}
+	}()
+
+	t.Cleanup(func() { _ = ln.Close() })
+
+	return f
+}
+
+func (f *fakeFluent) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+
+	dec := msgpack.NewDecoder(conn)
+
+	for {
+		m, err := decodeForwardMessage(dec)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				t.Errorf("fake fluent: %v", err)
+			}
+
+			return
+		}
+
+		f.mu.Lock()
+
+		f.messages = append(f.messages, m)
+
+		drop := f.dropAcks > 0
+		if drop {
+			f.dropAcks--
+		}
+
+		f.mu.Unlock()
+
+		chunk, ok := m.Option["chunk"].(string)
+		if !ok {
+			continue
+		}
+
+		if drop {
+			return
+		}
+
+		res, _ := msgpack.Marshal(map[string]string{"ack": chunk})
+
+		if _, err := conn.Write(res); err != nil {
+			return
+		}
+	}
+}
+
+// kick closes the open connections.
+func (f *fakeFluent) kick() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, conn := range f.open {
+		_ = conn.Close()
+	}
+
+	f.open = nil
+}
+
+func (f *fakeFluent) all() []forwardMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.messages)
+}
+
+func (f *fakeFluent) connections() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.conns
+}
+
+// decodeEvent decodes a `[time, record]` event - time being an EventTime.
+func decodeEvent(dec *msgpack.Decoder) (event, error) {
+	if n, err := dec.DecodeArrayLen(); err != nil || n != 2 {
+		return event{}, fmt.Errorf("event: array of %d: %w", n, err)
+	}
+
+	id, n, err := dec.DecodeExtHeader()
+	if err != nil || id != eventTimeExtID || n != 8 {
+		return event{}, fmt.Errorf("event time: ext %d of %d bytes: %w", id, n, err)
+	}
+
+	b := make([]byte, 8)
+
+	if err := dec.ReadFull(b); err != nil {
+		return event{}, err
+	}
+
+	e := event{Time: time.Unix(int64(binary.BigEndian.Uint32(b[:4])), int64(binary.BigEndian.Uint32(b[4:])))}
+
+	if e.Record, err = dec.DecodeMap(); err != nil {
+		return event{}, err
+	}
+
+	return e, nil
+}
+
+func decodeForwardMessage(dec *msgpack.Decoder) (forwardMessage, error) {
+	m := forwardMessage{}
+
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return m, err
+	}
+
+	if n != 3 {
+		return m, fmt.Errorf("message: array of %d", n)
+	}
+
+	if m.Tag, err = dec.DecodeString(); err != nil {
+		return m, err
+	}
+
+	code, err := dec.PeekCode()
+	if err != nil {
+		return m, err
+	}
+
+	var packed []byte
+
+	if msgpcode.IsBin(code) {
+		m.Packed = true
+
+		if packed, err = dec.DecodeBytes(); err != nil {
+			return m, err
+		}
+	} else {
+		count, err := dec.DecodeArrayLen()
+		if err != nil {
+			return m, err
+		}
+
+		for range count {
+			e, err := decodeEvent(dec)
+			if err != nil {
+				return m, err
+			}
+
+			m.Events = append(m.Events, e)
+		}
+	}
+
+	if m.Option, err = dec.DecodeMap(); err != nil {
+		return m, err
+	}
+
+	if !m.Packed {
+		return m, nil
+	}
+
+	if m.Option["compressed"] == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(packed))
+		if err != nil {
+			return m, err
+		}
+
+		if packed, err = io.ReadAll(zr); err != nil {
+			return m, err
+		}
+	}
+
+	r := bytes.NewReader(packed)
+	events := msgpack.NewDecoder(r)
+
+	for r.Len() > 0 {
+		e, err := decodeEvent(events)
+		if err != nil {
+			return m, err
+		}
+
+		m.Events = append(m.Events, e)
+	}
+
+	return m, nil
+}
+
+// waitFor polls `cond` for up to 5s.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOutput_Forward(t *testing.T) {
+	srv := newFakeFluent(t)
+
+	o, err := Output("tcp", srv.Addr().String(), level.Trace, []Option{WithTagPrefix("k8s.")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o.GetName() != "Fluent" {
+		t.Errorf("GetName() = %q, want Fluent", o.GetName())
+	}
+
+	l := sypl.New("api", o)
+
+	defer l.Close()
+
+	l.PrintlnWithOptions(level.Info, "hello",
+		sypl.WithFields(fields.Fields{"request_id": "r-1", "status": 200, "err": errors.New("boom"), "message": "shadowed"}),
+		sypl.WithTags("b", "a"),
+	)
+	l.Errorln("failed")
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without acks, a send completes on write.
+	waitFor(t, func() bool { return len(srv.all()) == 1 })
+
+	messages := srv.all()
+
+	if len(messages) != 1 || messages[0].Packed {
+		t.Fatalf("messages = %+v, want 1 Forward message", messages)
+	}
+
+	m := messages[0]
+
+	if m.Tag != "k8s.api" {
+		t.Errorf("tag = %q, want k8s.api", m.Tag)
+	}
+
+	if len(m.Events) != 2 || fmt.Sprint(m.Option["size"]) != "2" {
+		t.Fatalf("events = %+v, option = %v", m.Events, m.Option)
+	}
+
+	r := m.Events[0].Record
+
+	for k, want := range map[string]interface{}{
+		"message":    "hello",
+		"level":      "info",
+		"component":  "api",
+		"request_id": "r-1",
+		"status":     int64(200),
+		"err":        "boom",
+	} {
+		if got := r[k]; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("record[%q] = %v, want %v", k, got, want)
+		}
+	}
+
+	if tags, _ := r["tags"].([]interface{}); len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		t.Errorf("record tags = %v, want sorted [a b]", r["tags"])
+	}
+
+	if time.Since(m.Events[0].Time) > time.Minute || m.Events[0].Time.Nanosecond() == 0 {
+		t.Errorf("event time = %v, want now, with nanoseconds", m.Events[0].Time)
+	}
+
+	if r := m.Events[1].Record; r["level"] != "error" || r["message"] != "failed" {
+		t.Errorf("second record = %v", r)
+	}
+}
+
+func TestClient_Tags(t *testing.T) {
+	srv := newFakeFluent(t)
+
+	o, err := Output("tcp", srv.Addr().String(), level.Trace, []Option{WithTagFromTags(true), WithTag("app")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := sypl.New("api", o)
+
+	defer l.Close()
+
+	l.PrintlnWithOptions(level.Info, "tagged", sypl.WithTags("zeta", "audit"))
+	l.Infoln("untagged")
+
+	// Without a component name, the fallback tag.
+	if _, err := o.GetWriter().Write([]byte("direct\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(srv.all()) == 3 })
+
+	tags := []string{}
+
+	for _, m := range srv.all() {
+		tags = append(tags, m.Tag)
+	}
+
+	// One message per tag, in order of first appearance.
+	if want := []string{"audit", "api", "app"}; !slices.Equal(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+}
+
+func TestClient_PackedModes(t *testing.T) {
+	for _, mode := range []Mode{ModePackedForward, ModeCompressedPackedForward} {
+		t.Run(mode.String(), func(t *testing.T) {
+			srv := newFakeFluent(t)
+
+			c, err := New("tcp", srv.Addr().String(), WithMode(mode), WithFlushInterval(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer c.Close()
+
+			now := time.Now()
+
+			for i := range 3 {
+				_ = c.Add(Entry{Tag: "t", Time: now, Record: map[string]interface{}{"i": i}})
+			}
+
+			if err := c.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			waitFor(t, func() bool { return len(srv.all()) == 1 })
+
+			messages := srv.all()
+
+			if len(messages) != 1 || !messages[0].Packed || len(messages[0].Events) != 3 {
+				t.Fatalf("messages = %+v, want 1 packed message of 3 events", messages)
+			}
+
+			if _, ok := messages[0].Option["compressed"]; ok != (mode == ModeCompressedPackedForward) {
+				t.Errorf("option = %v", messages[0].Option)
+			}
+
+			if e := messages[0].Events[2]; !e.Time.Equal(now.Truncate(time.Nanosecond)) || fmt.Sprint(e.Record["i"]) != "2" {
+				t.Errorf("last event = %+v", e)
+			}
+		})
+	}
+}
+
+func TestClient_Ack(t *testing.T) {
+	srv := newFakeFluent(t)
+
+	srv.dropAcks = 1
+
+	c, err := New("tcp", srv.Addr().String(),
+		WithAck(time.Second),
+		WithFlushInterval(time.Hour),
+		WithRetry(2, time.Millisecond, time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	_ = c.Add(Entry{Tag: "t", Time: time.Now(), Record: map[string]interface{}{"k": "v"}})
+
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush = %v, want success after the retry", err)
+	}
+
+	messages := srv.all()
+
+	if len(messages) != 2 {
+		t.Fatalf("messages = %d, want 2 - the unacknowledged one, and the retry", len(messages))
+	}
+
+	chunk, _ := messages[0].Option["chunk"].(string)
+
+	if chunk == "" || messages[1].Option["chunk"] != chunk {
+		t.Errorf("chunks = %v, %v, want the same, non-empty", messages[0].Option["chunk"], messages[1].Option["chunk"])
+	}
+
+	if n := srv.connections(); n != 2 {
+		t.Errorf("connections = %d, want 2 - reconnected for the retry", n)
+	}
+}
+
+func TestClient_Reconnects(t *testing.T) {
+	srv := newFakeFluent(t)
+
+	c, err := New("tcp", srv.Addr().String(),
+		WithAck(time.Second),
+		WithFlushInterval(time.Hour),
+		WithRetry(3, time.Millisecond, time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	_ = c.Add(Entry{Tag: "t", Time: time.Now(), Record: map[string]interface{}{"n": 1}})
+
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Server-side disconnection - e.g. a Fluent Bit restart.
+	srv.kick()
+
+	_ = c.Add(Entry{Tag: "t", Time: time.Now(), Record: map[string]interface{}{"n": 2}})
+
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush after disconnection = %v, want success", err)
+	}
+
+	messages := srv.all()
+
+	if r := messages[len(messages)-1].Events[0].Record; fmt.Sprint(r["n"]) != "2" {
+		t.Errorf("last record = %v, want n=2", r)
+	}
+
+	if n := srv.connections(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+}
+
+func TestClient_DialFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing's listening anymore.
+	addr := ln.Addr().String()
+
+	_ = ln.Close()
+
+	c, err := New("tcp", addr, WithFlushInterval(time.Hour), WithRetry(1, time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.Add(Entry{Tag: "t", Time: time.Now()})
+
+	if err := c.Flush(); err == nil || !strings.Contains(err.Error(), `tags: ["t"]`) || !strings.Contains(err.Error(), "1 items dropped") {
+		t.Errorf("Flush = %v, want the dial error", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close = %v, want nil - nothing pending", err)
+	}
+}
+
+func TestClient_BatchSizeTriggersSend(t *testing.T) {
+	srv := newFakeFluent(t)
+
+	c, err := New("tcp", srv.Addr().String(), WithBatchSize(2), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	_ = c.Add(Entry{Tag: "t", Time: time.Now()})
+	_ = c.Add(Entry{Tag: "t", Time: time.Now()})
+
+	waitFor(t, func() bool { return len(srv.all()) == 1 })
+}
+
+func TestClient_Close(t *testing.T) {
+	srv := newFakeFluent(t)
+
+	c, err := New("tcp", srv.Addr().String(), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.Add(Entry{Tag: "t", Time: time.Now()})
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(srv.all()) == 1 })
+
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	if err := c.Add(Entry{Tag: "t"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Add after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New("udp", "127.0.0.1:24224"); err == nil {
+		t.Error("expected an error for udp")
+	}
+
+	if _, err := New("tcp", ""); err == nil {
+		t.Error("expected an error for an empty address")
+	}
+}
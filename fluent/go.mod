@@ -0,0 +1,20 @@
+module github.com/thalesfsp/sypl/fluent/v2
+
+go 1.23
+
+replace github.com/thalesfsp/sypl/v2 => ../
+
+require (
+	github.com/thalesfsp/sypl/v2 v2.0.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
+
+require (
+	github.com/acarl005/stripansi v0.0.0-20180116102854-5a71ef0e047d // indirect
+	github.com/fatih/color v1.18.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/sys v0.27.0 // indirect
+)
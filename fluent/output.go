@@ -0,0 +1,129 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package fluent
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// fluentOutput is a Fluent-backed `output.IOutput` carrying the Flush, and
+// Close capabilities.
+//
+// The tag, time, and record keys come from the MESSAGE - after processors
+// ran - while the "message" key is the formatted content. The message being
+// written is handed to the writer through `current`, like the Loki output
+// does.
+type fluentOutput struct {
+	*output.Proxy
+
+	client *Client
+
+	// mu serializes writes, guarding `current`.
+	mu sync.Mutex
+
+	// current is the message being written.
+	current message.IMessage
+}
+
+// Write writes the message through the output's pipeline.
+func (o *fluentOutput) Write(m message.IMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.current = m
+
+	defer func() { o.current = nil }()
+
+	return o.Proxy.Write(m)
+}
+
+// Flush sends the pending entries - see `Client.Flush`. After Close it's a
+// no-op.
+func (o *fluentOutput) Flush() error {
+	return o.client.Flush()
+}
+
+// Close sends the pending entries, and stops the client. It's idempotent.
+// Writes after Close return `ErrClosed`.
+func (o *fluentOutput) Close() error {
+	return o.client.Close()
+}
+
+// eventWriter is the output's writer: it turns the formatted content, and
+// the current message into an entry.
+type eventWriter struct {
+	o *fluentOutput
+}
+
+// Write conforms to the `io.Writer` interface.
+func (w *eventWriter) Write(p []byte) (int, error) {
+	// Called outside of `Write` - e.g. directly, via `GetWriter` - the
+	// content is sent at Info, with the default tag.
+	m := w.o.current
+	if m == nil {
+		m = message.New(level.Info, "")
+	}
+
+	// Trailing linebreaks - restored by Sypl's pipeline after formatting -
+	// aren't part of the message.
+	e := Entry{
+		Tag:    w.o.client.tagger.tag(m),
+		Time:   m.GetTimestamp(),
+		Record: record(m, strings.TrimRight(string(p), "\r\n")),
+	}
+
+	if err := w.o.client.Add(e); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+//////
+// Factory.
+//////
+
+// Output is a built-in `output` - named `Fluent` - that forwards messages to
+// a Fluentd/Fluent Bit `forward` input. See `New` for `network`, and `addr`,
+// and the `With*` options for modes, batching, acks, TLS, retries, and tags.
+//
+// Each message is an event:
+// - The tag is the component name - or the first tag, with
+// `WithTagFromTags` - prefixed per `WithTagPrefix`, defaulting to
+// `DefaultTag`.
+// - The time is the message's timestamp, with nanosecond precision.
+// - The record holds the message fields, plus "message" - the formatted
+// content, plain text by default - "level", "component", and "tags".
+//
+// Capabilities: `Flush() error` (sends the pending entries), and idempotent
+// `Close() error`. Sending is asynchronous: background failures are
+// delivered through `WithOnError`.
+func Output(
+	network, addr string,
+	maxLevel level.Level,
+	opts []Option,
+	processors ...processor.IProcessor,
+) (output.IOutput, error) {
+	client, err := New(network, addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &fluentOutput{client: client}
+
+	o.Proxy = output.NewProxy(output.New("Fluent", maxLevel, &eventWriter{o: o}, processors...), o)
+
+	return o, nil
+}
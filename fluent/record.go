@@ -0,0 +1,104 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package fluent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+// Record keys set by the Fluent output. They take precedence over message
+// fields of the same name.
+const (
+	ComponentKey = "component"
+	LevelKey     = "level"
+	MessageKey   = "message"
+	TagsKey      = "tags"
+)
+
+// tagger derives tags from messages - see the `WithTag*` options.
+type tagger struct {
+	// fallback is the tag of messages without a component name.
+	fallback string
+
+	// fromTags derives the tag from the message's tags.
+	fromTags bool
+
+	// prefix of every tag.
+	prefix string
+}
+
+// tag returns the tag of `m`.
+func (t *tagger) tag(m message.IMessage) string {
+	tag := m.GetComponentName()
+
+	if t.fromTags && len(m.GetTags()) > 0 {
+		tags := slices.Clone(m.GetTags())
+
+		slices.Sort(tags)
+
+		tag = tags[0]
+	}
+
+	if tag == "" {
+		tag = t.fallback
+	}
+
+	return t.prefix + tag
+}
+
+// newTagger is the `tagger` factory. It applies defaults.
+func newTagger() *tagger {
+	return &tagger{fallback: DefaultTag}
+}
+
+// record builds the record of `m`, whose formatted content is `content`:
+// message fields as record keys, plus "message", "level", "component", and
+// "tags".
+func record(m message.IMessage, content string) map[string]interface{} {
+	r := make(map[string]interface{}, len(m.GetFields())+4)
+
+	for k, v := range m.GetFields() {
+		if v == nil {
+			continue
+		}
+
+		r[k] = recordValue(v)
+	}
+
+	r[MessageKey] = content
+	r[LevelKey] = strings.ToLower(m.GetLevel().String())
+
+	if name := m.GetComponentName(); name != "" {
+		r[ComponentKey] = name
+	}
+
+	if tags := m.GetTags(); len(tags) > 0 {
+		sorted := slices.Clone(tags)
+
+		slices.Sort(sorted)
+
+		r[TagsKey] = sorted
+	}
+
+	return r
+}
+
+// recordValue renders errors, and stringers through their methods - which
+// MessagePack would otherwise encode as (often empty) structs. Anything else
+// is encoded as-is.
+func recordValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	return v
+}
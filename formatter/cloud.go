@@ -0,0 +1,313 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package formatter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/processor"
+	"github.com/thalesfsp/sypl/v2/shared"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// GCP Cloud Logging structured logging keys. Those not set by `GCP` -
+// trace, span, source location - are set via fields, e.g.
+// `fields.Fields{formatter.GCPKeyTrace: "projects/my-project/traces/abc"}`.
+const (
+	GCPKeyHTTPRequest    = "httpRequest"
+	GCPKeyLabels         = "logging.googleapis.com/labels"
+	GCPKeyMessage        = "message"
+	GCPKeySeverity       = "severity"
+	GCPKeySourceLocation = "sourceLocation"
+	GCPKeySpanID         = "logging.googleapis.com/spanId"
+	GCPKeyTags           = "tags"
+	GCPKeyTime           = "time"
+	GCPKeyTrace          = "logging.googleapis.com/trace"
+	GCPKeyTraceSampled   = "logging.googleapis.com/trace_sampled"
+)
+
+// CloudWatch document keys - the AWS Lambda JSON log format's.
+const (
+	CloudWatchKeyLevel     = "level"
+	CloudWatchKeyLogger    = "logger"
+	CloudWatchKeyMessage   = "message"
+	CloudWatchKeyTags      = "tags"
+	CloudWatchKeyTimestamp = "timestamp"
+)
+
+// Azure document keys - the Azure Monitor (Application Insights) trace
+// telemetry's.
+const (
+	AzureKeyMessage       = "message"
+	AzureKeyProperties    = "properties"
+	AzureKeySeverityLevel = "severityLevel"
+	AzureKeyTimestamp     = "timestamp"
+)
+
+// TagHTTPAccessLog marks HTTP access logs - e.g. `syplhttp.Middleware`'s.
+// `GCP` promotes the request fields of messages tagged with it - only - to
+// `httpRequest`.
+const TagHTTPAccessLog = "http_access"
+
+// CloudTimestampLayout is the `GCP`, `CloudWatch`, and `Azure` timestamp
+// layout: RFC3339, UTC, nanosecond precision.
+const CloudTimestampLayout = time.RFC3339Nano
+
+// httpRequestFields maps the `syplhttp` access-log fields to GCP's
+// `httpRequest` keys.
+var httpRequestFields = map[string]string{
+	"bytes":      "responseSize",
+	"latency":    "latency",
+	"method":     "requestMethod",
+	"path":       "requestUrl",
+	"remote_ip":  "remoteIp",
+	"status":     "status",
+	"user_agent": "userAgent",
+}
+
+//////
+// Helpers.
+//////
+
+// GCPSeverity maps a `level.Level` to a Cloud Logging severity: Fatal is
+// CRITICAL, Error ERROR, Warn WARNING, Info INFO, Debug, and Trace DEBUG,
+// anything else DEFAULT. Custom levels map like their `Base`.
+func GCPSeverity(l level.Level) string {
+	switch l.Base() {
+	case level.Fatal:
+		return "CRITICAL"
+	case level.Error:
+		return "ERROR"
+	case level.Warn:
+		return "WARNING"
+	case level.Info:
+		return "INFO"
+	case level.Debug, level.Trace:
+		return "DEBUG"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// CloudWatchLevel maps a `level.Level` to an AWS Lambda log level: FATAL,
+// ERROR, WARN, INFO, DEBUG, and TRACE - anything else is INFO. Custom
+// levels map like their `Base`.
+func CloudWatchLevel(l level.Level) string {
+	switch l.Base() {
+	case level.Fatal:
+		return "FATAL"
+	case level.Error:
+		return "ERROR"
+	case level.Warn:
+		return "WARN"
+	case level.Debug:
+		return "DEBUG"
+	case level.Trace:
+		return "TRACE"
+	default:
+		return "INFO"
+	}
+}
+
+// AzureSeverityLevel maps a `level.Level` to an Application Insights
+// severity level: Fatal is Critical, Error Error, Warn Warning, Debug, and
+// Trace Verbose, anything else Information. Custom levels map like their
+// `Base`.
+func AzureSeverityLevel(l level.Level) string {
+	switch l.Base() {
+	case level.Fatal:
+		return "Critical"
+	case level.Error:
+		return "Error"
+	case level.Warn:
+		return "Warning"
+	case level.Debug, level.Trace:
+		return "Verbose"
+	default:
+		return "Information"
+	}
+}
+
+// gcpHTTPRequestValue renders an `httpRequest` value per Cloud Logging's
+// schema: durations as seconds ("0.25s"), sizes as decimal strings.
+func gcpHTTPRequestValue(key string, v interface{}) interface{} {
+	switch key {
+	case "latency":
+		if d, ok := v.(time.Duration); ok {
+			return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+		}
+	case "responseSize":
+		return fmt.Sprint(v)
+	}
+
+	return v
+}
+
+// gcpMapBuilder builds the Cloud Logging document of `m`.
+func gcpMapBuilder(m message.IMessage) map[string]interface{} {
+	mM := map[string]interface{}{}
+
+	// Access logs - see `TagHTTPAccessLog` - are promoted to `httpRequest`.
+	// Unmarked messages keep their fields as-is: e.g. a payment "method", or
+	// a job "status".
+	f := m.GetFields()
+
+	promote := m.ContainTag(TagHTTPAccessLog)
+
+	httpRequest := map[string]interface{}{}
+
+	// Fields first: GCP keys take precedence over clashing fields.
+	for k, v := range f {
+		if v == nil {
+			continue
+		}
+
+		if key, ok := httpRequestFields[k]; ok && promote {
+			httpRequest[key] = gcpHTTPRequestValue(key, v)
+
+			continue
+		}
+
+		mM[k] = v
+	}
+
+	if len(httpRequest) > 0 {
+		mM[GCPKeyHTTPRequest] = httpRequest
+	}
+
+	mM[GCPKeySeverity] = GCPSeverity(m.GetLevel())
+	mM[GCPKeyMessage] = m.GetContent().GetProcessed()
+	mM[GCPKeyTime] = m.GetTimestamp().UTC().Format(CloudTimestampLayout)
+
+	if component := m.GetComponentName(); component != "" {
+		mM[GCPKeyLabels] = map[string]string{"component": component}
+	}
+
+	if tags := m.GetTags(); len(tags) != 0 {
+		mM[GCPKeyTags] = tags
+	}
+
+	return mM
+}
+
+// cloudWatchMapBuilder builds the CloudWatch document of `m`.
+func cloudWatchMapBuilder(m message.IMessage) map[string]interface{} {
+	mM := map[string]interface{}{}
+
+	// Fields first: CloudWatch keys take precedence over clashing fields.
+	for k, v := range m.GetFields() {
+		if v != nil {
+			mM[k] = v
+		}
+	}
+
+	mM[CloudWatchKeyTimestamp] = m.GetTimestamp().UTC().Format(CloudTimestampLayout)
+	mM[CloudWatchKeyLevel] = CloudWatchLevel(m.GetLevel())
+	mM[CloudWatchKeyMessage] = m.GetContent().GetProcessed()
+
+	if component := m.GetComponentName(); component != "" {
+		mM[CloudWatchKeyLogger] = component
+	}
+
+	if tags := m.GetTags(); len(tags) != 0 {
+		mM[CloudWatchKeyTags] = tags
+	}
+
+	return mM
+}
+
+// azureMapBuilder builds the Azure Monitor document of `m`.
+func azureMapBuilder(m message.IMessage) map[string]interface{} {
+	properties := map[string]interface{}{}
+
+	for k, v := range m.GetFields() {
+		if v != nil {
+			properties[k] = v
+		}
+	}
+
+	if component := m.GetComponentName(); component != "" {
+		properties["component"] = component
+	}
+
+	if tags := m.GetTags(); len(tags) != 0 {
+		properties["tags"] = tags
+	}
+
+	mM := map[string]interface{}{
+		AzureKeyMessage:       m.GetContent().GetProcessed(),
+		AzureKeySeverityLevel: AzureSeverityLevel(m.GetLevel()),
+		AzureKeyTimestamp:     m.GetTimestamp().UTC().Format(CloudTimestampLayout),
+	}
+
+	if len(properties) != 0 {
+		mM[AzureKeyProperties] = properties
+	}
+
+	return mM
+}
+
+//////
+// Built-in processors.
+//////
+
+// GCP is a Google Cloud Logging structured JSON formatter - the shape the
+// logging agent of GKE, Cloud Run, and Cloud Functions parses into
+// LogEntry fields. It automatically adds:
+// - severity - see `GCPSeverity`, e.g. Warn is WARNING.
+// - message
+// - time (RFC3339, UTC, nanoseconds).
+// - logging.googleapis.com/labels - the component.
+// - tags
+// - httpRequest - from the access-log fields of messages tagged with
+// `TagHTTPAccessLog`, e.g. `syplhttp`'s.
+// - Fields - top-level, so the special keys (`GCPKeyTrace`, `GCPKeySpanID`,
+// `GCPKeySourceLocation`, ...) map natively.
+func GCP() IFormatter {
+	return processor.New("GCP", func(m message.IMessage) error {
+		m.GetContent().SetProcessed(shared.Inline(gcpMapBuilder(m)))
+
+		return nil
+	})
+}
+
+// CloudWatch is an AWS CloudWatch Logs JSON formatter - the AWS Lambda JSON
+// log format, whose top-level keys CloudWatch Logs Insights discovers, and
+// filters on. It automatically adds:
+// - timestamp (RFC3339, UTC, nanoseconds).
+// - level - see `CloudWatchLevel`, e.g. Warn is WARN.
+// - message
+// - logger (component)
+// - tags
+// - Fields - top-level.
+func CloudWatch() IFormatter {
+	return processor.New("CloudWatch", func(m message.IMessage) error {
+		m.GetContent().SetProcessed(shared.Inline(cloudWatchMapBuilder(m)))
+
+		return nil
+	})
+}
+
+// Azure is an Azure Monitor JSON formatter - the Application Insights trace
+// telemetry shape. It automatically adds:
+// - timestamp (RFC3339, UTC, nanoseconds).
+// - severityLevel - see `AzureSeverityLevel`, e.g. Warn is Warning.
+// - message
+// - properties - the component, tags, and fields, queryable as custom
+// dimensions.
+func Azure() IFormatter {
+	return processor.New("Azure", func(m message.IMessage) error {
+		m.GetContent().SetProcessed(shared.Inline(azureMapBuilder(m)))
+
+		return nil
+	})
+}
@@ -0,0 +1,241 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/internal/sypltest"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+// warnMessage returns a Warn message with a component name, and tags.
+func warnMessage() message.IMessage {
+	m := message.New(level.Warn, sypltest.DefaultContentOutput)
+
+	m.SetComponentName(sypltest.DefaultComponentNameOutput)
+	m.AddTags("alpha", "beta")
+
+	return m
+}
+
+func TestCloudLevelMappings(t *testing.T) {
+	tests := []struct {
+		level      level.Level
+		gcp        string
+		cloudWatch string
+		azure      string
+	}{
+		{level.Fatal, "CRITICAL", "FATAL", "Critical"},
+		{level.Error, "ERROR", "ERROR", "Error"},
+		{level.Warn, "WARNING", "WARN", "Warning"},
+		{level.Info, "INFO", "INFO", "Information"},
+		{level.Debug, "DEBUG", "DEBUG", "Verbose"},
+		{level.Trace, "DEBUG", "TRACE", "Verbose"},
+		{level.None, "DEFAULT", "INFO", "Information"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			if got := GCPSeverity(tt.level); got != tt.gcp {
+				t.Errorf("GCPSeverity() = %q, want %q", got, tt.gcp)
+			}
+
+			if got := CloudWatchLevel(tt.level); got != tt.cloudWatch {
+				t.Errorf("CloudWatchLevel() = %q, want %q", got, tt.cloudWatch)
+			}
+
+			if got := AzureSeverityLevel(tt.level); got != tt.azure {
+				t.Errorf("AzureSeverityLevel() = %q, want %q", got, tt.azure)
+			}
+		})
+	}
+}
+
+func TestGCP_FullyLoadedMessage(t *testing.T) {
+	m := warnMessage()
+
+	m.SetFields(fields.Fields{
+		GCPKeyTrace: "projects/p/traces/abc",
+		"severity":  "clash",
+		"nilField":  nil,
+	})
+
+	if err := GCP().Run(m); err != nil {
+		t.Fatalf("GCP() error: %v", err)
+	}
+
+	if strings.Contains(strings.TrimSuffix(m.GetContent().GetProcessed(), "\n"), "\n") {
+		t.Error("GCP() should produce single-line JSON")
+	}
+
+	parsed := unmarshalProcessed(t, m)
+
+	for key, want := range map[string]interface{}{
+		GCPKeySeverity: "WARNING",
+		GCPKeyMessage:  sypltest.DefaultContentOutput,
+		GCPKeyTrace:    "projects/p/traces/abc",
+	} {
+		if parsed[key] != want {
+			t.Errorf("%s = %v, want %v", key, parsed[key], want)
+		}
+	}
+
+	if labels, ok := parsed[GCPKeyLabels].(map[string]interface{}); !ok || labels["component"] != sypltest.DefaultComponentNameOutput {
+		t.Errorf("labels = %v, want the component", parsed[GCPKeyLabels])
+	}
+
+	if ts, ok := parsed[GCPKeyTime].(string); !ok || !strings.HasSuffix(ts, "Z") {
+		t.Errorf("time = %v, want UTC", parsed[GCPKeyTime])
+	} else if _, err := time.Parse(CloudTimestampLayout, ts); err != nil {
+		t.Errorf("time = %v: %v", ts, err)
+	}
+
+	if tags, ok := parsed[GCPKeyTags].([]interface{}); !ok || len(tags) != 2 {
+		t.Errorf("tags = %v, want [alpha beta]", parsed[GCPKeyTags])
+	}
+
+	for _, key := range []string{"nilField", GCPKeyHTTPRequest, "level", "component"} {
+		if _, ok := parsed[key]; ok {
+			t.Errorf("Key %q should be absent, got %v", key, parsed[key])
+		}
+	}
+}
+
+func TestGCP_HTTPRequest(t *testing.T) {
+	m := message.New(level.Info, "GET /users 200")
+
+	m.AddTags(TagHTTPAccessLog)
+
+	m.SetFields(fields.Fields{
+		"method":     "GET",
+		"path":       "/users",
+		"status":     200,
+		"latency":    250 * time.Millisecond,
+		"bytes":      1024,
+		"user_agent": "curl",
+		"remote_ip":  "10.0.0.1",
+		"request_id": "r-1",
+	})
+
+	if err := GCP().Run(m); err != nil {
+		t.Fatalf("GCP() error: %v", err)
+	}
+
+	parsed := unmarshalProcessed(t, m)
+
+	httpRequest, ok := parsed[GCPKeyHTTPRequest].(map[string]interface{})
+	if !ok {
+		t.Fatalf("httpRequest = %v, want an object", parsed[GCPKeyHTTPRequest])
+	}
+
+	for key, want := range map[string]interface{}{
+		"requestMethod": "GET",
+		"requestUrl":    "/users",
+		"status":        float64(200),
+		"latency":       "0.25s",
+		"responseSize":  "1024",
+		"userAgent":     "curl",
+		"remoteIp":      "10.0.0.1",
+	} {
+		if httpRequest[key] != want {
+			t.Errorf("httpRequest.%s = %v, want %v", key, httpRequest[key], want)
+		}
+	}
+
+	// Promoted fields move; others stay top-level.
+	if _, ok := parsed["method"]; ok {
+		t.Error("method should be promoted to httpRequest")
+	}
+
+	if parsed["request_id"] != "r-1" {
+		t.Errorf("request_id = %v, want r-1", parsed["request_id"])
+	}
+
+	// Untagged, nothing's promoted - e.g. a payment's method, and status.
+	m = message.New(level.Info, "x")
+
+	m.SetFields(fields.Fields{"method": "card", "status": "settled", "path": "/etc/hosts"})
+
+	_ = GCP().Run(m)
+
+	parsed = unmarshalProcessed(t, m)
+
+	if parsed["method"] != "card" || parsed["status"] != "settled" || parsed["path"] != "/etc/hosts" ||
+		parsed[GCPKeyHTTPRequest] != nil {
+		t.Errorf("parsed = %v, want the fields top-level", parsed)
+	}
+}
+
+func TestCloudWatch_FullyLoadedMessage(t *testing.T) {
+	m := warnMessage()
+
+	m.SetFields(fields.Fields{"requestId": "r-1", "level": "clash", "nilField": nil})
+
+	if err := CloudWatch().Run(m); err != nil {
+		t.Fatalf("CloudWatch() error: %v", err)
+	}
+
+	parsed := unmarshalProcessed(t, m)
+
+	for key, want := range map[string]interface{}{
+		CloudWatchKeyLevel:   "WARN",
+		CloudWatchKeyLogger:  sypltest.DefaultComponentNameOutput,
+		CloudWatchKeyMessage: sypltest.DefaultContentOutput,
+		"requestId":          "r-1",
+	} {
+		if parsed[key] != want {
+			t.Errorf("%s = %v, want %v", key, parsed[key], want)
+		}
+	}
+
+	if _, err := time.Parse(CloudTimestampLayout, parsed[CloudWatchKeyTimestamp].(string)); err != nil {
+		t.Errorf("timestamp = %v: %v", parsed[CloudWatchKeyTimestamp], err)
+	}
+
+	if _, ok := parsed["nilField"]; ok {
+		t.Error("nil fields should be absent")
+	}
+}
+
+func TestAzure_FullyLoadedMessage(t *testing.T) {
+	m := fullyLoadedMessage()
+
+	if err := Azure().Run(m); err != nil {
+		t.Fatalf("Azure() error: %v", err)
+	}
+
+	parsed := unmarshalProcessed(t, m)
+
+	if parsed[AzureKeySeverityLevel] != "Information" || parsed[AzureKeyMessage] != sypltest.DefaultContentOutput {
+		t.Errorf("parsed = %v", parsed)
+	}
+
+	properties, ok := parsed[AzureKeyProperties].(map[string]interface{})
+	if !ok {
+		t.Fatalf("properties = %v, want an object", parsed[AzureKeyProperties])
+	}
+
+	if properties["component"] != sypltest.DefaultComponentNameOutput || properties["customField"] != "customValue" {
+		t.Errorf("properties = %v", properties)
+	}
+
+	if _, ok := properties["nilField"]; ok {
+		t.Error("nil fields should be absent")
+	}
+
+	// No properties, no key.
+	m = message.New(level.Error, "bare")
+
+	_ = Azure().Run(m)
+
+	if parsed := unmarshalProcessed(t, m); parsed[AzureKeySeverityLevel] != "Error" || parsed[AzureKeyProperties] != nil {
+		t.Errorf("parsed = %v", parsed)
+	}
+}
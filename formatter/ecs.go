@@ -0,0 +1,89 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package formatter
+
+import (
+	"strings"
+
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/processor"
+	"github.com/thalesfsp/sypl/v2/shared"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// ECSVersion is the Elastic Common Schema version `ECS` documents conform
+// to - the `ecs.version` key.
+const ECSVersion = "8.11.0"
+
+// ECSTimestampLayout is the `@timestamp` layout: RFC3339, UTC, millisecond
+// precision - Elasticsearch's default date resolution.
+const ECSTimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// ECS document keys.
+const (
+	ECSKeyLevel     = "log.level"
+	ECSKeyLogger    = "log.logger"
+	ECSKeyMessage   = "message"
+	ECSKeyTags      = "tags"
+	ECSKeyTimestamp = "@timestamp"
+	ECSKeyVersion   = "ecs.version"
+)
+
+//////
+// Helpers.
+//////
+
+// ecsMapBuilder builds the ECS document of `m`.
+func ecsMapBuilder(m message.IMessage) map[string]interface{} {
+	mM := map[string]interface{}{}
+
+	// Fields first: ECS keys take precedence over clashing fields.
+	for k, v := range m.GetFields() {
+		if v != nil {
+			mM[k] = v
+		}
+	}
+
+	mM[ECSKeyTimestamp] = m.GetTimestamp().UTC().Format(ECSTimestampLayout)
+	mM[ECSKeyLevel] = strings.ToLower(m.GetLevel().String())
+	mM[ECSKeyMessage] = m.GetContent().GetProcessed()
+	mM[ECSKeyVersion] = ECSVersion
+
+	if component := m.GetComponentName(); component != "" {
+		mM[ECSKeyLogger] = component
+	}
+
+	if tags := m.GetTags(); len(tags) != 0 {
+		mM[ECSKeyTags] = tags
+	}
+
+	return mM
+}
+
+//////
+// Built-in processors.
+//////
+
+// ECS is an Elastic Common Schema (ECS) JSON formatter - the ecs-logging
+// document shape, indexable as-is into Elasticsearch data streams, and
+// understood by Kibana's Logs UI. It automatically adds:
+// - @timestamp (RFC3339, UTC, milliseconds).
+// - log.level
+// - log.logger (component)
+// - message
+// - ecs.version
+// - tags
+// - Fields - top-level, so ECS field names (e.g. "trace.id",
+// "http.request.method") map natively.
+func ECS() IFormatter {
+	return processor.New("ECS", func(m message.IMessage) error {
+		m.GetContent().SetProcessed(shared.Inline(ecsMapBuilder(m)))
+
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/internal/sypltest"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+func TestECS_FullyLoadedMessage(t *testing.T) {
+	m := fullyLoadedMessage()
+
+	m.SetFields(fields.Fields{
+		"trace.id":  "abc",
+		"message":   "clash",
+		"nilField":  nil,
+		"log.level": "clash",
+	})
+
+	if err := ECS().Run(m); err != nil {
+		t.Fatalf("ECS() error: %v", err)
+	}
+
+	if strings.Contains(strings.TrimSuffix(m.GetContent().GetProcessed(), "\n"), "\n") {
+		t.Error("ECS() should produce single-line JSON")
+	}
+
+	parsed := unmarshalProcessed(t, m)
+
+	for key, want := range map[string]interface{}{
+		ECSKeyLevel:   "info",
+		ECSKeyLogger:  sypltest.DefaultComponentNameOutput,
+		ECSKeyMessage: sypltest.DefaultContentOutput,
+		ECSKeyVersion: ECSVersion,
+		"trace.id":    "abc",
+	} {
+		if parsed[key] != want {
+			t.Errorf("%s = %v, want %v", key, parsed[key], want)
+		}
+	}
+
+	ts, ok := parsed[ECSKeyTimestamp].(string)
+	if !ok || !strings.HasSuffix(ts, "Z") {
+		t.Fatalf("@timestamp = %v, want UTC", parsed[ECSKeyTimestamp])
+	}
+
+	if _, err := time.Parse(ECSTimestampLayout, ts); err != nil {
+		t.Errorf("@timestamp = %v: %v", ts, err)
+	}
+
+	if tags, ok := parsed[ECSKeyTags].([]interface{}); !ok || len(tags) != 2 {
+		t.Errorf("tags = %v, want [alpha beta]", parsed[ECSKeyTags])
+	}
+
+	// Legacy keys aren't part of the ECS shape.
+	for _, key := range []string{"nilField", "component", "timestamp", "level", "output"} {
+		if _, ok := parsed[key]; ok {
+			t.Errorf("Key %q should be absent, got %v", key, parsed[key])
+		}
+	}
+}
+
+func TestECS_MinimalMessage(t *testing.T) {
+	m := message.New(level.Warn, sypltest.DefaultContentOutput)
+
+	if err := ECS().Run(m); err != nil {
+		t.Fatalf("ECS() error: %v", err)
+	}
+
+	parsed := unmarshalProcessed(t, m)
+
+	if parsed[ECSKeyLevel] != "warn" {
+		t.Errorf("log.level = %v, want warn", parsed[ECSKeyLevel])
+	}
+
+	for _, key := range []string{ECSKeyLogger, ECSKeyTags} {
+		if _, ok := parsed[key]; ok {
+			t.Errorf("Key %q should be absent for a minimal message, got %v", key, parsed[key])
+		}
+	}
+}
@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -118,10 +119,19 @@ func Text() IFormatter {
 		fmt.Fprintf(w, "message=%s\t", m.GetContent().GetProcessed())
 		fmt.Fprintf(w, "timestamp=%s\t", m.GetTimestamp().Format(time.RFC3339))
 
-		// Should only process fields if any.
+		// Should only process fields if any - sorted, so the output is
+		// deterministic.
 		if len(m.GetFields()) != 0 {
-			for k, v := range m.GetFields() {
-				if v != nil {
+			keys := make([]string, 0, len(m.GetFields()))
+
+			for k := range m.GetFields() {
+				keys = append(keys, k)
+			}
+
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				if v := m.GetFields()[k]; v != nil {
 					fmt.Fprintf(w, "%s=%v\t", k, v)
 				}
 			}
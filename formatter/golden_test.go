@@ -0,0 +1,67 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package formatter_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/sypltest/golden"
+)
+
+// `-update` rewrites the golden files - see `golden.UpdateFlag`.
+var _ = flag.Bool(golden.UpdateFlag, false, "update the golden files")
+
+// goldenMessages returns the messages rendered by every formatter: a fully
+// loaded one, a minimal one, and an access log.
+func goldenMessages() []message.IMessage {
+	loaded := golden.Message(level.Warn, "disk almost full")
+
+	loaded.SetComponentName("api")
+	loaded.SetOutputName("Console")
+	loaded.AddTags("ops", "audit")
+	loaded.SetFields(fields.Fields{"disk": "/dev/sda1", "usage": 0.93, "nil": nil})
+
+	minimal := golden.Message(level.Error, "boom")
+
+	access := golden.Message(level.Info, "GET /users 200")
+
+	access.SetComponentName("http")
+	access.AddTags(formatter.TagHTTPAccessLog)
+	access.SetFields(fields.Fields{
+		"bytes":      512,
+		"latency":    1500 * time.Microsecond,
+		"method":     "GET",
+		"path":       "/users",
+		"request_id": "r-1",
+		"status":     200,
+	})
+
+	return []message.IMessage{loaded, minimal, access}
+}
+
+// Downstream parsers depend on the formatters' exact output: any change
+// must be deliberate - review, and commit the regenerated golden files
+// (`go test ./formatter -run Golden -update`).
+func TestFormatters_Golden(t *testing.T) {
+	for _, f := range []formatter.IFormatter{
+		formatter.Text(),
+		formatter.JSON(),
+		formatter.JSONPretty(),
+		formatter.ECS(),
+		formatter.GCP(),
+		formatter.CloudWatch(),
+		formatter.Azure(),
+	} {
+		t.Run(f.GetName(), func(t *testing.T) {
+			golden.AssertFormatter(t, f, f.GetName(), goldenMessages()...)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package kafka provides Sypl's Apache Kafka support: a producer-backed
+// `output.IOutput` built on franz-go. It lives in its own Go module
+// (github.com/thalesfsp/sypl/kafka/v2) so the core sypl module carries no
+// Kafka-related dependency - import this module only if you ship logs
+// through Kafka.
+//
+// Features:
+//   - Per-message topic: static (the `Output` topic), by tag - like
+//     `es.OutputWithTagMap`, see `WithTagTopics` - or by a func, see
+//     `WithTopicFunc`.
+//   - Record key from a message field - partition affinity, e.g. all
+//     messages of a tenant land on the same partition - see `WithKeyField`.
+//   - Batching, compression, and acknowledgement levels - see `WithLinger`,
+//     `WithBatchMaxBytes`, `WithCompression`, and `WithAcks`. Anything else
+//     franz-go supports (TLS, SASL, ...) via `WithClientOptions`.
+//   - Delivery errors - asynchronous by nature - are returned by the next
+//     Write, so they reach `Sypl.SetErrorHandler`, and by Flush. See also
+//     `WithOnError` for immediate notification.
+//   - `Flush`/`Close` capabilities, like `es.BulkOutput`: Sypl's
+//     `Flush`/`Close` - and the pre-exit flush on Fatal - drain it.
+//
+// Usage:
+//
+//	o, err := kafka.Output(
+//		[]string{"kafka:9092"},
+//		"logs",
+//		level.Info,
+//		[]kafka.Option{kafka.WithKeyField("tenant")},
+//	)
+package kafka
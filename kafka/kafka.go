@@ -0,0 +1,316 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// Defaults.
+const (
+	defaultFlushTimeout = 30 * time.Second
+
+	// maxPendingErrors bounds the delivery errors kept until the next Write,
+	// or Flush - the remainder is only counted.
+	maxPendingErrors = 100
+)
+
+var (
+	// ErrClosed is returned when writing to a closed Kafka output.
+	ErrClosed = errors.New("kafka output is closed")
+
+	// ErrNoTopic is returned when no topic is resolved for a message - see
+	// `Output`, `WithTagTopics`, and `WithTopicFunc`.
+	ErrNoTopic = errors.New("kafka output: no topic for the message")
+)
+
+// Acks is the acknowledgement level required from the brokers.
+type Acks int
+
+// Available acknowledgement levels.
+const (
+	// AcksAll waits for all in-sync replicas - the safest, and the default.
+	// It's the only level allowing idempotent writes.
+	AcksAll Acks = iota
+
+	// AcksLeader waits for the partition leader only.
+	AcksLeader
+
+	// AcksNone doesn't wait: delivery errors go mostly unnoticed.
+	AcksNone
+)
+
+// String interface implementation.
+func (a Acks) String() string {
+	switch a {
+	case AcksAll:
+		return "All"
+	case AcksLeader:
+		return "Leader"
+	case AcksNone:
+		return "None"
+	default:
+		return "Unknown"
+	}
+}
+
+// Compression is the record batch compression codec.
+type Compression int
+
+// Available compression codecs.
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionSnappy
+	CompressionLZ4
+	CompressionZstd
+)
+
+// String interface implementation.
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "None"
+	case CompressionGzip:
+		return "Gzip"
+	case CompressionSnappy:
+		return "Snappy"
+	case CompressionLZ4:
+		return "LZ4"
+	case CompressionZstd:
+		return "Zstd"
+	default:
+		return "Unknown"
+	}
+}
+
+// TopicFunc returns the topic of a message. An empty topic falls back to
+// the tag topics, then to the `Output` topic.
+type TopicFunc func(m message.IMessage) string
+
+// config is the Kafka output configuration.
+type config struct {
+	// acks is the required acknowledgement level.
+	acks Acks
+
+	// clientOpts are extra franz-go client options.
+	clientOpts []kgo.Opt
+
+	// compression codecs - in preference order. Empty means franz-go's
+	// default.
+	compression []kgo.CompressionCodec
+
+	// flushTimeout bounds Flush, and Close.
+	flushTimeout time.Duration
+
+	// keyField is the field whose value is the record key.
+	keyField string
+
+	// onError receives delivery errors, as they happen.
+	onError func(error)
+
+	// tagTopics maps tags to topics.
+	tagTopics map[string]string
+
+	// topic is the default topic.
+	topic string
+
+	// topicFunc returns the topic of a message.
+	topicFunc TopicFunc
+}
+
+// Option configures the Kafka output.
+type Option func(*config)
+
+// WithAcks sets the acknowledgement level. Defaults to `AcksAll`.
+//
+// NOTE: Levels other than `AcksAll` disable idempotent writes.
+func WithAcks(acks Acks) Option {
+	return func(c *config) {
+		c.acks = acks
+	}
+}
+
+// WithBatchMaxBytes sets the maximum size, in bytes, of a record batch.
+// Defaults to franz-go's - ~1MB.
+func WithBatchMaxBytes(n int32) Option {
+	return WithClientOptions(kgo.ProducerBatchMaxBytes(n))
+}
+
+// WithClientOptions sets extra franz-go client options - e.g. TLS, SASL,
+// or `kgo.MaxBufferedRecords`. They're applied last, overriding the ones
+// derived from the other options.
+func WithClientOptions(opts ...kgo.Opt) Option {
+	return func(c *config) {
+		c.clientOpts = append(c.clientOpts, opts...)
+	}
+}
+
+// WithCompression sets the record batch compression codecs, in preference
+// order - the first one supported by the brokers is used. Defaults to
+// franz-go's: Snappy, falling back to none.
+func WithCompression(codecs ...Compression) Option {
+	return func(c *config) {
+		c.compression = c.compression[:0]
+
+		for _, codec := range codecs {
+			switch codec {
+			case CompressionNone:
+				c.compression = append(c.compression, kgo.NoCompression())
+			case CompressionGzip:
+				c.compression = append(c.compression, kgo.GzipCompression())
+			case CompressionSnappy:
+				c.compression = append(c.compression, kgo.SnappyCompression())
+			case CompressionLZ4:
+				c.compression = append(c.compression, kgo.Lz4Compression())
+			case CompressionZstd:
+				c.compression = append(c.compression, kgo.ZstdCompression())
+			}
+		}
+	}
+}
+
+// WithFlushTimeout bounds Flush, and Close - undelivered records are
+// reported as an error. Defaults to 30s.
+func WithFlushTimeout(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.flushTimeout = d
+		}
+	}
+}
+
+// WithKeyField sets the field whose value - stringified - is the record
+// key: records with the same key land on the same partition. Messages
+// without the field are produced without a key - spread across
+// partitions.
+func WithKeyField(name string) Option {
+	return func(c *config) {
+		c.keyField = name
+	}
+}
+
+// WithLinger sets how long a partition's batch waits for more records
+// before being sent. Defaults to franz-go's - no lingering.
+func WithLinger(d time.Duration) Option {
+	return WithClientOptions(kgo.ProducerLinger(d))
+}
+
+// WithOnError sets the callback receiving delivery errors as they happen -
+// from franz-go's goroutines, so it must be concurrency-safe. Errors are
+// still returned by the next Write, and Flush.
+func WithOnError(fn func(error)) Option {
+	return func(c *config) {
+		c.onError = fn
+	}
+}
+
+// WithTagTopics routes messages by tag - like `es.OutputWithTagMap`: the
+// first - alphabetically - message tag found in `tagTopics` determines the
+// topic. Use `*` as the tag for a catch-all topic, taking precedence over
+// the `Output` topic.
+func WithTagTopics(tagTopics map[string]string) Option {
+	return func(c *config) {
+		for tag, topic := range tagTopics {
+			c.tagTopics[tag] = topic
+		}
+	}
+}
+
+// WithTopicFunc sets the func routing messages to topics. It takes
+// precedence over tag topics, and the `Output` topic - returning an empty
+// topic falls back to them.
+func WithTopicFunc(fn TopicFunc) Option {
+	return func(c *config) {
+		c.topicFunc = fn
+	}
+}
+
+//////
+// Helpers.
+//////
+
+// topicOf returns the topic of `m` - see `TopicFunc`, `WithTagTopics`.
+func (c *config) topicOf(m message.IMessage) string {
+	if c.topicFunc != nil {
+		if topic := c.topicFunc(m); topic != "" {
+			return topic
+		}
+	}
+
+	if len(c.tagTopics) > 0 {
+		for _, tag := range m.GetTags() {
+			if topic, ok := c.tagTopics[tag]; ok {
+				return topic
+			}
+		}
+
+		if topic, ok := c.tagTopics["*"]; ok {
+			return topic
+		}
+	}
+
+	return c.topic
+}
+
+// keyOf returns the record key of `m` - nil if none.
+func (c *config) keyOf(m message.IMessage) []byte {
+	if c.keyField == "" {
+		return nil
+	}
+
+	v, ok := m.GetFields()[c.keyField]
+	if !ok || v == nil {
+		return nil
+	}
+
+	return []byte(fmt.Sprint(v))
+}
+
+// kgoOpts returns the franz-go client options.
+func (c *config) kgoOpts(seeds []string) []kgo.Opt {
+	opts := []kgo.Opt{kgo.SeedBrokers(seeds...)}
+
+	switch c.acks {
+	case AcksLeader:
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()), kgo.DisableIdempotentWrite())
+	case AcksNone:
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()), kgo.DisableIdempotentWrite())
+	case AcksAll:
+		fallthrough
+	default:
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	}
+
+	if len(c.compression) > 0 {
+		opts = append(opts, kgo.ProducerBatchCompression(c.compression...))
+	}
+
+	return append(opts, c.clientOpts...)
+}
+
+// newConfig is the `config` factory. It applies defaults.
+func newConfig(topic string, opts ...Option) *config {
+	c := &config{
+		flushTimeout: defaultFlushTimeout,
+		tagTopics:    map[string]string{},
+		topic:        topic,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
@@ -0,0 +1,272 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// flusher is the Kafka output's Flush capability.
+type flusher interface {
+	Flush() error
+}
+
+func newCluster(t *testing.T, topics ...string) []string {
+	t.Helper()
+
+	c, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, topics...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(c.Close)
+
+	return c.ListenAddrs()
+}
+
+// consume reads `n` records from `topics`.
+func consume(t *testing.T, seeds []string, n int, topics ...string) []*kgo.Record {
+	t.Helper()
+
+	cl, err := kgo.NewClient(
+		kgo.SeedBrokers(seeds...),
+		kgo.ConsumeTopics(topics...),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cl.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	records := []*kgo.Record{}
+
+	for len(records) < n {
+		fetches := cl.PollFetches(ctx)
+		if ctx.Err() != nil {
+			t.Fatalf("consumed %d records, want %d", len(records), n)
+		}
+
+		records = append(records, fetches.Records()...)
+	}
+
+	return records
+}
+
+func TestOutput_RoutingAndKeys(t *testing.T) {
+	seeds := newCluster(t, "logs", "audit", "errors")
+
+	o, err := Output(seeds, "logs", level.Trace, []Option{
+		WithKeyField("tenant"),
+		WithTagTopics(map[string]string{"audit": "audit"}),
+		WithTopicFunc(func(m message.IMessage) string {
+			if m.GetLevel() == level.Error {
+				return "errors"
+			}
+
+			return ""
+		}),
+		WithCompression(CompressionZstd, CompressionNone),
+		WithLinger(time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := sypl.New("api", o)
+
+	l.PrintlnWithOptions(level.Info, "plain", sypl.WithFields(fields.Fields{"tenant": "acme"}))
+	l.PrintlnWithOptions(level.Info, "audited", sypl.WithTags("audit"))
+	l.Errorln("failed")
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	byTopic := map[string]*kgo.Record{}
+
+	for _, r := range consume(t, seeds, 3, "logs", "audit", "errors") {
+		byTopic[r.Topic] = r
+	}
+
+	plain := byTopic["logs"]
+	if plain == nil || string(plain.Key) != "acme" {
+		t.Fatalf("logs record = %+v", plain)
+	}
+
+	var doc map[string]any
+
+	if err := json.Unmarshal(plain.Value, &doc); err != nil || doc["message"] != "plain" {
+		t.Errorf("value = %s (%v)", plain.Value, err)
+	}
+
+	if r := byTopic["audit"]; r == nil || !strings.Contains(string(r.Value), "audited") || r.Key != nil {
+		t.Errorf("audit record = %+v", r)
+	}
+
+	if r := byTopic["errors"]; r == nil || !strings.Contains(string(r.Value), "failed") {
+		t.Errorf("errors record = %+v", r)
+	}
+}
+
+func TestOutput_DeliveryErrors(t *testing.T) {
+	seeds := newCluster(t, "logs")
+
+	delivered := make(chan error, 10)
+
+	o, err := Output(seeds, "missing", level.Trace, []Option{
+		WithClientOptions(kgo.UnknownTopicRetries(0)),
+		WithOnError(func(err error) { delivered <- err }),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu       sync.Mutex
+		reported []error
+	)
+
+	l := sypl.New("api", o).SetErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		reported = append(reported, err)
+	})
+
+	defer l.Close()
+
+	l.Infoln("lost")
+
+	if err := o.(flusher).Flush(); err == nil || !strings.Contains(err.Error(), `topic "missing"`) {
+		t.Fatalf("Flush = %v, want the delivery error", err)
+	}
+
+	<-delivered
+
+	// A delivery error surfaces through the error handler, on the next
+	// write.
+	l.Infoln("lost again")
+
+	select {
+	case <-delivered:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no delivery error")
+	}
+
+	l.Infoln("reporter")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(reported) == 0 || !strings.Contains(reported[0].Error(), "output Kafka") || !strings.Contains(reported[0].Error(), `topic "missing"`) {
+		t.Errorf("reported = %v", reported)
+	}
+}
+
+func TestOutput_NoTopic(t *testing.T) {
+	seeds := newCluster(t, "audit")
+
+	o, err := Output(seeds, "", level.Trace, []Option{WithTagTopics(map[string]string{"audit": "audit"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer o.(io.Closer).Close()
+
+	var got error
+
+	l := sypl.New("api", o).SetErrorHandler(func(err error) { got = err })
+
+	l.Infoln("untagged")
+
+	if !errors.Is(got, ErrNoTopic) {
+		t.Errorf("error = %v, want ErrNoTopic", got)
+	}
+}
+
+func TestOutput_Close(t *testing.T) {
+	seeds := newCluster(t, "logs")
+
+	o, err := Output(seeds, "logs", level.Trace, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := sypl.New("api", o)
+
+	l.Infoln("pending")
+
+	c := o.(io.Closer)
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	if err := o.(flusher).Flush(); err != nil {
+		t.Errorf("Flush after Close = %v, want a no-op", err)
+	}
+
+	if _, err := o.GetWriter().Write([]byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+
+	if records := consume(t, seeds, 1, "logs"); !strings.Contains(string(records[0].Value), "pending") {
+		t.Errorf("record = %s", records[0].Value)
+	}
+}
+
+func TestConfig_TopicOf(t *testing.T) {
+	c := newConfig("default", WithTagTopics(map[string]string{"b": "tb", "a": "ta", "*": "all"}))
+
+	for _, tc := range []struct {
+		tags []string
+		want string
+	}{
+		{[]string{"b", "a"}, "ta"},
+		{[]string{"b"}, "tb"},
+		{[]string{"z"}, "all"},
+		{nil, "all"},
+	} {
+		m := newMessage(tc.tags...)
+
+		if got := c.topicOf(m); got != tc.want {
+			t.Errorf("topicOf(tags %v) = %q, want %q", tc.tags, got, tc.want)
+		}
+	}
+
+	if got := newConfig("default").topicOf(newMessage()); got != "default" {
+		t.Errorf("topicOf = %q, want the default topic", got)
+	}
+}
+
+func newMessage(tags ...string) message.IMessage {
+	m := message.New(level.Info, "content")
+
+	m.AddTags(tags...)
+
+	return m
+}
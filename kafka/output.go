@@ -0,0 +1,241 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/processor"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// kafkaOutput is a Kafka-backed `output.IOutput` carrying the Flush, and
+// Close capabilities.
+//
+// The topic, key, and timestamp come from the MESSAGE - after processors
+// ran - while the record value is the formatted content. Like the Loki
+// output, the message being written is handed to the writer through
+// `current`: writes are serialized per output - producing is asynchronous,
+// so it's cheap.
+type kafkaOutput struct {
+	*output.Proxy
+
+	cfg *config
+
+	client *kgo.Client
+
+	// mu serializes writes, and Close, guarding `current`, and `closeErr`.
+	mu       sync.Mutex
+	current  message.IMessage
+	closeErr error
+
+	// closed is set by Close.
+	closed atomic.Bool
+
+	// errMu guards the pending delivery errors.
+	errMu         sync.Mutex
+	pendingErrs   []error
+	droppedErrors int
+}
+
+// Write writes the message through the output's pipeline. Pending delivery
+// errors - of previously written messages - are returned along with the
+// write error, if any: that's how they reach `Sypl.SetErrorHandler`.
+func (o *kafkaOutput) Write(m message.IMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.current = m
+
+	defer func() { o.current = nil }()
+
+	return errors.Join(o.Proxy.Write(m), o.takeErrors())
+}
+
+// Flush waits for the buffered records to be delivered - up to the flush
+// timeout - returning the pending delivery errors, if any. After Close it's
+// a no-op.
+func (o *kafkaOutput) Flush() error {
+	if o.closed.Load() {
+		return nil
+	}
+
+	return o.flush()
+}
+
+// Close flushes, and closes the producer. It's idempotent: subsequent calls
+// return the FIRST call's outcome. Writes after Close return `ErrClosed`.
+func (o *kafkaOutput) Close() error {
+	o.mu.Lock()
+
+	if o.closed.Load() {
+		o.mu.Unlock()
+
+		return o.closeErr
+	}
+
+	o.closed.Store(true)
+
+	o.mu.Unlock()
+
+	err := o.flush()
+
+	o.client.Close()
+
+	o.mu.Lock()
+	o.closeErr = err
+	o.mu.Unlock()
+
+	return err
+}
+
+// flush waits for the buffered records, returning the pending delivery
+// errors.
+func (o *kafkaOutput) flush() error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.cfg.flushTimeout)
+	defer cancel()
+
+	var err error
+
+	if flushErr := o.client.Flush(ctx); flushErr != nil {
+		err = fmt.Errorf("kafka output: flush: %w", flushErr)
+	}
+
+	return errors.Join(err, o.takeErrors())
+}
+
+// promise is the produce callback: it records delivery errors.
+func (o *kafkaOutput) promise(r *kgo.Record, err error) {
+	if err == nil {
+		return
+	}
+
+	err = fmt.Errorf("kafka output: failed delivering to topic %q: %w", r.Topic, err)
+
+	if o.cfg.onError != nil {
+		o.cfg.onError(err)
+	}
+
+	o.errMu.Lock()
+	defer o.errMu.Unlock()
+
+	if len(o.pendingErrs) >= maxPendingErrors {
+		o.droppedErrors++
+
+		return
+	}
+
+	o.pendingErrs = append(o.pendingErrs, err)
+}
+
+// takeErrors returns, and clears the pending delivery errors - nil if none.
+func (o *kafkaOutput) takeErrors() error {
+	o.errMu.Lock()
+	defer o.errMu.Unlock()
+
+	errs := o.pendingErrs
+
+	if o.droppedErrors > 0 {
+		errs = append(errs, fmt.Errorf("kafka output: %d more delivery errors", o.droppedErrors))
+	}
+
+	o.pendingErrs = nil
+	o.droppedErrors = 0
+
+	return errors.Join(errs...)
+}
+
+// recordWriter is the output's writer: it produces the formatted content as
+// a record of the current message's topic.
+type recordWriter struct {
+	o *kafkaOutput
+}
+
+// Write conforms to the `io.Writer` interface.
+func (w *recordWriter) Write(p []byte) (int, error) {
+	if w.o.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	// CLONED: the record outlives this call, but the `io.Writer` contract
+	// forbids retaining `p`. Trailing linebreaks - restored by Sypl's
+	// pipeline after formatting - aren't part of the record.
+	r := &kgo.Record{Value: bytes.Clone(bytes.TrimRight(p, "\r\n"))}
+
+	// Called outside of `Write` - e.g. directly, via `GetWriter` - only the
+	// `Output` topic applies.
+	if m := w.o.current; m != nil {
+		r.Topic = w.o.cfg.topicOf(m)
+		r.Key = w.o.cfg.keyOf(m)
+		r.Timestamp = m.GetTimestamp()
+	} else {
+		r.Topic = w.o.cfg.topic
+		r.Timestamp = time.Now()
+	}
+
+	if r.Topic == "" {
+		return 0, ErrNoTopic
+	}
+
+	// NOTE: Blocks when franz-go's buffer - `kgo.MaxBufferedRecords` - is
+	// full: backpressure instead of unbounded memory.
+	w.o.client.Produce(context.Background(), r, w.o.promise)
+
+	return len(p), nil
+}
+
+//////
+// Factory.
+//////
+
+// Output is a built-in `output` - named `Kafka` - that produces each
+// message as a record to the `seeds` brokers' cluster. `topic` is the
+// default topic - it may be empty when `WithTagTopics`, or `WithTopicFunc`
+// route every message. See the `With*` options for keys, batching,
+// compression, acknowledgements, and client settings.
+//
+// Delivery is asynchronous: its errors are returned by the next Write - so
+// they reach `Sypl.SetErrorHandler` - and by Flush. See also `WithOnError`.
+//
+// Capabilities: `Flush() error` (waits for the buffered records), and
+// idempotent `Close() error`.
+//
+// NOTE: By default, data is JSON-formatted - inline.
+func Output(
+	seeds []string,
+	topic string,
+	maxLevel level.Level,
+	opts []Option,
+	processors ...processor.IProcessor,
+) (output.IOutput, error) {
+	cfg := newConfig(topic, opts...)
+
+	client, err := kgo.NewClient(cfg.kgoOpts(seeds)...)
+	if err != nil {
+		return nil, fmt.Errorf("kafka output: failed creating the client: %w", err)
+	}
+
+	o := &kafkaOutput{cfg: cfg, client: client}
+
+	inner := output.New("Kafka", maxLevel, &recordWriter{o: o}, processors...).SetFormatter(formatter.JSON())
+
+	o.Proxy = output.NewProxy(inner, o)
+
+	return o, nil
+}
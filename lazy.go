@@ -0,0 +1,91 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl
+
+import (
+	"fmt"
+
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+)
+
+//////
+// Lazy, deferred message construction.
+//
+// The content, and fields of a message are built by a closure - called only
+// if at least one output would write the message at that level (see
+// `Enabled`) - so callers pay nothing - no formatting, no argument
+// evaluation - for filtered-out levels. Unlike the fast gate (see `SetFastGate`), it's always on, and
+// honors the `SYPL_LEVEL`, and `SYPL_FILTER` env vars.
+//
+// For deferred values in fields, see `fields.Lazy`.
+//////
+
+// LogFn prints, at the specified level, the content, and fields built by
+// `fn` - called only if at least one output would write the message. See
+// the package lazy notes.
+//
+// Example:
+//
+//	l.LogFn(level.Debug, func() (string, fields.Fields) {
+//		return "cache state", fields.Fields{"entries": cache.Dump()}
+//	})
+func (sypl *Sypl) LogFn(l level.Level, fn func() (string, fields.Fields)) {
+	// A nil logger takes the slow path: `process` owns the nil-receiver
+	// contract (log.Fatalf).
+	if sypl != nil && !sypl.Enabled(l) {
+		return
+	}
+
+	ct, f := fn()
+
+	sypl.PrintWithOptions(l, ct, WithFields(f))
+}
+
+// TraceFn prints @ the Trace level - see `LogFn`.
+func (sypl *Sypl) TraceFn(fn func() (string, fields.Fields)) {
+	sypl.LogFn(level.Trace, fn)
+}
+
+// DebugFn prints @ the Debug level - see `LogFn`.
+func (sypl *Sypl) DebugFn(fn func() (string, fields.Fields)) {
+	sypl.LogFn(level.Debug, fn)
+}
+
+// InfoFn prints @ the Info level - see `LogFn`.
+func (sypl *Sypl) InfoFn(fn func() (string, fields.Fields)) {
+	sypl.LogFn(level.Info, fn)
+}
+
+// WarnFn prints @ the Warn level - see `LogFn`.
+func (sypl *Sypl) WarnFn(fn func() (string, fields.Fields)) {
+	sypl.LogFn(level.Warn, fn)
+}
+
+// ErrorFn prints @ the Error level - see `LogFn`.
+func (sypl *Sypl) ErrorFn(fn func() (string, fields.Fields)) {
+	sypl.LogFn(level.Error, fn)
+}
+
+// FatalFn prints @ the Fatal level - see `LogFn` - then exits with
+// os.Exit(1).
+func (sypl *Sypl) FatalFn(fn func() (string, fields.Fields)) {
+	sypl.LogFn(level.Fatal, fn)
+}
+
+// Lazy prints, at the specified level, the value returned by `fn` - called
+// only if at least one output would write the message. The value is
+// formatted like `Print` does - e.g. `fmt.Stringer`s are called then.
+//
+// Example:
+//
+//	l.Lazy(level.Trace, func() any { return req.Dump() })
+func (sypl *Sypl) Lazy(l level.Level, fn func() any) {
+	if sypl != nil && !sypl.Enabled(l) {
+		return
+	}
+
+	sypl.PrintWithOptions(l, fmt.Sprint(fn()))
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl_test
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/shared"
+)
+
+// The closure runs only if an output would write the message - honoring
+// the env vars.
+func TestLogFn(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	buf, o := output.SafeBuffer(level.Info)
+
+	o.SetFormatter(formatter.Text())
+
+	l := sypl.New("lazy", o)
+
+	var calls atomic.Int32
+
+	fn := func(ct string) func() (string, fields.Fields) {
+		return func() (string, fields.Fields) {
+			calls.Add(1)
+
+			return ct, fields.Fields{"k": "v"}
+		}
+	}
+
+	l.DebugFn(fn("debug"))
+	l.Lazy(level.Trace, func() any { calls.Add(1); return "trace" })
+
+	if calls.Load() != 0 || buf.String() != "" {
+		t.Fatalf("gated levels must not call the closure, calls = %d, got: %q", calls.Load(), buf.String())
+	}
+
+	l.InfoFn(fn("info"))
+	l.Lazy(level.Warn, func() any { return "warn" })
+
+	if calls.Load() != 1 || !strings.Contains(buf.String(), "info") || !strings.Contains(buf.String(), "k=v") ||
+		!strings.Contains(buf.String(), "warn") {
+		t.Fatalf("calls = %d, got: %q", calls.Load(), buf.String())
+	}
+
+	// SYPL_LEVEL raises the output's level.
+	t.Setenv(shared.LevelEnvVar, "debug")
+
+	l.DebugFn(fn("raised"))
+
+	if !strings.Contains(buf.String(), "raised") {
+		t.Errorf("SYPL_LEVEL=debug must enable Debug, got: %q", buf.String())
+	}
+
+	// SYPL_FILTER filters the component out.
+	t.Setenv(shared.FilterEnvVar, "other")
+
+	calls.Store(0)
+
+	l.ErrorFn(fn("filtered"))
+
+	if calls.Load() != 0 {
+		t.Error("a filtered-out component must not call the closure")
+	}
+}
+
+// Deferred field values are resolved only when written - once, even with
+// several outputs.
+func TestLazyField(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	buf1, o1 := output.SafeBuffer(level.Info)
+	buf2, o2 := output.SafeBuffer(level.Info)
+
+	o1.SetFormatter(formatter.Text())
+	o2.SetFormatter(formatter.JSON())
+
+	l := sypl.New("lazy", o1, o2)
+
+	var calls atomic.Int32
+
+	value := func() fields.Valuer {
+		return fields.Lazy(func() any {
+			calls.Add(1)
+
+			return "expensive"
+		})
+	}
+
+	// Not written - Debug is filtered out - even with options.
+	l.PrintWithOptions(level.Debug, "gated", sypl.WithField("dump", value()))
+
+	if calls.Load() != 0 {
+		t.Fatalf("a filtered-out message must not resolve its fields, calls = %d", calls.Load())
+	}
+
+	l.PrintWithOptions(level.Info, "written", sypl.WithField("dump", value()))
+
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+
+	if !strings.Contains(buf1.String(), "dump=expensive") || !strings.Contains(buf2.String(), `"dump":"expensive"`) {
+		t.Errorf("got: %q, and %q", buf1.String(), buf2.String())
+	}
+}
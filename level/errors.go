@@ -7,3 +7,7 @@ package level
 import "errors"
 
 var ErrInvalidLevel = errors.New("invalid error level")
+
+// ErrLevelRegistered is returned when registering a level whose name, or
+// value is already in use - see `Register`.
+var ErrLevelRegistered = errors.New("level already registered")
@@ -22,6 +22,9 @@ import (
 // on v1's numeric values (e.g. `FromInt(3)` meaning Info, or persisted
 // integers) must be migrated - see MIGRATION-V2.md. Name-based lookups
 // (`FromString`, `MustFromString`, `String`) are unaffected.
+//
+// Custom levels can be registered in between built-in ones - see `Register`.
+// Compare levels with `Admits`, or `Verbosity`.
 type Level int
 
 // Available levels.
@@ -38,12 +41,18 @@ const (
 var names = []string{"none", "fatal", "error", "warn", "info", "debug", "trace"}
 
 // String interface implementation.
+//
+// NOTE: Custom levels return their registered name - see `Register`.
 func (l Level) String() string {
-	if l < None || l > Trace {
-		return "Unknown"
+	if l >= None && l <= Trace {
+		return names[l]
+	}
+
+	if name, ok := customName(l); ok {
+		return name
 	}
 
-	return names[l]
+	return "Unknown"
 }
 
 // FromInt returns a `Level` from a given integer.
@@ -51,7 +60,8 @@ func (l Level) String() string {
 // V2 BREAKING CHANGE: integers follow the v2 conventional order -
 // FromInt(3) is now Warn, and FromInt(4) is now Info (swapped from v1).
 //
-// NOTE: Failure will return "Unknown".
+// NOTE: Failure will return "Unknown". Custom levels are returned by their
+// value - see `Register`.
 func FromInt(level int) Level {
 	return Level(level)
 }
@@ -70,6 +80,10 @@ func FromString(level string) (Level, error) {
 		}
 	}
 
+	if l, ok := customFromName(level); ok {
+		return l, nil
+	}
+
 	return None, fmt.Errorf("%w: %s. Available: %s", ErrInvalidLevel, level, strings.Join(LevelsNames(), ", "))
 }
 
@@ -86,7 +100,11 @@ func MustFromString(level string) Level {
 		}
 	}
 
-	log.Fatalf("%s Invalid level: %s. Available: %s", shared.ErrorPrefix, level, strings.Join(names, ", "))
+	if l, ok := customFromName(level); ok {
+		return l
+	}
+
+	log.Fatalf("%s Invalid level: %s. Available: %s", shared.ErrorPrefix, level, strings.Join(LevelsNames(), ", "))
 
 	return None
 }
@@ -102,12 +120,13 @@ func LevelsToString(levels []Level) string {
 	return strings.Join(names, ",")
 }
 
-// LevelsNames returns the name of all available levels.
+// LevelsNames returns the name of all available levels - built-in, and
+// custom ones - sorted by verbosity.
 func LevelsNames() []string {
 	finalNames := []string{}
 
-	for _, name := range names {
-		finalNames = append(finalNames, strings.ToLower(name))
+	for _, l := range Levels() {
+		finalNames = append(finalNames, strings.ToLower(l.String()))
 	}
 
 	return finalNames
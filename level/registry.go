@@ -0,0 +1,248 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package level
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/thalesfsp/sypl/v2/shared"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// Scale is the verbosity spacing between built-in levels: built-in level
+// `N` sits at verbosity `N * Scale` - Fatal(10) Error(20) Warn(30) Info(40)
+// Debug(50) Trace(60). Custom levels are registered directly at their
+// verbosity, e.g.: 35 sits between Warn, and Info, 70 above Trace.
+const Scale = 10
+
+// nameRegex validates custom levels' names. Names are used in the
+// `SYPL_LEVEL` env var - `,`, and `:` are separators.
+var nameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// registry holds the custom levels.
+var registry = struct {
+	// mu guards `names`, and `levels`.
+	mu sync.RWMutex
+
+	// names of the custom levels.
+	names map[Level]string
+
+	// levels are the custom levels, sorted by verbosity.
+	levels []Level
+
+	// generation is incremented on every registration, and unregistration.
+	generation atomic.Uint64
+}{
+	names: map[Level]string{},
+}
+
+//////
+// Methods.
+//////
+
+// Verbosity returns the level's position in the verbosity order - see
+// `Scale`. The higher, the more verbose. Compare levels by verbosity, never
+// by their numeric values: custom levels sit in between built-in ones.
+func (l Level) Verbosity() int {
+	if l >= None && l <= Trace {
+		return int(l) * Scale
+	}
+
+	return int(l)
+}
+
+// Admits returns if a max level `l` admits a message at level `other` - i.e.
+// `other` is as, or less verbose than `l`.
+func (l Level) Admits(other Level) bool {
+	return other.Verbosity() <= l.Verbosity()
+}
+
+// Base returns the built-in level `l` falls under - rounding towards the more
+// verbose neighbour, capped at `Trace`. Built-in levels return themselves.
+// Use it to map levels to external severities, e.g.: a custom level
+// registered at 35 - between Warn, and Info - maps like Info.
+func (l Level) Base() Level {
+	if l >= None && l <= Trace {
+		return l
+	}
+
+	v := l.Verbosity()
+
+	if v <= 0 {
+		return None
+	}
+
+	return min(Level((v+Scale-1)/Scale), Trace)
+}
+
+// IsValid returns if `l` is a built-in, or a registered custom level.
+func (l Level) IsValid() bool {
+	if l >= None && l <= Trace {
+		return true
+	}
+
+	_, ok := customName(l)
+
+	return ok
+}
+
+//////
+// Helpers.
+//////
+
+// customName returns the name of a custom level, if registered.
+func customName(l Level) (string, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	name, ok := registry.names[l]
+
+	return name, ok
+}
+
+// customFromName returns the custom level named `name` - case-insensitive.
+func customFromName(name string) (Level, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	for l, n := range registry.names {
+		if strings.EqualFold(name, n) {
+			return l, true
+		}
+	}
+
+	return None, false
+}
+
+// Levels returns all levels - built-in, and custom ones - sorted by
+// verbosity.
+func Levels() []Level {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	levels := make([]Level, 0, int(Trace)+1+len(registry.levels))
+
+	for l := None; l <= Trace; l++ {
+		levels = append(levels, l)
+	}
+
+	levels = append(levels, registry.levels...)
+
+	slices.SortStableFunc(levels, func(a, b Level) int {
+		return a.Verbosity() - b.Verbosity()
+	})
+
+	return levels
+}
+
+// Generation returns the registry's generation - incremented on every
+// registration, and unregistration. Caches derived from the levels' names
+// key on it.
+func Generation() uint64 {
+	return registry.generation.Load()
+}
+
+//////
+// Registry.
+//////
+
+// Register registers a custom level `name` at verbosity `value` - see
+// `Scale`. It returns the level, usable anywhere a built-in one is:
+// `Sypl.Print`, outputs' max level, `FromString`, `SYPL_LEVEL`, etc.
+//
+// Rules:
+//   - `name` is lower-cased, and must match `^[a-z][a-z0-9_]*$`. It must not
+//     be in use
+//   - `value` must be at least `Scale`, must not be in use, and must not
+//     collide with a built-in level's verbosity (Scale multiples up to
+//     `Trace * Scale`).
+//
+// Example:
+//
+//	var Notice = level.MustRegister("notice", 35) // Between Warn, and Info.
+//
+// NOTE: Register levels at initialization - e.g. in package-level vars -
+// before logging.
+func Register(name string, value Level) (Level, error) {
+	name = strings.ToLower(name)
+
+	if !nameRegex.MatchString(name) {
+		return None, fmt.Errorf("%w: %q. Names must match %s", ErrInvalidLevel, name, nameRegex)
+	}
+
+	if value < Scale || (value <= Trace*Scale && value%Scale == 0) {
+		return None, fmt.Errorf(
+			"%w: %q value %d. Values must be at least %d, and not collide with built-in levels' verbosity",
+			ErrInvalidLevel, name, value, Scale,
+		)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	for i, n := range names {
+		if n == name {
+			return None, fmt.Errorf("%w: %q is the built-in level %d", ErrLevelRegistered, name, i)
+		}
+	}
+
+	for l, n := range registry.names {
+		if n == name || l == value {
+			return None, fmt.Errorf("%w: %q, or value %d, already in use by %q (%d)", ErrLevelRegistered, name, value, n, l)
+		}
+	}
+
+	registry.names[value] = name
+
+	registry.levels = append(registry.levels, value)
+
+	slices.Sort(registry.levels)
+
+	registry.generation.Add(1)
+
+	return value, nil
+}
+
+// MustRegister is like `Register`, but failure will log, and exit.
+func MustRegister(name string, value Level) Level {
+	l, err := Register(name, value)
+	if err != nil {
+		log.Fatalf("%s %s", shared.ErrorPrefix, err)
+	}
+
+	return l
+}
+
+// Unregister removes the custom level `l` - see `Register`. It returns
+// `ErrInvalidLevel` if `l` isn't a registered custom level.
+//
+// NOTE: Meant for tests - register in the test, and unregister in its
+// cleanup, e.g.: `t.Cleanup(func() { _ = level.Unregister(notice) })`. Don't
+// unregister levels still in use.
+func Unregister(l Level) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.names[l]; !ok {
+		return fmt.Errorf("%w: %d is not a registered custom level", ErrInvalidLevel, l)
+	}
+
+	delete(registry.names, l)
+
+	registry.levels = slices.DeleteFunc(registry.levels, func(other Level) bool { return other == l })
+
+	registry.generation.Add(1)
+
+	return nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package level
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// resetRegistry restores the custom levels once the test finishes - and
+// moves the generation on, so caches keyed on it don't serve entries
+// computed with the test's levels.
+func resetRegistry(t *testing.T) {
+	t.Helper()
+
+	registry.mu.RLock()
+	names, levels := maps.Clone(registry.names), slices.Clone(registry.levels)
+	registry.mu.RUnlock()
+
+	t.Cleanup(func() {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+
+		registry.names = names
+		registry.levels = levels
+
+		registry.generation.Add(1)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	resetRegistry(t)
+
+	notice, err := Register("Notice", 35)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	verbose2 := MustRegister("verbose2", 70)
+
+	if notice.String() != "notice" || verbose2.String() != "verbose2" {
+		t.Errorf("String() = %q, %q, want notice, verbose2", notice, verbose2)
+	}
+
+	if l, err := FromString("NOTICE"); err != nil || l != notice {
+		t.Errorf("FromString(NOTICE) = %v, %v, want notice", l, err)
+	}
+
+	if l := MustFromString("verbose2"); l != verbose2 {
+		t.Errorf("MustFromString(verbose2) = %v, want verbose2", l)
+	}
+
+	// Custom levels are listed by verbosity, in between built-in ones.
+	want := []string{"none", "fatal", "error", "warn", "notice", "info", "debug", "trace", "verbose2"}
+
+	if got := LevelsNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LevelsNames() = %v, want %v", got, want)
+	}
+
+	// Built-in numeric values are untouched.
+	if int(Warn) != 3 || int(Info) != 4 || FromInt(4) != Info {
+		t.Error("built-in numeric values must not change")
+	}
+
+	if !notice.IsValid() || Level(36).IsValid() {
+		t.Error("IsValid() must be true only for registered levels")
+	}
+
+	tests := []struct {
+		name  string
+		value Level
+		want  error
+	}{
+		{name: "", value: 36, want: ErrInvalidLevel},
+		{name: "not:ice", value: 36, want: ErrInvalidLevel},
+		{name: "low", value: 5, want: ErrInvalidLevel},
+		{name: "builtin_verbosity", value: 40, want: ErrInvalidLevel},
+		{name: "info", value: 36, want: ErrLevelRegistered},
+		{name: "notice", value: 36, want: ErrLevelRegistered},
+		{name: "other", value: 35, want: ErrLevelRegistered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Register(tt.name, tt.value); !errors.Is(err, tt.want) {
+				t.Errorf("Register(%q, %d) error = %v, want %v", tt.name, tt.value, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	resetRegistry(t)
+
+	generation := Generation()
+
+	notice := MustRegister("notice", 35)
+
+	if err := Unregister(notice); err != nil {
+		t.Fatalf("Unregister() error = %v", err)
+	}
+
+	if notice.IsValid() || slices.Contains(Levels(), notice) {
+		t.Error("unregistered level still known")
+	}
+
+	if _, err := FromString("notice"); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("FromString(notice) error = %v, want ErrInvalidLevel", err)
+	}
+
+	if got := Generation(); got != generation+2 {
+		t.Errorf("Generation() = %d, want %d - moved on by both changes", got, generation+2)
+	}
+
+	if err := Unregister(notice); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("second Unregister() error = %v, want ErrInvalidLevel", err)
+	}
+
+	// The name, and value are free again.
+	if _, err := Register("notice", 35); err != nil {
+		t.Errorf("Register() after Unregister() error = %v", err)
+	}
+}
+
+func TestLevel_VerbosityAdmitsBase(t *testing.T) {
+	resetRegistry(t)
+
+	notice := MustRegister("notice", 35)
+	verbose2 := MustRegister("verbose2", 70)
+
+	tests := []struct {
+		name      string
+		max       Level
+		l         Level
+		admits    bool
+		verbosity int
+	}{
+		{name: "warn caps notice", max: Warn, l: notice, admits: false, verbosity: 35},
+		{name: "info admits notice", max: Info, l: notice, admits: true, verbosity: 35},
+		{name: "notice admits warn", max: notice, l: Warn, admits: true, verbosity: 30},
+		{name: "notice caps info", max: notice, l: Info, admits: false, verbosity: 40},
+		{name: "trace caps verbose2", max: Trace, l: verbose2, admits: false, verbosity: 70},
+		{name: "verbose2 admits trace", max: verbose2, l: Trace, admits: true, verbosity: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.max.Admits(tt.l); got != tt.admits {
+				t.Errorf("%s.Admits(%s) = %v, want %v", tt.max, tt.l, got, tt.admits)
+			}
+
+			if got := tt.l.Verbosity(); got != tt.verbosity {
+				t.Errorf("%s.Verbosity() = %d, want %d", tt.l, got, tt.verbosity)
+			}
+		})
+	}
+
+	if notice.Base() != Info || verbose2.Base() != Trace || Warn.Base() != Warn || Level(15).Base() != Error {
+		t.Errorf("Base() = %s, %s, %s, %s", notice.Base(), verbose2.Base(), Warn.Base(), Level(15).Base())
+	}
+}
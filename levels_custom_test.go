@@ -0,0 +1,105 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/shared"
+)
+
+// registerNotice registers "notice" - a custom level between Warn, and
+// Info - for the test's duration.
+func registerNotice(t *testing.T) level.Level {
+	t.Helper()
+
+	notice, err := level.Register("notice", 35)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := level.Unregister(notice); err != nil {
+			t.Errorf("Unregister() error = %v", err)
+		}
+	})
+
+	return notice
+}
+
+// Custom levels nest between built-in ones: an output capped at Warn hides
+// them, one capped at Info shows them - and capping at a custom level hides
+// the more verbose built-in ones.
+func TestCustomLevel_Visibility(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	notice := registerNotice(t)
+
+	tests := []struct {
+		cap     level.Level
+		visible []string
+		hidden  []string
+	}{
+		{cap: level.Warn, visible: []string{"msg-warn"}, hidden: []string{"msg-notice", "msg-info"}},
+		{cap: notice, visible: []string{"msg-warn", "msg-notice"}, hidden: []string{"msg-info"}},
+		{cap: level.Info, visible: []string{"msg-warn", "msg-notice", "msg-info"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cap.String(), func(t *testing.T) {
+			buf, o := output.SafeBuffer(tt.cap)
+
+			// The fast gate compares by verbosity too.
+			l := sypl.New("custom-level", o).SetFastGate(true)
+
+			l.Warnln("msg-warn")
+			l.Println(notice, "msg-notice")
+			l.Infoln("msg-info")
+
+			for _, s := range tt.visible {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("cap %s must show %q, got: %q", tt.cap, s, buf.String())
+				}
+			}
+
+			for _, s := range tt.hidden {
+				if strings.Contains(buf.String(), s) {
+					t.Errorf("cap %s must hide %q, got: %q", tt.cap, s, buf.String())
+				}
+			}
+		})
+	}
+}
+
+// Custom levels are named by formatters, and usable in `SYPL_LEVEL`.
+func TestCustomLevel_NameAndEnvVar(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	notice := registerNotice(t)
+
+	buf, o := output.SafeBuffer(level.Warn)
+
+	o.SetFormatter(formatter.Text())
+
+	l := sypl.New("custom-level", o)
+
+	l.Println(notice, "hidden")
+
+	t.Setenv(shared.LevelEnvVar, "notice")
+
+	l.Println(notice, "shown")
+	l.Infoln("still hidden")
+
+	got := buf.String()
+
+	if strings.Contains(got, "hidden") || !strings.Contains(got, "shown") || !strings.Contains(got, "level=notice") {
+		t.Errorf("SYPL_LEVEL=notice must show notice only, got: %q", got)
+	}
+}
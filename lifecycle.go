@@ -42,11 +42,18 @@ func (sypl *Sypl) Flush() error {
 
 // Close closes every registered output implementing `io.Closer`, in
 // registration order, aggregating all errors via `errors.Join`. Outputs
-// lacking the capability are skipped.
+// lacking the capability are skipped. The output workers - see
+// `DispatchWorkerPool` - are stopped first, once they drained their queues,
+// if this logger created them: child, and derived loggers share them, thus
+// leave them running.
 //
 // NOTE: The outputs are snapshotted under the read lock, which is released
 // BEFORE any Close call.
 func (sypl *Sypl) Close() error {
+	if _, d := sypl.getDispatch(); d != nil && d.owner == sypl {
+		d.stop()
+	}
+
 	outputs := sypl.GetOutputs()
 
 	errs := make([]error, 0, len(outputs))
@@ -0,0 +1,36 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package loki provides Sypl's Grafana Loki support: a batching push API
+// client, and the ready-to-use `output.IOutput` factory. It lives in its own
+// Go module (github.com/thalesfsp/sypl/loki/v2) so the core sypl module
+// carries no Loki-related dependency - import this module only if you log to
+// Loki.
+//
+// Features:
+//   - Two encodings: snappy-compressed protobuf (default), and JSON - see
+//     `WithEncoding`.
+//   - Batching by entries count, total lines size, and interval - see
+//     `WithBatchSize`, `WithBatchBytes`, and `WithFlushInterval`.
+//   - Stream labels from a configurable subset of the message: component,
+//     and level by default, plus static labels, promoted fields, and
+//     promoted tags. The line is the formatted message - JSON by default -
+//     so high-cardinality data stays out of the labels, but queryable.
+//   - Out-of-order safety: entries are sorted by timestamp per stream, and
+//     pushes are serialized.
+//   - Retries - with exponential backoff, in order - on transport errors,
+//     429, and 5xx - see `WithRetry` - and bounded buffering while Loki is
+//     down - see `WithMaxBuffered`. Batching is the core's `batcher`
+//     package, shared with `output.HTTP`.
+//   - `Flush`/`Close` capabilities, like `es.BulkOutput`: Sypl's
+//     `Flush`/`Close` - and the pre-exit flush on Fatal - drain it.
+//
+// Usage:
+//
+//	o, err := loki.Output(
+//		"http://loki:3100/loki/api/v1/push",
+//		level.Info,
+//		[]loki.Option{loki.WithLabels(map[string]string{"job": "api"})},
+//	)
+package loki
@@ -0,0 +1,206 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package loki
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+//////
+// Streams.
+//////
+
+// stream is a set of entries sharing the same labels.
+type stream struct {
+	// key is the canonical - Prometheus-style - labels string, e.g.
+	// `{component="api", level="info"}`.
+	key string
+
+	labels map[string]string
+
+	entries []Entry
+}
+
+// labelsString renders labels the Prometheus way - sorted by name, values
+// quoted - which is also the protobuf encoding's stream identifier.
+func labelsString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+
+	for name := range labels {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	var b strings.Builder
+
+	b.WriteByte('{')
+
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// groupStreams groups the entries by labels. Streams are sorted by key, and
+// each stream's entries by timestamp - stable, so equal timestamps keep the
+// write order: Loki rejects out-of-order entries within a stream (unless
+// configured otherwise), and concurrently logged messages can reach the
+// batch slightly out of order.
+func groupStreams(entries []Entry) []*stream {
+	byKey := map[string]*stream{}
+
+	streams := []*stream{}
+
+	for _, e := range entries {
+		key := labelsString(e.Labels)
+
+		s, ok := byKey[key]
+		if !ok {
+			s = &stream{key: key, labels: e.Labels}
+
+			byKey[key] = s
+
+			streams = append(streams, s)
+		}
+
+		s.entries = append(s.entries, e)
+	}
+
+	sort.Slice(streams, func(i, j int) bool { return streams[i].key < streams[j].key })
+
+	for _, s := range streams {
+		sort.SliceStable(s.entries, func(i, j int) bool {
+			return s.entries[i].Timestamp.Before(s.entries[j].Timestamp)
+		})
+	}
+
+	return streams
+}
+
+//////
+// JSON encoding.
+//////
+
+// jsonStream is the JSON push API stream.
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// jsonPushRequest is the JSON push API request.
+type jsonPushRequest struct {
+	Streams []jsonStream `json:"streams"`
+}
+
+// encodeJSON encodes the streams as a JSON push request - timestamps are
+// nanosecond epoch strings.
+func encodeJSON(streams []*stream) ([]byte, error) {
+	req := jsonPushRequest{Streams: make([]jsonStream, 0, len(streams))}
+
+	for _, s := range streams {
+		js := jsonStream{Stream: s.labels, Values: make([][2]string, 0, len(s.entries))}
+
+		if js.Stream == nil {
+			js.Stream = map[string]string{}
+		}
+
+		for _, e := range s.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.Timestamp.UnixNano(), 10), e.Line})
+		}
+
+		req.Streams = append(req.Streams, js)
+	}
+
+	return json.Marshal(req)
+}
+
+//////
+// Protobuf encoding.
+//
+// Hand-encoded `logproto.PushRequest` - avoiding Loki's, and gogoproto's
+// dependency trees:
+//
+//	message PushRequest   { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter  { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+//	message Timestamp     { int64 seconds = 1; int32 nanos = 2; }
+//////
+
+// Field numbers.
+const (
+	pbPushRequestStreams   protowire.Number = 1
+	pbStreamLabels         protowire.Number = 1
+	pbStreamEntries        protowire.Number = 2
+	pbEntryTimestamp       protowire.Number = 1
+	pbEntryLine            protowire.Number = 2
+	pbTimestampSeconds     protowire.Number = 1
+	pbTimestampNanoseconds protowire.Number = 2
+)
+
+// appendMessage appends an embedded message field.
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, msg)
+}
+
+// encodeEntry encodes an `EntryAdapter`.
+func encodeEntry(e Entry) []byte {
+	var ts []byte
+
+	// Zero values are omitted - proto3 semantics.
+	if sec := e.Timestamp.Unix(); sec != 0 {
+		ts = protowire.AppendTag(ts, pbTimestampSeconds, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(sec))
+	}
+
+	if nsec := e.Timestamp.Nanosecond(); nsec != 0 {
+		ts = protowire.AppendTag(ts, pbTimestampNanoseconds, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(nsec))
+	}
+
+	b := appendMessage(nil, pbEntryTimestamp, ts)
+
+	b = protowire.AppendTag(b, pbEntryLine, protowire.BytesType)
+
+	return protowire.AppendString(b, e.Line)
+}
+
+// encodeProtobuf encodes the streams as a snappy-compressed protobuf push
+// request.
+func encodeProtobuf(streams []*stream) []byte {
+	var req []byte
+
+	for _, s := range streams {
+		sb := protowire.AppendTag(nil, pbStreamLabels, protowire.BytesType)
+		sb = protowire.AppendString(sb, s.key)
+
+		for _, e := range s.entries {
+			sb = appendMessage(sb, pbStreamEntries, encodeEntry(e))
+		}
+
+		req = appendMessage(req, pbPushRequestStreams, sb)
+	}
+
+	// Loki expects the snappy BLOCK format - not the framed one.
+	return snappy.Encode(nil, req)
+}
@@ -0,0 +1,20 @@
+module github.com/thalesfsp/sypl/loki/v2
+
+go 1.23
+
+replace github.com/thalesfsp/sypl/v2 => ../
+
+require (
+	github.com/golang/snappy v1.0.0
+	github.com/thalesfsp/sypl/v2 v2.0.0
+	google.golang.org/protobuf v1.36.5
+)
+
+require (
+	github.com/acarl005/stripansi v0.0.0-20180116102854-5a71ef0e047d // indirect
+	github.com/fatih/color v1.18.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	golang.org/x/sys v0.27.0 // indirect
+)
@@ -0,0 +1,119 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package loki
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+// Label names.
+const (
+	ComponentLabel = "component"
+	LevelLabel     = "level"
+	TagsLabel      = "tags"
+)
+
+// labeler derives stream labels from messages - see the `With*Label*`
+// options.
+type labeler struct {
+	// component adds the "component" label.
+	component bool
+
+	// fields promoted to labels.
+	fields []string
+
+	// level adds the "level" label.
+	level bool
+
+	// static labels.
+	static map[string]string
+
+	// tags promoted to the "tags" label.
+	tags map[string]struct{}
+}
+
+// labels returns the stream labels of `m`.
+func (l *labeler) labels(m message.IMessage) map[string]string {
+	labels := make(map[string]string, len(l.static)+len(l.fields)+3)
+
+	for k, v := range l.static {
+		labels[k] = v
+	}
+
+	if l.component && m.GetComponentName() != "" {
+		labels[ComponentLabel] = m.GetComponentName()
+	}
+
+	if l.level {
+		labels[LevelLabel] = strings.ToLower(m.GetLevel().String())
+	}
+
+	if len(l.fields) > 0 {
+		f := m.GetFields()
+
+		for _, key := range l.fields {
+			if v, ok := f[key]; ok && v != nil {
+				if s := fmt.Sprint(v); s != "" {
+					labels[sanitizeLabelName(key)] = s
+				}
+			}
+		}
+	}
+
+	if len(l.tags) > 0 {
+		present := []string{}
+
+		for _, t := range m.GetTags() {
+			if _, ok := l.tags[t]; ok {
+				present = append(present, t)
+			}
+		}
+
+		if len(present) > 0 {
+			slices.Sort(present)
+
+			labels[TagsLabel] = strings.Join(present, ",")
+		}
+	}
+
+	return labels
+}
+
+// sanitizeLabelName makes `name` a valid Prometheus label name -
+// `[a-zA-Z_][a-zA-Z0-9_]*` - replacing invalid characters with "_".
+func sanitizeLabelName(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	b := []byte(name)
+
+	for i, c := range b {
+		valid := c == '_' ||
+			(c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(i > 0 && c >= '0' && c <= '9')
+
+		if !valid {
+			b[i] = '_'
+		}
+	}
+
+	return string(b)
+}
+
+// newLabeler is the `labeler` factory. It applies defaults.
+func newLabeler() *labeler {
+	return &labeler{
+		component: true,
+		level:     true,
+		static:    map[string]string{},
+		tags:      map[string]struct{}{},
+	}
+}
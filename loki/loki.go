@@ -0,0 +1,425 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package loki
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/batcher"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// Defaults.
+const (
+	defaultBatchBytes    = 1 << 20
+	defaultBatchSize     = 1000
+	defaultFlushInterval = time.Second
+	defaultMaxBuffered   = 10000
+	defaultMaxBackoff    = 5 * time.Second
+	defaultMaxRetries    = 5
+	defaultMinBackoff    = 100 * time.Millisecond
+	defaultTimeout       = 10 * time.Second
+)
+
+// ErrClosed is returned when writing to a closed Loki client.
+var ErrClosed = errors.New("loki output is closed")
+
+// Encoding is the push request encoding.
+type Encoding int
+
+// Available encodings.
+const (
+	// EncodingProtobuf is the snappy-compressed protobuf encoding - Loki's
+	// native, and most efficient one. It's the default.
+	EncodingProtobuf Encoding = iota
+
+	// EncodingJSON is the JSON encoding.
+	EncodingJSON
+)
+
+// String interface implementation.
+func (e Encoding) String() string {
+	switch e {
+	case EncodingProtobuf:
+		return "Protobuf"
+	case EncodingJSON:
+		return "JSON"
+	default:
+		return "Unknown"
+	}
+}
+
+// Entry is a log line, and its stream labels.
+type Entry struct {
+	// Labels identify the stream.
+	Labels map[string]string
+
+	// Line is the log line.
+	Line string
+
+	// Timestamp of the entry.
+	Timestamp time.Time
+}
+
+// Option configures the Loki client.
+type Option func(*Client)
+
+// WithBatchSize sets the maximum number of entries per push. Defaults to
+// 1000.
+func WithBatchSize(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithBatchBytes sets the maximum total lines size, in bytes, per push.
+// Defaults to 1MiB.
+func WithBatchBytes(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.batchBytes = n
+		}
+	}
+}
+
+// WithFlushInterval sets the periodic push interval. Defaults to 1s.
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.flushInterval = d
+		}
+	}
+}
+
+// WithEncoding sets the push request encoding. Defaults to
+// `EncodingProtobuf`.
+func WithEncoding(e Encoding) Option {
+	return func(c *Client) {
+		c.encoding = e
+	}
+}
+
+// WithHeaders sets extra push request headers.
+func WithHeaders(headers map[string]string) Option {
+	return func(c *Client) {
+		for k, v := range headers {
+			c.headers.Set(k, v)
+		}
+	}
+}
+
+// WithTenantID sets the tenant - the `X-Scope-OrgID` header - for
+// multi-tenant Loki deployments.
+func WithTenantID(id string) Option {
+	return WithHeaders(map[string]string{"X-Scope-OrgID": id})
+}
+
+// WithBasicAuth sets the push request basic authentication.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithHTTPClient sets the HTTP client. Defaults to one with a 10s timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.httpClient = hc
+		}
+	}
+}
+
+// WithMaxBuffered sets the maximum number of pending entries - e.g. while
+// Loki is down. Beyond it, entries are dropped: `Add` returns
+// `batcher.ErrBufferFull`. Defaults to 10000.
+func WithMaxBuffered(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxBuffered = n
+		}
+	}
+}
+
+// WithRetry sets the maximum number of retries of pushes failing with a
+// transport error, a 429, or a 5xx status - and the backoff bounds: the
+// delay starts at `minBackoff`, doubling up to `maxBackoff`. Defaults to 5
+// retries, from 100ms up to 5s - non-positive bounds keep the defaults, so
+// retries never busy-loop. `maxRetries <= 0` disables retries.
+func WithRetry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+
+		if minBackoff > 0 {
+			c.minBackoff = minBackoff
+		}
+
+		if maxBackoff > 0 {
+			c.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithOnError sets the callback receiving background - periodic, or
+// size-triggered - push failures. Entries of a push still failing after the
+// retries are dropped.
+func WithOnError(cb func(error)) Option {
+	return func(c *Client) {
+		c.onError = cb
+	}
+}
+
+// WithLabels sets static labels added to every stream - e.g. "job", or
+// "env".
+func WithLabels(labels map[string]string) Option {
+	return func(c *Client) {
+		for k, v := range labels {
+			c.labeler.static[sanitizeLabelName(k)] = v
+		}
+	}
+}
+
+// WithFieldLabels promotes the given message fields to stream labels - the
+// value `fmt.Sprint`-ed. Only promote LOW-cardinality fields: every distinct
+// value creates a new stream. High-cardinality ones (IDs, durations, ...)
+// belong in the line - where they always are, regardless.
+func WithFieldLabels(keys ...string) Option {
+	return func(c *Client) {
+		c.labeler.fields = append(c.labeler.fields, keys...)
+	}
+}
+
+// WithTagLabels promotes the given tags - when present on the message - to
+// the "tags" stream label: sorted, and comma-joined.
+func WithTagLabels(tags ...string) Option {
+	return func(c *Client) {
+		for _, t := range tags {
+			c.labeler.tags[t] = struct{}{}
+		}
+	}
+}
+
+// WithComponentLabel enables, or disables the "component" stream label - the
+// logger name. Enabled by default.
+func WithComponentLabel(enabled bool) Option {
+	return func(c *Client) {
+		c.labeler.component = enabled
+	}
+}
+
+// WithLevelLabel enables, or disables the "level" stream label. Enabled by
+// default.
+func WithLevelLabel(enabled bool) Option {
+	return func(c *Client) {
+		c.labeler.level = enabled
+	}
+}
+
+// Client pushes entries to Loki, batched - see the `batcher` package.
+// Entries are accumulated, and pushed - one push at a time, so streams stay
+// ordered, retries included - when the batch reaches its size, or bytes
+// limit, periodically, and on `Flush`/`Close`. At most `WithMaxBuffered`
+// entries are pending.
+type Client struct {
+	// Push configuration.
+	batchBytes    int
+	batchSize     int
+	encoding      Encoding
+	flushInterval time.Duration
+	headers       http.Header
+	httpClient    *http.Client
+	maxBackoff    time.Duration
+	maxBuffered   int
+	maxRetries    int
+	minBackoff    time.Duration
+	onError       func(error)
+	password      string
+	pushURL       string
+	username      string
+
+	// labeler derives stream labels from messages.
+	labeler *labeler
+
+	// batcher accumulates, and pushes the entries.
+	batcher *batcher.Batcher[Entry]
+}
+
+//////
+// Methods.
+//////
+
+// Add enqueues an entry. A full batch is pushed in the background. After
+// Close, it returns `ErrClosed`; with `WithMaxBuffered` entries pending,
+// `batcher.ErrBufferFull` - the entry is dropped.
+func (c *Client) Add(e Entry) error {
+	err := c.batcher.Add(e)
+
+	if errors.Is(err, batcher.ErrClosed) {
+		return ErrClosed
+	}
+
+	return err
+}
+
+// Flush synchronously pushes the pending entries - retrying per
+// `WithRetry` - returning the push error, if any. After Close, it's a no-op.
+func (c *Client) Flush() error {
+	return c.batcher.Flush()
+}
+
+// Close stops the periodic push, and pushes the pending entries. It's
+// idempotent: subsequent calls return the FIRST call's outcome. Adds after
+// Close return `ErrClosed`.
+func (c *Client) Close() error {
+	return c.batcher.Close()
+}
+
+//////
+// Helpers.
+//////
+
+// push pushes a batch - once. See `batcher.Config.Send`.
+func (c *Client) push(batch []Entry) error {
+	streams := groupStreams(batch)
+
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+
+	switch c.encoding {
+	case EncodingJSON:
+		contentType = "application/json"
+
+		body, err = encodeJSON(streams)
+	case EncodingProtobuf:
+		fallthrough
+	default:
+		contentType = "application/x-protobuf"
+
+		body = encodeProtobuf(streams)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed encoding the loki push request: %w", err)
+	}
+
+	retryable, err := c.sendOnce(body, contentType)
+	if err != nil {
+		err = fmt.Errorf("failed pushing to loki: %w", err)
+
+		if retryable {
+			return batcher.Retryable(err)
+		}
+	}
+
+	return err
+}
+
+// sendOnce POSTs the body once, reporting whether a failure is retryable.
+func (c *Client) sendOnce(body []byte, contentType string) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, c.pushURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	for k, v := range c.headers {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		// Drained, so the connection is reused.
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	err = fmt.Errorf("status: %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError, err
+}
+
+//////
+// Factory.
+//////
+
+// New returns a new Loki client pushing to `pushURL` - e.g.
+// "http://loki:3100/loki/api/v1/push". See the `With*` options for
+// batching, encoding, labels, retries, and authentication.
+//
+// NOTE: Pushes are asynchronous, and batched - deliver failures through
+// `WithOnError`, and drain with `Flush`, or `Close`.
+func New(pushURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(pushURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid loki push URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid loki push URL %q: scheme must be http, or https", pushURL)
+	}
+
+	c := &Client{
+		batchBytes:    defaultBatchBytes,
+		batchSize:     defaultBatchSize,
+		encoding:      EncodingProtobuf,
+		flushInterval: defaultFlushInterval,
+		headers:       http.Header{},
+		httpClient:    &http.Client{Timeout: defaultTimeout},
+		labeler:       newLabeler(),
+		maxBackoff:    defaultMaxBackoff,
+		maxBuffered:   defaultMaxBuffered,
+		maxRetries:    defaultMaxRetries,
+		minBackoff:    defaultMinBackoff,
+		pushURL:       pushURL,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.batcher = batcher.New(batcher.Config[Entry]{
+		Send:          c.push,
+		Size:          func(e Entry) int { return len(e.Line) },
+		FlushItems:    c.batchSize,
+		FlushBytes:    c.batchBytes,
+		FlushInterval: c.flushInterval,
+		MaxBuffered:   c.maxBuffered,
+		MaxRetries:    c.maxRetries,
+		MinBackoff:    c.minBackoff,
+		MaxBackoff:    c.maxBackoff,
+		OnError:       c.onError,
+	})
+
+	return c, nil
+}
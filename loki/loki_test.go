@@ -0,0 +1,450 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/batcher"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fakeLoki records push requests, answering with the queued statuses -
+// then 204.
+type fakeLoki struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+}
+
+func newFakeLoki(t *testing.T, statuses ...int) *fakeLoki {
+	t.Helper()
+
+	f := &fakeLoki{statuses: statuses}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		f.mu.Lock()
+
+		f.requests = append(f.requests, r)
+		f.bodies = append(f.bodies, body)
+
+		status := http.StatusNoContent
+
+		if len(f.statuses) > 0 {
+			status, f.statuses = f.statuses[0], f.statuses[1:]
+		}
+
+		f.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeLoki) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.bodies)
+}
+
+func (f *fakeLoki) last() (*http.Request, []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests[len(f.requests)-1], f.bodies[len(f.bodies)-1]
+}
+
+func decodeJSON(t *testing.T, body []byte) jsonPushRequest {
+	t.Helper()
+
+	var req jsonPushRequest
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("invalid JSON push request: %v: %s", err, body)
+	}
+
+	return req
+}
+
+func TestOutput_JSONLabels(t *testing.T) {
+	srv := newFakeLoki(t)
+
+	o, err := Output(srv.URL, level.Trace, []Option{
+		WithEncoding(EncodingJSON),
+		WithFieldLabels("env"),
+		WithLabels(map[string]string{"job": "test"}),
+		WithTagLabels("audit"),
+		WithTenantID("tenant-1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := sypl.New("api", o)
+
+	defer l.Close()
+
+	l.PrintlnWithOptions(level.Info, "hello",
+		sypl.WithFields(fields.Fields{"env": "prod", "request_id": "r-1"}),
+		sypl.WithTags("audit", "other"),
+	)
+	l.Errorln("boom")
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, body := srv.last()
+
+	if r.Header.Get("X-Scope-OrgID") != "tenant-1" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", r.Header)
+	}
+
+	req := decodeJSON(t, body)
+
+	if len(req.Streams) != 2 {
+		t.Fatalf("streams = %+v", req.Streams)
+	}
+
+	// Sorted by labels: `{component="api", env="prod", ...}` first.
+	infoStream, errStream := req.Streams[0], req.Streams[1]
+
+	wantInfo := map[string]string{"component": "api", "env": "prod", "job": "test", "level": "info", "tags": "audit"}
+
+	for k, v := range wantInfo {
+		if infoStream.Stream[k] != v {
+			t.Errorf("info label %s = %q, want %q (labels: %v)", k, infoStream.Stream[k], v, infoStream.Stream)
+		}
+	}
+
+	if _, ok := infoStream.Stream["request_id"]; ok {
+		t.Error("high-cardinality field promoted to a label")
+	}
+
+	if line := infoStream.Values[0][1]; !strings.Contains(line, `"request_id":"r-1"`) || strings.HasSuffix(line, "\n") {
+		t.Errorf("info line = %q", line)
+	}
+
+	if errStream.Stream["level"] != "error" || len(errStream.Values) != 1 {
+		t.Errorf("error stream = %+v", errStream)
+	}
+}
+
+func TestClient_SortsPerStream(t *testing.T) {
+	srv := newFakeLoki(t)
+
+	c, err := New(srv.URL, WithEncoding(EncodingJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	base := time.Unix(1700000000, 0)
+
+	labels := map[string]string{"job": "x"}
+
+	for _, offset := range []int{3, 1, 2} {
+		if err := c.Add(Entry{Labels: labels, Line: "l", Timestamp: base.Add(time.Duration(offset))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, body := srv.last()
+
+	values := decodeJSON(t, body).Streams[0].Values
+
+	for i, want := range []string{"1700000000000000001", "1700000000000000002", "1700000000000000003"} {
+		if values[i][0] != want {
+			t.Errorf("values[%d] timestamp = %s, want %s", i, values[i][0], want)
+		}
+	}
+}
+
+// decodeProtobuf decodes a snappy-compressed push request into labels ->
+// (timestamps, lines).
+func decodeProtobuf(t *testing.T, body []byte) map[string][][2]any {
+	t.Helper()
+
+	raw, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fieldsOf returns the length-delimited, and varint fields of a message.
+	fieldsOf := func(b []byte) map[protowire.Number][]any {
+		out := map[protowire.Number][]any{}
+
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatal(protowire.ParseError(n))
+			}
+
+			b = b[n:]
+
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+
+				out[num] = append(out[num], v)
+
+				b = b[n:]
+			case protowire.VarintType:
+				v, n := protowire.ConsumeVarint(b)
+
+				out[num] = append(out[num], v)
+
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %d", typ)
+			}
+		}
+
+		return out
+	}
+
+	streams := map[string][][2]any{}
+
+	for _, s := range fieldsOf(raw)[pbPushRequestStreams] {
+		sf := fieldsOf(s.([]byte))
+
+		labels := string(sf[pbStreamLabels][0].([]byte))
+
+		for _, e := range sf[pbStreamEntries] {
+			ef := fieldsOf(e.([]byte))
+
+			ts := fieldsOf(ef[pbEntryTimestamp][0].([]byte))
+
+			streams[labels] = append(streams[labels], [2]any{
+				time.Unix(int64(ts[pbTimestampSeconds][0].(uint64)), int64(ts[pbTimestampNanoseconds][0].(uint64))),
+				string(ef[pbEntryLine][0].([]byte)),
+			})
+		}
+	}
+
+	return streams
+}
+
+func TestClient_Protobuf(t *testing.T) {
+	srv := newFakeLoki(t)
+
+	c, err := New(srv.URL, WithBasicAuth("user", "pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	ts := time.Unix(1700000000, 42)
+
+	if err := c.Add(Entry{Labels: map[string]string{"level": "info", "job": `a"b`}, Line: "hello", Timestamp: ts}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, body := srv.last()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+	}
+
+	if r.Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+	}
+
+	streams := decodeProtobuf(t, body)
+
+	entries, ok := streams[`{job="a\"b", level="info"}`]
+	if !ok || len(entries) != 1 {
+		t.Fatalf("streams = %v", streams)
+	}
+
+	if got := entries[0][0].(time.Time); !got.Equal(ts) || entries[0][1] != "hello" {
+		t.Errorf("entry = %v", entries[0])
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	srv := newFakeLoki(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+
+	c, err := New(srv.URL, WithRetry(3, time.Millisecond, 2*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	_ = c.Add(Entry{Line: "x", Timestamp: time.Now()})
+
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush = %v, want success after retries", err)
+	}
+
+	if n := srv.count(); n != 3 {
+		t.Errorf("push attempts = %d, want 3", n)
+	}
+
+	// Client errors aren't retried.
+	srv.mu.Lock()
+	srv.statuses = []int{http.StatusBadRequest}
+	srv.mu.Unlock()
+
+	_ = c.Add(Entry{Line: "y", Timestamp: time.Now()})
+
+	if err := c.Flush(); err == nil || !strings.Contains(err.Error(), "status: 400") {
+		t.Errorf("Flush = %v, want the 400", err)
+	}
+
+	if n := srv.count(); n != 4 {
+		t.Errorf("push attempts = %d, want 4", n)
+	}
+}
+
+// Zero backoffs keep the defaults - retries don't busy-loop.
+func TestClient_RetryZeroBackoff(t *testing.T) {
+	srv := newFakeLoki(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	c, err := New(srv.URL, WithRetry(2, 0, 0), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	_ = c.Add(Entry{Line: "x", Timestamp: time.Now()})
+
+	start := time.Now()
+
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush = %v, want success after retries", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 3*defaultMinBackoff {
+		t.Errorf("2 retries took %s - want at least %s", elapsed, 3*defaultMinBackoff)
+	}
+}
+
+func TestClient_MaxBuffered(t *testing.T) {
+	srv := newFakeLoki(t)
+
+	c, err := New(srv.URL, WithMaxBuffered(1), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	_ = c.Add(Entry{Line: "a", Timestamp: time.Now()})
+
+	if err := c.Add(Entry{Line: "b", Timestamp: time.Now()}); !errors.Is(err, batcher.ErrBufferFull) {
+		t.Errorf("Add = %v, want batcher.ErrBufferFull", err)
+	}
+}
+
+func TestClient_BatchSizeTriggersPush(t *testing.T) {
+	srv := newFakeLoki(t)
+
+	var errs atomic.Int32
+
+	c, err := New(srv.URL,
+		WithBatchSize(2),
+		WithFlushInterval(time.Hour),
+		WithOnError(func(error) { errs.Add(1) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	_ = c.Add(Entry{Line: "a", Timestamp: time.Now()})
+	_ = c.Add(Entry{Line: "b", Timestamp: time.Now()})
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for srv.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if srv.count() != 1 || errs.Load() != 0 {
+		t.Errorf("pushes = %d, errors = %d", srv.count(), errs.Load())
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	srv := newFakeLoki(t)
+
+	c, err := New(srv.URL, WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.Add(Entry{Line: "pending", Timestamp: time.Now()})
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if srv.count() != 1 {
+		t.Errorf("pushes = %d, want 1 - Close drains", srv.count())
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	if err := c.Add(Entry{Line: "late"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Add after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestNew_InvalidURL(t *testing.T) {
+	if _, err := New("loki:3100"); err == nil {
+		t.Error("expected an error for a scheme-less URL")
+	}
+}
+
+func TestSanitizeLabelName(t *testing.T) {
+	for in, want := range map[string]string{
+		"service.name": "service_name",
+		"1st":          "_st",
+		"ok_Name9":     "ok_Name9",
+		"":             "_",
+	} {
+		if got := sanitizeLabelName(in); got != want {
+			t.Errorf("sanitizeLabelName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package loki
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// lokiOutput is a Loki-backed `output.IOutput` carrying the Flush, and
+// Close capabilities.
+//
+// Stream labels, and the timestamp come from the MESSAGE - after processors
+// ran - while the line is the formatted content. The output's pipeline ends
+// with a plain `io.Writer` call, so the message being written is handed to
+// the writer through `current`: writes are serialized per output - the
+// builtin writer already serializes the final write; this extends it to the
+// processors.
+type lokiOutput struct {
+	*output.Proxy
+
+	client *Client
+
+	// mu serializes writes, guarding `current`.
+	mu sync.Mutex
+
+	// current is the message being written.
+	current message.IMessage
+}
+
+// Write writes the message through the output's pipeline.
+func (o *lokiOutput) Write(m message.IMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.current = m
+
+	defer func() { o.current = nil }()
+
+	return o.Proxy.Write(m)
+}
+
+// Flush pushes the pending entries - see `Client.Flush`. After Close it's a
+// no-op.
+func (o *lokiOutput) Flush() error {
+	return o.client.Flush()
+}
+
+// Close pushes the pending entries, and stops the client. It's idempotent.
+// Writes after Close return `ErrClosed`.
+func (o *lokiOutput) Close() error {
+	return o.client.Close()
+}
+
+// lineWriter is the output's writer: it turns the formatted content into
+// an entry of the current message's stream.
+type lineWriter struct {
+	o *lokiOutput
+}
+
+// Write conforms to the `io.Writer` interface.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	// Trailing linebreaks - restored by Sypl's pipeline after formatting -
+	// aren't part of the line.
+	e := Entry{Line: strings.TrimRight(string(p), "\r\n")}
+
+	// Called outside of `Write` - e.g. directly, via `GetWriter` - only the
+	// static labels apply.
+	if m := w.o.current; m != nil {
+		e.Labels = w.o.client.labeler.labels(m)
+		e.Timestamp = m.GetTimestamp()
+	} else {
+		e.Labels = w.o.client.labeler.labels(message.New(level.None, ""))
+		e.Timestamp = time.Now()
+	}
+
+	if err := w.o.client.Add(e); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+//////
+// Factory.
+//////
+
+// Output is a built-in `output` - named `Loki` - that batches messages into
+// Loki push requests. See `New` for `pushURL`, and the `With*` options for
+// batching, encoding, stream labels, retries, and authentication.
+//
+// Stream labels default to "component", and "level"; promote other
+// LOW-cardinality data via `WithLabels`, `WithFieldLabels`, and
+// `WithTagLabels`. The line is the formatted message - JSON by default - so
+// every field, high-cardinality ones included, stays queryable via LogQL's
+// `| json`.
+//
+// Capabilities: `Flush() error` (pushes the pending entries), and
+// idempotent `Close() error`. Pushing is asynchronous: background failures
+// are delivered through `WithOnError`.
+func Output(
+	pushURL string,
+	maxLevel level.Level,
+	opts []Option,
+	processors ...processor.IProcessor,
+) (output.IOutput, error) {
+	client, err := New(pushURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &lokiOutput{client: client}
+
+	// NOTE: INLINE JSON - a Loki line is a single line.
+	inner := output.New("Loki", maxLevel, &lineWriter{o: o}, processors...).SetFormatter(formatter.JSON())
+
+	o.Proxy = output.NewProxy(inner, o)
+
+	return o, nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package message
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thalesfsp/sypl/v2/shared"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// crockford is the ULID base32 alphabet - Crockford's.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator generates monotonic ULIDs.
+type ulidGenerator struct {
+	// clock provides the timestamps - see `NewULIDGenerator`.
+	clock func() time.Time
+
+	// mu guards the last ULID's state.
+	mu sync.Mutex
+
+	// ms is the last ULID's timestamp, in Unix milliseconds.
+	ms uint64
+
+	// entropy is the last ULID's 80-bit random part: the high 16 bits, and
+	// the low 64 bits.
+	entropyHi uint16
+	entropyLo uint64
+}
+
+// next returns the next ULID. Within the same millisecond, the random part
+// is incremented, so ULIDs generated by the same generator strictly sort in
+// generation order.
+func (g *ulidGenerator) next() string {
+	ms := uint64(g.clock().UnixMilli())
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ms <= g.ms {
+		// Same millisecond - or a clock step back: monotonic increment.
+		// On the (practically impossible) 80-bit overflow, the timestamp
+		// moves forward.
+		g.entropyLo++
+
+		if g.entropyLo == 0 {
+			g.entropyHi++
+
+			if g.entropyHi == 0 {
+				g.ms++
+			}
+		}
+	} else {
+		var b [10]byte
+
+		if _, err := rand.Read(b[:]); err != nil {
+			log.Println(shared.ErrorPrefix, "ULID: Failed to read entropy", err)
+		}
+
+		g.ms = ms
+		g.entropyHi = binary.BigEndian.Uint16(b[:2])
+		g.entropyLo = binary.BigEndian.Uint64(b[2:])
+	}
+
+	return encodeULID(g.ms<<16|uint64(g.entropyHi), g.entropyLo)
+}
+
+// uuidV7Generator generates monotonic UUIDv7s.
+type uuidV7Generator struct {
+	// clock provides the timestamps - see `NewUUIDv7Generator`.
+	clock func() time.Time
+
+	// mu guards the last UUID's state.
+	mu sync.Mutex
+
+	// ms is the last UUID's timestamp, in Unix milliseconds.
+	ms uint64
+
+	// randA, and randB are the last UUID's 12, and 62 random bits.
+	randA uint16
+	randB uint64
+}
+
+// next returns the next UUIDv7. Within the same millisecond, the random
+// bits are incremented, so UUIDs generated by the same generator strictly
+// sort in generation order.
+func (g *uuidV7Generator) next() string {
+	ms := uint64(g.clock().UnixMilli())
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ms <= g.ms {
+		// Same millisecond - or a clock step back: monotonic increment.
+		// On the (practically impossible) 74-bit overflow, the timestamp
+		// moves forward.
+		g.randB = (g.randB + 1) & (1<<62 - 1)
+
+		if g.randB == 0 {
+			g.randA = (g.randA + 1) & (1<<12 - 1)
+
+			if g.randA == 0 {
+				g.ms++
+			}
+		}
+	} else {
+		var b [10]byte
+
+		if _, err := rand.Read(b[:]); err != nil {
+			log.Println(shared.ErrorPrefix, "UUIDv7: Failed to read entropy", err)
+		}
+
+		g.ms = ms
+		g.randA = binary.BigEndian.Uint16(b[:2]) & (1<<12 - 1)
+		g.randB = binary.BigEndian.Uint64(b[2:]) & (1<<62 - 1)
+	}
+
+	var id uuid.UUID
+
+	binary.BigEndian.PutUint64(id[:8], g.ms<<16|0x7000|uint64(g.randA))
+	binary.BigEndian.PutUint64(id[8:], 0x8000000000000000|g.randB)
+
+	return id.String()
+}
+
+//////
+// Helpers.
+//////
+
+// clockOrNow returns `clock`, or `time.Now` if nil.
+func clockOrNow(clock func() time.Time) func() time.Time {
+	if clock == nil {
+		return time.Now
+	}
+
+	return clock
+}
+
+// encodeULID encodes the 128-bit ULID - `hi`, and `lo` halves - as 26
+// Crockford base32 characters.
+func encodeULID(hi, lo uint64) string {
+	var b [26]byte
+
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = crockford[lo&31]
+
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(b[:])
+}
+
+//////
+// Built-in ID generators - see `WithIDGenerator`.
+//////
+
+// UUIDv4 generates a random UUIDv4 - the default message ID.
+func UUIDv4() string {
+	return generateUUID()
+}
+
+// UUIDv7 generates a time-ordered UUIDv7: a Unix millisecond timestamp -
+// on the wall clock - then random bits - monotonic within the process. See
+// `NewUUIDv7Generator` to follow an injected clock.
+func UUIDv7() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		log.Println(shared.ErrorPrefix, "UUIDv7: Failed to generate UUID for message", err)
+	}
+
+	return id.String()
+}
+
+// NewUUIDv7Generator returns a UUIDv7 generator whose timestamps follow
+// `clock` - e.g. the one set via `Sypl.SetClock`. A nil clock means
+// `time.Now`. Monotonic: within the same millisecond, IDs still sort in
+// generation order.
+func NewUUIDv7Generator(clock func() time.Time) func() string {
+	g := &uuidV7Generator{clock: clockOrNow(clock)}
+
+	return g.next
+}
+
+// NewULIDGenerator returns a ULID generator: 26-character, lexicographically
+// time-sortable IDs - a Unix millisecond timestamp, on `clock`, then 80
+// random bits - monotonic: within the same millisecond, IDs still sort in
+// generation order. A nil clock means `time.Now`; pass the one set via
+// `Sypl.SetClock`, so the timestamps follow it.
+func NewULIDGenerator(clock func() time.Time) func() string {
+	g := &ulidGenerator{clock: clockOrNow(clock)}
+
+	return g.next
+}
+
+// NewCounterGenerator returns a generator of sequential IDs - zero-padded
+// to 20 digits, "00000000000000000001", "00000000000000000002", ..., so
+// they sort as text - for deterministic tests, and replays. Thread-safe.
+func NewCounterGenerator() func() string {
+	var n atomic.Uint64
+
+	return func() string {
+		return fmt.Sprintf("%020d", n.Add(1))
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package message
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEncodeULID(t *testing.T) {
+	for _, tt := range []struct {
+		hi, lo uint64
+		want   string
+	}{
+		{0, 0, "00000000000000000000000000"},
+		{0, 1, "00000000000000000000000001"},
+		{0, 32, "00000000000000000000000010"},
+		{math.MaxUint64, math.MaxUint64, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+	} {
+		if got := encodeULID(tt.hi, tt.lo); got != tt.want {
+			t.Errorf("encodeULID(%x, %x) = %q, want %q", tt.hi, tt.lo, got, tt.want)
+		}
+	}
+}
+
+func TestNewULIDGenerator(t *testing.T) {
+	gen := NewULIDGenerator(nil)
+
+	before := time.Now().UnixMilli()
+
+	ids := make([]string, 1000)
+
+	for i := range ids {
+		ids[i] = gen()
+	}
+
+	// Monotonic, and unique - even within the same millisecond.
+	if !slices.IsSorted(ids) || len(slices.Compact(slices.Clone(ids))) != len(ids) {
+		t.Fatal("ULIDs should be strictly increasing")
+	}
+
+	for _, id := range ids {
+		if len(id) != 26 || strings.Trim(id, crockford) != "" {
+			t.Fatalf("ULID %q isn't 26 Crockford base32 characters", id)
+		}
+	}
+
+	// The first 10 characters are the millisecond timestamp.
+	prefix := encodeULID(uint64(before)<<16, 0)[:10]
+
+	if ids[0][:10] < prefix {
+		t.Errorf("ULID %q predates %q", ids[0], prefix)
+	}
+}
+
+// The timestamps follow the injected clock.
+func TestNewULIDGenerator_Clock(t *testing.T) {
+	frozen := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	gen := NewULIDGenerator(func() time.Time { return frozen })
+
+	prefix := encodeULID(uint64(frozen.UnixMilli())<<16, 0)[:10]
+
+	a, b := gen(), gen()
+
+	if a[:10] != prefix || b[:10] != prefix || a >= b {
+		t.Errorf("ULIDs = %q, %q, want increasing, with the %q timestamp", a, b, prefix)
+	}
+}
+
+func TestNewUUIDv7Generator(t *testing.T) {
+	frozen := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	gen := NewUUIDv7Generator(func() time.Time { return frozen })
+
+	ids := make([]string, 1000)
+
+	for i := range ids {
+		ids[i] = gen()
+	}
+
+	if !slices.IsSorted(ids) || len(slices.Compact(slices.Clone(ids))) != len(ids) {
+		t.Fatal("UUIDv7s should be strictly increasing")
+	}
+
+	for _, id := range ids {
+		u, err := uuid.Parse(id)
+		if err != nil || u.Version() != 7 || u.Variant() != uuid.RFC4122 {
+			t.Fatalf("%q isn't a RFC 9562 version 7 UUID: %v", id, err)
+		}
+
+		if sec, nsec := u.Time().UnixTime(); time.Unix(sec, nsec).UnixMilli() != frozen.UnixMilli() {
+			t.Fatalf("UUIDv7 %q timestamp = %v, want %v", id, time.Unix(sec, nsec).UTC(), frozen)
+		}
+	}
+}
+
+func TestUUIDv7(t *testing.T) {
+	a, b := UUIDv7(), UUIDv7()
+
+	if len(a) != 36 || a[14] != '7' || a >= b {
+		t.Errorf("UUIDv7() = %q, %q, want increasing version 7 UUIDs", a, b)
+	}
+
+	if v4 := UUIDv4(); len(v4) != 36 || v4[14] != '4' {
+		t.Errorf("UUIDv4() = %q", v4)
+	}
+}
+
+func TestNewCounterGenerator(t *testing.T) {
+	gen := NewCounterGenerator()
+
+	if gen() != "00000000000000000001" || gen() != "00000000000000000002" {
+		t.Fatal("counter should start at 1, increment, and be zero-padded")
+	}
+
+	// Sorts as text: the 10th ID sorts after the 9th.
+	for range 6 {
+		gen()
+	}
+
+	if ninth, tenth := gen(), gen(); ninth >= tenth {
+		t.Errorf("%q should sort before %q", ninth, tenth)
+	}
+
+	// Thread-safe: no duplicates.
+	seen := sync.Map{}
+
+	wg := sync.WaitGroup{}
+
+	for range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				if _, dup := seen.LoadOrStore(gen(), struct{}{}); dup {
+					t.Error("duplicate counter ID")
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Independent generators.
+	if NewCounterGenerator()() != "00000000000000000001" {
+		t.Error("generators should be independent")
+	}
+}
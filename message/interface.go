@@ -113,6 +113,10 @@ type IMessage interface {
 	// SetTimestamp sets the timestamp.
 	SetTimestamp(timestamp time.Time) IMessage
 
+	// GetClock returns the clock injected at creation - see `WithClock` -,
+	// if any, otherwise nil.
+	GetClock() func() time.Time
+
 	// IsEmpty returns true if the message is empty.
 	IsEmpty() bool
 }
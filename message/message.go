@@ -86,6 +86,14 @@ type message struct {
 
 	// The point in time when the message was created.
 	Timestamp time.Time
+
+	// clock is the clock injected at creation - see `WithClock`. Nil by
+	// default.
+	clock func() time.Time
+
+	// owner is the pool entry of a pooled message - nil otherwise. See
+	// `Acquire`.
+	owner *pooled
 }
 
 // String interface implementation.
@@ -327,6 +335,12 @@ func (m *message) SetTimestamp(timestamp time.Time) IMessage {
 	return m
 }
 
+// GetClock returns the clock injected at creation - see `WithClock` -, if
+// any, otherwise nil.
+func (m *message) GetClock() func() time.Time {
+	return m.clock
+}
+
 // IsEmpty returns true if the message is empty.
 func (m *message) IsEmpty() bool {
 	return strings.Trim(m.GetContent().GetOriginal(), "\f\t\r\n ") == ""
@@ -350,6 +364,14 @@ func Copy(m IMessage) IMessage {
 	// they are copied from the source message below.
 	msg := newMessage(m.GetLevel(), m.GetContent().GetOriginal())
 
+	copyInto(msg, m, fields.Fields{})
+
+	return msg
+}
+
+// copyInto copies `m` into the bare message `msg` - see `Copy` - deep
+// copying fields into `dstFields`.
+func copyInto(msg *message, m IMessage, dstFields fields.Fields) {
 	// Copy `options.Tags`. Should be a real copy, not slice aliasing.
 	if mTags := m.GetMessage().Tags; mTags != nil {
 		tags := make([]string, len(mTags))
@@ -374,11 +396,11 @@ func Copy(m IMessage) IMessage {
 
 	// Fields should be deep copied - per-output copies are processed
 	// concurrently.
-	msg.SetFields(fields.Copy(m.GetFields(), fields.Fields{}))
+	msg.SetFields(fields.Copy(m.GetFields(), dstFields))
 	msg.SetFlag(m.GetFlag())
 
-	gLB := *m.getLineBreaker()
-	msg.setLineBreaker(&gLB)
+	// `msg` owns its linebreaker.
+	*msg.lineBreaker = *m.getLineBreaker()
 
 	msg.SetOutputName(m.GetOutputName())
 	msg.SetOutputsNames(m.GetOutputsNames())
@@ -386,7 +408,7 @@ func Copy(m IMessage) IMessage {
 	msg.SetProcessorsNames(m.GetProcessorsNames())
 	msg.SetTimestamp(m.GetTimestamp())
 
-	return msg
+	msg.clock = m.GetClock()
 }
 
 //////
@@ -410,10 +432,18 @@ func newMessage(l level.Level, ct string) *message {
 	}
 }
 
-// New is the Message factory.
+// New is the Message factory. By default, the timestamp is `time.Now`, and
+// the ID a UUIDv4 - see `WithClock`, and `WithIDGenerator` to inject
+// others, e.g. for deterministic tests.
 //
 // NOTE: Changes in the `Message` or `Options` data structure may reflects here.
-func New(l level.Level, ct string) IMessage {
+func New(l level.Level, ct string, opts ...Option) IMessage {
+	cfg := newConfig{idGenerator: generateUUID}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	m := newMessage(l, ct)
 
 	// The UUID (crypto/rand), and the content hash (SHA-1) are EXPENSIVE
@@ -421,8 +451,55 @@ func New(l level.Level, ct string) IMessage {
 	// Text formatter) never read them - so they are computed lazily, and
 	// memoized on the first `GetID`/`GetContentBasedHashID` call.
 	m.contentBasedHashID = newLazyString(func() string { return generateID(ct) })
-	m.id = newLazyString(generateUUID)
-	m.Timestamp = time.Now()
+	m.id = newLazyString(cfg.idGenerator)
+	m.Timestamp = cfg.now()
+	m.clock = cfg.clock
 
 	return m
 }
+
+//////
+// Options.
+//////
+
+// newConfig is the `New` configuration.
+type newConfig struct {
+	// clock is the injected clock - nil means `time.Now`.
+	clock       func() time.Time
+	idGenerator func() string
+}
+
+// now returns the current time on the injected clock, if any, otherwise
+// `time.Now`.
+func (c newConfig) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+
+	return time.Now()
+}
+
+// Option configures a message created by `New`.
+type Option func(*newConfig)
+
+// WithClock sets the clock providing the message's timestamp. Defaults to
+// `time.Now`. A nil clock is ignored. The message carries it - see
+// `GetClock` - so the time-dependent processors measure it on that clock.
+func WithClock(clock func() time.Time) Option {
+	return func(c *newConfig) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+// WithIDGenerator sets the generator of the message's ID. Defaults to
+// UUIDv4. Like the default, it runs lazily - on the first `GetID` call. A
+// nil generator is ignored.
+func WithIDGenerator(idGenerator func() string) Option {
+	return func(c *newConfig) {
+		if idGenerator != nil {
+			c.idGenerator = idGenerator
+		}
+	}
+}
@@ -35,3 +35,29 @@ func BenchmarkMessageCopy(b *testing.B) {
 		_ = Copy(m)
 	}
 }
+
+// BenchmarkMessageAcquireRelease measures the pooled counterpart of
+// `BenchmarkMessageNew`.
+func BenchmarkMessageAcquireRelease(b *testing.B) {
+	b.ReportAllocs()
+
+	for range b.N {
+		Release(Acquire(level.Info, "benchmark message"))
+	}
+}
+
+// BenchmarkMessageAcquireCopy measures the pooled counterpart of
+// `BenchmarkMessageCopy`.
+func BenchmarkMessageAcquireCopy(b *testing.B) {
+	m := New(level.Info, "benchmark message")
+
+	m.SetFields(fields.Fields{"key1": "value1", "key2": 2})
+	m.AddTags("tag1", "tag2")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		Release(AcquireCopy(m))
+	}
+}
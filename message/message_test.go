@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/thalesfsp/sypl/v2/flag"
 	"github.com/thalesfsp/sypl/v2/internal/sypltest"
@@ -63,6 +64,46 @@ func TestNewMessage(t *testing.T) {
 	}
 }
 
+func TestNew_WithClockAndIDGenerator(t *testing.T) {
+	frozen := time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	calls := 0
+
+	m := New(level.Info, "Test",
+		WithClock(func() time.Time { return frozen }),
+		WithIDGenerator(func() string {
+			calls++
+
+			return "id-1"
+		}),
+	)
+
+	if !m.GetTimestamp().Equal(frozen) {
+		t.Errorf("GetTimestamp() = %v, want %v", m.GetTimestamp(), frozen)
+	}
+
+	// Lazy, like the default.
+	if calls != 0 {
+		t.Errorf("ID generated %d times before GetID, want 0", calls)
+	}
+
+	if m.GetID() != "id-1" || m.GetID() != "id-1" || calls != 1 {
+		t.Errorf("GetID() = %q, generated %d times, want id-1, once", m.GetID(), calls)
+	}
+
+	// Copies share the ID.
+	if Copy(m).GetID() != "id-1" {
+		t.Errorf("Copy().GetID() = %q, want id-1", Copy(m).GetID())
+	}
+
+	// Nil options are ignored.
+	m = New(level.Info, "Test", WithClock(nil), WithIDGenerator(nil))
+
+	if m.GetTimestamp().IsZero() || m.GetID() == "" {
+		t.Errorf("nil options should keep the defaults, got %v, %q", m.GetTimestamp(), m.GetID())
+	}
+}
+
 func TestCopy(t *testing.T) {
 	tests := []struct {
 		name string
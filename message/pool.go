@@ -0,0 +1,171 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package message
+
+import (
+	"sync"
+
+	"github.com/thalesfsp/sypl/v2/content"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/flag"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/options"
+	"github.com/thalesfsp/sypl/v2/status"
+)
+
+//////
+// Pooling.
+//
+// Pooled messages - see `Acquire`, and `AcquireCopy` - are recycled by
+// `Release`, cutting per-message allocations: the message, its options, its
+// linebreaker, and its fields, and tags maps are reused.
+//
+// Lifecycle - the owner acquires, and releases:
+//   - After `Release`, the message MUST NOT be used - it may already be
+//     another message.
+//   - Processors, and outputs MUST NOT retain a message - nor its fields
+//     map - beyond their call. Those that do - e.g. `output.Async` - retain
+//     `Retain(m)`: a copy, if `m` is pooled.
+//
+// Messages from `New`, and `Copy` aren't pooled: `Release` ignores them, and
+// `Retain` returns them as is.
+//////
+
+// pooled is a pool entry: a message, and the parts it points to, allocated
+// together.
+type pooled struct {
+	message
+
+	options     options.Options
+	lineBreaker lineBreaker
+
+	// fields is the entry's own fields map - reused, unlike maps set via
+	// `SetFields`, which belong to the caller.
+	fields fields.Fields
+}
+
+// knownLineBreakers are the linebreakers pooled messages strip. Read-only.
+var knownLineBreakers = []string{"\n", "\r"}
+
+// messagePool recycles `pooled` entries.
+var messagePool = sync.Pool{
+	New: func() any {
+		return &pooled{
+			fields:  fields.Fields{},
+			message: message{tags: map[string]struct{}{}},
+		}
+	},
+}
+
+//////
+// Helpers.
+//////
+
+// acquire returns a bare pooled message - see `newMessage`.
+func acquire(l level.Level, ct string) *message {
+	p, _ := messagePool.Get().(*pooled)
+
+	p.options = options.Options{
+		Fields:          p.fields,
+		Flag:            flag.None,
+		OutputsNames:    []string{},
+		ProcessorsNames: []string{},
+		Tags:            []string{},
+	}
+
+	p.lineBreaker = lineBreaker{
+		ControlChars:      []string{},
+		KnownLineBreakers: knownLineBreakers,
+		Status:            status.Enabled,
+	}
+
+	p.message = message{
+		Options: &p.options,
+
+		Content:     content.New(ct),
+		Level:       l,
+		lineBreaker: &p.lineBreaker,
+		owner:       p,
+		tags:        p.message.tags,
+	}
+
+	return &p.message
+}
+
+// IsPooled returns if `m` is a pooled message - see `Acquire`.
+func IsPooled(m IMessage) bool {
+	return m != nil && m.GetMessage().owner != nil
+}
+
+// Retain returns a message safe to retain beyond the call that received
+// `m`: `m` itself if it isn't pooled, otherwise an unpooled copy - see
+// `Copy`. Deferred field values are copied unresolved.
+func Retain(m IMessage) IMessage {
+	if !IsPooled(m) {
+		return m
+	}
+
+	return Copy(m)
+}
+
+// Release returns a pooled message to the pool. It's a no-op for messages
+// not pooled, or already released. See the pooling notes.
+func Release(m IMessage) {
+	if m == nil {
+		return
+	}
+
+	msg := m.GetMessage()
+
+	p := msg.owner
+
+	if p == nil {
+		return
+	}
+
+	// Fields set via `SetFields` belong to the caller - only the entry's own
+	// map is cleared.
+	clear(p.fields)
+	clear(msg.tags)
+
+	tags := msg.tags
+
+	p.message = message{tags: tags}
+
+	messagePool.Put(p)
+}
+
+//////
+// Factory.
+//////
+
+// Acquire is like `New`, but the message is pooled - see the pooling notes.
+// `Release` it once done.
+func Acquire(l level.Level, ct string, opts ...Option) IMessage {
+	cfg := newConfig{idGenerator: generateUUID}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	m := acquire(l, ct)
+
+	m.contentBasedHashID = newLazyString(func() string { return generateID(ct) })
+	m.id = newLazyString(cfg.idGenerator)
+	m.Timestamp = cfg.now()
+	m.clock = cfg.clock
+
+	return m
+}
+
+// AcquireCopy is like `Copy`, but the copy is pooled - see the pooling
+// notes. `Release` it once done.
+func AcquireCopy(m IMessage) IMessage {
+	msg := acquire(m.GetLevel(), m.GetContent().GetOriginal())
+
+	copyInto(msg, m, msg.owner.fields)
+
+	return msg
+}
@@ -0,0 +1,190 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package message
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/flag"
+	"github.com/thalesfsp/sypl/v2/level"
+)
+
+func TestAcquire_IsFresh(t *testing.T) {
+	// Dirty a pooled message, release it, and acquire again - whichever
+	// entry the pool hands out must look brand new.
+	m := Acquire(level.Error, "dirty\n")
+
+	m.GetFields()["key"] = "value"
+	m.AddTags("tag")
+	m.SetFlag(flag.Force)
+	m.SetOutputsNames([]string{"out"})
+	m.SetComponentName("component")
+	m.Strip()
+
+	Release(m)
+
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	n := Acquire(level.Info, "fresh", WithClock(func() time.Time { return ts }))
+	defer Release(n)
+
+	if !IsPooled(n) {
+		t.Fatal("IsPooled() = false, want true")
+	}
+
+	if n.GetLevel() != level.Info || n.GetContent().GetOriginal() != "fresh" {
+		t.Errorf("got %s %q, want info %q", n.GetLevel(), n.GetContent().GetOriginal(), "fresh")
+	}
+
+	if len(n.GetFields()) != 0 || len(n.GetTags()) != 0 || len(n.GetOutputsNames()) != 0 {
+		t.Errorf("fields %v, tags %v, outputs %v - want empty", n.GetFields(), n.GetTags(), n.GetOutputsNames())
+	}
+
+	if n.GetFlag() != flag.None || n.GetComponentName() != "" {
+		t.Errorf("flag %s, component %q - want none, empty", n.GetFlag(), n.GetComponentName())
+	}
+
+	if !n.GetTimestamp().Equal(ts) {
+		t.Errorf("GetTimestamp() = %s, want %s", n.GetTimestamp(), ts)
+	}
+
+	if n.GetID() == "" {
+		t.Error("GetID() is empty")
+	}
+}
+
+func TestAcquireCopy_MatchesCopy(t *testing.T) {
+	m := New(level.Warn, "content")
+
+	m.SetFields(fields.Fields{"key": "value"})
+	m.AddTags("tag1", "tag2")
+	m.SetOutputsNames([]string{"out"})
+
+	c := AcquireCopy(m)
+	defer Release(c)
+
+	want := Copy(m)
+
+	if c.GetID() != want.GetID() || c.GetContentBasedHashID() != want.GetContentBasedHashID() {
+		t.Error("identity not shared with the source")
+	}
+
+	if fmt.Sprint(c.GetFields()) != fmt.Sprint(want.GetFields()) ||
+		fmt.Sprint(c.GetTags()) != fmt.Sprint(want.GetTags()) ||
+		fmt.Sprint(c.GetOutputsNames()) != fmt.Sprint(want.GetOutputsNames()) {
+		t.Errorf("AcquireCopy() = %v %v, want %v %v", c.GetFields(), c.GetTags(), want.GetFields(), want.GetTags())
+	}
+
+	// Isolated from the source.
+	c.GetFields()["key"] = "changed"
+
+	if m.GetFields()["key"] != "value" {
+		t.Error("AcquireCopy() aliases the source's fields")
+	}
+}
+
+func TestRelease_KeepsCallerFields(t *testing.T) {
+	f := fields.Fields{"key": "value"}
+
+	m := Acquire(level.Info, "content")
+	m.SetFields(f)
+
+	Release(m)
+
+	// Fields set via `SetFields` belong to the caller.
+	if f["key"] != "value" {
+		t.Errorf("Release() cleared the caller's fields: %v", f)
+	}
+}
+
+func TestRelease_NotPooledAndTwice(t *testing.T) {
+	m := New(level.Info, "content")
+	m.AddTags("tag")
+
+	// No-op for messages not pooled.
+	Release(m)
+	Release(nil)
+
+	if !m.ContainTag("tag") {
+		t.Error("Release() modified a message not pooled")
+	}
+
+	// Released twice: the second call is a no-op - the entry is in the pool
+	// once.
+	p := Acquire(level.Info, "content")
+
+	Release(p)
+	Release(p)
+
+	a, b := Acquire(level.Info, "a"), Acquire(level.Info, "b")
+	defer Release(a)
+	defer Release(b)
+
+	if a.GetMessage() == b.GetMessage() {
+		t.Error("double Release() handed out the same entry twice")
+	}
+}
+
+func TestRetain(t *testing.T) {
+	m := New(level.Info, "content")
+
+	if Retain(m) != m {
+		t.Error("Retain() copied a message not pooled")
+	}
+
+	p := Acquire(level.Info, "content")
+	p.SetFields(fields.Fields{"key": "value"})
+
+	r := Retain(p)
+
+	Release(p)
+
+	if IsPooled(r) {
+		t.Error("Retain() returned a pooled message")
+	}
+
+	if r.GetContent().GetOriginal() != "content" || r.GetFields()["key"] != "value" {
+		t.Errorf("retained message changed after Release(): %q %v", r.GetContent().GetOriginal(), r.GetFields())
+	}
+}
+
+func TestPool_Concurrent(t *testing.T) {
+	src := New(level.Info, "content")
+	src.SetFields(fields.Fields{"key": "value"})
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range 500 {
+				ct := fmt.Sprintf("%d-%d", i, j)
+
+				m := Acquire(level.Info, ct)
+				m.GetFields()["n"] = j
+				m.AddTags(ct)
+
+				c := AcquireCopy(src)
+				c.GetFields()["n"] = j
+
+				if m.GetContent().GetOriginal() != ct || m.GetFields()["n"] != j || !m.ContainTag(ct) {
+					t.Errorf("pooled message shared: %q %v", m.GetContent().GetOriginal(), m.GetFields())
+				}
+
+				Release(c)
+				Release(m)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
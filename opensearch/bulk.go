@@ -0,0 +1,374 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchutil"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// defaultBulkCloseTimeout bounds how long Flush, and Close wait for the
+// indexer to drain.
+const defaultBulkCloseTimeout = 30 * time.Second
+
+// ErrBulkClosed is returned when writing to a closed bulk output.
+var ErrBulkClosed = errors.New("opensearch bulk output is closed")
+
+// newBulkIndexer is a seam for tests.
+var newBulkIndexer = opensearchutil.NewBulkIndexer
+
+// BulkOption configures the `OpenSearchBulk` output.
+type BulkOption func(*OpenSearchBulk)
+
+// BulkWithFlushBytes sets the flush threshold in bytes. Defaults to
+// opensearchutil's 5MB.
+func BulkWithFlushBytes(n int) BulkOption {
+	return func(o *OpenSearchBulk) {
+		o.flushBytes = n
+	}
+}
+
+// BulkWithFlushInterval sets the periodic flush interval. Defaults to
+// opensearchutil's 30s.
+func BulkWithFlushInterval(d time.Duration) BulkOption {
+	return func(o *OpenSearchBulk) {
+		o.flushInterval = d
+	}
+}
+
+// BulkWithNumWorkers sets how many workers flush concurrently. Defaults to
+// opensearchutil's runtime.NumCPU().
+func BulkWithNumWorkers(n int) BulkOption {
+	return func(o *OpenSearchBulk) {
+		o.numWorkers = n
+	}
+}
+
+// BulkWithOnError sets the callback receiving per-item indexing failures,
+// and indexer-level (e.g. flush) failures. The callback may be called
+// concurrently.
+func BulkWithOnError(cb func(error)) BulkOption {
+	return func(o *OpenSearchBulk) {
+		o.onError = cb
+	}
+}
+
+// BulkWithCloseTimeout bounds how long Flush, and Close wait for the
+// indexer to drain. Defaults to 30s.
+func BulkWithCloseTimeout(d time.Duration) BulkOption {
+	return func(o *OpenSearchBulk) {
+		o.closeTimeout = d
+	}
+}
+
+// OpenSearchBulk `Output` definition: it batches documents through
+// opensearchutil's BulkIndexer instead of one request per document.
+type OpenSearchBulk struct {
+	// Client is the OpenSearch client.
+	Client *opensearchapi.Client
+
+	// Config is the OpenSearch configuration.
+	Config Config
+
+	// DynamicIndex is a function which defines the name of the index, and
+	// evaluated at the index time.
+	DynamicIndex DynamicIndexFunc
+
+	// Indexer configuration.
+	closeTimeout  time.Duration
+	flushBytes    int
+	flushInterval time.Duration
+	numWorkers    int
+	onError       func(error)
+
+	// mu guards the indexer - swapped on Flush - and the closed flag.
+	// closeErr records the first Close outcome - making Close idempotent:
+	// subsequent calls return it without re-closing.
+	mu       sync.Mutex
+	closed   bool
+	closeErr error
+	indexer  opensearchutil.BulkIndexer
+}
+
+//////
+// Methods.
+//////
+
+// Write conforms to the `io.Writer` interface: it enqueues the document
+// into the bulk indexer. Indexing is asynchronous - per-item failures are
+// delivered to the `BulkWithOnError` callback, never panics. After Close,
+// it returns `ErrBulkClosed`.
+//
+// NDJSON safety: _bulk items must be single-line JSON. Multi-line
+// documents are compacted; non-compactable ones are rejected - reported
+// through the error callback, and returned as the write error - so one bad
+// payload can't corrupt the whole batch.
+func (o *OpenSearchBulk) Write(data []byte) (int, error) {
+	parsedData, err := parseBody(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+
+	// CLONED, and trimmed: the indexer reads the payload AFTER this call
+	// returns, but the `io.Writer` contract forbids retaining `p` - and a
+	// trailing linebreak would inject a blank line into the NDJSON body.
+	doc := bytes.Clone(bytes.TrimRight(data, "\r\n"))
+
+	// _bulk is NDJSON: each item's source must be a SINGLE line.
+	// Single-line payloads skip this entirely: no extra allocation on the
+	// fast path.
+	if bytes.ContainsAny(doc, "\r\n") {
+		var compacted bytes.Buffer
+
+		if err := json.Compact(&compacted, doc); err != nil {
+			err = fmt.Errorf(
+				"refusing to enqueue a multi-line, non-compactable document - it would corrupt the NDJSON _bulk stream: %w",
+				err,
+			)
+
+			if o.onError != nil {
+				o.onError(err)
+			}
+
+			return 0, err
+		}
+
+		doc = compacted.Bytes()
+	}
+
+	item := opensearchutil.BulkIndexerItem{
+		Action:    "index",
+		Body:      bytes.NewReader(doc),
+		Index:     o.DynamicIndex(),
+		OnFailure: o.reportItemFailure,
+	}
+
+	// Check if parsedData has an id.
+	//
+	// NOTE: A non-string id is skipped - not an error. A logging library
+	// must never panic the host application on an odd payload.
+	if id, ok := parsedData["id"].(string); ok {
+		item.DocumentID = id
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return 0, ErrBulkClosed
+	}
+
+	// Guard against an uninitialized indexer - never panic the host
+	// application.
+	if o.indexer == nil {
+		return 0, errors.New("opensearch bulk indexer isn't initialized")
+	}
+
+	if err := o.indexer.Add(context.Background(), item); err != nil {
+		return 0, fmt.Errorf("failed adding document to the bulk indexer: %w", err)
+	}
+
+	return len(data), nil
+}
+
+// Flush drains the bulk indexer - waiting, bounded by the close timeout
+// (default: 30s, see `BulkWithCloseTimeout`), until every enqueued document
+// is sent - then swaps in a fresh indexer, so the output keeps working.
+// After Close it's a no-op.
+//
+// When REBUILDING the indexer fails, the error is returned - never
+// log.Fatalf. Writes then degrade to the uninitialized-indexer guard until
+// a later Flush rebuilds it.
+func (o *OpenSearchBulk) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.closeTimeout)
+	defer cancel()
+
+	errs := []error{}
+
+	// opensearchutil's BulkIndexer has no flush primitive: closing it
+	// drains it.
+	if o.indexer != nil {
+		if err := o.indexer.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed flushing the bulk indexer: %w", err))
+		}
+	}
+
+	// A fresh indexer is swapped in EVEN when the drain failed - the old
+	// one is closed.
+	fresh, err := o.newIndexer()
+	if err != nil {
+		o.indexer = nil
+
+		errs = append(errs, fmt.Errorf("failed rebuilding the bulk indexer after the flush: %w", err))
+
+		return errors.Join(errs...)
+	}
+
+	o.indexer = fresh
+
+	return errors.Join(errs...)
+}
+
+// Close drains the bulk indexer - waiting, bounded by the close timeout
+// (default: 30s, see `BulkWithCloseTimeout`) - and shuts it down. It's
+// idempotent: subsequent calls return the FIRST call's outcome without
+// re-closing. Writes after Close return `ErrBulkClosed` - never panic.
+func (o *OpenSearchBulk) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return o.closeErr
+	}
+
+	o.closed = true
+
+	// The indexer may be nil - a previous rebuild failed - nothing to
+	// drain then.
+	if o.indexer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), o.closeTimeout)
+		defer cancel()
+
+		if err := o.indexer.Close(ctx); err != nil {
+			o.closeErr = fmt.Errorf("failed closing the bulk indexer: %w", err)
+		}
+	}
+
+	return o.closeErr
+}
+
+//////
+// Helpers.
+//////
+
+// reportItemFailure delivers a per-item indexing failure to the error
+// callback, if any.
+func (o *OpenSearchBulk) reportItemFailure(
+	_ context.Context,
+	item opensearchutil.BulkIndexerItem,
+	res opensearchapi.BulkRespItem,
+	err error,
+) {
+	if o.onError == nil {
+		return
+	}
+
+	if err != nil {
+		o.onError(fmt.Errorf(
+			`failed indexing document (index: "%s", id: "%s"): %w`,
+			item.Index,
+			item.DocumentID,
+			err,
+		))
+
+		return
+	}
+
+	// NOTE: Unlike esutil's, the item error is a pointer - nil-guarded.
+	errType, reason := "", ""
+
+	if res.Error != nil {
+		errType, reason = res.Error.Type, res.Error.Reason
+	}
+
+	o.onError(fmt.Errorf(
+		`failed indexing document (index: "%s", id: "%s", status: %d): %s: %s`,
+		item.Index,
+		item.DocumentID,
+		res.Status,
+		errType,
+		reason,
+	))
+}
+
+// newIndexer builds a bulk indexer from the stored configuration -
+// returning the construction failure instead of exiting: it's reachable at
+// RUNTIME through Flush's indexer swap.
+func (o *OpenSearchBulk) newIndexer() (opensearchutil.BulkIndexer, error) {
+	return newBulkIndexer(opensearchutil.BulkIndexerConfig{
+		Client:        o.Client,
+		FlushBytes:    o.flushBytes,
+		FlushInterval: o.flushInterval,
+		NumWorkers:    o.numWorkers,
+		OnError: func(_ context.Context, err error) {
+			if o.onError != nil {
+				o.onError(fmt.Errorf("bulk indexer error: %w", err))
+			}
+		},
+	})
+}
+
+//////
+// Factory.
+//////
+
+// NewBulk returns a new `OpenSearchBulk` client indexing into `indexName`.
+//
+// NOTE: Indexing is asynchronous, and batched - deliver failures through
+// `BulkWithOnError`, and drain with `Flush`, or `Close`.
+func NewBulk(
+	indexName string,
+	osConfig Config,
+	opts ...BulkOption,
+) *OpenSearchBulk {
+	return NewBulkWithDynamicIndex(func() string { return indexName }, osConfig, opts...)
+}
+
+// NewBulkWithDynamicIndex returns a new `OpenSearchBulk` client. It allows
+// to define a function which defines the name of the index, and evaluated
+// at the index time.
+//
+// NOTE: Indexing is asynchronous, and batched - deliver failures through
+// `BulkWithOnError`, and drain with `Flush`, or `Close`.
+func NewBulkWithDynamicIndex(
+	dynamicIndexFunc DynamicIndexFunc,
+	osConfig Config,
+	opts ...BulkOption,
+) *OpenSearchBulk {
+	// Client creation, and the connectivity ping mirror the sync factory -
+	// including its log.Fatalf failure behavior.
+	base := NewWithDynamicIndex(dynamicIndexFunc, osConfig)
+
+	o := &OpenSearchBulk{
+		Client:       base.Client,
+		Config:       osConfig,
+		DynamicIndex: base.DynamicIndex,
+
+		closeTimeout: defaultBulkCloseTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	indexer, err := o.newIndexer()
+	if err != nil {
+		log.Fatalf("Error creating the OpenSearch bulk indexer: %s", err)
+	}
+
+	o.indexer = indexer
+
+	return o
+}
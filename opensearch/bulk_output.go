@@ -0,0 +1,125 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package opensearch
+
+import (
+	"fmt"
+
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// bulkOutput is an OpenSearch-bulk-backed `output.IOutput` carrying the
+// Flush, and Close capabilities.
+type bulkOutput struct {
+	*output.Proxy
+
+	os *OpenSearchBulk
+}
+
+// Flush drains the bulk indexer - see `OpenSearchBulk.Flush`. After Close
+// it's a no-op.
+func (o *bulkOutput) Flush() error {
+	return o.os.Flush()
+}
+
+// Close drains, and shuts the bulk indexer down. It's idempotent. Writes
+// after Close return `ErrBulkClosed`.
+func (o *bulkOutput) Close() error {
+	return o.os.Close()
+}
+
+//////
+// Factory.
+//////
+
+// bulkOutputFactory builds OpenSearch bulk outputs.
+func bulkOutputFactory(
+	outputName string,
+	dynamicIndexFunc DynamicIndexFunc,
+	osConfig Config,
+	maxLevel level.Level,
+	bulkOpts []BulkOption,
+	processors ...processor.IProcessor,
+) output.IOutput {
+	client := NewBulkWithDynamicIndex(dynamicIndexFunc, osConfig, bulkOpts...)
+
+	// NOTE: INLINE JSON - not JSONPretty like the sync factory: the _bulk
+	// NDJSON protocol requires each document on a single line.
+	inner := output.New(outputName, maxLevel, client, processors...).SetFormatter(formatter.JSON())
+
+	o := &bulkOutput{os: client}
+
+	o.Proxy = output.NewProxy(inner, o)
+
+	return o
+}
+
+//////
+// Builtins.
+//////
+
+// BulkOutput is a built-in `output` - named `OpenSearchBulk`, that writes
+// to OpenSearch batching documents into _bulk requests - the drop-in
+// counterpart of `es.BulkOutput`.
+//
+// Capabilities: `Flush() error` (drains the indexer), and idempotent
+// `Close() error`. Indexing is asynchronous: per-item failures are
+// delivered through `BulkWithOnError`.
+//
+// NOTE: By default, data is JSON-formatted.
+// NOTE: It's the caller's responsibility to create the index, define its
+// mapping, and settings.
+func BulkOutput(
+	indexName string,
+	osConfig Config,
+	maxLevel level.Level,
+	bulkOpts []BulkOption,
+	processors ...processor.IProcessor,
+) output.IOutput {
+	return bulkOutputFactory(
+		"OpenSearchBulk",
+		func() string { return indexName },
+		osConfig,
+		maxLevel,
+		bulkOpts,
+		processors...,
+	)
+}
+
+// BulkOutputWithDynamicIndex is a built-in `output` - named
+// `OpenSearchBulkWithDynamicIndex-{index}` that writes to OpenSearch
+// batching documents into _bulk requests. It allows to define a function
+// that returns the index name to be used, evaluated at the index time.
+//
+// Capabilities: `Flush() error` (drains the indexer), and idempotent
+// `Close() error`. Indexing is asynchronous: per-item failures are
+// delivered through `BulkWithOnError`.
+//
+// NOTE: By default, data is JSON-formatted.
+// NOTE: It's the caller's responsibility to create the index, define its
+// mapping, and settings.
+func BulkOutputWithDynamicIndex(
+	dynamicIndexFunc DynamicIndexFunc,
+	osConfig Config,
+	maxLevel level.Level,
+	bulkOpts []BulkOption,
+	processors ...processor.IProcessor,
+) output.IOutput {
+	return bulkOutputFactory(
+		fmt.Sprintf("OpenSearchBulkWithDynamicIndex-%s", dynamicIndexFunc()),
+		dynamicIndexFunc,
+		osConfig,
+		maxLevel,
+		bulkOpts,
+		processors...,
+	)
+}
@@ -0,0 +1,304 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package opensearch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+//////
+// Test helpers.
+//////
+
+// bulkHandler answers a _bulk request reporting each item with `status` -
+// 201 is a success, anything else a failure.
+func bulkHandler(status func() int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		// NDJSON: one action line + one source line per item.
+		lines := strings.Count(string(body), "\n")
+
+		items := []string{}
+
+		for range lines / 2 {
+			s := status()
+
+			if s == http.StatusCreated {
+				items = append(items, `{"index":{"_index":"idx","status":201,"result":"created"}}`)
+
+				continue
+			}
+
+			items = append(items, fmt.Sprintf(
+				`{"index":{"_index":"idx","status":%d,"error":{"type":"some_exception","reason":"status %d"}}}`,
+				s, s,
+			))
+		}
+
+		fmt.Fprintf(w, `{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	}
+}
+
+// bulkErrorCollector concurrency-safely accumulates callback errors.
+type bulkErrorCollector struct {
+	mu     sync.Mutex
+	errors []error
+}
+
+func (c *bulkErrorCollector) callback() func(error) {
+	return func(err error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.errors = append(c.errors, err)
+	}
+}
+
+func (c *bulkErrorCollector) all() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]error, len(c.errors))
+	copy(out, c.errors)
+
+	return out
+}
+
+// bulkItems returns the (action, source) line pairs of the captured _bulk
+// requests.
+func bulkItems(requests []capturedRequest) [][2]string {
+	items := [][2]string{}
+
+	for _, r := range requests {
+		lines := strings.Split(strings.TrimSuffix(r.Body, "\n"), "\n")
+
+		for i := 0; i+1 < len(lines); i += 2 {
+			items = append(items, [2]string{lines[i], lines[i+1]})
+		}
+	}
+
+	return items
+}
+
+//////
+// Bulk client.
+//////
+
+func TestOpenSearchBulk_Write_BatchesIntoBulkRequests(t *testing.T) {
+	cfg, recorder := newFakeOpenSearch(t, nil)
+
+	o := NewBulk(testIndex, cfg, BulkWithNumWorkers(1))
+
+	// Trailing linebreaks are trimmed; multi-line documents compacted.
+	for _, doc := range []string{
+		"{\"message\":\"one\"}\n",
+		`{"id":"doc-2","message":"two"}`,
+		"{\n  \"message\": \"three\"\n}",
+	} {
+		if _, err := o.Write([]byte(doc)); err != nil {
+			t.Fatalf("Write() error = %v, want nil", err)
+		}
+	}
+
+	if err := o.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v, want nil", err)
+	}
+
+	requests := recorder.all()
+
+	if len(requests) != 1 || requests[0].Path != "/_bulk" {
+		t.Fatalf("Expected 1 _bulk request, got %+v", requests)
+	}
+
+	items := bulkItems(requests)
+
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %q", items)
+	}
+
+	if items[0][0] != `{"index":{"_index":"test-index"}}` || items[0][1] != `{"message":"one"}` {
+		t.Errorf("item 0 = %q", items[0])
+	}
+
+	if !strings.Contains(items[1][0], `"_id":"doc-2"`) {
+		t.Errorf("item 1 action = %s, want the document id", items[1][0])
+	}
+
+	if items[2][1] != `{"message":"three"}` {
+		t.Errorf("item 2 = %s, want compacted", items[2][1])
+	}
+
+	// The output keeps working after a Flush.
+	if _, err := o.Write([]byte(`{"message":"four"}`)); err != nil {
+		t.Fatalf("Write() after Flush error = %v, want nil", err)
+	}
+
+	if err := o.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	if got := len(bulkItems(recorder.all())); got != 4 {
+		t.Errorf("Expected 4 items after Close, got %d", got)
+	}
+}
+
+func TestOpenSearchBulk_Write_RejectsNonCompactableMultiLine(t *testing.T) {
+	cfg, _ := newFakeOpenSearch(t, nil)
+
+	collector := &bulkErrorCollector{}
+
+	o := NewBulk(testIndex, cfg, BulkWithOnError(collector.callback()))
+
+	defer o.Close()
+
+	// Two documents on two lines: decodable, but not compactable.
+	if _, err := o.Write([]byte("{\"message\":\"a\"}\n{\"message\":\"b\"}")); err == nil {
+		t.Error("Write() should reject a non-compactable multi-line document")
+	}
+
+	if len(collector.all()) != 1 {
+		t.Errorf("callback errors = %v, want 1", collector.all())
+	}
+}
+
+func TestOpenSearchBulk_PerItemFailuresReachCallback(t *testing.T) {
+	cfg, _ := newFakeOpenSearch(t, bulkHandler(func() int { return http.StatusBadRequest }))
+
+	collector := &bulkErrorCollector{}
+
+	o := NewBulk(testIndex, cfg, BulkWithNumWorkers(1), BulkWithOnError(collector.callback()))
+
+	_, _ = o.Write([]byte(`{"id":"doc-1","message":"bad"}`))
+
+	if err := o.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	errs := collector.all()
+
+	if len(errs) != 1 {
+		t.Fatalf("callback errors = %v, want 1", errs)
+	}
+
+	want := `failed indexing document (index: "test-index", id: "doc-1", status: 400): some_exception: status 400`
+
+	if errs[0].Error() != want {
+		t.Errorf("callback error = %q, want %q", errs[0], want)
+	}
+}
+
+func TestOpenSearchBulk_CloseIsIdempotentAndGuardsWrites(t *testing.T) {
+	cfg, _ := newFakeOpenSearch(t, nil)
+
+	o := NewBulk(testIndex, cfg)
+
+	if err := o.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	if err := o.Close(); err != nil {
+		t.Fatalf("second Close() error = %v, want nil", err)
+	}
+
+	if err := o.Flush(); err != nil {
+		t.Errorf("Flush() after Close error = %v, want nil", err)
+	}
+
+	if _, err := o.Write([]byte(`{"message":"late"}`)); !errors.Is(err, ErrBulkClosed) {
+		t.Errorf("Write() after Close error = %v, want ErrBulkClosed", err)
+	}
+}
+
+//////
+// Bulk outputs.
+//////
+
+func TestOpenSearchBulkOutput(t *testing.T) {
+	cfg, recorder := newFakeOpenSearch(t, nil)
+
+	o := BulkOutput(testIndex, cfg, level.Info, []BulkOption{BulkWithNumWorkers(1)})
+
+	if o.GetName() != "OpenSearchBulk" {
+		t.Errorf("GetName() = %q, want OpenSearchBulk", o.GetName())
+	}
+
+	if err := o.Write(message.New(level.Info, "hello")); err != nil {
+		t.Fatalf("Write() error = %v, want nil", err)
+	}
+
+	// Level gating.
+	if err := o.Write(message.New(level.Debug, "dropped")); err != nil {
+		t.Fatalf("Write() error = %v, want nil", err)
+	}
+
+	flusher, ok := o.(interface{ Flush() error })
+	if !ok {
+		t.Fatal("BulkOutput should carry the Flush capability")
+	}
+
+	if err := flusher.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v, want nil", err)
+	}
+
+	items := bulkItems(recorder.all())
+
+	if len(items) != 1 || !strings.Contains(items[0][1], `"message":"hello"`) {
+		t.Fatalf("items = %q, want the single-line hello document", items)
+	}
+
+	closer, ok := o.(interface{ Close() error })
+	if !ok {
+		t.Fatal("BulkOutput should carry the Close capability")
+	}
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+}
+
+func TestOpenSearchBulkWithDynamicIndexOutput(t *testing.T) {
+	cfg, recorder := newFakeOpenSearch(t, nil)
+
+	current := "idx-1"
+
+	o := BulkOutputWithDynamicIndex(
+		func() string { return current },
+		cfg,
+		level.Info,
+		[]BulkOption{BulkWithNumWorkers(1)},
+	)
+
+	if o.GetName() != "OpenSearchBulkWithDynamicIndex-idx-1" {
+		t.Errorf("GetName() = %q", o.GetName())
+	}
+
+	_ = o.Write(message.New(level.Info, "one"))
+
+	current = "idx-2"
+
+	_ = o.Write(message.New(level.Info, "two"))
+
+	if err := o.(interface{ Close() error }).Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	items := bulkItems(recorder.all())
+
+	if len(items) != 2 ||
+		!strings.Contains(items[0][0], `"_index":"idx-1"`) ||
+		!strings.Contains(items[1][0], `"_index":"idx-2"`) {
+		t.Errorf("items = %q, want routed to idx-1, then idx-2", items)
+	}
+}
@@ -0,0 +1,31 @@
+// Package opensearch provides Sypl's OpenSearch support: the low-level
+// client, and the ready-to-use `output.IOutput` factories. It mirrors the
+// `es` module - same factories, tag maps, dynamic indexes, and document
+// shape - so switching backends is a matter of changing the constructor.
+// It lives in its own Go module (github.com/thalesfsp/sypl/opensearch/v2)
+// so the core sypl module carries no OpenSearch dependency.
+//
+// NOTE: OpenSearch's API diverges from go-elasticsearch v8's - e.g. the
+// product header check - hence the dedicated client (opensearch-go).
+//
+// Client features:
+// - Message's content by default is JSON-formatted.
+// - Provides multiple ways to set the index name: static, or dynamic - which
+// is evaluated at index time.
+// - Two indexing strategies: `OpenSearch` (one request per document), and
+// `OpenSearchBulk` (documents batched into _bulk requests via
+// opensearchutil's BulkIndexer - see `NewBulk`, and
+// `NewBulkWithDynamicIndex`). The bulk client indexes asynchronously:
+// failures are delivered through `BulkWithOnError`, and `Flush`/`Close`
+// drain it.
+//
+// Output factories - counterparts of the `es` ones:
+// - `Output`
+// - `OutputWithDynamicIndex`
+// - `OutputWithTagMap`
+// - `BulkOutput`
+// - `BulkOutputWithDynamicIndex`
+//
+// NOTE: It's the caller's responsibility to create the index, define its
+// mapping, and settings.
+package opensearch
@@ -0,0 +1,154 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+)
+
+//////
+// Const, vars, and types.
+//////
+
+var contextTimeout = 5 * time.Second
+
+// DynamicIndexFunc is a function which defines the name of the index, and
+// evaluated at the index time.
+type DynamicIndexFunc func() string
+
+// Config is the OpenSearch configuration.
+type Config = opensearchgo.Config
+
+// OpenSearch `Output` definition.
+type OpenSearch struct {
+	// Client is the OpenSearch client.
+	Client *opensearchapi.Client
+
+	// Config is the OpenSearch configuration.
+	Config Config
+
+	// DynamicIndex is a function which defines the name of the index, and
+	// evaluated at the index time.
+	DynamicIndex DynamicIndexFunc
+}
+
+//////
+// Methods.
+//////
+
+// Write conforms to the `io.Writer` interface.
+func (o *OpenSearch) Write(data []byte) (int, error) {
+	parsedData, err := parseBody(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+
+	// Set up the request object.
+	req := opensearchapi.IndexReq{
+		Body:  bytes.NewReader(data),
+		Index: o.DynamicIndex(),
+	}
+
+	// Check if parsedData as an id.
+	//
+	// NOTE: A non-string id is skipped - not an error. A logging library must
+	// never panic the host application on an odd payload.
+	if id, ok := parsedData["id"].(string); ok {
+		req.DocumentID = id
+	}
+
+	// Guard against an uninitialized client - never panic the host
+	// application.
+	if o.Client == nil {
+		return 0, errors.New("opensearch client isn't initialized")
+	}
+
+	// Perform the request with the client.
+	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
+	defer cancel()
+
+	res, err := o.Client.Index(ctx, req)
+	if err != nil {
+		// Error responses are decoded by the client - surface their reason.
+		var structErr *opensearchgo.StructError
+		if errors.As(err, &structErr) {
+			return 0, fmt.Errorf("failed indexing document: %s", structErr.Err.Reason)
+		}
+
+		return 0, fmt.Errorf("failed indexing document: %w", err)
+	}
+
+	// Verify if document was really created/updated.
+	if res.Result == "created" || res.Result == "updated" {
+		return len(data), nil
+	}
+
+	return 0, fmt.Errorf("unexpected result: %+v", res.Result)
+}
+
+//////
+// Helpers.
+//////
+
+// parseBody decodes a JSON document.
+func parseBody(r io.Reader) (map[string]interface{}, error) {
+	var b map[string]interface{}
+
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("failed parsing the body: %w", err)
+	}
+
+	return b, nil
+}
+
+//////
+// Factory.
+//////
+
+// New returns a new `OpenSearch` client.
+func New(
+	indexName string,
+	osConfig Config,
+) *OpenSearch {
+	return NewWithDynamicIndex(func() string { return indexName }, osConfig)
+}
+
+// NewWithDynamicIndex returns a new `OpenSearch` client. It allows to define
+// is a function which defines the name of the index, and evaluated at the
+// index time.
+func NewWithDynamicIndex(
+	dynamicIndexFunc DynamicIndexFunc,
+	osConfig Config,
+) *OpenSearch {
+	client, err := opensearchapi.NewClient(opensearchapi.Config{Client: osConfig})
+	if err != nil {
+		log.Fatalf("Error creating the OpenSearch client: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
+	defer cancel()
+
+	// NOTE: The client consumes, and closes the response body - persistent
+	// TCP connections are re-used.
+	if _, err := client.Info(ctx, nil); err != nil {
+		log.Fatalf("Error pinging OpenSearch: %s", err)
+	}
+
+	return &OpenSearch{
+		Client:       client,
+		Config:       osConfig,
+		DynamicIndex: dynamicIndexFunc,
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package opensearch
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//////
+// Test helpers.
+//////
+
+// testIndex is the recurring static test index name.
+const testIndex = "test-index"
+
+// infoBody is a minimal OpenSearch Info (ping) response.
+const infoBody = `{
+	"name": "fake-node",
+	"cluster_name": "fake-cluster",
+	"cluster_uuid": "abc123",
+	"version": {"distribution": "opensearch", "number": "2.17.0"},
+	"tagline": "The OpenSearch Project: https://opensearch.org/"
+}`
+
+// capturedRequest records what the fake OpenSearch server received.
+type capturedRequest struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// requestRecorder concurrency-safely accumulates captured requests.
+type requestRecorder struct {
+	mu       sync.Mutex
+	requests []capturedRequest
+}
+
+func (rr *requestRecorder) add(r capturedRequest) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.requests = append(rr.requests, r)
+}
+
+func (rr *requestRecorder) all() []capturedRequest {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	out := make([]capturedRequest, len(rr.requests))
+	copy(out, rr.requests)
+
+	return out
+}
+
+// newFakeOpenSearch starts a fake OpenSearch server - WITHOUT
+// Elasticsearch's product header. It answers the Info ping ("/"), records
+// any other request, and delegates it to `handler` - by default, documents
+// are created.
+func newFakeOpenSearch(t *testing.T, handler http.HandlerFunc) (Config, *requestRecorder) {
+	t.Helper()
+
+	recorder := &requestRecorder{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, infoBody)
+
+			return
+		}
+
+		var body bytes.Buffer
+
+		_, _ = body.ReadFrom(r.Body)
+
+		recorder.add(capturedRequest{Method: r.Method, Path: r.URL.Path, Body: body.String()})
+
+		// The recorder consumed the body - hand the handler a copy.
+		r.Body = nopReadCloser{bytes.NewReader(body.Bytes())}
+
+		switch {
+		case handler != nil:
+			handler(w, r)
+		case strings.HasSuffix(r.URL.Path, "/_bulk"):
+			bulkHandler(func() int { return http.StatusCreated })(w, r)
+		default:
+			fmt.Fprint(w, `{"_index":"test-index","_id":"1","result":"created"}`)
+		}
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return Config{Addresses: []string{srv.URL}}, recorder
+}
+
+// nopReadCloser wraps a reader into an io.ReadCloser.
+type nopReadCloser struct{ *bytes.Reader }
+
+func (nopReadCloser) Close() error { return nil }
+
+//////
+// Factories.
+//////
+
+func TestNew(t *testing.T) {
+	cfg, _ := newFakeOpenSearch(t, nil)
+
+	o := New(testIndex, cfg)
+
+	if o.Client == nil {
+		t.Fatal("New() should set a non-nil client")
+	}
+
+	if got := o.DynamicIndex(); got != testIndex {
+		t.Errorf("DynamicIndex() = %q, want %q", got, testIndex)
+	}
+}
+
+//////
+// Write.
+//////
+
+func TestOpenSearch_Write(t *testing.T) {
+	cfg, recorder := newFakeOpenSearch(t, nil)
+
+	current := "idx-1"
+
+	o := NewWithDynamicIndex(func() string { return current }, cfg)
+
+	payload := []byte(`{"message":"hello"}`)
+
+	n, err := o.Write(payload)
+	if err != nil {
+		t.Fatalf("Write() error = %v, want nil", err)
+	}
+
+	if n != len(payload) {
+		t.Errorf("Write() = %d, want %d", n, len(payload))
+	}
+
+	// The index is evaluated at index time.
+	current = "idx-2"
+
+	if _, err := o.Write([]byte(`{"id":"doc-1","message":"hello"}`)); err != nil {
+		t.Fatalf("Write() error = %v, want nil", err)
+	}
+
+	requests := recorder.all()
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %+v", requests)
+	}
+
+	if requests[0].Method != http.MethodPost || requests[0].Path != "/idx-1/_doc" || requests[0].Body != string(payload) {
+		t.Errorf("First request = %+v", requests[0])
+	}
+
+	// Documents with a string id are PUT under it.
+	if requests[1].Method != http.MethodPut || requests[1].Path != "/idx-2/_doc/doc-1" {
+		t.Errorf("Second request = %+v", requests[1])
+	}
+}
+
+func TestOpenSearch_Write_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		payload string
+		wantErr string
+	}{
+		{
+			name:    "non-JSON payload",
+			payload: "not json",
+			wantErr: "failed parsing the body",
+		},
+		{
+			name:    "error response",
+			status:  http.StatusBadRequest,
+			body:    `{"error":{"type":"mapper_parsing_exception","reason":"failed to parse"},"status":400}`,
+			payload: `{"message":"hello"}`,
+			wantErr: "failed indexing document: failed to parse",
+		},
+		{
+			name:    "unexpected result",
+			status:  http.StatusOK,
+			body:    `{"result":"noop"}`,
+			payload: `{"message":"hello"}`,
+			wantErr: "unexpected result: noop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _ := newFakeOpenSearch(t, func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+
+				fmt.Fprint(w, tt.body)
+			})
+
+			_, err := New(testIndex, cfg).Write([]byte(tt.payload))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Write() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenSearch_Write_UninitializedClient(t *testing.T) {
+	o := &OpenSearch{DynamicIndex: func() string { return testIndex }}
+
+	if _, err := o.Write([]byte(`{"message":"hello"}`)); err == nil {
+		t.Error("Write() with a nil client should error, not panic")
+	}
+}
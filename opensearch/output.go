@@ -0,0 +1,163 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package opensearch
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// TagMapItem is an item of the OpenSearch tag map.
+type TagMapItem struct {
+	DynamicIndexFunc DynamicIndexFunc
+	Level            level.Level
+}
+
+// TagMap is a map of tags to index names.
+type TagMap = map[string]TagMapItem
+
+//////
+// Helpers.
+//////
+
+// NewTagMapItem is a helper to create `TagMapItem`.
+func NewTagMapItem(
+	l level.Level,
+	dynamicIndexFunc DynamicIndexFunc,
+) TagMapItem {
+	return TagMapItem{
+		DynamicIndexFunc: dynamicIndexFunc,
+		Level:            l,
+	}
+}
+
+//////
+// Factory.
+//////
+
+// outputFactory builds OpenSearch outputs.
+func outputFactory(
+	outputName string,
+	dynamicIndexFunc DynamicIndexFunc,
+	osConfig Config,
+	maxLevel level.Level,
+	processors ...processor.IProcessor,
+) output.IOutput {
+	return output.New(outputName,
+		maxLevel,
+		NewWithDynamicIndex(dynamicIndexFunc, osConfig),
+		processors...,
+	).SetFormatter(formatter.JSONPretty())
+}
+
+//////
+// Builtins.
+//////
+
+// Output is a built-in `output` - named `OpenSearch`, that writes to
+// OpenSearch - the drop-in counterpart of `es.Output`.
+//
+// NOTE: By default, data is JSON-formatted - the same document shape as the
+// es outputs.
+// NOTE: It's the caller's responsibility to create the index, define its
+// mapping, and settings.
+func Output(
+	indexName string,
+	osConfig Config,
+	maxLevel level.Level,
+	processors ...processor.IProcessor,
+) output.IOutput {
+	return outputFactory(
+		"OpenSearch",
+		func() string { return indexName },
+		osConfig,
+		maxLevel,
+		processors...,
+	)
+}
+
+// OutputWithTagMap is a built-in `output` - named `OpenSearchWithTagMap-{tag}`
+// that writes to OpenSearch. It allows to define a map of tags and indexes.
+// The index name is a function which defines the name of the index and is
+// evaluated at the index time.
+//
+// IT'S THE CALLER'S RESPONSIBILITY TO DEFINE A CATCH-ALL INDEX - IF DESIRED.
+// TO ACHIEVE THIS, USE `*` AS THE TAG NAME.
+//
+// NOTE: By default, data is JSON-formatted.
+// NOTE: It's the caller's responsibility to create the index, define its
+// mapping, and settings.
+func OutputWithTagMap(
+	tagMap TagMap,
+	osConfig Config,
+	processors ...processor.IProcessor,
+) []output.IOutput {
+	outputs := make([]output.IOutput, 0, len(tagMap))
+	tags := make([]string, 0, len(tagMap))
+
+	var catchAll TagMapItem
+
+	for tag, tagMapItem := range tagMap {
+		if tag == "*" {
+			catchAll = tagMapItem
+		} else {
+			outputs = append(outputs, outputFactory(
+				fmt.Sprintf("OpenSearchWithTagMap-%s", tag),
+				tagMapItem.DynamicIndexFunc,
+				osConfig,
+				tagMapItem.Level,
+				// NOTE: Clone before appending - never alias the caller's
+				// backing array across iterations.
+				append(slices.Clone(processors), processor.PrintOnlyIfTagged(tag))...,
+			))
+
+			tags = append(tags, tag)
+		}
+	}
+
+	if catchAll.DynamicIndexFunc != nil {
+		outputs = append(outputs, outputFactory(
+			"OpenSearchWithTagMap-*",
+			catchAll.DynamicIndexFunc,
+			osConfig,
+			catchAll.Level,
+			append(slices.Clone(processors), processor.PrintOnlyIfNotTaggedWith(tags...))...,
+		))
+	}
+
+	return outputs
+}
+
+// OutputWithDynamicIndex is a built-in `output` - named
+// `OpenSearchWithDynamicIndex` that writes to OpenSearch. It allows to
+// define a function that returns the index name to be used, evaluated at
+// the index time.
+//
+// NOTE: By default, data is JSON-formatted.
+// NOTE: It's the caller's responsibility to create the index, define its
+// mapping, and settings.
+func OutputWithDynamicIndex(
+	dynamicIndexFunc DynamicIndexFunc,
+	osConfig Config,
+	maxLevel level.Level,
+	processors ...processor.IProcessor,
+) output.IOutput {
+	return outputFactory(
+		fmt.Sprintf("OpenSearchWithDynamicIndex-%s", dynamicIndexFunc()),
+		dynamicIndexFunc,
+		osConfig,
+		maxLevel,
+		processors...,
+	)
+}
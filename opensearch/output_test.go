@@ -0,0 +1,165 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package opensearch
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+//////
+// Test helpers.
+//////
+
+// indexesOf returns the sorted, de-duplicated indexes the captured
+// document requests targeted.
+func indexesOf(requests []capturedRequest) []string {
+	indexes := []string{}
+
+	for _, r := range requests {
+		index, _, _ := strings.Cut(strings.TrimPrefix(r.Path, "/"), "/")
+
+		if !slices.Contains(indexes, index) {
+			indexes = append(indexes, index)
+		}
+	}
+
+	slices.Sort(indexes)
+
+	return indexes
+}
+
+//////
+// Outputs.
+//////
+
+func TestNewTagMapItem(t *testing.T) {
+	item := NewTagMapItem(level.Debug, func() string { return "idx-item" })
+
+	if item.Level != level.Debug || item.DynamicIndexFunc() != "idx-item" {
+		t.Errorf("NewTagMapItem() = %+v", item)
+	}
+}
+
+func TestOpenSearchOutput(t *testing.T) {
+	cfg, recorder := newFakeOpenSearch(t, nil)
+
+	o := Output("idx-plain", cfg, level.Trace)
+
+	if o.GetName() != "OpenSearch" {
+		t.Errorf("GetName() = %q, want %q", o.GetName(), "OpenSearch")
+	}
+
+	// Same document shape as the es outputs.
+	if o.GetFormatter() == nil || o.GetFormatter().GetName() != "JSONPretty" {
+		t.Errorf("GetFormatter() = %v, want the JSONPretty formatter", o.GetFormatter())
+	}
+
+	if err := o.Write(message.New(level.Info, "os message")); err != nil {
+		t.Fatalf("Write() error = %v, want nil", err)
+	}
+
+	requests := recorder.all()
+
+	if got := indexesOf(requests); len(got) != 1 || got[0] != "idx-plain" {
+		t.Fatalf("Indexed into %v, want [idx-plain]", got)
+	}
+
+	parsed := map[string]interface{}{}
+
+	if err := json.Unmarshal([]byte(requests[0].Body), &parsed); err != nil {
+		t.Fatalf("Indexed body isn't valid JSON: %v", err)
+	}
+
+	if parsed["message"] != "os message" {
+		t.Errorf(`Indexed body message = %v, want "os message"`, parsed["message"])
+	}
+}
+
+func TestOpenSearchWithDynamicIndexOutput(t *testing.T) {
+	cfg, recorder := newFakeOpenSearch(t, nil)
+
+	day := "2026-07-11"
+
+	o := OutputWithDynamicIndex(func() string { return "idx-" + day }, cfg, level.Trace)
+
+	if o.GetName() != "OpenSearchWithDynamicIndex-idx-2026-07-11" {
+		t.Errorf("GetName() = %q", o.GetName())
+	}
+
+	_ = o.Write(message.New(level.Info, "one"))
+
+	day = "2026-07-12"
+
+	_ = o.Write(message.New(level.Info, "two"))
+
+	if got := indexesOf(recorder.all()); !slices.Equal(got, []string{"idx-2026-07-11", "idx-2026-07-12"}) {
+		t.Errorf("Indexed into %v, want both days", got)
+	}
+}
+
+func TestOpenSearchWithTagMapOutput_Routing(t *testing.T) {
+	cfg, recorder := newFakeOpenSearch(t, nil)
+
+	outputs := OutputWithTagMap(TagMap{
+		"alpha": NewTagMapItem(level.Trace, func() string { return "idx-alpha" }),
+		"beta":  NewTagMapItem(level.Trace, func() string { return "idx-beta" }),
+		"*":     NewTagMapItem(level.Trace, func() string { return "idx-catchall" }),
+	}, cfg)
+
+	names := []string{}
+
+	for _, o := range outputs {
+		names = append(names, o.GetName())
+	}
+
+	slices.Sort(names)
+
+	if want := []string{
+		"OpenSearchWithTagMap-*",
+		"OpenSearchWithTagMap-alpha",
+		"OpenSearchWithTagMap-beta",
+	}; !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{name: "tagged", tags: []string{"alpha"}, want: []string{"idx-alpha"}},
+		{name: "multi-tagged", tags: []string{"alpha", "beta"}, want: []string{"idx-alpha", "idx-beta"}},
+		{name: "untagged", want: []string{"idx-catchall"}},
+		{name: "unmapped tag", tags: []string{"gamma"}, want: []string{"idx-catchall"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(recorder.all())
+
+			// Emulates the logger: every output gets its own copy.
+			for _, o := range outputs {
+				m := message.New(level.Info, "routed message")
+
+				m.AddTags(tt.tags...)
+
+				if err := o.Write(m); err != nil {
+					t.Fatalf("Write() to %q error = %v, want nil", o.GetName(), err)
+				}
+			}
+
+			if got := indexesOf(recorder.all()[before:]); !slices.Equal(got, tt.want) {
+				t.Errorf("Indexed into %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
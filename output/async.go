@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"sync"
 	"time"
 
@@ -21,6 +22,10 @@ import (
 // defaultAsyncBufferSize is the default async buffer capacity.
 const defaultAsyncBufferSize = 1024
 
+// defaultAsyncMaxBatchSize is the default max number of messages the worker
+// drains at once into an `IBatchOutput`.
+const defaultAsyncMaxBatchSize = 128
+
 var (
 	// ErrAsyncClosed is returned when writing to a closed async output.
 	ErrAsyncClosed = errors.New("async output is closed")
@@ -93,6 +98,17 @@ func AsyncWithErrorHandler(handler func(error)) AsyncOption {
 	}
 }
 
+// AsyncWithMaxBatchSize sets the max number of messages the worker drains
+// at once into the wrapped output, when it's an `IBatchOutput`. Default: 128.
+// Non-positive values fall back to the default.
+func AsyncWithMaxBatchSize(size int) AsyncOption {
+	return func(a *asyncOutput) {
+		if size > 0 {
+			a.maxBatchSize = size
+		}
+	}
+}
+
 // AsyncWithFlushInterval periodically flushes the WRAPPED output - useful
 // for time-buffered inner outputs (e.g. the ElasticSearch bulk output).
 // Zero (the default) disables it. Flush errors are delivered to the error
@@ -113,8 +129,13 @@ type asyncOutput struct {
 	capacity      int
 	errorHandler  func(error)
 	flushInterval time.Duration
+	maxBatchSize  int
 	policy        AsyncPolicy
 
+	// batchInner is the wrapped output, if it's an `IBatchOutput` - nil
+	// otherwise.
+	batchInner IBatchOutput
+
 	// mu guards the mutable state below. `cond` is signaled whenever the
 	// buffer, the in-flight marker, or the closed flag change.
 	mu   sync.Mutex
@@ -152,9 +173,13 @@ type asyncOutput struct {
 
 // Write enqueues the message. The message is expected to be this output's
 // own copy - Sypl isolates messages per output - so retaining it is safe.
-// Behavior on a full buffer is determined by the policy. After Close, it
-// returns `ErrAsyncClosed`.
+// A pooled message is copied first - see `message.Retain`. Behavior on a
+// full buffer is determined by the policy. After Close, it returns
+// `ErrAsyncClosed`.
 func (a *asyncOutput) Write(m message.IMessage) error {
+	// Copy-on-retain: the queue outlives the call, a pooled message doesn't.
+	m = message.Retain(m)
+
 	a.mu.Lock()
 
 	if a.closed {
@@ -217,6 +242,18 @@ func (a *asyncOutput) Write(m message.IMessage) error {
 	return nil
 }
 
+// WriteBatch enqueues the messages, in order - see `Write`. Errors are
+// aggregated via `errors.Join`.
+func (a *asyncOutput) WriteBatch(messages []message.IMessage) error {
+	errs := []error{}
+
+	for _, m := range messages {
+		errs = append(errs, a.Write(m))
+	}
+
+	return errors.Join(errs...)
+}
+
 // Flush guarantees - SNAPSHOT semantics - that every message enqueued
 // BEFORE the call was resolved (written to the wrapped output, or dropped
 // by policy), then flushes the wrapped output, if it implements
@@ -323,6 +360,25 @@ func (a *asyncOutput) dequeueLocked() (message.IMessage, uint64) {
 	return m, seq
 }
 
+// dequeueBatchLocked removes, and returns the `n` oldest buffered messages
+// with the sequence of the first one. The caller must hold `mu`, and
+// guarantee the buffer holds at least `n` messages.
+func (a *asyncOutput) dequeueBatchLocked(n int) ([]message.IMessage, uint64) {
+	batch := slices.Clone(a.queue[:n])
+
+	seq := a.headSeq
+
+	a.headSeq += uint64(n)
+
+	remaining := copy(a.queue, a.queue[n:])
+
+	clear(a.queue[remaining:])
+
+	a.queue = a.queue[:remaining]
+
+	return batch, seq
+}
+
 // minUnresolvedSeqLocked returns the lowest sequence not yet resolved -
 // neither written to the wrapped output, nor dropped by policy. When
 // everything resolved, it returns `enqueuedSeq + 1`. The caller must hold
@@ -352,6 +408,19 @@ func (a *asyncOutput) writeInner(m message.IMessage) (err error) {
 	return a.inner.Write(m)
 }
 
+// writeInnerBatch writes the messages at once to the wrapped output - an
+// `IBatchOutput` - converting a panic into an error, exactly like
+// `writeInner`.
+func (a *asyncOutput) writeInnerBatch(messages []message.IMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("async: panic in output %q: %v", a.GetName(), r)
+		}
+	}()
+
+	return a.batchInner.WriteBatch(messages)
+}
+
 // flushInner flushes the wrapped output, if it implements `Flush() error` -
 // converting a panic into an error, exactly like `writeInner`.
 func (a *asyncOutput) flushInner() (err error) {
@@ -393,8 +462,8 @@ func (a *asyncOutput) notifyDrop(total uint64) {
 }
 
 // worker sequentially drains the buffer to the wrapped output, preserving
-// FIFO order. It exits - after draining any remaining messages - when the
-// output is closed.
+// FIFO order - in batches, if it's an `IBatchOutput`. It exits - after
+// draining any remaining messages - when the output is closed.
 func (a *asyncOutput) worker() {
 	defer close(a.workerDone)
 
@@ -411,15 +480,33 @@ func (a *asyncOutput) worker() {
 			return
 		}
 
-		m, seq := a.dequeueLocked()
+		var (
+			batch []message.IMessage
+			m     message.IMessage
+			seq   uint64
+		)
+
+		// The whole batch is in flight - `inFlightSeq` is its first, the
+		// lowest unresolved sequence.
+		if a.batchInner != nil {
+			batch, seq = a.dequeueBatchLocked(min(len(a.queue), a.maxBatchSize))
+		} else {
+			m, seq = a.dequeueLocked()
+		}
 
 		a.inFlightSeq = seq
 
-		// The freed slot may unblock writers.
+		// The freed slots may unblock writers.
 		a.cond.Broadcast()
 		a.mu.Unlock()
 
-		err := a.writeInner(m)
+		var err error
+
+		if batch != nil {
+			err = a.writeInnerBatch(batch)
+		} else {
+			err = a.writeInner(m)
+		}
 
 		a.mu.Lock()
 		a.inFlightSeq = 0
@@ -467,6 +554,9 @@ func (a *asyncOutput) flusher() {
 // - `Close() error`: flushes, stops the worker, and closes `o` - if `o`
 // implements `io.Closer`. Idempotent. Writes after Close return
 // `ErrAsyncClosed`.
+// - `IBatchOutput`: `WriteBatch` enqueues the messages. When `o` is an
+// `IBatchOutput` too, the worker drains batches into it - see
+// `AsyncWithMaxBatchSize`.
 //
 // Hung sinks: direct Flush, and Close calls wait - UNBOUNDED - on the
 // wrapped output's in-flight write, so a sink that never returns blocks
@@ -480,11 +570,14 @@ func (a *asyncOutput) flusher() {
 // handling, and periodic flushing.
 func Async(o IOutput, opts ...AsyncOption) IOutput {
 	a := &asyncOutput{
-		capacity:   defaultAsyncBufferSize,
-		policy:     AsyncPolicyBlock,
-		workerDone: make(chan struct{}),
+		capacity:     defaultAsyncBufferSize,
+		maxBatchSize: defaultAsyncMaxBatchSize,
+		policy:       AsyncPolicyBlock,
+		workerDone:   make(chan struct{}),
 	}
 
+	a.batchInner, _ = o.(IBatchOutput)
+
 	a.Proxy = NewProxy(o, a)
 
 	a.cond = sync.NewCond(&a.mu)
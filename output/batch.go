@@ -0,0 +1,79 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"errors"
+	"io"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Batch writes.
+//
+// Outputs implementing `IBatchOutput` write several messages at once:
+//   - File outputs (`File`, `FileBased`, and `RotatingFile`) process each
+//     message as usual, then write all contents with a single write.
+//   - `Async` enqueues them - its worker drains batches into the wrapped
+//     output, when it's an `IBatchOutput`.
+//
+// Wrappers (see `Proxy`) don't forward the capability: writers expecting one
+// message per write - e.g. sockets, or HTTP sinks - are never handed
+// concatenated contents.
+//////
+
+// fileOutput is a file-backed `IOutput` carrying the `IBatchOutput`
+// capability.
+type fileOutput struct {
+	*Proxy
+
+	base *output
+}
+
+// WriteBatch processes each message like `Write` does, then writes the
+// contents with a single write.
+func (o *fileOutput) WriteBatch(messages []message.IMessage) error {
+	return o.base.writeBatch(messages)
+}
+
+// WriteBatch writes the messages to `o`, in order: at once, if `o` is an
+// `IBatchOutput`, otherwise one by one - all errors aggregated via
+// `errors.Join`.
+func WriteBatch(o IOutput, messages []message.IMessage) error {
+	if b, ok := o.(IBatchOutput); ok {
+		return b.WriteBatch(messages)
+	}
+
+	errs := []error{}
+
+	for _, m := range messages {
+		errs = append(errs, o.Write(m))
+	}
+
+	return errors.Join(errs...)
+}
+
+//////
+// Factory.
+//////
+
+// newFileOutput is the `fileOutput` factory - see `New`.
+func newFileOutput(
+	name string,
+	maxLevel level.Level,
+	w io.Writer,
+	processors ...processor.IProcessor,
+) *fileOutput {
+	base, _ := New(name, maxLevel, w, processors...).(*output)
+
+	o := &fileOutput{base: base}
+
+	o.Proxy = NewProxy(base, o)
+
+	return o
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+// countingWriter counts Write calls.
+type countingWriter struct {
+	mu     sync.Mutex
+	writes int
+	buf    strings.Builder
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writes++
+
+	return c.buf.Write(p)
+}
+
+func TestFileBased_WriteBatch(t *testing.T) {
+	w := &countingWriter{}
+
+	o, ok := FileBased("File", level.Info, w).(IBatchOutput)
+	if !ok {
+		t.Fatal("FileBased() should implement IBatchOutput")
+	}
+
+	// Messages are processed - and gated - one by one, then written at once.
+	if err := o.WriteBatch([]message.IMessage{
+		message.New(level.Info, "first\n"),
+		message.New(level.Debug, "muted\n"),
+		message.New(level.Info, "second\n"),
+	}); err != nil {
+		t.Fatalf("WriteBatch() error = %v, want nil", err)
+	}
+
+	if w.writes != 1 || w.buf.String() != "first\nsecond\n" {
+		t.Errorf("got %d writes: %q, want 1: %q", w.writes, w.buf.String(), "first\nsecond\n")
+	}
+
+	// All gated out: nothing to write.
+	if err := o.WriteBatch([]message.IMessage{message.New(level.Debug, "muted\n")}); err != nil {
+		t.Fatalf("WriteBatch() error = %v, want nil", err)
+	}
+
+	if w.writes != 1 {
+		t.Errorf("got %d writes, want 1", w.writes)
+	}
+}
+
+func TestFileBased_WriteBatchError(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	o := FileBased("File", level.Info, &failingWriter{err: errBoom})
+
+	err := WriteBatch(o, []message.IMessage{message.New(level.Info, "content")})
+
+	if !errors.Is(err, errBoom) {
+		t.Errorf("WriteBatch() error = %v, want %v", err, errBoom)
+	}
+}
+
+func TestWriteBatch_Fallback(t *testing.T) {
+	buf, o := newBufferedOutput(level.Info)
+
+	// Outputs not batch-aware are written one by one.
+	if _, ok := o.(IBatchOutput); ok {
+		t.Fatal("New() shouldn't implement IBatchOutput")
+	}
+
+	if err := WriteBatch(o, []message.IMessage{
+		message.New(level.Info, "first\n"),
+		message.New(level.Info, "second\n"),
+	}); err != nil {
+		t.Fatalf("WriteBatch() error = %v, want nil", err)
+	}
+
+	if got := buf.String(); got != "first\nsecond\n" {
+		t.Errorf("got %q, want %q", got, "first\nsecond\n")
+	}
+
+	errBoom := errors.New("boom")
+
+	err := WriteBatch(New("Failing", level.Info, &failingWriter{err: errBoom}), []message.IMessage{
+		message.New(level.Info, "first"),
+		message.New(level.Info, "second"),
+	})
+
+	if !errors.Is(err, errBoom) || strings.Count(err.Error(), "boom") != 2 {
+		t.Errorf("WriteBatch() error = %v, want both writes' errors", err)
+	}
+}
+
+func TestRotatingFile_WriteBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	o, ok := newRotatingFile(t, path, RotationConfig{MaxSizeBytes: 1024}).(IBatchOutput)
+	if !ok {
+		t.Fatal("RotatingFile() should implement IBatchOutput")
+	}
+
+	if err := o.WriteBatch([]message.IMessage{
+		message.New(level.Info, "first\n"),
+		message.New(level.Info, "second\n"),
+	}); err != nil {
+		t.Fatalf("WriteBatch() error = %v, want nil", err)
+	}
+
+	if err := o.(interface{ Close() error }).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "first\nsecond\n" {
+		t.Errorf("got %q, want %q", content, "first\nsecond\n")
+	}
+}
+
+func TestAsync_DrainsBatches(t *testing.T) {
+	gate := newGatedWriter()
+
+	a := Async(FileBased("File", level.Trace, gate), AsyncWithMaxBatchSize(10))
+
+	if _, ok := a.(IBatchOutput); !ok {
+		t.Fatal("Async() should implement IBatchOutput")
+	}
+
+	want := strings.Builder{}
+
+	// The worker blocks writing the first message...
+	writeString(t, a, asyncMsg0)
+
+	want.WriteString(asyncMsg0)
+
+	<-gate.started
+
+	// ...while the next 30 are buffered - then drained in 3 batches.
+	batch := []message.IMessage{}
+
+	for i := 1; i <= 30; i++ {
+		ct := fmt.Sprintf("m%d\n", i)
+
+		batch = append(batch, message.New(level.Info, ct))
+
+		want.WriteString(ct)
+	}
+
+	if err := a.(IBatchOutput).WriteBatch(batch); err != nil {
+		t.Fatalf("WriteBatch() error = %v, want nil", err)
+	}
+
+	for range 4 {
+		gate.release <- struct{}{}
+	}
+
+	if err := asyncClose(t, a); err != nil {
+		t.Fatal(err)
+	}
+
+	if writes := 1 + len(gate.started); writes != 4 {
+		t.Errorf("got %d writes, want 4", writes)
+	}
+
+	if got := gate.buf.String(); got != want.String() {
+		t.Errorf("got %q, want %q", got, want.String())
+	}
+}
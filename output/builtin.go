@@ -55,13 +55,15 @@ func StdErr(processors ...processor.IProcessor) IOutput {
 }
 
 // FileBased is a built-in `output`, that writes to the specified file.
+//
+// Capabilities: `IBatchOutput` - a batch is written with a single write.
 func FileBased(
 	name string,
 	maxLevel level.Level,
 	writer io.Writer,
 	processors ...processor.IProcessor,
 ) IOutput {
-	return New(name, maxLevel, writer, processors...)
+	return newFileOutput(name, maxLevel, writer, processors...)
 }
 
 // File is a built-in `output` - named `File`, that writes to the specified file.
@@ -70,6 +72,8 @@ func FileBased(
 // stdout.
 // NOTE: If no path is provided, it'll create one in the OS's temp directory.
 // NOTE: If the dir and/or file does not exist, it will be created.
+//
+// Capabilities: `IBatchOutput` - see `FileBased`.
 func File(name string, path string, maxLevel level.Level, processors ...processor.IProcessor) IOutput {
 	// Should create a file in the OS temp. File name should be unique (UUIDv4).
 	if path == "" {
@@ -15,6 +15,16 @@
 //   - ElasticSearchBulk (and ...WithDynamicIndex): batches documents into
 //     _bulk requests via esutil's BulkIndexer - the high-throughput sibling
 //     of ElasticSearch.
+//   - HTTP: batches formatter-rendered messages into NDJSON, or JSON array
+//     requests to any endpoint (webhooks, Splunk HEC, Datadog, internal
+//     collectors) - headers, auth, gzip, a body template, and retries.
+//   - Journald: the systemd journal's native protocol over its unix
+//     datagram socket - PRIORITY from the level, SYSLOG_IDENTIFIER from the
+//     component, message fields as journal fields, and a memfd fallback for
+//     large entries (Linux).
+//   - Net: streams framed messages (newline, or length-prefixed) to a TCP,
+//     UDP, or unix socket peer - e.g. Fluent Bit, Vector, Logstash - with
+//     TLS, write deadlines, and buffered reconnection with backoff.
 //   - RotatingFile: a file output with native size-based rotation, backup
 //     timestamping, and count/age pruning.
 //   - Recorder: captures structured snapshots of everything written - a
@@ -23,5 +33,7 @@
 // Outputs that buffer implement `Flush() error`; outputs owning resources
 // implement `Close() error` (io.Closer). Close is idempotent, and writes
 // after Close return a typed, per-output sentinel error - never a panic.
-// Flush after Close is a no-op.
+// Flush after Close is a no-op. Outputs able to write several messages at
+// once - File, FileBased, RotatingFile, Async, and the ElasticSearch bulk
+// one - implement `IBatchOutput`: Async drains batches into them.
 package output
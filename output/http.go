@@ -0,0 +1,486 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/batcher"
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// HTTP output defaults.
+const (
+	defaultHTTPFlushBytes    = 1 << 20
+	defaultHTTPFlushInterval = 5 * time.Second
+	defaultHTTPFlushItems    = 500
+	defaultHTTPMaxBuffered   = 10000
+	defaultHTTPMaxBackoff    = 5 * time.Second
+	defaultHTTPMaxRetries    = 3
+	defaultHTTPMinBackoff    = 100 * time.Millisecond
+	defaultHTTPTimeout       = 10 * time.Second
+)
+
+// ErrHTTPClosed is returned when writing to a closed HTTP output.
+var ErrHTTPClosed = errors.New("http output is closed")
+
+// HTTPEncoding determines how a batch is encoded into the request body.
+type HTTPEncoding int
+
+// Available encodings.
+const (
+	// HTTPEncodingNDJSON is newline-delimited: one item per line. It's the
+	// default.
+	HTTPEncodingNDJSON HTTPEncoding = iota
+
+	// HTTPEncodingJSONArray is a JSON array of items. Non-JSON items - e.g.
+	// rendered by the Text formatter - are encoded as JSON strings.
+	HTTPEncodingJSONArray
+)
+
+// String interface implementation.
+func (e HTTPEncoding) String() string {
+	switch e {
+	case HTTPEncodingNDJSON:
+		return "NDJSON"
+	case HTTPEncodingJSONArray:
+		return "JSONArray"
+	default:
+		return "Unknown"
+	}
+}
+
+// HTTPTemplateData is the data the `HTTPConfig.BodyTemplate` is executed
+// with.
+type HTTPTemplateData struct {
+	// Body is the batch encoded per `HTTPConfig.Encoding`.
+	Body string
+
+	// Items are the formatter-rendered messages - single line.
+	Items []string
+}
+
+// HTTPConfig configures the HTTP output. Zero values mean defaults.
+type HTTPConfig struct {
+	// URL is the endpoint. Required.
+	URL string
+
+	// Method is the request method. Defaults to POST.
+	Method string
+
+	// Headers are extra request headers - e.g. an API key.
+	Headers map[string]string
+
+	// Username, and Password set basic authentication, when any is set.
+	Username string
+	Password string
+
+	// BearerToken sets the `Authorization: Bearer` header, when set.
+	BearerToken string
+
+	// Encoding of the batch. Defaults to `HTTPEncodingNDJSON`.
+	Encoding HTTPEncoding
+
+	// BodyTemplate - a `text/template` - renders the request body from
+	// `HTTPTemplateData`, e.g. Splunk HEC's event envelope:
+	//
+	//	{{range .Items}}{"event":{{.}}}{{end}}
+	//
+	// The "json" function JSON-encodes a value - e.g. `{{json .}}` wraps a
+	// non-JSON item as a string. Defaults to the encoded batch as-is.
+	BodyTemplate string
+
+	// ContentType overrides the Content-Type header. Defaults to
+	// "application/x-ndjson" (NDJSON), or "application/json".
+	ContentType string
+
+	// Gzip compresses the request body - `Content-Encoding: gzip`.
+	Gzip bool
+
+	// FlushItems is the maximum number of messages per request. Defaults to
+	// 500.
+	FlushItems int
+
+	// FlushBytes is the flush threshold in bytes. Defaults to 1MiB.
+	FlushBytes int
+
+	// FlushInterval is the periodic flush interval. Defaults to 5s.
+	FlushInterval time.Duration
+
+	// MaxBuffered is the maximum number of pending messages - e.g. while the
+	// endpoint is down. Beyond it, messages are dropped: the write fails
+	// with `batcher.ErrBufferFull` - reported to the logger's error handler.
+	// Defaults to 10000.
+	MaxBuffered int
+
+	// MaxRetries of requests failing with a transport error, a 429, or a
+	// 5xx status. Defaults to 3. Negative disables retries.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry - doubled after each
+	// one, up to `MaxBackoff`. Defaults to 100ms, and 5s. Batches are kept
+	// in order: a batch awaiting its retry is sent before newer messages.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Client is the HTTP client. Defaults to one with a 10s timeout.
+	Client *http.Client
+
+	// OnError receives background - periodic, or size-triggered - flush
+	// failures. A batch still failing after the retries is dropped. The
+	// callback may be called concurrently with `Flush`.
+	OnError func(error)
+}
+
+// httpBatcher is a concurrency-safe, batching HTTP writer - see the
+// `batcher` package.
+type httpBatcher struct {
+	*batcher.Batcher[[]byte]
+
+	cfg HTTPConfig
+
+	// tmpl is the parsed `BodyTemplate`, if any.
+	tmpl *template.Template
+}
+
+//////
+// httpBatcher methods.
+//////
+
+// Write enqueues the rendered message. A full batch is sent in the
+// background. After Close, it returns `ErrHTTPClosed`.
+//
+// io.Writer interface implementation.
+func (b *httpBatcher) Write(p []byte) (int, error) {
+	if err := b.Add(httpItem(p)); err != nil {
+		if errors.Is(err, batcher.ErrClosed) {
+			return 0, ErrHTTPClosed
+		}
+
+		return 0, fmt.Errorf("http output: message dropped: %w", err)
+	}
+
+	return len(p), nil
+}
+
+// send sends a batch - once. See `batcher.Config.Send`.
+func (b *httpBatcher) send(batch [][]byte) error {
+	body, err := b.encode(batch)
+	if err != nil {
+		return fmt.Errorf("http output: failed encoding the batch: %w", err)
+	}
+
+	retryable, err := b.sendOnce(body)
+	if err != nil {
+		err = fmt.Errorf("http output: failed sending the batch: %w", err)
+
+		if retryable {
+			return batcher.Retryable(err)
+		}
+	}
+
+	return err
+}
+
+// encode renders the request body - encoding, template, and compression.
+func (b *httpBatcher) encode(batch [][]byte) ([]byte, error) {
+	var body []byte
+
+	switch b.cfg.Encoding {
+	case HTTPEncodingJSONArray:
+		items := make([][]byte, 0, len(batch))
+
+		for _, item := range batch {
+			if !json.Valid(item) {
+				//nolint:errchkjson // Marshaling a string never fails.
+				item, _ = json.Marshal(string(item))
+			}
+
+			items = append(items, item)
+		}
+
+		body = append(append([]byte{'['}, bytes.Join(items, []byte{','})...), ']')
+	case HTTPEncodingNDJSON:
+		fallthrough
+	default:
+		body = append(bytes.Join(batch, []byte{'\n'}), '\n')
+	}
+
+	if b.tmpl != nil {
+		data := HTTPTemplateData{Body: string(body), Items: make([]string, 0, len(batch))}
+
+		for _, item := range batch {
+			data.Items = append(data.Items, string(item))
+		}
+
+		var rendered bytes.Buffer
+
+		if err := b.tmpl.Execute(&rendered, data); err != nil {
+			return nil, fmt.Errorf("failed executing the body template: %w", err)
+		}
+
+		body = rendered.Bytes()
+	}
+
+	if b.cfg.Gzip {
+		var compressed bytes.Buffer
+
+		zw := gzip.NewWriter(&compressed)
+
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+
+		body = compressed.Bytes()
+	}
+
+	return body, nil
+}
+
+// sendOnce sends the body once, reporting whether a failure is retryable.
+func (b *httpBatcher) sendOnce(body []byte) (bool, error) {
+	req, err := http.NewRequest(b.cfg.Method, b.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	for k, v := range b.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	req.Header.Set("Content-Type", b.cfg.ContentType)
+
+	if b.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	if b.cfg.Username != "" || b.cfg.Password != "" {
+		req.SetBasicAuth(b.cfg.Username, b.cfg.Password)
+	}
+
+	if b.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+b.cfg.BearerToken)
+	}
+
+	resp, err := b.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		// Drained, so the connection is reused.
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	err = fmt.Errorf("status: %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError, err
+}
+
+//////
+// Output wrapper.
+//////
+
+// httpOutput is an HTTP-backed `IOutput` carrying the Flush, and Close
+// capabilities.
+type httpOutput struct {
+	*Proxy
+
+	writer *httpBatcher
+}
+
+// Flush sends the pending batch. After Close it's a no-op.
+func (o *httpOutput) Flush() error {
+	return o.writer.Flush()
+}
+
+// Close sends the pending batch, and stops the periodic flush. It's
+// idempotent. Writes after Close return `ErrHTTPClosed`.
+func (o *httpOutput) Close() error {
+	return o.writer.Close()
+}
+
+//////
+// Helpers.
+//////
+
+// httpItem returns the rendered message as a batch item: a single line.
+func httpItem(p []byte) []byte {
+	// CLONED: the batch outlives the write, but the `io.Writer` contract
+	// forbids retaining `p` - the builtin logger reuses its buffer.
+	item := bytes.Clone(bytes.TrimRight(p, "\r\n"))
+
+	// Each item is a single line - interior linebreaks would break NDJSON
+	// framing, and are meaningless in the other encodings.
+	if bytes.ContainsAny(item, "\r\n") {
+		var compacted bytes.Buffer
+
+		if json.Valid(item) && json.Compact(&compacted, item) == nil {
+			item = compacted.Bytes()
+		} else {
+			// Not JSON: encoded as a JSON string - escaping the
+			// linebreaks.
+			//nolint:errchkjson // Marshaling a string never fails.
+			item, _ = json.Marshal(string(item))
+		}
+	}
+
+	return item
+}
+
+// applyHTTPDefaults validates `cfg`, and fills its zero values.
+func applyHTTPDefaults(cfg *HTTPConfig) error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return fmt.Errorf("http output: invalid URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("http output: invalid URL %q: scheme must be http, or https", cfg.URL)
+	}
+
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/json"
+
+		if cfg.Encoding == HTTPEncodingNDJSON && cfg.BodyTemplate == "" {
+			cfg.ContentType = "application/x-ndjson"
+		}
+	}
+
+	if cfg.FlushItems <= 0 {
+		cfg.FlushItems = defaultHTTPFlushItems
+	}
+
+	if cfg.FlushBytes <= 0 {
+		cfg.FlushBytes = defaultHTTPFlushBytes
+	}
+
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultHTTPFlushInterval
+	}
+
+	if cfg.MaxBuffered <= 0 {
+		cfg.MaxBuffered = defaultHTTPMaxBuffered
+	}
+
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = defaultHTTPMaxRetries
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultHTTPMinBackoff
+	}
+
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultHTTPMaxBackoff
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return nil
+}
+
+//////
+// Factory.
+//////
+
+// HTTP is a built-in `output` that POSTs - by default - batches of
+// formatter-rendered messages to an HTTP endpoint: a generic sink for
+// webhooks, and collectors without a dedicated integration (e.g. Splunk
+// HEC, Datadog). See `HTTPConfig` for encoding, headers, authentication,
+// gzip, batching, retries, and the body template.
+//
+// Batching - see the `batcher` package - mirrors the ElasticSearch bulk
+// output: a batch is sent when it reaches `FlushItems`, or `FlushBytes`,
+// every `FlushInterval`, and on Flush/Close. Requests are serialized, so
+// batches arrive in order. At most `MaxBuffered` messages are pending.
+//
+// Capabilities: `Flush() error` (sends the pending batch), and idempotent
+// `Close() error`. Sending is asynchronous: background failures are
+// delivered through `HTTPConfig.OnError`.
+//
+// NOTE: By default, data is JSON-formatted - inline.
+// NOTE: Like `RotatingFile`, it returns an error - it never calls
+// log.Fatalf.
+func HTTP(
+	name string,
+	maxLevel level.Level,
+	cfg HTTPConfig,
+	processors ...processor.IProcessor,
+) (IOutput, error) {
+	if err := applyHTTPDefaults(&cfg); err != nil {
+		return nil, err
+	}
+
+	b := &httpBatcher{cfg: cfg}
+
+	if cfg.BodyTemplate != "" {
+		tmpl, err := template.New(name).Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				encoded, err := json.Marshal(v)
+
+				return string(encoded), err
+			},
+		}).Parse(cfg.BodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("http output: invalid body template: %w", err)
+		}
+
+		b.tmpl = tmpl
+	}
+
+	b.Batcher = batcher.New(batcher.Config[[]byte]{
+		Send:          b.send,
+		Size:          func(item []byte) int { return len(item) },
+		FlushItems:    cfg.FlushItems,
+		FlushBytes:    cfg.FlushBytes,
+		FlushInterval: cfg.FlushInterval,
+		MaxBuffered:   cfg.MaxBuffered,
+		MaxRetries:    cfg.MaxRetries,
+		MinBackoff:    cfg.MinBackoff,
+		MaxBackoff:    cfg.MaxBackoff,
+		OnError:       cfg.OnError,
+	})
+
+	o := &httpOutput{writer: b}
+
+	o.Proxy = NewProxy(New(name, maxLevel, b, processors...).SetFormatter(formatter.JSON()), o)
+
+	return o, nil
+}
@@ -0,0 +1,372 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/batcher"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+// fakeCollector records requests, answering with the queued statuses - then
+// 200.
+type fakeCollector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+}
+
+func newFakeCollector(t *testing.T, statuses ...int) *fakeCollector {
+	t.Helper()
+
+	f := &fakeCollector{statuses: statuses}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("invalid gzip body: %v", err)
+			}
+
+			body = zr
+		}
+
+		content, _ := io.ReadAll(body)
+
+		f.mu.Lock()
+
+		f.requests = append(f.requests, r)
+		f.bodies = append(f.bodies, content)
+
+		status := http.StatusOK
+
+		if len(f.statuses) > 0 {
+			status, f.statuses = f.statuses[0], f.statuses[1:]
+		}
+
+		f.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeCollector) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.bodies)
+}
+
+func (f *fakeCollector) last() (*http.Request, []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests[len(f.requests)-1], f.bodies[len(f.bodies)-1]
+}
+
+// flusher is the HTTP output's Flush capability.
+type flusher interface {
+	Flush() error
+}
+
+func writeAndFlush(t *testing.T, o IOutput, contents ...string) {
+	t.Helper()
+
+	for _, c := range contents {
+		if err := o.Write(message.New(level.Info, c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := o.(flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTP_NDJSON(t *testing.T) {
+	srv := newFakeCollector(t)
+
+	o, err := HTTP("webhook", level.Trace, HTTPConfig{
+		URL:           srv.URL,
+		Headers:       map[string]string{"X-Api-Key": "k"},
+		BearerToken:   "t",
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer o.(io.Closer).Close()
+
+	writeAndFlush(t, o, "first", "second")
+
+	r, body := srv.last()
+
+	if r.Method != http.MethodPost ||
+		r.Header.Get("Content-Type") != "application/x-ndjson" ||
+		r.Header.Get("X-Api-Key") != "k" ||
+		r.Header.Get("Authorization") != "Bearer t" {
+		t.Errorf("request = %s %v", r.Method, r.Header)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("body = %q", body)
+	}
+
+	for i, want := range []string{"first", "second"} {
+		var doc map[string]any
+
+		if err := json.Unmarshal([]byte(lines[i]), &doc); err != nil {
+			t.Fatalf("line %d isn't JSON: %v: %s", i, err, lines[i])
+		}
+
+		if doc["message"] != want {
+			t.Errorf("line %d = %s, want message %q", i, lines[i], want)
+		}
+	}
+}
+
+func TestHTTP_JSONArrayGzipBasicAuth(t *testing.T) {
+	srv := newFakeCollector(t)
+
+	o, err := HTTP("webhook", level.Trace, HTTPConfig{
+		URL:           srv.URL,
+		Encoding:      HTTPEncodingJSONArray,
+		Gzip:          true,
+		Username:      "user",
+		Password:      "pass",
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer o.(io.Closer).Close()
+
+	writeAndFlush(t, o, "a", "b", "c")
+
+	r, body := srv.last()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+	}
+
+	var docs []map[string]any
+
+	if err := json.Unmarshal(body, &docs); err != nil {
+		t.Fatalf("body isn't a JSON array: %v: %s", err, body)
+	}
+
+	if len(docs) != 3 || docs[2]["message"] != "c" {
+		t.Errorf("docs = %v", docs)
+	}
+}
+
+func TestHTTP_BodyTemplate(t *testing.T) {
+	srv := newFakeCollector(t)
+
+	o, err := HTTP("hec", level.Trace, HTTPConfig{
+		URL:           srv.URL,
+		BodyTemplate:  `{{range .Items}}{"event":{{.}}}{{end}}`,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer o.(io.Closer).Close()
+
+	writeAndFlush(t, o, "x", "y")
+
+	r, body := srv.last()
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+
+	for _, want := range []string{"x", "y"} {
+		var envelope struct {
+			Event map[string]any `json:"event"`
+		}
+
+		if err := dec.Decode(&envelope); err != nil {
+			t.Fatalf("invalid envelope: %v: %s", err, body)
+		}
+
+		if envelope.Event["message"] != want {
+			t.Errorf("envelope = %v, want message %q", envelope, want)
+		}
+	}
+
+	if _, err := HTTP("hec", level.Trace, HTTPConfig{URL: srv.URL, BodyTemplate: "{{"}); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestHTTP_MultilineItems(t *testing.T) {
+	if got := string(httpItem([]byte("{\n  \"a\": 1\n}\n"))); got != `{"a":1}` {
+		t.Errorf("JSON item = %s", got)
+	}
+
+	if got := string(httpItem([]byte("line 1\nline 2\n"))); got != `"line 1\nline 2"` {
+		t.Errorf("text item = %s", got)
+	}
+}
+
+func TestHTTP_Retry(t *testing.T) {
+	srv := newFakeCollector(t, http.StatusTooManyRequests, http.StatusBadGateway)
+
+	o, err := HTTP("webhook", level.Trace, HTTPConfig{
+		URL:           srv.URL,
+		MinBackoff:    time.Millisecond,
+		MaxBackoff:    2 * time.Millisecond,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer o.(io.Closer).Close()
+
+	writeAndFlush(t, o, "x")
+
+	if n := srv.count(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+
+	// Client errors aren't retried.
+	srv.mu.Lock()
+	srv.statuses = []int{http.StatusBadRequest}
+	srv.mu.Unlock()
+
+	_ = o.Write(message.New(level.Info, "y"))
+
+	if err := o.(flusher).Flush(); err == nil || !strings.Contains(err.Error(), "status: 400") {
+		t.Errorf("Flush = %v, want the 400", err)
+	}
+
+	if n := srv.count(); n != 4 {
+		t.Errorf("attempts = %d, want 4", n)
+	}
+}
+
+func TestHTTP_MaxBuffered(t *testing.T) {
+	srv := newFakeCollector(t)
+
+	o, err := HTTP("webhook", level.Trace, HTTPConfig{
+		URL:           srv.URL,
+		FlushInterval: time.Hour,
+		MaxBuffered:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer o.(io.Closer).Close()
+
+	_ = o.Write(message.New(level.Info, "a"))
+	_ = o.Write(message.New(level.Info, "b"))
+
+	if err := o.Write(message.New(level.Info, "c")); !errors.Is(err, batcher.ErrBufferFull) {
+		t.Errorf("Write = %v, want batcher.ErrBufferFull", err)
+	}
+}
+
+func TestHTTP_FlushItemsTriggersSend(t *testing.T) {
+	srv := newFakeCollector(t)
+
+	o, err := HTTP("webhook", level.Trace, HTTPConfig{
+		URL:           srv.URL,
+		FlushItems:    2,
+		FlushInterval: time.Hour,
+		OnError:       func(err error) { t.Errorf("OnError: %v", err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer o.(io.Closer).Close()
+
+	_ = o.Write(message.New(level.Info, "a"))
+	_ = o.Write(message.New(level.Info, "b"))
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for srv.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if srv.count() != 1 {
+		t.Errorf("requests = %d, want 1", srv.count())
+	}
+}
+
+func TestHTTP_Close(t *testing.T) {
+	srv := newFakeCollector(t)
+
+	o, err := HTTP("webhook", level.Trace, HTTPConfig{URL: srv.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = o.Write(message.New(level.Info, "pending"))
+
+	c := o.(io.Closer)
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if srv.count() != 1 {
+		t.Errorf("requests = %d, want 1 - Close drains", srv.count())
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	if err := o.(flusher).Flush(); err != nil {
+		t.Errorf("Flush after Close = %v, want a no-op", err)
+	}
+
+	if _, err := o.GetWriter().Write([]byte("late")); !errors.Is(err, ErrHTTPClosed) {
+		t.Errorf("Write after Close = %v, want ErrHTTPClosed", err)
+	}
+}
+
+func TestHTTP_InvalidURL(t *testing.T) {
+	if _, err := HTTP("webhook", level.Trace, HTTPConfig{URL: "collector:8080"}); err == nil {
+		t.Error("expected an error for a scheme-less URL")
+	}
+}
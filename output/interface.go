@@ -58,3 +58,14 @@ type IOutput interface {
 	// Write write the message to the defined output.
 	Write(m message.IMessage) error
 }
+
+// IBatchOutput is an output capable of writing several messages at once -
+// e.g. with a single write, or request. It's optional: detect it with a type
+// assertion, or use `WriteBatch`.
+type IBatchOutput interface {
+	IOutput
+
+	// WriteBatch writes the messages, in order. Each one is processed, and
+	// written - or not - as `Write` would.
+	WriteBatch(messages []message.IMessage) error
+}
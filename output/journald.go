@@ -0,0 +1,345 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// DefaultJournaldSocket is the journal's native protocol socket.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// Journal fields set by the Journald output. User fields can't override
+// them.
+const (
+	JournaldFieldMessage          = "MESSAGE"
+	JournaldFieldPriority         = "PRIORITY"
+	JournaldFieldSyslogIdentifier = "SYSLOG_IDENTIFIER"
+
+	// JournaldFieldTag is repeated once per message tag - query with
+	// `journalctl SYPL_TAG=<tag>`.
+	JournaldFieldTag = "SYPL_TAG"
+)
+
+// journaldMaxFieldName is the journal's field name length limit.
+const journaldMaxFieldName = 64
+
+// ErrJournaldClosed is returned when writing to a closed Journald output.
+var ErrJournaldClosed = errors.New("journald output is closed")
+
+// JournaldConfig configures the Journald output. Zero values mean defaults.
+type JournaldConfig struct {
+	// Socket is the journal's socket. Defaults to `DefaultJournaldSocket`.
+	Socket string
+
+	// Identifier is the SYSLOG_IDENTIFIER of messages without a component
+	// name. Defaults to the executable's name.
+	Identifier string
+}
+
+// journaldWriter sends entries to the journal over a unix datagram socket.
+type journaldWriter struct {
+	cfg JournaldConfig
+
+	// mu guards the connection, and the closed flag.
+	mu     sync.Mutex
+	closed bool
+	conn   *net.UnixConn
+}
+
+// send sends the `entry` datagram. Entries too large for a datagram are
+// handed over as a sealed memory file, where supported - see
+// `journaldSendFallback`.
+func (w *journaldWriter) send(entry []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrJournaldClosed
+	}
+
+	if _, err := w.conn.Write(entry); err != nil {
+		return journaldSendFallback(w.conn, entry, err)
+	}
+
+	return nil
+}
+
+// close closes the connection. It's idempotent.
+func (w *journaldWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	return w.conn.Close()
+}
+
+// journaldOutput is a journald-backed `IOutput` carrying the Close
+// capability.
+//
+// Journal fields come from the MESSAGE - after processors ran - while
+// MESSAGE is the formatted content. The message being written is handed to
+// the writer through `current`, like the Loki output does.
+type journaldOutput struct {
+	*Proxy
+
+	writer *journaldWriter
+
+	// mu serializes writes, guarding `current`.
+	mu sync.Mutex
+
+	// current is the message being written.
+	current message.IMessage
+}
+
+// Write writes the message through the output's pipeline.
+func (o *journaldOutput) Write(m message.IMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.current = m
+
+	defer func() { o.current = nil }()
+
+	return o.Proxy.Write(m)
+}
+
+// Close closes the journal socket. It's idempotent. Writes after Close
+// return `ErrJournaldClosed`.
+func (o *journaldOutput) Close() error {
+	return o.writer.close()
+}
+
+// journaldEntryWriter is the output's writer: it turns the formatted
+// content, and the current message into a journal entry.
+type journaldEntryWriter struct {
+	o *journaldOutput
+}
+
+// Write conforms to the `io.Writer` interface.
+func (w *journaldEntryWriter) Write(p []byte) (int, error) {
+	// Called outside of `Write` - e.g. directly, via `GetWriter` - the
+	// content is logged at Info.
+	m := w.o.current
+	if m == nil {
+		m = message.New(level.Info, "")
+	}
+
+	// Trailing linebreaks - restored by Sypl's pipeline after formatting -
+	// aren't part of the message.
+	entry := journaldEntry(m, bytes.TrimRight(p, "\r\n"), w.o.writer.cfg.Identifier)
+
+	if err := w.o.writer.send(entry); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+//////
+// Helpers.
+//////
+
+// JournaldPriority maps a `level.Level` to a syslog priority: Fatal is
+// "crit" (2), Error "err" (3), Warn "warning" (4), Info "info" (6), Debug,
+// and Trace "debug" (7). Custom levels map like their `Base`.
+func JournaldPriority(l level.Level) int {
+	switch l.Base() {
+	case level.Fatal:
+		return 2
+	case level.Error:
+		return 3
+	case level.Warn:
+		return 4
+	case level.Debug, level.Trace:
+		return 7
+	default:
+		return 6
+	}
+}
+
+// JournaldFieldName sanitizes `key` into a journal field name: uppercase
+// A-Z, 0-9, and `_` - other characters become `_` - not starting with `_`
+// (reserved for trusted fields), nor a digit (prefixed with `X`), and up to
+// 64 characters. E.g. "user.id" becomes "USER_ID". Returns "" when nothing
+// is left.
+func JournaldFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+
+	sanitized := strings.TrimLeft(string(name), "_")
+
+	if sanitized != "" && sanitized[0] >= '0' && sanitized[0] <= '9' {
+		sanitized = "X" + sanitized
+	}
+
+	if len(sanitized) > journaldMaxFieldName {
+		sanitized = sanitized[:journaldMaxFieldName]
+	}
+
+	return sanitized
+}
+
+// journaldValue renders a field value: strings, and byte slices as-is,
+// errors, and stringers through their methods, anything else as JSON.
+func journaldValue(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	case error:
+		return []byte(v.Error())
+	case fmt.Stringer:
+		return []byte(v.String())
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return []byte(fmt.Sprint(v))
+	}
+
+	return encoded
+}
+
+// appendJournaldField appends a field in the native protocol's encoding:
+// `NAME=value\n` - or, for values with linebreaks, `NAME\n`, the value's
+// little-endian 64-bit length, the value, and `\n`.
+func appendJournaldField(buf []byte, name string, value []byte) []byte {
+	buf = append(buf, name...)
+
+	if bytes.IndexByte(value, '\n') < 0 {
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+
+		return append(buf, '\n')
+	}
+
+	buf = append(buf, '\n')
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(value)))
+	buf = append(buf, value...)
+
+	return append(buf, '\n')
+}
+
+// journaldEntry builds the journal entry of `m`, whose formatted content is
+// `content`.
+func journaldEntry(m message.IMessage, content []byte, identifier string) []byte {
+	buf := appendJournaldField(nil, JournaldFieldMessage, content)
+
+	buf = appendJournaldField(buf, JournaldFieldPriority, []byte(strconv.Itoa(JournaldPriority(m.GetLevel()))))
+
+	if name := m.GetComponentName(); name != "" {
+		identifier = name
+	}
+
+	buf = appendJournaldField(buf, JournaldFieldSyslogIdentifier, []byte(identifier))
+
+	for _, tag := range m.GetTags() {
+		buf = appendJournaldField(buf, JournaldFieldTag, []byte(tag))
+	}
+
+	// Sorted, so entries are deterministic.
+	keys := make([]string, 0, len(m.GetFields()))
+
+	for key := range m.GetFields() {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		name := JournaldFieldName(key)
+
+		switch name {
+		case "", JournaldFieldMessage, JournaldFieldPriority, JournaldFieldSyslogIdentifier, JournaldFieldTag:
+			continue
+		}
+
+		value := m.GetFields()[key]
+		if value == nil {
+			continue
+		}
+
+		buf = appendJournaldField(buf, name, journaldValue(value))
+	}
+
+	return buf
+}
+
+//////
+// Factory.
+//////
+
+// Journald is a built-in `output` - named `Journald` - that sends messages
+// to the systemd journal over its native protocol, with structured fields
+// queryable via `journalctl FIELD=value`:
+// - MESSAGE is the formatted content - plain text by default.
+// - PRIORITY is mapped from the level - see `JournaldPriority`.
+// - SYSLOG_IDENTIFIER is the component name - or `JournaldConfig.Identifier`.
+// - Each tag is a `SYPL_TAG` field.
+// - Message fields are journal fields, named per `JournaldFieldName` - e.g.
+// "user.id" is USER_ID.
+//
+// Entries too large for a datagram are sent as a sealed memfd (Linux).
+//
+// Capabilities: idempotent `Close() error`. Writes after Close return
+// `ErrJournaldClosed`.
+//
+// NOTE: Like `HTTP`, it returns an error - e.g. on hosts without journald -
+// it never calls log.Fatalf.
+func Journald(
+	maxLevel level.Level,
+	cfg JournaldConfig,
+	processors ...processor.IProcessor,
+) (IOutput, error) {
+	if cfg.Socket == "" {
+		cfg.Socket = DefaultJournaldSocket
+	}
+
+	if cfg.Identifier == "" {
+		cfg.Identifier = filepath.Base(os.Args[0])
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: cfg.Socket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("journald output: failed connecting to %q: %w", cfg.Socket, err)
+	}
+
+	o := &journaldOutput{writer: &journaldWriter{cfg: cfg, conn: conn}}
+
+	o.Proxy = NewProxy(New("Journald", maxLevel, &journaldEntryWriter{o: o}, processors...), o)
+
+	return o, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// journaldSendFallback hands an `entry` too large for a datagram - `err` is
+// EMSGSIZE, or ENOBUFS - over as a sealed memfd, passed via SCM_RIGHTS:
+// journald reads the entry from it. Other errors are returned as-is.
+func journaldSendFallback(conn *net.UnixConn, entry []byte, err error) error {
+	if !errors.Is(err, unix.EMSGSIZE) && !errors.Is(err, unix.ENOBUFS) {
+		return err
+	}
+
+	fd, err := unix.MemfdCreate("sypl-journald", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return fmt.Errorf("journald output: failed creating the memfd: %w", err)
+	}
+	defer unix.Close(fd)
+
+	for written := 0; written < len(entry); {
+		n, err := unix.Write(fd, entry[written:])
+		if err != nil {
+			return fmt.Errorf("journald output: failed writing the memfd: %w", err)
+		}
+
+		written += n
+	}
+
+	// journald only accepts sealed memfds - the entry can't change under it.
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, seals); err != nil {
+		return fmt.Errorf("journald output: failed sealing the memfd: %w", err)
+	}
+
+	// NOTE: `WriteMsgUnix` refuses connected datagram sockets - sendmsg(2)
+	// on the raw socket doesn't.
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("journald output: failed sending the memfd: %w", err)
+	}
+
+	var sendErr error
+
+	if err := raw.Write(func(sock uintptr) bool {
+		sendErr = unix.Sendmsg(int(sock), nil, unix.UnixRights(fd), nil, 0)
+
+		return !errors.Is(sendErr, unix.EAGAIN)
+	}); err != nil {
+		return fmt.Errorf("journald output: failed sending the memfd: %w", err)
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("journald output: failed sending the memfd: %w", sendErr)
+	}
+
+	return nil
+}
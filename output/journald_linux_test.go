@@ -0,0 +1,84 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"golang.org/x/sys/unix"
+)
+
+func TestJournald_LargeEntryViaMemfd(t *testing.T) {
+	socket, conn := newFakeJournal(t)
+
+	o, err := Journald(level.Info, JournaldConfig{Socket: socket})
+	if err != nil {
+		t.Fatalf("Journald() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	// Beyond the socket's send buffer - EMSGSIZE.
+	content := strings.Repeat("x", 4<<20)
+
+	if err := o.Write(message.New(level.Info, content)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	oob := make([]byte, unix.CmsgSpace(4))
+
+	n, oobn, _, _, err := conn.ReadMsgUnix(make([]byte, 1), oob)
+	if err != nil {
+		t.Fatalf("ReadMsgUnix() error = %v", err)
+	}
+
+	if n != 0 {
+		t.Errorf("Expected an empty datagram, got %d bytes", n)
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("control messages = %v, %v", msgs, err)
+	}
+
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("fds = %v, %v", fds, err)
+	}
+
+	f := os.NewFile(uintptr(fds[0]), "memfd")
+
+	defer f.Close()
+
+	seals, err := unix.FcntlInt(f.Fd(), unix.F_GET_SEALS, 0)
+	if err != nil || seals&unix.F_SEAL_WRITE == 0 {
+		t.Errorf("memfd seals = %b, %v, want sealed", seals, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The descriptor shares the sender's offset - read from the start, like
+	// journald's mmap does.
+	entry, err := io.ReadAll(io.NewSectionReader(f, 0, info.Size()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := parseJournalEntry(t, entry)[JournaldFieldMessage]; len(got) != 1 || got[0] != content {
+		t.Errorf("MESSAGE length = %d, want %d", len(strings.Join(got, "")), len(content))
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package output
+
+import "net"
+
+// journaldSendFallback returns `err`: the memfd fallback is Linux-only -
+// as is journald.
+func journaldSendFallback(_ *net.UnixConn, _ []byte, err error) error {
+	return err
+}
@@ -0,0 +1,231 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+// newFakeJournal listens on a unix datagram socket standing in for
+// journald.
+func newFakeJournal(t *testing.T) (string, *net.UnixConn) {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return socket, conn
+}
+
+// parseJournalEntry decodes a native protocol entry into its - possibly
+// repeated - fields.
+func parseJournalEntry(t *testing.T, entry []byte) map[string][]string {
+	t.Helper()
+
+	parsed := map[string][]string{}
+
+	for len(entry) > 0 {
+		nl := bytes.IndexByte(entry, '\n')
+		if nl < 0 {
+			t.Fatalf("unterminated field: %q", entry)
+		}
+
+		line := entry[:nl]
+
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			parsed[string(name)] = append(parsed[string(name)], string(value))
+			entry = entry[nl+1:]
+
+			continue
+		}
+
+		// Binary-safe encoding: NAME\n, 64-bit LE length, value, \n.
+		rest := entry[nl+1:]
+		size := binary.LittleEndian.Uint64(rest[:8])
+		value := rest[8 : 8+size]
+
+		if rest[8+size] != '\n' {
+			t.Fatalf("binary field %q isn't newline-terminated", line)
+		}
+
+		parsed[string(line)] = append(parsed[string(line)], string(value))
+		entry = rest[8+size+1:]
+	}
+
+	return parsed
+}
+
+// readJournalEntry reads the next datagram.
+func readJournalEntry(t *testing.T, conn *net.UnixConn) map[string][]string {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 1<<16)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("failed reading the entry: %v", err)
+	}
+
+	return parseJournalEntry(t, buf[:n])
+}
+
+func TestJournald_StructuredEntry(t *testing.T) {
+	socket, conn := newFakeJournal(t)
+
+	o, err := Journald(level.Trace, JournaldConfig{Socket: socket, Identifier: "fallback"})
+	if err != nil {
+		t.Fatalf("Journald() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	if o.GetName() != "Journald" {
+		t.Errorf("GetName() = %q, want Journald", o.GetName())
+	}
+
+	m := message.New(level.Warn, "disk almost full")
+
+	m.SetComponentName("storage")
+	m.AddTags("beta", "alpha")
+	m.SetFields(fields.Fields{
+		"user.id":   42,
+		"path":      "/var",
+		"stack":     "line 1\nline 2",
+		"_trusted":  "spoofed?",
+		"1st":       true,
+		"priority":  "overridden?",
+		"nilField":  nil,
+		"err-cause": errors.New("ENOSPC"),
+	})
+
+	if err := o.Write(m); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	entry := readJournalEntry(t, conn)
+
+	for name, want := range map[string][]string{
+		JournaldFieldMessage:          {"disk almost full"},
+		JournaldFieldPriority:         {"4"},
+		JournaldFieldSyslogIdentifier: {"storage"},
+		JournaldFieldTag:              {"alpha", "beta"},
+		"USER_ID":                     {"42"},
+		"PATH":                        {"/var"},
+		"STACK":                       {"line 1\nline 2"},
+		"TRUSTED":                     {"spoofed?"},
+		"X1ST":                        {"true"},
+		"ERR_CAUSE":                   {"ENOSPC"},
+	} {
+		if got := entry[name]; !slices.Equal(got, want) {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, ok := entry["NILFIELD"]; ok {
+		t.Error("nil fields should be skipped")
+	}
+}
+
+func TestJournald_IdentifierFallbackAndPriorities(t *testing.T) {
+	socket, conn := newFakeJournal(t)
+
+	o, err := Journald(level.Trace, JournaldConfig{Socket: socket, Identifier: "myapp"})
+	if err != nil {
+		t.Fatalf("Journald() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	for l, want := range map[level.Level]string{
+		level.Fatal: "2",
+		level.Error: "3",
+		level.Info:  "6",
+		level.Trace: "7",
+	} {
+		if err := o.Write(message.New(l, "msg")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		entry := readJournalEntry(t, conn)
+
+		if got := entry[JournaldFieldPriority]; !slices.Equal(got, []string{want}) {
+			t.Errorf("%s PRIORITY = %q, want %s", l, got, want)
+		}
+
+		if got := entry[JournaldFieldSyslogIdentifier]; !slices.Equal(got, []string{"myapp"}) {
+			t.Errorf("SYSLOG_IDENTIFIER = %q, want myapp", got)
+		}
+	}
+}
+
+func TestJournald_CloseAndErrors(t *testing.T) {
+	if _, err := Journald(level.Info, JournaldConfig{
+		Socket: filepath.Join(t.TempDir(), "missing.sock"),
+	}); err == nil {
+		t.Error("Journald() without a listening journal should error")
+	}
+
+	socket, _ := newFakeJournal(t)
+
+	o, err := Journald(level.Info, JournaldConfig{Socket: socket})
+	if err != nil {
+		t.Fatalf("Journald() error = %v", err)
+	}
+
+	closer := o.(interface{ Close() error })
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+
+	if err := o.Write(message.New(level.Info, "late")); !errors.Is(err, ErrJournaldClosed) {
+		t.Errorf("Write() after Close error = %v, want ErrJournaldClosed", err)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	for key, want := range map[string]string{
+		"user.id":               "USER_ID",
+		"HTTP_STATUS":           "HTTP_STATUS",
+		"__cursor":              "CURSOR",
+		"9lives":                "X9LIVES",
+		"...":                   "",
+		"content-type":          "CONTENT_TYPE",
+		string(make([]byte, 0)): "",
+	} {
+		if got := JournaldFieldName(key); got != want {
+			t.Errorf("JournaldFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	long := JournaldFieldName(string(bytes.Repeat([]byte("a"), 100)))
+
+	if len(long) != journaldMaxFieldName {
+		t.Errorf("JournaldFieldName() length = %d, want %d", len(long), journaldMaxFieldName)
+	}
+}
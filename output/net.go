@@ -0,0 +1,556 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// Net output defaults.
+const (
+	defaultNetBufferSize   = 1000
+	defaultNetDialTimeout  = 5 * time.Second
+	defaultNetMaxBackoff   = 30 * time.Second
+	defaultNetMinBackoff   = 100 * time.Millisecond
+	defaultNetWriteTimeout = time.Second
+)
+
+var (
+	// ErrNetClosed is returned when writing to a closed Net output.
+	ErrNetClosed = errors.New("net output is closed")
+
+	// ErrNetDropped is reported - through `NetWithOnError` - when a write
+	// evicts the oldest buffered frame: the reconnect buffer is full. It's
+	// reported once per outage - see `Dropped` for the count.
+	ErrNetDropped = errors.New("net output: reconnect buffer full, dropped the oldest frame")
+
+	// ErrNetUndelivered is returned by Flush, and Close when buffered frames
+	// couldn't be delivered - the peer is unreachable.
+	ErrNetUndelivered = errors.New("net output: frames undelivered")
+)
+
+// NetFraming determines how messages are delimited on the wire.
+type NetFraming int
+
+// Available framings.
+const (
+	// NetFramingNewline terminates each message with `\n` - e.g. Fluent
+	// Bit's, and Vector's tcp sources, Logstash's json_lines codec. It's
+	// the default.
+	NetFramingNewline NetFraming = iota
+
+	// NetFramingLengthPrefix prefixes each message with its length - a
+	// 4-byte, big-endian unsigned integer.
+	NetFramingLengthPrefix
+)
+
+// String interface implementation.
+func (f NetFraming) String() string {
+	switch f {
+	case NetFramingNewline:
+		return "Newline"
+	case NetFramingLengthPrefix:
+		return "LengthPrefix"
+	default:
+		return "Unknown"
+	}
+}
+
+// NetOption configures the Net output.
+type NetOption func(*netConfig)
+
+// netConfig is the Net output configuration.
+type netConfig struct {
+	bufferSize   int
+	dialTimeout  time.Duration
+	framing      NetFraming
+	maxBackoff   time.Duration
+	maxLevel     level.Level
+	minBackoff   time.Duration
+	name         string
+	onError      func(error)
+	processors   []processor.IProcessor
+	tlsConfig    *tls.Config
+	writeTimeout time.Duration
+}
+
+// NetWithName sets the output name. Defaults to "Net".
+func NetWithName(name string) NetOption {
+	return func(c *netConfig) {
+		c.name = name
+	}
+}
+
+// NetWithMaxLevel sets the output max level. Defaults to Info.
+func NetWithMaxLevel(l level.Level) NetOption {
+	return func(c *netConfig) {
+		c.maxLevel = l
+	}
+}
+
+// NetWithProcessors sets the output processors.
+func NetWithProcessors(processors ...processor.IProcessor) NetOption {
+	return func(c *netConfig) {
+		c.processors = processors
+	}
+}
+
+// NetWithFraming sets the framing. Defaults to `NetFramingNewline`.
+func NetWithFraming(framing NetFraming) NetOption {
+	return func(c *netConfig) {
+		c.framing = framing
+	}
+}
+
+// NetWithTLS enables TLS - stream networks only (tcp*, unix).
+func NetWithTLS(cfg *tls.Config) NetOption {
+	return func(c *netConfig) {
+		c.tlsConfig = cfg
+	}
+}
+
+// NetWithDialTimeout bounds each connection attempt. Defaults to 5s.
+func NetWithDialTimeout(d time.Duration) NetOption {
+	return func(c *netConfig) {
+		if d > 0 {
+			c.dialTimeout = d
+		}
+	}
+}
+
+// NetWithWriteTimeout sets each write's deadline, so a stalled peer never
+// blocks the logger: a timed-out write drops the connection, and buffers
+// the frame for the reconnection. Defaults to 1s.
+func NetWithWriteTimeout(d time.Duration) NetOption {
+	return func(c *netConfig) {
+		if d > 0 {
+			c.writeTimeout = d
+		}
+	}
+}
+
+// NetWithBackoff sets the reconnection backoff: it starts at `minBackoff`,
+// doubling after each failed attempt, up to `maxBackoff`. Defaults to 100ms,
+// and 30s.
+func NetWithBackoff(minBackoff, maxBackoff time.Duration) NetOption {
+	return func(c *netConfig) {
+		if minBackoff > 0 {
+			c.minBackoff = minBackoff
+		}
+
+		if maxBackoff > 0 {
+			c.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// NetWithBufferSize sets how many frames are buffered while disconnected.
+// Defaults to 1000.
+func NetWithBufferSize(n int) NetOption {
+	return func(c *netConfig) {
+		if n > 0 {
+			c.bufferSize = n
+		}
+	}
+}
+
+// NetWithOnError sets the callback receiving background failures -
+// connection losses, failed reconnection attempts, and the first frame
+// eviction of an outage (`ErrNetDropped`). It may be called concurrently.
+func NetWithOnError(cb func(error)) NetOption {
+	return func(c *netConfig) {
+		c.onError = cb
+	}
+}
+
+// netWriter writes frames to the peer, buffering them while disconnected.
+type netWriter struct {
+	cfg     netConfig
+	network string
+	addr    string
+
+	// mu guards the connection, the buffer, and the closed, and dropping
+	// flags. dropping is set on the first eviction of an outage - see
+	// `ErrNetDropped` - and cleared once the buffer drains.
+	mu       sync.Mutex
+	closed   bool
+	conn     net.Conn
+	pending  [][]byte
+	dropping bool
+
+	// dropped counts the evicted frames.
+	dropped atomic.Uint64
+
+	// reconnectCh kicks the reconnection worker; done stops it.
+	reconnectCh chan struct{}
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// Write conforms to the `io.Writer` interface: it frames, and sends `p` -
+// or buffers it while disconnected. A buffered frame is written: evictions
+// of older frames aren't write errors - see `ErrNetDropped`.
+func (w *netWriter) Write(p []byte) (int, error) {
+	frame := w.frame(bytes.TrimRight(p, "\r\n"))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrNetClosed
+	}
+
+	// Buffered frames go first - preserving order.
+	if w.conn != nil && len(w.pending) == 0 {
+		if err := w.writeFrame(frame); err == nil {
+			return len(p), nil
+		}
+	}
+
+	w.buffer(frame)
+
+	return len(p), nil
+}
+
+// Flush sends the buffered frames. It returns `ErrNetUndelivered` when some
+// remain - the peer is unreachable. After Close it's a no-op.
+func (w *netWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	return w.drain()
+}
+
+// Close stops reconnecting, sends the buffered frames - if connected - and
+// closes the connection. It's idempotent. Frames it couldn't deliver are
+// reported as `ErrNetUndelivered`.
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+
+	if w.closed {
+		w.mu.Unlock()
+
+		return nil
+	}
+
+	w.closed = true
+
+	close(w.done)
+
+	w.mu.Unlock()
+
+	// The worker may be dialing - it observes `closed` once done.
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	errs := []error{}
+
+	if err := w.drain(); err != nil {
+		errs = append(errs, err)
+	}
+
+	w.pending = nil
+
+	if w.conn != nil {
+		if err := w.conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("net output: failed closing the connection: %w", err))
+		}
+
+		w.conn = nil
+	}
+
+	return errors.Join(errs...)
+}
+
+//////
+// Helpers.
+//////
+
+// frame frames `payload` per the configured framing - in a fresh slice: the
+// `io.Writer` contract forbids retaining `p`.
+func (w *netWriter) frame(payload []byte) []byte {
+	if w.cfg.framing == NetFramingLengthPrefix {
+		frame := make([]byte, 4, 4+len(payload))
+
+		binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+
+		return append(frame, payload...)
+	}
+
+	frame := make([]byte, 0, len(payload)+1)
+
+	return append(append(frame, payload...), '\n')
+}
+
+// writeFrame writes `frame` under the write deadline. On failure, the
+// connection is dropped, and the reconnection kicked. Callers hold `mu`.
+func (w *netWriter) writeFrame(frame []byte) error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.cfg.writeTimeout)); err != nil {
+		w.disconnect(err)
+
+		return err
+	}
+
+	if _, err := w.conn.Write(frame); err != nil {
+		w.disconnect(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// drain sends the buffered frames, in order. Callers hold `mu`.
+func (w *netWriter) drain() error {
+	for len(w.pending) > 0 && w.conn != nil {
+		if err := w.writeFrame(w.pending[0]); err != nil {
+			break
+		}
+
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+	}
+
+	if len(w.pending) > 0 {
+		return fmt.Errorf("%w: %d buffered", ErrNetUndelivered, len(w.pending))
+	}
+
+	w.dropping = false
+
+	return nil
+}
+
+// buffer appends `frame` to the reconnect buffer - evicting the oldest one
+// when full, counted, and reported once per outage. Callers hold `mu`.
+func (w *netWriter) buffer(frame []byte) {
+	if len(w.pending) >= w.cfg.bufferSize {
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+
+		w.dropped.Add(1)
+
+		if !w.dropping {
+			w.dropping = true
+
+			w.reportError(ErrNetDropped)
+		}
+	}
+
+	w.pending = append(w.pending, frame)
+}
+
+// disconnect drops the connection, and kicks the reconnection. Callers hold
+// `mu`.
+func (w *netWriter) disconnect(cause error) {
+	_ = w.conn.Close()
+
+	w.conn = nil
+
+	w.reportError(fmt.Errorf("net output: lost the connection to %s %s: %w", w.network, w.addr, cause))
+
+	select {
+	case w.reconnectCh <- struct{}{}:
+	default:
+	}
+}
+
+// reportError delivers `err` to the error callback, if any.
+func (w *netWriter) reportError(err error) {
+	if w.cfg.onError != nil {
+		w.cfg.onError(err)
+	}
+}
+
+// dial connects to the peer.
+func (w *netWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.cfg.dialTimeout}
+
+	if w.cfg.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, w.network, w.addr, w.cfg.tlsConfig)
+	}
+
+	return dialer.Dial(w.network, w.addr)
+}
+
+// worker reconnects - with backoff - whenever kicked, then sends the
+// buffered frames.
+func (w *netWriter) worker() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.reconnectCh:
+		}
+
+		backoff := w.cfg.minBackoff
+
+		for {
+			conn, err := w.dial()
+			if err == nil {
+				w.mu.Lock()
+
+				if w.closed {
+					w.mu.Unlock()
+
+					_ = conn.Close()
+
+					return
+				}
+
+				w.conn = conn
+
+				// A failure re-kicks the reconnection.
+				_ = w.drain()
+
+				w.mu.Unlock()
+
+				break
+			}
+
+			w.reportError(fmt.Errorf("net output: failed connecting to %s %s: %w", w.network, w.addr, err))
+
+			select {
+			case <-w.done:
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff = min(backoff*2, w.cfg.maxBackoff)
+		}
+	}
+}
+
+// netOutput is a network-backed `IOutput` carrying the Flush, Close, and
+// Dropped capabilities.
+type netOutput struct {
+	*Proxy
+
+	writer *netWriter
+}
+
+// Flush sends the buffered frames - see `ErrNetUndelivered`. After Close
+// it's a no-op.
+func (o *netOutput) Flush() error {
+	return o.writer.Flush()
+}
+
+// Close sends the buffered frames, and closes the connection. It's
+// idempotent. Writes after Close return `ErrNetClosed`.
+func (o *netOutput) Close() error {
+	return o.writer.Close()
+}
+
+// Dropped returns how many buffered frames were evicted - see
+// `ErrNetDropped`.
+func (o *netOutput) Dropped() uint64 {
+	return o.writer.dropped.Load()
+}
+
+//////
+// Factory.
+//////
+
+// Net is a built-in `output` - named `Net` by default - that streams
+// messages to a TCP, UDP, or unix socket peer - e.g. Fluent Bit, Vector, or
+// Logstash. `network` is one of "tcp", "tcp4", "tcp6", "udp", "udp4",
+// "udp6", "unix", "unixgram".
+//
+// Messages are framed per `NetWithFraming` - newline by default - and
+// written under a deadline (`NetWithWriteTimeout`), so a stalled peer
+// never blocks the logger. A failed, or timed-out write drops the
+// connection: frames are buffered in memory (`NetWithBufferSize`) while a
+// background worker reconnects with exponential backoff
+// (`NetWithBackoff`), then sent in order.
+//
+// When the first connection attempt fails, the output starts disconnected -
+// reconnecting in the background - instead of failing: the peer may simply
+// not be up yet.
+//
+// Capabilities: `Flush() error` (sends the buffered frames), idempotent
+// `Close() error`, and `Dropped() uint64` (evicted frames). Background
+// failures are delivered through `NetWithOnError`.
+//
+// NOTE: By default, data is JSON-formatted - inline.
+// NOTE: Like `HTTP`, it returns an error - on an invalid configuration - it
+// never calls log.Fatalf.
+func Net(network, addr string, opts ...NetOption) (IOutput, error) {
+	cfg := netConfig{
+		bufferSize:   defaultNetBufferSize,
+		dialTimeout:  defaultNetDialTimeout,
+		maxBackoff:   defaultNetMaxBackoff,
+		maxLevel:     level.Info,
+		minBackoff:   defaultNetMinBackoff,
+		name:         "Net",
+		writeTimeout: defaultNetWriteTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	case "udp", "udp4", "udp6", "unixgram":
+		if cfg.tlsConfig != nil {
+			return nil, fmt.Errorf("net output: TLS requires a stream network, got %q", network)
+		}
+	default:
+		return nil, fmt.Errorf("net output: unsupported network %q", network)
+	}
+
+	if addr == "" {
+		return nil, errors.New("net output: address is required")
+	}
+
+	w := &netWriter{
+		addr:        addr,
+		cfg:         cfg,
+		done:        make(chan struct{}),
+		network:     network,
+		reconnectCh: make(chan struct{}, 1),
+	}
+
+	if conn, err := w.dial(); err == nil {
+		w.conn = conn
+	} else {
+		w.reportError(fmt.Errorf("net output: failed connecting to %s %s: %w", network, addr, err))
+
+		w.reconnectCh <- struct{}{}
+	}
+
+	w.wg.Add(1)
+
+	go w.worker()
+
+	o := &netOutput{writer: w}
+
+	o.Proxy = NewProxy(New(cfg.name, cfg.maxLevel, w, cfg.processors...).SetFormatter(formatter.JSON()), o)
+
+	return o, nil
+}
@@ -0,0 +1,536 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+// lineCollector accepts connections, collecting newline-framed lines.
+type lineCollector struct {
+	net.Listener
+
+	mu    sync.Mutex
+	lines []string
+	conns []net.Conn
+}
+
+func newLineCollector(t *testing.T, ln net.Listener) *lineCollector {
+	t.Helper()
+
+	c := &lineCollector{Listener: ln}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			c.mu.Lock()
+			c.conns = append(c.conns, conn)
+			c.mu.Unlock()
+
+			go func() {
+				scanner := bufio.NewScanner(conn)
+
+				for scanner.Scan() {
+					c.mu.Lock()
+					c.lines = append(c.lines, scanner.Text())
+					c.mu.Unlock()
+				}
+			}()
+		}
+	}()
+
+	t.Cleanup(func() {
+		ln.Close()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for _, conn := range c.conns {
+			conn.Close()
+		}
+	})
+
+	return c
+}
+
+// dropConnections closes every accepted connection - a peer restart.
+func (c *lineCollector) dropConnections() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+
+	c.conns = nil
+}
+
+func (c *lineCollector) all() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.lines...)
+}
+
+// waitLines polls until `n` lines arrived.
+func (c *lineCollector) waitLines(t *testing.T, n int) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for len(c.all()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d lines, got %q", n, c.all())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	return c.all()
+}
+
+// messageOf decodes the message of a JSON line.
+func messageOf(t *testing.T, line string) string {
+	t.Helper()
+
+	var parsed map[string]any
+
+	if err := json.Unmarshal([]byte(line), &parsed); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", line, err)
+	}
+
+	return parsed["message"].(string)
+}
+
+// selfSignedTLS returns a server, and a client config trusting each other.
+func selfSignedTLS(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		&tls.Config{RootCAs: pool}
+}
+
+func TestNet_TCPNewlineFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := newLineCollector(t, ln)
+
+	o, err := Net("tcp", ln.Addr().String(), NetWithName("Vector"), NetWithMaxLevel(level.Debug))
+	if err != nil {
+		t.Fatalf("Net() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	if o.GetName() != "Vector" || o.GetMaxLevel() != level.Debug {
+		t.Errorf("output = %s/%s", o.GetName(), o.GetMaxLevel())
+	}
+
+	for _, content := range []string{"one", "two"} {
+		if err := o.Write(message.New(level.Info, content)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	lines := collector.waitLines(t, 2)
+
+	if messageOf(t, lines[0]) != "one" || messageOf(t, lines[1]) != "two" {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestNet_LengthPrefixFramingOverUnix(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "net.sock")
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	defer ln.Close()
+
+	frames := make(chan string, 2)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		for {
+			var size uint32
+
+			if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+				return
+			}
+
+			payload := make([]byte, size)
+
+			if _, err := io.ReadFull(conn, payload); err != nil {
+				return
+			}
+
+			frames <- string(payload)
+		}
+	}()
+
+	o, err := Net("unix", socket, NetWithFraming(NetFramingLengthPrefix))
+	if err != nil {
+		t.Fatalf("Net() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	_ = o.Write(message.New(level.Info, "framed"))
+
+	select {
+	case frame := <-frames:
+		if strings.HasSuffix(frame, "\n") || messageOf(t, frame) != "framed" {
+			t.Errorf("frame = %q", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame received")
+	}
+}
+
+func TestNet_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pc.Close()
+
+	o, err := Net("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Net() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	_ = o.Write(message.New(level.Info, "datagram"))
+
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 4096)
+
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messageOf(t, strings.TrimSuffix(string(buf[:n]), "\n")) != "datagram" {
+		t.Errorf("datagram = %q", buf[:n])
+	}
+}
+
+func TestNet_TLS(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := newLineCollector(t, ln)
+
+	o, err := Net("tcp", ln.Addr().String(), NetWithTLS(clientTLS))
+	if err != nil {
+		t.Fatalf("Net() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	_ = o.Write(message.New(level.Info, "secret"))
+
+	if lines := collector.waitLines(t, 1); messageOf(t, lines[0]) != "secret" {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestNet_ReconnectsAndDeliversBufferedFrames(t *testing.T) {
+	// Reserve an address, and start with the peer down.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+
+	ln.Close()
+
+	errs := make(chan error, 100)
+
+	o, err := Net("tcp", addr,
+		NetWithBackoff(5*time.Millisecond, 20*time.Millisecond),
+		NetWithOnError(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Net() error = %v, want the output to start disconnected", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	// Buffered while the peer is down.
+	_ = o.Write(message.New(level.Info, "early"))
+
+	if err := o.(interface{ Flush() error }).Flush(); !errors.Is(err, ErrNetUndelivered) {
+		t.Errorf("Flush() error = %v, want ErrNetUndelivered", err)
+	}
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection failures should reach the error callback")
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("address reuse unavailable: %v", err)
+	}
+
+	collector := newLineCollector(t, ln)
+
+	if lines := collector.waitLines(t, 1); messageOf(t, lines[0]) != "early" {
+		t.Errorf("lines = %q", lines)
+	}
+
+	// A peer restart: the next writes reconnect, in order.
+	collector.dropConnections()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for i := 0; len(collector.all()) < 2; i++ {
+		if time.Now().After(deadline) {
+			t.Fatalf("no delivery after the peer restart: %q", collector.all())
+		}
+
+		_ = o.Write(message.New(level.Info, "after"))
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if last := collector.all()[1]; messageOf(t, last) != "after" {
+		t.Errorf("line = %q", last)
+	}
+}
+
+func TestNet_StalledPeerDoesNotBlock(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	// Accepts, never reads.
+	stalled := make(chan net.Conn, 10)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			stalled <- conn
+		}
+	}()
+
+	defer func() {
+		for len(stalled) > 0 {
+			(<-stalled).Close()
+		}
+	}()
+
+	o, err := Net("tcp", ln.Addr().String(), NetWithWriteTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Net() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	// Enough to fill the kernel buffers.
+	payload := strings.Repeat("x", 1<<20)
+
+	for range 32 {
+		start := time.Now()
+
+		_ = o.Write(message.New(level.Info, payload))
+
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("Write() blocked for %s", elapsed)
+		}
+	}
+}
+
+func TestNet_FullBufferDropsTheOldest(t *testing.T) {
+	// The peer is down.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+
+	ln.Close()
+
+	var (
+		mu      sync.Mutex
+		dropped int
+	)
+
+	o, err := Net("tcp", addr,
+		NetWithBufferSize(2),
+		NetWithBackoff(time.Hour, time.Hour),
+		NetWithOnError(func(err error) {
+			if errors.Is(err, ErrNetDropped) {
+				mu.Lock()
+				dropped++
+				mu.Unlock()
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Net() error = %v", err)
+	}
+
+	defer o.(interface{ Close() error }).Close()
+
+	// Evicting the oldest frames isn't a write error.
+	for _, content := range []string{"one", "two", "three", "four"} {
+		if err := o.Write(message.New(level.Info, content)); err != nil {
+			t.Fatalf("Write(%q) error = %v, want nil", content, err)
+		}
+	}
+
+	if n := o.(*netOutput).Dropped(); n != 2 {
+		t.Errorf("Dropped() = %d, want 2", n)
+	}
+
+	// Reported once per outage.
+	mu.Lock()
+
+	if dropped != 1 {
+		t.Errorf("ErrNetDropped reported %d times, want 1", dropped)
+	}
+
+	mu.Unlock()
+
+	w := o.(*netOutput).writer
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pending) != 2 || !strings.Contains(string(w.pending[0]), `"three"`) {
+		t.Errorf("pending = %q, want three, and four", w.pending)
+	}
+}
+
+func TestNet_ConfigurationAndClose(t *testing.T) {
+	for _, tt := range []struct {
+		network string
+		addr    string
+		opts    []NetOption
+	}{
+		{network: "ip", addr: "127.0.0.1:1"},
+		{network: "tcp", addr: ""},
+		{network: "udp", addr: "127.0.0.1:1", opts: []NetOption{NetWithTLS(&tls.Config{})}},
+	} {
+		if _, err := Net(tt.network, tt.addr, tt.opts...); err == nil {
+			t.Errorf("Net(%q, %q) should error", tt.network, tt.addr)
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newLineCollector(t, ln)
+
+	o, err := Net("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Net() error = %v", err)
+	}
+
+	closer := o.(interface{ Close() error })
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+
+	if err := o.Write(message.New(level.Info, "late")); !errors.Is(err, ErrNetClosed) {
+		t.Errorf("Write() after Close error = %v, want ErrNetClosed", err)
+	}
+
+	if got := NetFramingLengthPrefix.String(); got != "LengthPrefix" {
+		t.Errorf("String() = %q", got)
+	}
+}
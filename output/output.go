@@ -15,6 +15,7 @@ import (
 	"sync"
 	"syscall"
 
+	"github.com/thalesfsp/sypl/v2/fields"
 	"github.com/thalesfsp/sypl/v2/flag"
 	"github.com/thalesfsp/sypl/v2/formatter"
 	"github.com/thalesfsp/sypl/v2/internal/builtin"
@@ -225,9 +226,39 @@ func (o *output) SetWriter(w io.Writer) IOutput {
 // Write the message to the defined output. In case of any error, it can be
 // introspected, providing more information about the failure. The error will be
 // the type of `ProcessingError`.
-//
-//nolint:nestif
 func (o *output) Write(m message.IMessage) error {
+	if !o.prepare(m) {
+		return nil
+	}
+
+	if err := o.write(m); err != nil {
+		log.Println(shared.ErrorPrefix, err)
+
+		return err
+	}
+
+	return nil
+}
+
+//////
+// Helpers.
+//////
+
+// contains checks if `list` contains - exact, case-insensitive match - the
+// specified `name`.
+func contains(list []string, name string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prepare runs the processors, and returns if the message should be
+// written - see `Write`.
+func (o *output) prepare(m message.IMessage) bool {
 	// Should allows to specify `Output`(s).
 	processorsNames := o.GetProcessorsNames()
 
@@ -247,58 +278,30 @@ func (o *output) Write(m message.IMessage) error {
 	// Should print the message - regardless of the level, if flagged
 	// with `Force`.
 	if m.GetFlag() == flag.Force || m.GetFlag() == flag.SkipAndForce {
-		if err := o.write(m); err != nil {
-			log.Println(shared.ErrorPrefix, err)
-
-			return err
-		}
-	} else {
-		// Debug capability.
-		finalMaxLevel := o.GetMaxLevel()
-
-		// Should only run if Debug env var is set.
-		if os.Getenv(shared.LevelEnvVar) != "" {
-			debug := m.GetDebugEnvVarRegexes()
-
-			l, _, ok := debug.Level()
-
-			if ok {
-				finalMaxLevel = l
-			}
-		}
-
-		// Should only print if message `level` isn't above `MaxLevel`.
-		// Should only print if `level` isn't `None`.
-		// Should only print if not flagged with `Mute`, or `SkipAndMute`.
-		if m.GetLevel() != level.None &&
-			m.GetLevel() <= finalMaxLevel &&
-			m.GetFlag() != flag.Mute &&
-			m.GetFlag() != flag.SkipAndMute {
-			if err := o.write(m); err != nil {
-				log.Println(shared.ErrorPrefix, err)
-
-				return err
-			}
-		}
+		return true
 	}
 
-	return nil
-}
+	// Debug capability.
+	finalMaxLevel := o.GetMaxLevel()
 
-//////
-// Helpers.
-//////
+	// Should only run if Debug env var is set.
+	if os.Getenv(shared.LevelEnvVar) != "" {
+		debug := m.GetDebugEnvVarRegexes()
 
-// contains checks if `list` contains - exact, case-insensitive match - the
-// specified `name`.
-func contains(list []string, name string) bool {
-	for _, item := range list {
-		if strings.EqualFold(item, name) {
-			return true
+		l, _, ok := debug.Level()
+
+		if ok {
+			finalMaxLevel = l
 		}
 	}
 
-	return false
+	// Should only print if message `level` isn't above `MaxLevel`.
+	// Should only print if `level` isn't `None`.
+	// Should only print if not flagged with `Mute`, or `SkipAndMute`.
+	return m.GetLevel() != level.None &&
+		finalMaxLevel.Admits(m.GetLevel()) &&
+		m.GetFlag() != flag.Mute &&
+		m.GetFlag() != flag.SkipAndMute
 }
 
 // Processors logic of the Write method.
@@ -326,6 +329,17 @@ func (o *output) processProcessors(m message.IMessage, processorsNames []string)
 
 // DRY for the writing step.
 func (o *output) write(m message.IMessage) error {
+	o.format(m)
+
+	return o.emit(m.GetContent().GetProcessed())
+}
+
+// format resolves deferred fields, formats the message, and restores its
+// linebreak(s).
+func (o *output) format(m message.IMessage) {
+	// Deferred field values are resolved only now - the message is written.
+	m.SetFields(fields.Resolve(m.GetFields()))
+
 	// Should only format if any, and if not flagged.
 	if o.GetFormatter() != nil &&
 		m.GetFlag() != flag.Skip &&
@@ -338,11 +352,12 @@ func (o *output) write(m message.IMessage) error {
 
 	// Restore linebreak(s), if needed.
 	m.Restore()
+}
 
+// emit writes the processed content to the writer.
+func (o *output) emit(content string) error {
 	// Write to writer.
-	if err := o.GetBuiltinLogger().OutputBuiltin(
-		m.GetContent().GetProcessed(),
-	); err != nil {
+	if err := o.GetBuiltinLogger().OutputBuiltin(content); err != nil {
 		// It means application using Sypl was piped, but the pipe was broken so
 		// nothing to do.
 		if errors.Is(err, syscall.EPIPE) {
@@ -366,6 +381,34 @@ func (o *output) write(m message.IMessage) error {
 	return nil
 }
 
+// writeBatch processes each message like `Write` does, and writes the
+// written ones' contents - concatenated - with a single write.
+func (o *output) writeBatch(messages []message.IMessage) error {
+	var content strings.Builder
+
+	for _, m := range messages {
+		if !o.prepare(m) {
+			continue
+		}
+
+		o.format(m)
+
+		content.WriteString(m.GetContent().GetProcessed())
+	}
+
+	if content.Len() == 0 {
+		return nil
+	}
+
+	if err := o.emit(content.String()); err != nil {
+		log.Println(shared.ErrorPrefix, err)
+
+		return err
+	}
+
+	return nil
+}
+
 //////
 // Factory.
 //////
@@ -125,6 +125,9 @@ func (r *RecorderOutput) Reset() {
 
 // capture snapshots the pending message. `processed` is the exact text the
 // pipeline wrote.
+//
+// NOTE: Only the snapshot is retained - never the message, nor its fields
+// map - so pooled messages are safe (see `message.Acquire`).
 func (r *RecorderOutput) capture(processed string) {
 	// Reading `pending` is safe: capture runs downstream of Write - on the
 	// same goroutine - while `writeMu` is held.
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
 	"github.com/thalesfsp/sypl/v2/processor"
 	"github.com/thalesfsp/sypl/v2/shared"
 )
@@ -344,13 +345,21 @@ func (w *rotatingWriter) prune() error {
 //////
 
 // rotatingFileOutput is a rotating-file-backed `IOutput` carrying the Flush,
-// and Close capabilities.
+// Close, and `IBatchOutput` capabilities.
 type rotatingFileOutput struct {
 	*Proxy
 
+	base   *output
 	writer *rotatingWriter
 }
 
+// WriteBatch processes each message like `Write` does, then writes the
+// contents with a single write - rotating, if needed, before it: a batch
+// never spans two files.
+func (o *rotatingFileOutput) WriteBatch(messages []message.IMessage) error {
+	return o.base.writeBatch(messages)
+}
+
 // Flush syncs the live file to stable storage. After Close it's a no-op.
 // With no open live file - a prior mid-rotation failure - it returns
 // `ErrRotatingFileUnavailable`.
@@ -387,8 +396,8 @@ func openLogFile(path string) (*os.File, error) {
 // timestamp>`, and reopened fresh - then backups beyond `cfg.MaxBackups`,
 // or older than `cfg.MaxAgeDays`, are pruned (inline, no goroutines).
 //
-// Capabilities: `Flush() error` (file sync), and idempotent `Close() error`.
-// Writes after Close return `ErrRotatingFileClosed`.
+// Capabilities: `Flush() error` (file sync), idempotent `Close() error`, and
+// `IBatchOutput`. Writes after Close return `ErrRotatingFileClosed`.
 //
 // Notes:
 // - Unlike `File`, it returns an error - it never calls log.Fatalf.
@@ -437,9 +446,11 @@ func RotatingFile(
 		size: size,
 	}
 
-	o := &rotatingFileOutput{writer: w}
+	base, _ := New(name, maxLevel, w, processors...).(*output)
+
+	o := &rotatingFileOutput{base: base, writer: w}
 
-	o.Proxy = NewProxy(New(name, maxLevel, w, processors...), o)
+	o.Proxy = NewProxy(base, o)
 
 	return o, nil
 }
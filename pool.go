@@ -0,0 +1,71 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl
+
+import (
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+//////
+// Message pooling.
+//
+// An OPT-IN optimization: when enabled, messages created by the Print-family
+// calls - and their per-output copies - are pooled (see `message.Acquire`),
+// and released once every output finished writing them, cutting per-message
+// allocations.
+//
+// Contract:
+//   - Processors, and outputs MUST NOT retain a message - nor its fields map
+//     - beyond their call. Those that do retain `message.Retain(m)` - a copy
+//     of a pooled message. The built-in ones - e.g. `output.Async`, and
+//     `output.Recorder` - already do.
+//   - Messages built by the caller - e.g. via `NewMessage` - and printed via
+//     `PrintMessage` are never pooled, nor released: the caller owns them.
+//////
+
+// SetMessagePooling toggles the opt-in message pooling. Default: disabled -
+// zero behavior change. Child, and derived loggers inherit it.
+func (sypl *Sypl) SetMessagePooling(enabled bool) *Sypl {
+	sypl.lock()
+	defer sypl.unlock()
+
+	sypl.pooling = enabled
+
+	return sypl
+}
+
+// MessagePoolingEnabled returns whether message pooling is enabled.
+func (sypl *Sypl) MessagePoolingEnabled() bool {
+	sypl.rLock()
+	defer sypl.rUnlock()
+
+	return sypl.pooling
+}
+
+//////
+// Helpers.
+//////
+
+// acquireMessage creates a message owned by the logger - pooled, if pooling
+// is enabled. The caller releases it - see `message.Release` - once
+// processed.
+func (sypl *Sypl) acquireMessage(l level.Level, ct string) message.IMessage {
+	if sypl != nil && sypl.MessagePoolingEnabled() {
+		return sypl.buildMessage(message.Acquire, l, ct)
+	}
+
+	return sypl.NewMessage(l, ct)
+}
+
+// copyMessage copies `m` for an output - pooled, if `pooled`. The caller
+// releases it - see `message.Release` - once written.
+func copyMessage(m message.IMessage, pooled bool) message.IMessage {
+	if pooled {
+		return message.AcquireCopy(m)
+	}
+
+	return message.Copy(m)
+}
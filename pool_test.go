@@ -0,0 +1,143 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl_test
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/safebuffer"
+)
+
+func TestSetMessagePooling_Inherited(t *testing.T) {
+	l := sypl.New("pooling")
+
+	if l.MessagePoolingEnabled() {
+		t.Fatal("MessagePoolingEnabled() = true by default")
+	}
+
+	l.SetMessagePooling(true)
+
+	if !l.New("child").MessagePoolingEnabled() {
+		t.Error("child logger didn't inherit message pooling")
+	}
+
+	if !l.With(fields.Fields{"key": "value"}).MessagePoolingEnabled() {
+		t.Error("derived logger didn't inherit message pooling")
+	}
+}
+
+func TestMessagePooling_ConcurrentOutputs(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	const (
+		goroutines = 8
+		perG       = 200
+	)
+
+	asyncBuf := &safebuffer.Buffer{}
+	syncBuf := &safebuffer.Buffer{}
+
+	async := output.Async(output.New("Async", level.Trace, asyncBuf), output.AsyncWithBufferSize(goroutines*perG))
+
+	recorder, recorderOutput := output.Recorder(level.Trace)
+
+	l := sypl.New("pooling", async, recorderOutput, output.New("Sync", level.Trace, syncBuf)).
+		SetMessagePooling(true)
+
+	l.SetFields(fields.Fields{"global": "value"})
+
+	var wg sync.WaitGroup
+
+	for g := range goroutines {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range perG {
+				id := fmt.Sprintf("%d-%d", g, i)
+
+				l.PrintWithOptions(level.Info, id+"\n", sypl.WithField("id", id), sypl.WithTags(id))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{}
+
+	for g := range goroutines {
+		for i := range perG {
+			want = append(want, fmt.Sprintf("%d-%d", g, i))
+		}
+	}
+
+	sort.Strings(want)
+
+	for name, buf := range map[string]*safebuffer.Buffer{"Async": asyncBuf, "Sync": syncBuf} {
+		got := contents(buf)
+
+		sort.Strings(got)
+
+		if !equalStrings(got, want) {
+			t.Errorf("%s: got %d lines, want %d - messages lost, or reused", name, len(got), len(want))
+		}
+	}
+
+	records := recorder.Messages()
+
+	if len(records) != len(want) {
+		t.Fatalf("recorder: got %d records, want %d", len(records), len(want))
+	}
+
+	// Each snapshot must hold its own message's fields, and tags - not those
+	// of a message reusing the pool entry.
+	for _, r := range records {
+		id := r.OriginalContent[:len(r.OriginalContent)-1]
+
+		if r.Fields["id"] != id || r.Fields["global"] != "value" {
+			t.Errorf("record %q: fields %v", id, r.Fields)
+		}
+
+		if len(r.Tags) != 1 || r.Tags[0] != id {
+			t.Errorf("record %q: tags %v", id, r.Tags)
+		}
+	}
+}
+
+func TestMessagePooling_CallerMessagesNotReleased(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	buf := &safebuffer.Buffer{}
+
+	l := sypl.New("pooling", output.New("Buffer", level.Trace, buf)).SetMessagePooling(true)
+
+	m := l.NewMessage(level.Info, "mine\n")
+	m.SetFields(fields.Fields{"key": "value"})
+
+	l.PrintMessage(m)
+
+	// The caller still owns `m` - printing it again must work.
+	l.PrintMessage(m)
+
+	if got := buf.String(); got != "mine\nmine\n" {
+		t.Errorf("got %q, want %q", got, "mine\nmine\n")
+	}
+
+	if m.GetContent().GetOriginal() != "mine\n" || m.GetFields()["key"] != "value" {
+		t.Errorf("caller's message changed: %q %v", m.GetContent().GetOriginal(), m.GetFields())
+	}
+}
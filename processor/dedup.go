@@ -27,7 +27,8 @@ type dedupConfig struct {
 	// message's content-based hash ID.
 	keyFn func(m message.IMessage) string
 
-	// now is the injectable clock. Defaults to `time.Now`.
+	// now is the injectable clock. Defaults to the message's clock - see
+	// `messageTime`.
 	now func() time.Time
 }
 
@@ -98,7 +99,7 @@ type deduper struct {
 func (d *deduper) run(m message.IMessage) error {
 	key := d.cfg.keyFn(m)
 
-	now := d.cfg.now()
+	now := messageTime(m, d.cfg.now)
 
 	var muted bool
 
@@ -159,7 +160,6 @@ func defaultDedupKeyFn(m message.IMessage) string {
 func newDeduper(window time.Duration, opts ...DedupOption) *deduper {
 	cfg := dedupConfig{
 		keyFn: defaultDedupKeyFn,
-		now:   time.Now,
 	}
 
 	for _, opt := range opts {
@@ -81,6 +81,71 @@ func TestDedup_WindowExpiryReallowsAndReportsCount(t *testing.T) {
 	}
 }
 
+// Without an injected clock, windows are measured on the wall clock - not
+// on messages' timestamps: back-dated, or out-of-order ones don't move them.
+func TestDedup_WallClockIgnoresMessageTimestamp(t *testing.T) {
+	p := Dedup(time.Hour)
+
+	ts := time.Unix(0, 0)
+
+	run := func(ts time.Time) bool {
+		t.Helper()
+
+		m := message.New(level.Info, "a")
+		m.SetTimestamp(ts)
+
+		if err := p.Run(m); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		return m.GetFlag() == flag.Mute
+	}
+
+	if run(ts) {
+		t.Fatal("first 'a' must pass")
+	}
+
+	// Timestamps hours apart, and going back: still the same wall-clock
+	// window.
+	for _, ts := range []time.Time{ts.Add(2 * time.Hour), ts.Add(-time.Hour), ts.Add(24 * time.Hour)} {
+		if !run(ts) {
+			t.Fatalf("duplicate timestamped %v must be muted within the wall-clock window", ts)
+		}
+	}
+}
+
+// The clock a message was created with - the logger's one - drives the
+// window.
+func TestDedup_MessageClockDrivesWindow(t *testing.T) {
+	p := Dedup(time.Second)
+
+	now := time.Unix(0, 0)
+
+	clock := func() time.Time { return now }
+
+	run := func() bool {
+		t.Helper()
+
+		m := message.New(level.Info, "a", message.WithClock(clock))
+
+		if err := p.Run(m); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		return m.GetFlag() == flag.Mute
+	}
+
+	if run() || !run() {
+		t.Fatal("duplicate within the window must be muted")
+	}
+
+	now = now.Add(time.Second)
+
+	if run() {
+		t.Fatal("'a' must pass once the message clock crosses the window")
+	}
+}
+
 // Negative control: a key re-passing without suppressed duplicates must NOT
 // fire the counter callback.
 func TestDedup_CounterNotCalledWithoutSuppressions(t *testing.T) {
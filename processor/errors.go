@@ -48,10 +48,13 @@ func (p *ProcessingError) Error() string {
 }
 
 // NewProcessingError returns a new `ProcessingError`.
+//
+// NOTE: The error may outlive the message - a pooled one is copied, see
+// `message.Retain`.
 func NewProcessingError(m message.IMessage, e error) error {
 	return &ProcessingError{
 		Cause:         e,
-		Message:       m,
+		Message:       message.Retain(m),
 		OutputName:    m.GetOutputName(),
 		ProcessorName: m.GetProcessorName(),
 	}
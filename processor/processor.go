@@ -5,6 +5,8 @@
 package processor
 
 import (
+	"time"
+
 	"github.com/thalesfsp/sypl/v2/message"
 	"github.com/thalesfsp/sypl/v2/status"
 )
@@ -61,6 +63,28 @@ func (p *processor) Run(m message.IMessage) error {
 	return p.f(m)
 }
 
+//////
+// Helpers.
+//////
+
+// messageTime returns the point in time time-dependent processors measure
+// `m` at: `clock`, if injected, otherwise the clock `m` was created with -
+// the logger's one, if set, see `Sypl.SetClock` -, otherwise `time.Now`.
+//
+// NOTE: The message's timestamp is never used: back-dated, or out-of-order
+// ones (e.g. replays) would move, or reset the windows.
+func messageTime(m message.IMessage, clock func() time.Time) time.Time {
+	if clock != nil {
+		return clock()
+	}
+
+	if clock := m.GetClock(); clock != nil {
+		return clock()
+	}
+
+	return time.Now()
+}
+
 //////
 // Factory.
 //////
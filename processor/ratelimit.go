@@ -18,7 +18,8 @@ type rateLimitConfig struct {
 	// muted in the elapsed window - only if at least one was muted.
 	callback func(dropped uint64)
 
-	// now is the injectable clock. Defaults to `time.Now`.
+	// now is the injectable clock. Defaults to the message's clock - see
+	// `messageTime`.
 	now func() time.Time
 }
 
@@ -80,7 +81,7 @@ type rateLimiter struct {
 // run implements the limiting algorithm. It mutes - `flag.Mute` - messages
 // beyond `maxPerWindow`, within each window.
 func (r *rateLimiter) run(m message.IMessage) error {
-	now := r.cfg.now()
+	now := messageTime(m, r.cfg.now)
 
 	r.mu.Lock()
 
@@ -134,7 +135,7 @@ func newRateLimiter(
 	window time.Duration,
 	opts ...RateLimitOption,
 ) *rateLimiter {
-	cfg := rateLimitConfig{now: time.Now}
+	cfg := rateLimitConfig{}
 
 	for _, opt := range opts {
 		opt(&cfg)
@@ -36,8 +36,8 @@ type SampleConfig struct {
 	// processed content.
 	KeyFn func(m message.IMessage) string
 
-	// now is the injectable clock. Defaults to `time.Now`. Tests use it to
-	// make time-dependent logic deterministic.
+	// now is the injectable clock. Defaults to the message's clock - see
+	// `messageTime`. Tests use it to make time-dependent logic deterministic.
 	now func() time.Time
 }
 
@@ -67,7 +67,7 @@ type sampler struct {
 func (s *sampler) run(m message.IMessage) error {
 	key := s.cfg.KeyFn(m)
 
-	now := s.cfg.now()
+	now := messageTime(m, s.cfg.now)
 
 	s.mu.Lock()
 
@@ -126,10 +126,6 @@ func newSampler(cfg SampleConfig) *sampler {
 		cfg.KeyFn = defaultSampleKeyFn
 	}
 
-	if cfg.now == nil {
-		cfg.now = time.Now
-	}
-
 	return &sampler{
 		cfg:     cfg,
 		entries: map[string]*sampleEntry{},
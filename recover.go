@@ -0,0 +1,265 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+)
+
+//////
+// Panic recovery.
+//
+// Replaces ad-hoc `defer recover()` blocks: the panic value, and the stack
+// are captured, and logged through the NORMAL output pipeline - processors,
+// formatters, and routing apply - as structured fields:
+//   - "panic": the recovered value, `fmt.Sprint`-ed;
+//   - "stack": the goroutine stack at the recovery point.
+//
+// The message is tagged `PanicTag`. At `level.Fatal` the standard Fatal
+// contract applies: outputs are flushed (time-bounded), and the process
+// exits. Otherwise, when re-panicking is requested, the logger is flushed -
+// so async, and batching outputs drain - BEFORE the panic resumes.
+//////
+
+// PanicTag tags every message logged by the panic recovery helpers.
+const PanicTag = "panic"
+
+// Panic recovery field names.
+const (
+	PanicFieldName = "panic"
+	StackFieldName = "stack"
+)
+
+// recoverConfig is the panic recovery optional configuration.
+type recoverConfig struct {
+	// level is the level the panic is logged at. Defaults to `level.Error`.
+	level level.Level
+
+	// repanic resumes panicking after logging, and flushing.
+	repanic bool
+
+	// fields are extra structured fields added to the panic message.
+	fields fields.Fields
+}
+
+// RecoverOption allows to specify optional panic recovery configuration.
+type RecoverOption func(*recoverConfig)
+
+// RecoverWithLevel sets the level the panic is logged at. Defaults to
+// `level.Error`. `level.Fatal` exits the process - after the standard,
+// time-bounded, pre-exit flush - so re-panicking never happens.
+func RecoverWithLevel(l level.Level) RecoverOption {
+	return func(cfg *recoverConfig) {
+		cfg.level = l
+	}
+}
+
+// RecoverWithRepanic makes the helper resume panicking - with the original
+// value - after logging, and flushing the logger. Default: the panic is
+// swallowed.
+func RecoverWithRepanic() RecoverOption {
+	return func(cfg *recoverConfig) {
+		cfg.repanic = true
+	}
+}
+
+// RecoverWithFields adds structured fields to the panic message - e.g. the
+// goroutine's purpose. The "panic", and "stack" fields take precedence.
+func RecoverWithFields(f fields.Fields) RecoverOption {
+	return func(cfg *recoverConfig) {
+		cfg.fields = fields.Copy(f, nil)
+	}
+}
+
+// newRecoverConfig is the `recoverConfig` factory. It applies defaults.
+func newRecoverConfig(opts ...RecoverOption) *recoverConfig {
+	cfg := &recoverConfig{level: level.Error}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// RecoverAndLog recovers from a panic - if any - logging the panic value,
+// and the stack. It MUST be deferred directly, e.g.:
+//
+//	defer l.RecoverAndLog()
+//
+// Calling it any other way - e.g. from inside another deferred closure -
+// recovers nothing: that's how Go's `recover` works. See the `Recover*`
+// options for the level, re-panicking, and extra fields.
+func (sypl *Sypl) RecoverAndLog(opts ...RecoverOption) {
+	if r := recover(); r != nil {
+		sypl.handlePanic(r, newRecoverConfig(opts...))
+	}
+}
+
+// Go runs `fn` in a new goroutine, recovering - and logging through `l` -
+// any panic it raises. By default the panic is swallowed, so one
+// misbehaving goroutine can't take the process down; use
+// `RecoverWithRepanic` to restore the crash after logging.
+func Go(l *Sypl, fn func(), opts ...RecoverOption) {
+	go func() {
+		defer l.RecoverAndLog(opts...)
+
+		fn()
+	}()
+}
+
+// RecoverHandler is an `http.Handler` middleware recovering from panics
+// raised by `next`: the panic is logged - with the request method, and path
+// as fields - and, if nothing was written yet, a `500 Internal Server Error`
+// is returned to the client.
+//
+// Notes:
+//   - `http.ErrAbortHandler` is re-panicked untouched, and unlogged - it's
+//     net/http's sanctioned way to abort a response.
+//   - With `RecoverWithRepanic`, the panic resumes after logging, and
+//     flushing - net/http then aborts the connection instead of answering
+//     500.
+//   - Once `next` started writing the response, the status can't change:
+//     the partial response is left as-is.
+func (sypl *Sypl) RecoverHandler(next http.Handler, opts ...RecoverOption) http.Handler {
+	cfg := newRecoverConfig(opts...)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &trackingResponseWriter{ResponseWriter: w}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			//nolint:errorlint // Identity check - exactly as net/http does.
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			panicCfg := *cfg
+
+			panicCfg.fields = fields.Fields{}
+
+			fields.Copy(cfg.fields, panicCfg.fields)
+
+			panicCfg.fields["method"] = r.Method
+			panicCfg.fields["path"] = r.URL.Path
+
+			sypl.handlePanic(rec, &panicCfg)
+
+			if !tw.wroteHeader {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(tw, r)
+	})
+}
+
+//////
+// Helpers.
+//////
+
+// handlePanic logs the recovered value `r` - with the stack - and, if
+// configured, flushes, and re-panics.
+func (sypl *Sypl) handlePanic(r any, cfg *recoverConfig) {
+	// NOTE: `fields.Copy` returns nil for a nil source - the map is always
+	// allocated here.
+	f := fields.Fields{}
+
+	fields.Copy(cfg.fields, f)
+
+	f[PanicFieldName] = fmt.Sprint(r)
+	f[StackFieldName] = string(debug.Stack())
+
+	// NOTE: At `level.Fatal`, the process exits here - after the standard
+	// pre-exit flush.
+	sypl.PrintWithOptions(
+		cfg.level,
+		fmt.Sprintf("panic recovered: %v\n", r),
+		WithFields(f),
+		WithTags(PanicTag),
+	)
+
+	if !cfg.repanic {
+		return
+	}
+
+	// Drain async, and batching outputs BEFORE the panic resumes - it will,
+	// most likely, terminate the process.
+	if err := sypl.Flush(); err != nil {
+		if h := sypl.GetErrorHandler(); h != nil {
+			h(fmt.Errorf("panic recovery: %w", err))
+		}
+	}
+
+	// The ORIGINAL value resumes - `errors.Is`/`As` keep working upstream.
+	panic(r)
+}
+
+// trackingResponseWriter records whether the response was committed, so the
+// recovery middleware doesn't write a second header.
+//
+// NOTE: `http.Flusher`, and `http.Hijacker` are implemented directly -
+// streaming, and upgrading handlers commonly type-assert them.
+type trackingResponseWriter struct {
+	http.ResponseWriter
+
+	wroteHeader bool
+}
+
+// WriteHeader implements `http.ResponseWriter`.
+func (w *trackingResponseWriter) WriteHeader(statusCode int) {
+	w.wroteHeader = true
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements `http.ResponseWriter`.
+func (w *trackingResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements `http.Flusher` - a no-op when the underlying writer
+// can't flush.
+func (w *trackingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+
+		f.Flush()
+	}
+}
+
+// Hijack implements `http.Hijacker` - `http.ErrNotSupported` when the
+// underlying writer can't hijack. A hijacked connection counts as committed.
+func (w *trackingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.wroteHeader = true
+	}
+
+	return conn, rw, err
+}
+
+// Unwrap exposes the underlying writer to `http.ResponseController`.
+func (w *trackingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
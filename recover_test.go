@@ -0,0 +1,348 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+)
+
+var errPanicBoom = errors.New("panic boom")
+
+// recoveredRecord returns the single record `rec` captured, failing the test
+// otherwise.
+func recoveredRecord(t *testing.T, rec *output.RecorderOutput) output.Record {
+	t.Helper()
+
+	records := rec.Messages()
+
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	return records[0]
+}
+
+// The panic value, and the stack are logged @ Error - by default - as
+// fields, tagged, and the panic is swallowed.
+func TestRecoverAndLog_LogsAndSwallows(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	l := sypl.New("recover", o)
+
+	func() {
+		defer l.RecoverAndLog(sypl.RecoverWithFields(fields.Fields{"job": "sync"}))
+
+		panic("kaboom")
+	}()
+
+	r := recoveredRecord(t, rec)
+
+	if r.Level != level.Error {
+		t.Errorf("Level = %s, want error", r.Level)
+	}
+
+	if !strings.Contains(r.OriginalContent, "panic recovered: kaboom") {
+		t.Errorf("OriginalContent = %q", r.OriginalContent)
+	}
+
+	if r.Fields[sypl.PanicFieldName] != "kaboom" {
+		t.Errorf("panic field = %v, want kaboom", r.Fields[sypl.PanicFieldName])
+	}
+
+	if stack, _ := r.Fields[sypl.StackFieldName].(string); !strings.Contains(stack, "TestRecoverAndLog_LogsAndSwallows") {
+		t.Errorf("stack field doesn't carry the panicking frame: %q", stack)
+	}
+
+	if r.Fields["job"] != "sync" {
+		t.Errorf("job field = %v, want sync", r.Fields["job"])
+	}
+
+	if len(r.Tags) != 1 || r.Tags[0] != sypl.PanicTag {
+		t.Errorf("Tags = %v, want [%s]", r.Tags, sypl.PanicTag)
+	}
+}
+
+// Without a panic, nothing is logged.
+func TestRecoverAndLog_NoPanic(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	l := sypl.New("recover-nopanic", o)
+
+	func() {
+		defer l.RecoverAndLog()
+	}()
+
+	if rec.Len() != 0 {
+		t.Errorf("expected no record, got %d", rec.Len())
+	}
+}
+
+// flushCountingOutput counts Flush calls.
+type flushCountingOutput struct {
+	output.IOutput
+
+	mu      sync.Mutex
+	flushes int
+}
+
+func (f *flushCountingOutput) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.flushes++
+
+	return nil
+}
+
+// Re-panicking logs, FLUSHES, then resumes with the ORIGINAL value.
+func TestRecoverAndLog_RepanicFlushesFirst(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	fo := &flushCountingOutput{IOutput: o}
+
+	l := sypl.New("recover-repanic", fo)
+
+	var recovered any
+
+	func() {
+		defer func() { recovered = recover() }()
+
+		func() {
+			defer l.RecoverAndLog(sypl.RecoverWithRepanic(), sypl.RecoverWithLevel(level.Warn))
+
+			panic(errPanicBoom)
+		}()
+	}()
+
+	err, ok := recovered.(error)
+	if !ok || !errors.Is(err, errPanicBoom) {
+		t.Fatalf("re-panicked value = %v, want the original error", recovered)
+	}
+
+	if fo.flushes != 1 {
+		t.Errorf("Flush calls = %d, want 1", fo.flushes)
+	}
+
+	if r := recoveredRecord(t, rec); r.Level != level.Warn {
+		t.Errorf("Level = %s, want warn", r.Level)
+	}
+}
+
+// Go recovers the goroutine's panic - the process survives.
+func TestGo_RecoversGoroutinePanic(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	l := sypl.New("recover-go", o)
+
+	sypl.Go(l, func() { panic("in goroutine") })
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for rec.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if r := recoveredRecord(t, rec); r.Fields[sypl.PanicFieldName] != "in goroutine" {
+		t.Errorf("panic field = %v", r.Fields[sypl.PanicFieldName])
+	}
+}
+
+// The middleware answers 500, and logs the request method, and path.
+func TestRecoverHandler_Returns500(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	l := sypl.New("recover-http", o)
+
+	h := l.RecoverHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler boom")
+	}))
+
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+
+	r := recoveredRecord(t, rec)
+
+	if r.Fields["method"] != http.MethodPost || r.Fields["path"] != "/orders" {
+		t.Errorf("request fields = %v", r.Fields)
+	}
+}
+
+// A committed response keeps its status - no superfluous WriteHeader.
+func TestRecoverHandler_CommittedResponse(t *testing.T) {
+	_, o := output.Recorder(level.Trace)
+
+	l := sypl.New("recover-http-committed", o)
+
+	h := l.RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+
+		panic("late boom")
+	}))
+
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want 202", w.Code)
+	}
+}
+
+// Streaming handlers keep flushing, and upgrading ones keep hijacking,
+// through the middleware.
+func TestRecoverHandler_Streams(t *testing.T) {
+	_, o := output.Recorder(level.Trace)
+
+	l := sypl.New("recover-http-stream", o)
+
+	// The handler only writes the second event once the client received the
+	// first one - a buffered writer would deadlock.
+	received := make(chan struct{})
+
+	h := l.RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("http.Hijacker hidden by the middleware")
+		}
+
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("http.Flusher hidden by the middleware")
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		_, _ = w.Write([]byte("data: first\n\n"))
+
+		f.Flush()
+
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Error("first event not flushed")
+
+			return
+		}
+
+		_, _ = w.Write([]byte("data: second\n\n"))
+	}))
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL) //nolint:noctx // Test.
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	buf := make([]byte, len("data: first\n\n"))
+
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	close(received)
+
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(buf) + string(rest); got != "data: first\n\ndata: second\n\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// `http.ErrAbortHandler` passes through untouched, and unlogged.
+func TestRecoverHandler_ErrAbortHandlerPassesThrough(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	l := sypl.New("recover-http-abort", o)
+
+	h := l.RecoverHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	var recovered any
+
+	func() {
+		defer func() { recovered = recover() }()
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if recovered != http.ErrAbortHandler { //nolint:errorlint // Identity check.
+		t.Errorf("recovered = %v, want http.ErrAbortHandler", recovered)
+	}
+
+	if rec.Len() != 0 {
+		t.Errorf("expected no record, got %d", rec.Len())
+	}
+}
+
+// At `level.Fatal`, the standard Fatal contract applies: log, flush, exit 1.
+func TestRecoverAndLog_FatalExits(t *testing.T) {
+	if os.Getenv("SYPL_TEST_RECOVER_FATAL") == "1" {
+		buf, o := namedSafeBuffer("RecoverFatal", level.Trace)
+
+		l := sypl.New("recover-fatal", o)
+
+		l.SetErrorHandler(func(error) {})
+
+		defer func() {
+			// Unreachable when Fatal exits.
+			os.Stderr.WriteString(buf.String())
+
+			os.Exit(42)
+		}()
+
+		defer l.RecoverAndLog(sypl.RecoverWithLevel(level.Fatal))
+
+		panic("fatal boom")
+	}
+
+	//nolint:gosec // Re-running the test binary itself.
+	cmd := exec.Command(os.Args[0], "-test.run=TestRecoverAndLog_FatalExits$")
+
+	cmd.Env = append(os.Environ(), "SYPL_TEST_RECOVER_FATAL=1")
+
+	var stderr bytes.Buffer
+
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected subprocess to exit with an error, got %v (stderr: %s)", err, stderr.String())
+	}
+
+	if code := exitErr.ExitCode(); code != 1 {
+		t.Errorf("expected exit code 1 (Fatal), got %d (stderr: %s)", code, stderr.String())
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+)
+
+//////
+// Routing rules.
+//
+// An output's max level admits everything at, or below it. Routing rules
+// declare, per output, WHICH messages it receives - e.g. only Warn, and Error
+// to a "Slack" output - without `PrintOnlyAtLevel` processors on every
+// output:
+//   - An output named by rules receives only messages matching at least one
+//     of them
+//   - Outputs NOT named by any rule receive every message - as without rules
+//   - Rules are evaluated once per message, before the per-output copies
+//     are made: outputs the message isn't routed to cost nothing.
+//
+// The output's max level, status, and a message's explicit outputs (see
+// `WithOutputsNames`) still apply on top.
+//////
+
+// Condition determines if a message matches a routing rule - see `Rule`.
+type Condition func(m message.IMessage) bool
+
+// Rule routes messages matching ALL its conditions to the named outputs.
+// A rule without conditions matches every message.
+type Rule struct {
+	// Outputs names the outputs - case-insensitive - matching messages are
+	// routed to.
+	Outputs []string
+
+	// Conditions a message must match - all of them.
+	Conditions []Condition
+}
+
+// match returns if `m` matches all the rule's conditions.
+func (r Rule) match(m message.IMessage) bool {
+	for _, c := range r.Conditions {
+		if !c(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// router evaluates routing rules. Immutable once built - see `newRouter` -,
+// so it's shared by child, and derived loggers.
+type router struct {
+	// rules in evaluation order.
+	rules []Rule
+
+	// restricted are the (lower-cased) names of the outputs named by rules.
+	restricted map[string]struct{}
+}
+
+// routedMessage is the message conditions see: its component is the
+// logging logger's - like the per-output copies' - without modifying the
+// message, possibly owned by the caller (see `PrintMessage`).
+type routedMessage struct {
+	message.IMessage
+
+	component string
+}
+
+// GetComponentName returns the logging logger's name.
+func (m routedMessage) GetComponentName() string {
+	return m.component
+}
+
+// route returns the (lower-cased) names of the outputs - among the
+// restricted ones - `m`, logged by the `component` logger, is routed to.
+func (r *router) route(m message.IMessage, component string) map[string]struct{} {
+	routed := map[string]struct{}{}
+
+	view := routedMessage{IMessage: m, component: component}
+
+	for _, rule := range r.rules {
+		if !rule.match(view) {
+			continue
+		}
+
+		for _, name := range rule.Outputs {
+			routed[strings.ToLower(name)] = struct{}{}
+		}
+	}
+
+	return routed
+}
+
+// allows returns if `outputName` receives the message routed to `routed` -
+// see `route`.
+func (r *router) allows(routed map[string]struct{}, outputName string) bool {
+	name := strings.ToLower(outputName)
+
+	if _, ok := r.restricted[name]; !ok {
+		return true
+	}
+
+	_, ok := routed[name]
+
+	return ok
+}
+
+//////
+// Methods.
+//////
+
+// SetRoutingRules sets the routing rules - replacing existing ones. No rules
+// (the default) routes every message to every output. Child, and derived
+// loggers inherit them.
+//
+// Example - only Warn, and Error to the "Slack" output:
+//
+//	l.SetRoutingRules(
+//		sypl.NewRule([]string{"Slack"}, sypl.LevelBetween(level.Error, level.Warn)),
+//	)
+func (sypl *Sypl) SetRoutingRules(rules ...Rule) *Sypl {
+	sypl.lock()
+	defer sypl.unlock()
+
+	sypl.router = newRouter(rules)
+
+	return sypl
+}
+
+// AddRoutingRules appends routing rules - see `SetRoutingRules`.
+func (sypl *Sypl) AddRoutingRules(rules ...Rule) *Sypl {
+	sypl.lock()
+	defer sypl.unlock()
+
+	var existing []Rule
+
+	if sypl.router != nil {
+		existing = sypl.router.rules
+	}
+
+	sypl.router = newRouter(append(slices.Clone(existing), rules...))
+
+	return sypl
+}
+
+// GetRoutingRules returns the routing rules.
+func (sypl *Sypl) GetRoutingRules() []Rule {
+	sypl.rLock()
+	defer sypl.rUnlock()
+
+	if sypl.router == nil {
+		return nil
+	}
+
+	return slices.Clone(sypl.router.rules)
+}
+
+// getRouter returns the router - nil without rules.
+func (sypl *Sypl) getRouter() *router {
+	sypl.rLock()
+	defer sypl.rUnlock()
+
+	return sypl.router
+}
+
+//////
+// Factory.
+//////
+
+// newRouter is the `router` factory. Returns nil without rules.
+func newRouter(rules []Rule) *router {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	r := &router{
+		rules:      slices.Clone(rules),
+		restricted: map[string]struct{}{},
+	}
+
+	for _, rule := range rules {
+		for _, name := range rule.Outputs {
+			r.restricted[strings.ToLower(name)] = struct{}{}
+		}
+	}
+
+	return r
+}
+
+// NewRule returns a rule routing messages matching ALL `conditions` to
+// the outputs named `outputsNames`.
+func NewRule(outputsNames []string, conditions ...Condition) Rule {
+	return Rule{
+		Outputs:    slices.Clone(outputsNames),
+		Conditions: conditions,
+	}
+}
+
+//////
+// Built-in conditions.
+//////
+
+// LevelBetween matches messages whose level is within `from`, and `to` -
+// inclusive, by verbosity, in either order. E.g.: `LevelBetween(level.Error,
+// level.Warn)` matches Error, Warn, and custom levels in between.
+func LevelBetween(from, to level.Level) Condition {
+	lo, hi := from.Verbosity(), to.Verbosity()
+
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	return func(m message.IMessage) bool {
+		v := m.GetLevel().Verbosity()
+
+		return v >= lo && v <= hi
+	}
+}
+
+// AtLevels matches messages at any of the `levels`.
+func AtLevels(levels ...level.Level) Condition {
+	return func(m message.IMessage) bool {
+		return slices.Contains(levels, m.GetLevel())
+	}
+}
+
+// HasTags matches messages tagged with ALL `tags`.
+func HasTags(tags ...string) Condition {
+	return func(m message.IMessage) bool {
+		for _, tag := range tags {
+			if !m.ContainTag(tag) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// HasField matches messages whose field `key` equals `value` - compared by
+// their string representation, so e.g. `int`, and `int64` 42 match.
+func HasField(key string, value any) Condition {
+	want := fmt.Sprint(value)
+
+	return func(m message.IMessage) bool {
+		v, ok := m.GetFields()[key]
+
+		return ok && fmt.Sprint(v) == want
+	}
+}
+
+// FromComponents matches messages logged by any of the named components -
+// loggers' names, case-insensitive.
+func FromComponents(names ...string) Condition {
+	return func(m message.IMessage) bool {
+		return contains(names, m.GetComponentName())
+	}
+}
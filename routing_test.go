@@ -0,0 +1,162 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypl_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/safebuffer"
+)
+
+// routingRecorders returns a logger with "Console", "Slack", and "Audit"
+// buffer outputs. "Slack" counts the messages entering its pipeline.
+func routingRecorders(counter *int, mu *sync.Mutex) (*sypl.Sypl, map[string]*safebuffer.Buffer) {
+	bufs := map[string]*safebuffer.Buffer{}
+
+	outputs := []output.IOutput{}
+
+	for _, name := range []string{"Console", "Slack", "Audit"} {
+		buf := &safebuffer.Buffer{}
+
+		o := output.New(name, level.Trace, buf)
+
+		if name == "Slack" {
+			o.AddProcessors(recordingProcessor(counter, mu))
+		}
+
+		bufs[name] = buf
+
+		outputs = append(outputs, o)
+	}
+
+	return sypl.New("routing", outputs...), bufs
+}
+
+// contents returns the buffered lines.
+func contents(buf *safebuffer.Buffer) []string {
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func TestRoutingRules(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	var (
+		counter int
+		mu      sync.Mutex
+	)
+
+	l, bufs := routingRecorders(&counter, &mu)
+
+	l.SetRoutingRules(
+		sypl.NewRule([]string{"slack"}, sypl.LevelBetween(level.Warn, level.Error)),
+		sypl.NewRule([]string{"Audit"}, sypl.HasTags("audit")),
+		sypl.NewRule([]string{"Audit"}, sypl.HasField("tenant", "acme"), sypl.FromComponents("ROUTING")),
+	)
+
+	l.Errorln("error")
+	l.Warnln("warn")
+	l.Infoln("info")
+	l.PrintWithOptions(level.Debug, "tagged\n", sypl.WithTags("audit"))
+	l.PrintWithOptions(level.Info, "field\n", sypl.WithFields(fields.Fields{"tenant": "acme"}))
+
+	// Derived loggers inherit the rules - the component condition fails.
+	l.New("other").PrintWithOptions(level.Info, "other field\n", sypl.WithFields(fields.Fields{"tenant": "acme"}))
+
+	tests := []struct {
+		output string
+		want   []string
+	}{
+		{output: "Console", want: []string{"error", "warn", "info", "tagged", "field", "other field"}},
+		{output: "Slack", want: []string{"error", "warn"}},
+		{output: "Audit", want: []string{"tagged", "field"}},
+	}
+
+	for _, tt := range tests {
+		if got := contents(bufs[tt.output]); !equalStrings(got, tt.want) {
+			t.Errorf("%s received %q, want %q", tt.output, got, tt.want)
+		}
+	}
+
+	// Messages not routed to "Slack" never entered its pipeline.
+	if counter != 2 {
+		t.Errorf("Slack processed %d messages, want 2", counter)
+	}
+
+	// Negative control: without rules, every output receives everything.
+	if got := l.SetRoutingRules().GetRoutingRules(); got != nil {
+		t.Fatalf("GetRoutingRules() = %v, want nil", got)
+	}
+
+	l.Infoln("unrouted")
+
+	for name, buf := range bufs {
+		if got := contents(buf); got[len(got)-1] != "unrouted" {
+			t.Errorf("%s received %q, want unrouted last", name, got)
+		}
+	}
+}
+
+// Routing a caller's message - see `PrintMessage` - leaves its component
+// untouched: conditions see the logging logger's name.
+func TestRoutingRules_CallerMessageUntouched(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	var (
+		counter int
+		mu      sync.Mutex
+	)
+
+	l, bufs := routingRecorders(&counter, &mu)
+
+	l.SetRoutingRules(sypl.NewRule([]string{"Audit"}, sypl.FromComponents("routing")))
+
+	m := l.NewMessage(level.Info, "shared\n")
+
+	m.SetComponentName("caller")
+
+	l.PrintMessage(m)
+	l.New("other").PrintMessage(m)
+
+	if got := m.GetComponentName(); got != "caller" {
+		t.Errorf("component name = %q, want caller", got)
+	}
+
+	// Only the "routing" logger's print reached "Audit".
+	if got, want := contents(bufs["Audit"]), []string{"shared"}; !equalStrings(got, want) {
+		t.Errorf("Audit received %q, want %q", got, want)
+	}
+}
+
+func TestAddRoutingRules(t *testing.T) {
+	clearSyplEnvVars(t)
+
+	var (
+		counter int
+		mu      sync.Mutex
+	)
+
+	l, bufs := routingRecorders(&counter, &mu)
+
+	l.AddRoutingRules(sypl.NewRule([]string{"Slack"}, sypl.AtLevels(level.Error)))
+	l.AddRoutingRules(sypl.NewRule([]string{"Slack"}, sypl.AtLevels(level.Debug)))
+
+	if got := len(l.GetRoutingRules()); got != 2 {
+		t.Fatalf("GetRoutingRules() = %d rules, want 2", got)
+	}
+
+	l.Errorln("error")
+	l.Infoln("info")
+	l.Debugln("debug")
+
+	if got, want := contents(bufs["Slack"]), []string{"error", "debug"}; !equalStrings(got, want) {
+		t.Errorf("Slack received %q, want %q", got, want)
+	}
+}
@@ -69,14 +69,21 @@ type Sypl struct {
 	mu *sync.RWMutex
 
 	// NOTE: Changes here may reflect in the `New(name string)` method (Child).
+	clock                func() time.Time
 	contextExtractor     func(ctx context.Context) fields.Fields
 	defaultIoWriterLevel level.Level
+	dispatchMode         DispatchMode
+	dispatcher           *dispatcher
 	errorHandler         func(err error)
 	fastGate             bool
 	fields               fields.Fields
+	idGenerator          func() string
 	outputs              []output.IOutput
+	pooling              bool
+	router               *router
 	status               status.Status
 	tags                 []string
+	verbosity            int
 }
 
 // String interface implementation.
@@ -175,7 +182,11 @@ func (sypl *Sypl) SetDefaultIoWriterLevel(l level.Level) {
 // NOTE: This is a convenient method, if it doesn't fits your need, just
 // implement the way you need.
 func (sypl *Sypl) Write(p []byte) (int, error) {
-	sypl.process(message.New(sypl.GetDefaultIoWriterLevel(), string(p)))
+	m := sypl.acquireMessage(sypl.GetDefaultIoWriterLevel(), string(p))
+
+	sypl.process(m)
+
+	message.Release(m)
 
 	return 0, nil
 }
@@ -204,14 +215,20 @@ func (sypl *Sypl) PrintWithOptions(l level.Level, ct string, o ...OptionFunc) IS
 		return sypl
 	}
 
-	m := message.New(l, ct)
+	owned := sypl.acquireMessage(l, ct)
+
+	m := owned
 
 	// Iterate over the options.
 	for _, opt := range o {
 		m = opt(m)
 	}
 
-	return sypl.PrintMessage(m)
+	sypl.process(m)
+
+	message.Release(owned)
+
+	return sypl
 }
 
 // PrintlnWithOptions is a more flexible way of printing, allowing to specify
@@ -276,11 +293,15 @@ func (sypl *Sypl) Println(l level.Level, args ...interface{}) ISypl {
 // - Only exported fields of the data structure will be printed.
 // - Message isn't processed.
 func (sypl *Sypl) PrintPretty(l level.Level, data interface{}) ISypl {
-	msg := message.New(l, fmt.Sprint(shared.Prettify(data)))
+	msg := sypl.acquireMessage(l, fmt.Sprint(shared.Prettify(data)))
 
 	msg.SetFlag(flag.Skip)
 
-	return sypl.PrintMessage(msg)
+	sypl.process(msg)
+
+	message.Release(msg)
+
+	return sypl
 }
 
 // PrintlnPretty prints data structures as JSON text, also adding a new line
@@ -290,10 +311,14 @@ func (sypl *Sypl) PrintPretty(l level.Level, data interface{}) ISypl {
 // - Only exported fields of the data structure will be printed.
 // - Message isn't processed.
 func (sypl *Sypl) PrintlnPretty(l level.Level, data interface{}) ISypl {
-	msg := message.New(l, fmt.Sprintln(shared.Prettify(data)))
+	msg := sypl.acquireMessage(l, fmt.Sprintln(shared.Prettify(data)))
 	msg.SetFlag(flag.Skip)
 
-	return sypl.PrintMessage(msg)
+	sypl.process(msg)
+
+	message.Release(msg)
+
+	return sypl
 }
 
 // PrintMessagesToOutputs allows you to concurrently print messages, each one,
@@ -304,7 +329,7 @@ func (sypl *Sypl) PrintMessagesToOutputs(messagesToOutputs ...MessageToOutput) I
 	messages := []message.IMessage{}
 
 	for _, mto := range messagesToOutputs {
-		m := message.New(mto.Level, mto.Content)
+		m := sypl.acquireMessage(mto.Level, mto.Content)
 		m.SetOutputsNames([]string{mto.OutputName})
 
 		messages = append(messages, m)
@@ -312,6 +337,10 @@ func (sypl *Sypl) PrintMessagesToOutputs(messagesToOutputs ...MessageToOutput) I
 
 	sypl.process(messages...)
 
+	for _, m := range messages {
+		message.Release(m)
+	}
+
 	return sypl
 }
 
@@ -326,7 +355,7 @@ func (sypl *Sypl) PrintMessagesToOutputsWithOptions(
 	messages := []message.IMessage{}
 
 	for _, mto := range messagesToOutputs {
-		m := message.New(mto.Level, mto.Content)
+		m := sypl.acquireMessage(mto.Level, mto.Content)
 		m.SetOutputsNames([]string{mto.OutputName})
 
 		messages = append(messages, mergeOptions(m, o))
@@ -334,17 +363,23 @@ func (sypl *Sypl) PrintMessagesToOutputsWithOptions(
 
 	sypl.process(messages...)
 
+	for _, m := range messages {
+		message.Release(m)
+	}
+
 	return sypl
 }
 
 // PrintNewLine prints a new line. It always print, independent of the level,
 // and without any processing.
 func (sypl *Sypl) PrintNewLine() ISypl {
-	m := message.New(level.Info, "\n")
+	m := sypl.acquireMessage(level.Info, "\n")
 	m.SetFlag(flag.SkipAndForce)
 
 	sypl.process(m)
 
+	message.Release(m)
+
 	return sypl
 }
 
@@ -687,10 +722,17 @@ func (sypl *Sypl) New(name string) *Sypl {
 	// NOTE: The outputs slice is cloned by the factory.
 	s := New(name, sypl.outputs...)
 
+	s.clock = sypl.clock
 	s.defaultIoWriterLevel = sypl.defaultIoWriterLevel
+	s.dispatchMode = sypl.dispatchMode
+	s.dispatcher = sypl.dispatcher
 	s.fields = maps.Clone(sypl.fields)
+	s.idGenerator = sypl.idGenerator
+	s.pooling = sypl.pooling
+	s.router = sypl.router
 	s.status = sypl.status
 	s.tags = slices.Clone(sypl.tags)
+	s.verbosity = sypl.verbosity
 
 	return s
 }
@@ -701,78 +743,95 @@ func (sypl *Sypl) process(messages ...message.IMessage) {
 		log.Fatalf("%s %s", shared.ErrorPrefix, ErrSyplNotInitialized)
 	}
 
+	mode, d := sypl.getDispatch()
+
 	// Written from concurrently processed messages, so it needs to be
 	// atomic.
 	var shouldExit atomic.Bool
 
-	wg := sync.WaitGroup{}
-
-	for _, m := range messages {
-		// https://golang.org/doc/faq#closures_and_goroutines
-		m := m
+	// Ordered modes process messages one by one, on the caller's goroutine.
+	if mode != DispatchConcurrent {
+		for _, m := range messages {
+			if sypl.processMessage(m, mode, d) {
+				shouldExit.Store(true)
+			}
+		}
+	} else {
+		wg := sync.WaitGroup{}
 
-		wg.Add(1)
+		for _, m := range messages {
+			// https://golang.org/doc/faq#closures_and_goroutines
+			m := m
 
-		go func() {
-			defer wg.Done()
+			wg.Add(1)
 
-			// Do nothing if message is flagged with `SkipAndMute` - it
-			// should not be processed, neither printed.
-			if m.GetFlag() == flag.SkipAndMute {
-				return
-			}
+			go func() {
+				defer wg.Done()
 
-			// Should allows to filter logging by components names.
-			syplFilterEnvVar := os.Getenv(shared.FilterEnvVar)
+				if sypl.processMessage(m, mode, d) {
+					shouldExit.Store(true)
+				}
+			}()
+		}
 
-			if syplFilterEnvVar != "" &&
-				!filterMatch(syplFilterEnvVar, sypl.GetName()) {
-				return
-			}
+		wg.Wait()
+	}
 
-			// Should allows to specify `Output`(s).
-			outputsNames := sypl.GetOutputsNames()
+	// Should exit if `level` is `Fatal`.
+	if shouldExit.Load() {
+		sypl.flushBeforeExit()
 
-			if len(m.GetOutputsNames()) > 0 {
-				outputsNames = m.GetOutputsNames()
-			}
+		os.Exit(1)
+	}
+}
 
-			m.SetOutputsNames(outputsNames)
+// processMessage processes a message, and writes it to the outputs. It
+// returns if the process should exit - a Fatal message was written.
+func (sypl *Sypl) processMessage(m message.IMessage, mode DispatchMode, d *dispatcher) bool {
+	// Do nothing if message is flagged with `SkipAndMute` - it should not be
+	// processed, neither printed.
+	if m.GetFlag() == flag.SkipAndMute {
+		return false
+	}
 
-			// Should allows to set global fields.
-			// Per-message fields should have precedence.
-			if sypl.GetFields() != nil {
-				finalFields := fields.Fields{}
-				finalFields = fields.Copy(sypl.GetFields(), finalFields)
-				finalFields = fields.Copy(m.GetFields(), finalFields)
-				m.SetFields(finalFields)
-			}
+	// Should allows to filter logging by components names.
+	syplFilterEnvVar := os.Getenv(shared.FilterEnvVar)
 
-			// Should allows to set global tags.
-			// Per-message tags should have precedence.
-			if sypl.GetTags() != nil {
-				finalTags := []string{}
-				finalTags = append(finalTags, sypl.GetTags()...)
-				finalTags = append(finalTags, m.GetTags()...)
-				m.AddTags(finalTags...)
-			}
+	if syplFilterEnvVar != "" &&
+		!filterMatch(syplFilterEnvVar, sypl.GetName()) {
+		return false
+	}
 
-			sypl.processOutputs(m, outputsNames)
+	// Should allows to specify `Output`(s).
+	outputsNames := sypl.GetOutputsNames()
 
-			if m.GetLevel() == level.Fatal {
-				shouldExit.Store(true)
-			}
-		}()
+	if len(m.GetOutputsNames()) > 0 {
+		outputsNames = m.GetOutputsNames()
 	}
 
-	wg.Wait()
+	m.SetOutputsNames(outputsNames)
 
-	// Should exit if `level` is `Fatal`.
-	if shouldExit.Load() {
-		sypl.flushBeforeExit()
+	// Should allows to set global fields.
+	// Per-message fields should have precedence.
+	if sypl.GetFields() != nil {
+		finalFields := fields.Fields{}
+		finalFields = fields.Copy(sypl.GetFields(), finalFields)
+		finalFields = fields.Copy(m.GetFields(), finalFields)
+		m.SetFields(finalFields)
+	}
 
-		os.Exit(1)
+	// Should allows to set global tags.
+	// Per-message tags should have precedence.
+	if sypl.GetTags() != nil {
+		finalTags := []string{}
+		finalTags = append(finalTags, sypl.GetTags()...)
+		finalTags = append(finalTags, m.GetTags()...)
+		m.AddTags(finalTags...)
 	}
+
+	sypl.processOutputs(m, outputsNames, mode, d)
+
+	return m.GetLevel() == level.Fatal
 }
 
 // flushBeforeExit best-effort flushes BEFORE the Fatal exit, so
@@ -873,19 +932,39 @@ type outputWrite struct {
 	msg message.IMessage
 }
 
-// Outputs logic of the Process method.
-func (sypl *Sypl) processOutputs(m message.IMessage, outputsNames []string) {
-	// Gather the eligible outputs - enabled, and named (listed) ones - each
-	// receiving its own isolated copy of the message.
+// Outputs logic of the Process method. See the dispatch modes notes.
+func (sypl *Sypl) processOutputs(
+	m message.IMessage,
+	outputsNames []string,
+	mode DispatchMode,
+	d *dispatcher,
+) {
+	// Routing rules are evaluated once - before any copy is made.
+	r := sypl.getRouter()
+
+	var routed map[string]struct{}
+
+	if r != nil {
+		routed = r.route(m, sypl.GetName())
+	}
+
+	// Gather the eligible outputs - enabled, named (listed), and routed
+	// ones - each receiving its own isolated copy of the message.
 	writes := []outputWrite{}
 
+	pooled := sypl.MessagePoolingEnabled()
+
 	for _, o := range sypl.GetOutputs() {
 		if o.GetStatus() != status.Enabled || !contains(outputsNames, o.GetName()) {
 			continue
 		}
 
+		if r != nil && !r.allows(routed, o.GetName()) {
+			continue
+		}
+
 		// Message is isolated per `Output`.
-		msg := message.Copy(m)
+		msg := copyMessage(m, pooled)
 
 		msg.SetComponentName(sypl.GetName())
 		msg.SetOutputName(o.GetName())
@@ -901,11 +980,40 @@ func (sypl *Sypl) processOutputs(m message.IMessage, outputsNames []string) {
 		writes = append(writes, outputWrite{o: o, msg: msg})
 	}
 
+	switch {
+	case len(writes) == 0:
+		return
+	case mode == DispatchWorkerPool && d != nil:
+		errs := d.dispatch(writes)
+
+		// Errors are handled on the caller's goroutine - see the dispatch
+		// modes notes.
+		for i, w := range writes {
+			sypl.handleWriteError(w.o, errs[i])
+
+			message.Release(w.msg)
+		}
+
+		return
+	case mode != DispatchConcurrent:
+		// Ordered - also the worker pool mode of a zero-value Sypl, which
+		// has no dispatcher.
+		for _, w := range writes {
+			sypl.writeToOutput(w.o, w.msg)
+
+			message.Release(w.msg)
+		}
+
+		return
+	}
+
 	// Fast path: a single receiving output is written INLINE, on the calling
 	// goroutine - no goroutine spawn, no WaitGroup.
 	if len(writes) == 1 {
 		sypl.writeToOutput(writes[0].o, writes[0].msg)
 
+		message.Release(writes[0].msg)
+
 		return
 	}
 
@@ -919,6 +1027,8 @@ func (sypl *Sypl) processOutputs(m message.IMessage, outputsNames []string) {
 			defer wg.Done()
 
 			sypl.writeToOutput(w.o, w.msg)
+
+			message.Release(w.msg)
 		}(w)
 	}
 
@@ -930,7 +1040,12 @@ func (sypl *Sypl) processOutputs(m message.IMessage, outputsNames []string) {
 // handler when one is set (see `SetErrorHandler`) - silently swallowed (the
 // historical behavior) otherwise.
 func (sypl *Sypl) writeToOutput(o output.IOutput, msg message.IMessage) {
-	err := o.Write(msg)
+	sypl.handleWriteError(o, o.Write(msg))
+}
+
+// handleWriteError delivers `o`'s write error - if any - to the error
+// handler - see `writeToOutput`.
+func (sypl *Sypl) handleWriteError(o output.IOutput, err error) {
 	if err == nil {
 		return
 	}
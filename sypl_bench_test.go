@@ -111,3 +111,71 @@ func BenchmarkPrintWithOptions_Fields(b *testing.B) {
 		l.PrintWithOptions(level.Info, "benchmark message", sypl.WithFields(f))
 	}
 }
+
+// BenchmarkPrint_SingleConsoleOutput_Pooling measures the simplest hot path
+// with the opt-in message pooling enabled - compare allocs/op with
+// `BenchmarkPrint_SingleConsoleOutput`.
+func BenchmarkPrint_SingleConsoleOutput_Pooling(b *testing.B) {
+	l := sypl.New("bench", discardOutput("Discard")).SetMessagePooling(true)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		l.Print(level.Info, "benchmark message")
+	}
+}
+
+// BenchmarkPrint_TwoOutputs_Pooling measures the concurrent fan-out path with
+// the opt-in message pooling enabled - compare allocs/op with
+// `BenchmarkPrint_TwoOutputs`.
+func BenchmarkPrint_TwoOutputs_Pooling(b *testing.B) {
+	l := sypl.New(
+		"bench",
+		discardOutput("DiscardA"),
+		discardOutput("DiscardB"),
+	).SetMessagePooling(true)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		l.Print(level.Info, "benchmark message")
+	}
+}
+
+// BenchmarkPrint_TwoOutputs_Ordered measures the fan-out path with the
+// ordered dispatch mode - outputs written sequentially, no goroutine spawn.
+func BenchmarkPrint_TwoOutputs_Ordered(b *testing.B) {
+	l := sypl.New(
+		"bench",
+		discardOutput("DiscardA"),
+		discardOutput("DiscardB"),
+	).SetDispatchMode(sypl.DispatchOrdered)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		l.Print(level.Info, "benchmark message")
+	}
+}
+
+// BenchmarkPrint_TwoOutputs_WorkerPool measures the fan-out path with the
+// worker pool dispatch mode - one long-lived worker per output.
+func BenchmarkPrint_TwoOutputs_WorkerPool(b *testing.B) {
+	l := sypl.New(
+		"bench",
+		discardOutput("DiscardA"),
+		discardOutput("DiscardB"),
+	).SetDispatchMode(sypl.DispatchWorkerPool)
+
+	defer l.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		l.Print(level.Info, "benchmark message")
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/thalesfsp/sypl/v2/level"
 	"github.com/thalesfsp/sypl/v2/message"
 	"github.com/thalesfsp/sypl/v2/options"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/safebuffer"
 )
 
 // mergeOptions with everything set must override fields, flag, outputs
@@ -88,3 +90,61 @@ func TestMergeOptions_ZeroValueLeavesMessageUntouched(t *testing.T) {
 		t.Fatalf("empty options tags changed message tags: %v", got.GetTags())
 	}
 }
+
+// The workers pool is only created by switching to `DispatchWorkerPool` -
+// per-request derived loggers don't allocate one - and then shared.
+func TestDispatcher_Lazy(t *testing.T) {
+	l := New("dispatch")
+
+	if l.dispatcher != nil || l.With(fields.Fields{"k": "v"}).dispatcher != nil {
+		t.Fatal("dispatcher allocated outside the worker pool mode")
+	}
+
+	l.SetDispatchMode(DispatchWorkerPool)
+
+	d := l.dispatcher
+
+	if d == nil {
+		t.Fatal("worker pool mode without dispatcher")
+	}
+
+	l.SetDispatchMode(DispatchOrdered).SetDispatchMode(DispatchWorkerPool)
+
+	if l.dispatcher != d || l.With(nil).dispatcher != d || l.New("child").dispatcher != d {
+		t.Error("dispatcher not kept, or not shared")
+	}
+}
+
+// Closing a child, or derived logger leaves the shared workers running - only
+// the logger which created the pool stops them.
+func TestDispatcher_OnlyOwnerStops(t *testing.T) {
+	l := New("dispatch", output.New("Buffer", level.Trace, &safebuffer.Buffer{})).
+		SetDispatchMode(DispatchWorkerPool)
+
+	l.Infoln("content")
+
+	workers := func() int {
+		l.dispatcher.mu.RLock()
+		defer l.dispatcher.mu.RUnlock()
+
+		return len(l.dispatcher.workers)
+	}
+
+	for _, derived := range []*Sypl{l.New("child"), l.With(fields.Fields{"k": "v"})} {
+		if err := derived.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if workers() != 1 {
+			t.Fatal("derived logger stopped the shared workers")
+		}
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if workers() != 0 {
+		t.Error("owner didn't stop the workers")
+	}
+}
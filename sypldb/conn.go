@@ -0,0 +1,367 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypldb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+var (
+	// ErrNamedParametersUnsupported mirrors database/sql's error for legacy
+	// drivers receiving named parameters.
+	ErrNamedParametersUnsupported = errors.New("sypldb: driver does not support the use of Named Parameters")
+
+	// ErrTxOptionsUnsupported mirrors database/sql's error for legacy drivers
+	// receiving non-default transaction options.
+	ErrTxOptionsUnsupported = errors.New("sypldb: driver does not support non-default transaction options")
+)
+
+//////
+// Helpers.
+//////
+
+// namedValuesToValues converts arguments for legacy - context-less -
+// driver interfaces.
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, 0, len(named))
+
+	for _, nv := range named {
+		if nv.Name != "" {
+			return nil, ErrNamedParametersUnsupported
+		}
+
+		values = append(values, nv.Value)
+	}
+
+	return values, nil
+}
+
+// valuesToNamedValues converts arguments from legacy - context-less -
+// driver interfaces.
+func valuesToNamedValues(values []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, 0, len(values))
+
+	for i, v := range values {
+		named = append(named, driver.NamedValue{Ordinal: i + 1, Value: v})
+	}
+
+	return named
+}
+
+//////
+// Conn.
+//////
+
+// conn is the logging `driver.Conn`. It implements every optional interface,
+// forwarding to the wrapped connection, or falling back as database/sql
+// would.
+type conn struct {
+	driver.Conn
+
+	lg *logger
+}
+
+// Prepare implements `driver.Conn`.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{Stmt: s, conn: c.Conn, lg: c.lg, query: query}, nil
+}
+
+// PrepareContext implements `driver.ConnPrepareContext`.
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		return c.Prepare(query)
+	}
+
+	s, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{Stmt: s, conn: c.Conn, lg: c.lg, query: query}, nil
+}
+
+// BeginTx implements `driver.ConnBeginTx`.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bt.BeginTx(ctx, opts)
+	}
+
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, ErrTxOptionsUnsupported
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	//nolint:staticcheck // Legacy fallback - as database/sql does.
+	return c.Conn.Begin()
+}
+
+// ExecContext implements `driver.ExecerContext`. Without direct execution
+// support, `driver.ErrSkip` makes database/sql prepare - the statement is
+// then logged by the prepared statement.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	var (
+		result driver.Result
+		err    error
+	)
+
+	switch e := c.Conn.(type) {
+	case driver.ExecerContext:
+		result, err = e.ExecContext(ctx, query, args)
+	case driver.Execer: //nolint:staticcheck // Legacy fallback - as database/sql does.
+		var values []driver.Value
+
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = e.Exec(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+
+	c.lg.log(ctx, statement{
+		args:      args,
+		err:       err,
+		operation: OperationExec,
+		query:     query,
+		result:    result,
+		start:     start,
+	})
+
+	return result, err
+}
+
+// QueryContext implements `driver.QueryerContext`. Without direct querying
+// support, `driver.ErrSkip` makes database/sql prepare - the statement is
+// then logged by the prepared statement.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+
+	var (
+		rows driver.Rows
+		err  error
+	)
+
+	switch q := c.Conn.(type) {
+	case driver.QueryerContext:
+		rows, err = q.QueryContext(ctx, query, args)
+	case driver.Queryer: //nolint:staticcheck // Legacy fallback - as database/sql does.
+		var values []driver.Value
+
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = q.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+
+	c.lg.log(ctx, statement{
+		args:      args,
+		err:       err,
+		operation: OperationQuery,
+		query:     query,
+		start:     start,
+	})
+
+	return rows, err
+}
+
+// Ping implements `driver.Pinger`. Without support, it's a no-op - as
+// database/sql does.
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	return nil
+}
+
+// ResetSession implements `driver.SessionResetter`. Without support, it's a
+// no-op - as database/sql does.
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+// IsValid implements `driver.Validator`. Without support, the connection is
+// valid - as database/sql assumes.
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+
+	return true
+}
+
+// CheckNamedValue implements `driver.NamedValueChecker`. Without support,
+// `driver.ErrSkip` makes database/sql apply its default conversion.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+
+	return driver.ErrSkip
+}
+
+//////
+// Stmt.
+//////
+
+// stmt is the logging `driver.Stmt`.
+type stmt struct {
+	driver.Stmt
+
+	// conn is the WRAPPED connection - its named value checker applies.
+	conn driver.Conn
+
+	lg *logger
+
+	// query is the prepared SQL.
+	query string
+}
+
+// Exec implements `driver.Stmt`.
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+// Query implements `driver.Stmt`.
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+// ExecContext implements `driver.StmtExecContext`.
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	var (
+		result driver.Result
+		err    error
+	)
+
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+
+		if values, err = namedValuesToValues(args); err == nil {
+			//nolint:staticcheck // Legacy fallback - as database/sql does.
+			result, err = s.Stmt.Exec(values)
+		}
+	}
+
+	s.lg.log(ctx, statement{
+		args:      args,
+		err:       err,
+		operation: OperationExec,
+		query:     s.query,
+		result:    result,
+		start:     start,
+	})
+
+	return result, err
+}
+
+// QueryContext implements `driver.StmtQueryContext`.
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+
+	var (
+		rows driver.Rows
+		err  error
+	)
+
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+
+		if values, err = namedValuesToValues(args); err == nil {
+			//nolint:staticcheck // Legacy fallback - as database/sql does.
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+
+	s.lg.log(ctx, statement{
+		args:      args,
+		err:       err,
+		operation: OperationQuery,
+		query:     s.query,
+		start:     start,
+	})
+
+	return rows, err
+}
+
+// CheckNamedValue implements `driver.NamedValueChecker`, resolving - as
+// database/sql does - the wrapped statement's checker, or else the
+// connection's, then - on `driver.ErrSkip` - the statement's column
+// converter, and finally - via `driver.ErrSkip` - the default conversion.
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	nvc, ok := s.Stmt.(driver.NamedValueChecker)
+	if !ok {
+		nvc, ok = s.conn.(driver.NamedValueChecker)
+	}
+
+	if ok {
+		if err := nvc.CheckNamedValue(nv); !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+
+	//nolint:staticcheck // Legacy fallback - as database/sql does.
+	cc, ok := s.Stmt.(driver.ColumnConverter)
+	if !ok {
+		return driver.ErrSkip
+	}
+
+	arg := nv.Value
+
+	if vr, ok := arg.(driver.Valuer); ok {
+		v, err := vr.Value()
+		if err != nil {
+			return err
+		}
+
+		if !driver.IsValue(v) {
+			return fmt.Errorf("sypldb: non-Value type %T returned from Value", v)
+		}
+
+		arg = v
+	}
+
+	v, err := cc.ColumnConverter(nv.Ordinal - 1).ConvertValue(arg)
+	if err != nil {
+		return err
+	}
+
+	nv.Value = v
+
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package sypldb provides database/sql query logging: `Wrap`, and
+// `WrapConnector` decorate a `driver.Driver`, or a `driver.Connector`,
+// logging every executed statement through a sypl logger - using only the
+// standard library.
+//
+// Every `Exec`, and `Query` - direct, or through a prepared statement - is
+// logged with the SQL as content, and the following structured fields:
+//
+//	field          value
+//	-------------  ---------------------------------------------------
+//	operation      "exec", or "query"
+//	args           the arguments - see `WithArgsRedactor`
+//	duration       time.Duration the driver took
+//	rows_affected  exec only, when the driver reports it
+//	error          only for failed statements
+//
+// The level is `level.Debug` - see `WithLevel` - escalated to `level.Warn`
+// (tagged `SlowTag`) for statements lasting `WithSlowThreshold`, or more,
+// and to `level.Error` for failed ones. Normal statements can be sampled via
+// the existing `processor.Sample` - see `WithSampling`; slow, and failed
+// ones always pass.
+//
+// Entries go through the context's logger, when present - see
+// `sypl.NewContext` - so statements run on behalf of a request carry its
+// fields, falling back to the wrapper's one.
+//
+// Usage:
+//
+//	db := sql.OpenDB(sypldb.WrapConnector(connector, l))
+//
+// or, for drivers only exposing a `driver.Driver`:
+//
+//	sql.Register("postgres-sypl", sypldb.Wrap(&pq.Driver{}, l))
+//
+//	db, err := sql.Open("postgres-sypl", dsn)
+//
+// NOTE: The wrapper forwards every optional driver interface it knows about
+// (context-aware execution, and preparation, transactions options, session
+// reset, validation, named value checking), falling back exactly as
+// database/sql would when the wrapped driver lacks one. Transactions, rows,
+// and results are returned unwrapped.
+package sypldb
@@ -0,0 +1,115 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypldb
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/thalesfsp/sypl/v2"
+)
+
+//////
+// Driver, and connector.
+//////
+
+// wrappedDriver is the logging `driver.Driver`.
+type wrappedDriver struct {
+	driver.Driver
+
+	lg *logger
+}
+
+// Open implements `driver.Driver`.
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{Conn: c, lg: d.lg}, nil
+}
+
+// wrappedDriverContext is the logging `driver.Driver`, for drivers also
+// implementing `driver.DriverContext`.
+type wrappedDriverContext struct {
+	*wrappedDriver
+}
+
+// OpenConnector implements `driver.DriverContext`.
+func (d *wrappedDriverContext) OpenConnector(name string) (driver.Connector, error) {
+	//nolint:forcetypeassert // Guaranteed by `Wrap`.
+	c, err := d.Driver.(driver.DriverContext).OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &connector{Connector: c, driver: d, lg: d.lg}, nil
+}
+
+// connector is the logging `driver.Connector`.
+type connector struct {
+	driver.Connector
+
+	// driver is the wrapped driver returned by `Driver`.
+	driver driver.Driver
+
+	lg *logger
+}
+
+// Connect implements `driver.Connector`.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{Conn: dc, lg: c.lg}, nil
+}
+
+// Driver implements `driver.Connector`.
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close closes the wrapped connector - if it implements `io.Closer` -
+// database/sql calls it on `DB.Close`.
+func (c *connector) Close() error {
+	if closer, ok := c.Connector.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+//////
+// Factory.
+//////
+
+// Wrap returns a `driver.Driver` logging the statements executed through
+// `d`'s connections via `l` - see the package documentation. The result
+// implements `driver.DriverContext` if, and only if, `d` does.
+func Wrap(d driver.Driver, l *sypl.Sypl, opts ...Option) driver.Driver {
+	wd := &wrappedDriver{Driver: d, lg: &logger{cfg: newConfig(opts...), l: l}}
+
+	if _, ok := d.(driver.DriverContext); ok {
+		return &wrappedDriverContext{wrappedDriver: wd}
+	}
+
+	return wd
+}
+
+// WrapConnector returns a `driver.Connector` logging the statements executed
+// through `c`'s connections via `l` - see the package documentation. Use it
+// with `sql.OpenDB`.
+func WrapConnector(c driver.Connector, l *sypl.Sypl, opts ...Option) driver.Connector {
+	lg := &logger{cfg: newConfig(opts...), l: l}
+
+	return &connector{
+		Connector: c,
+		driver:    &wrappedDriver{Driver: c.Driver(), lg: lg},
+		lg:        lg,
+	}
+}
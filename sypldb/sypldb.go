@@ -0,0 +1,236 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypldb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/flag"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// SlowTag tags statements lasting the slow threshold, or more.
+const SlowTag = "slow"
+
+// RedactedValue replaces redacted arguments - see `RedactAll`.
+const RedactedValue = "REDACTED"
+
+// Statement log field names.
+const (
+	FieldArgs         = "args"
+	FieldDuration     = "duration"
+	FieldError        = "error"
+	FieldOperation    = "operation"
+	FieldRowsAffected = "rows_affected"
+)
+
+// Operations.
+const (
+	OperationExec  = "exec"
+	OperationQuery = "query"
+)
+
+// ArgsRedactor returns the loggable representation of a statement's
+// arguments. `nil` omits the "args" field.
+type ArgsRedactor func(query string, args []driver.NamedValue) []any
+
+// config is the wrapper optional configuration.
+type config struct {
+	// argsRedactor renders the arguments.
+	argsRedactor ArgsRedactor
+
+	// level of normal statements.
+	level level.Level
+
+	// sampler - if any - samples normal statements.
+	sampler processor.IProcessor
+
+	// slowLevel is the level of slow statements.
+	slowLevel level.Level
+
+	// slowThreshold is the slow statement duration. `<= 0` disables it.
+	slowThreshold time.Duration
+}
+
+// Option allows to specify optional wrapper configuration.
+type Option func(*config)
+
+// WithArgsRedactor sets the arguments renderer - e.g. `RedactAll`, or one
+// masking specific columns. Default: arguments are logged as-is.
+func WithArgsRedactor(fn ArgsRedactor) Option {
+	return func(cfg *config) {
+		cfg.argsRedactor = fn
+	}
+}
+
+// WithLevel sets the level of normal statements. Default: `level.Debug`.
+func WithLevel(l level.Level) Option {
+	return func(cfg *config) {
+		cfg.level = l
+	}
+}
+
+// WithSampling samples normal statements with the existing `processor.Sample`
+// - keyed, by default, by level + SQL, so each statement shape is sampled
+// independently. Slow, and failed statements always pass.
+func WithSampling(sampleCfg processor.SampleConfig) Option {
+	return func(cfg *config) {
+		cfg.sampler = processor.Sample(sampleCfg)
+	}
+}
+
+// WithSlowThreshold escalates statements lasting `d`, or more, to the slow
+// level - `level.Warn` unless set via `WithSlowLevel` - tagged `SlowTag`.
+// Default: disabled.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.slowThreshold = d
+	}
+}
+
+// WithSlowLevel sets the level of slow statements. Default: `level.Warn`.
+func WithSlowLevel(l level.Level) Option {
+	return func(cfg *config) {
+		cfg.slowLevel = l
+	}
+}
+
+// newConfig is the `config` factory. It applies defaults.
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		argsRedactor: logArgs,
+		level:        level.Debug,
+		slowLevel:    level.Warn,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+//////
+// Helpers.
+//////
+
+// logArgs is the default `ArgsRedactor`: arguments as-is.
+func logArgs(_ string, args []driver.NamedValue) []any {
+	values := make([]any, 0, len(args))
+
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+
+	return values
+}
+
+// RedactAll is an `ArgsRedactor` replacing every argument with
+// `RedactedValue` - the arguments count stays visible.
+func RedactAll(_ string, args []driver.NamedValue) []any {
+	values := make([]any, 0, len(args))
+
+	for range args {
+		values = append(values, RedactedValue)
+	}
+
+	return values
+}
+
+// statement is an executed statement outcome.
+type statement struct {
+	args      []driver.NamedValue
+	err       error
+	operation string
+	query     string
+	result    driver.Result
+	start     time.Time
+}
+
+// logger logs statements.
+type logger struct {
+	cfg *config
+
+	l *sypl.Sypl
+}
+
+// log emits the statement entry through the context's logger, if any,
+// otherwise the wrapper's one. `driver.ErrSkip` isn't an outcome -
+// database/sql retries another way - so it isn't logged.
+func (lg *logger) log(ctx context.Context, s statement) {
+	if errors.Is(s.err, driver.ErrSkip) {
+		return
+	}
+
+	l := sypl.FromContextOrDefault(ctx, lg.l)
+
+	if l == nil {
+		return
+	}
+
+	duration := time.Since(s.start)
+
+	slow := lg.cfg.slowThreshold > 0 && duration >= lg.cfg.slowThreshold
+
+	lvl := lg.cfg.level
+
+	switch {
+	case s.err != nil:
+		lvl = level.Error
+	case slow:
+		lvl = lg.cfg.slowLevel
+	}
+
+	// Gated BEFORE building the entry - redacting args, and formatting the
+	// query aren't free, and `PrintMessage` doesn't fast-gate.
+	if !l.Enabled(lvl) {
+		return
+	}
+
+	f := fields.Fields{
+		FieldDuration:  duration,
+		FieldOperation: s.operation,
+	}
+
+	if s.err != nil {
+		f[FieldError] = s.err.Error()
+	}
+
+	if args := lg.cfg.argsRedactor(s.query, s.args); args != nil {
+		f[FieldArgs] = args
+	}
+
+	if s.result != nil {
+		if n, err := s.result.RowsAffected(); err == nil {
+			f[FieldRowsAffected] = n
+		}
+	}
+
+	m := sypl.WithFields(f)(l.NewMessage(lvl, fmt.Sprintln(s.query)))
+
+	if slow {
+		m.AddTags(SlowTag)
+	}
+
+	// Only normal statements are sampled.
+	if lg.cfg.sampler != nil && s.err == nil && !slow {
+		if err := lg.cfg.sampler.Run(m); err != nil || m.GetFlag() == flag.Mute {
+			return
+		}
+	}
+
+	l.PrintMessage(m)
+}
@@ -0,0 +1,353 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+var errFakeFailure = errors.New("fake failure")
+
+// registrations makes registered driver names unique - `sql.Register`
+// panics on duplicates, e.g. with `-count`.
+var registrations atomic.Int64
+
+//////
+// In-memory fake driver. Statements starting with "FAIL" fail, with "SLOW"
+// last 30ms; exec affects 3 rows, and query returns a single row.
+//////
+
+// run executes `query`.
+func run(query string) error {
+	switch {
+	case strings.HasPrefix(query, "FAIL"):
+		return errFakeFailure
+	case strings.HasPrefix(query, "SLOW"):
+		time.Sleep(30 * time.Millisecond)
+	}
+
+	return nil
+}
+
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 3, nil }
+
+type fakeRows struct{ done bool }
+
+func (*fakeRows) Columns() []string { return []string{"n"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+
+	r.done = true
+
+	dest[0] = int64(1)
+
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct{ query string }
+
+func (*fakeStmt) Close() error  { return nil }
+func (*fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if err := run(s.query); err != nil {
+		return nil, err
+	}
+
+	return fakeResult{}, nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if err := run(s.query); err != nil {
+		return nil, err
+	}
+
+	return &fakeRows{}, nil
+}
+
+// fakeConn only prepares - database/sql always goes through statements.
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+// fakeDirectConn also executes directly.
+type fakeDirectConn struct{ fakeConn }
+
+func (fakeDirectConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := run(query); err != nil {
+		return nil, err
+	}
+
+	return fakeResult{}, nil
+}
+
+func (fakeDirectConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := run(query); err != nil {
+		return nil, err
+	}
+
+	return &fakeRows{}, nil
+}
+
+type fakeDriver struct{ direct bool }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	if d.direct {
+		return fakeDirectConn{}, nil
+	}
+
+	return fakeConn{}, nil
+}
+
+type fakeConnector struct{ d fakeDriver }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c fakeConnector) Driver() driver.Driver                        { return c.d }
+
+//////
+// Helpers.
+//////
+
+// openDB returns a DB backed by the wrapped fake driver, and the recorder.
+func openDB(t *testing.T, direct bool, opts ...Option) (*sql.DB, *output.RecorderOutput) {
+	t.Helper()
+
+	rec, o := output.Recorder(level.Trace)
+
+	db := sql.OpenDB(WrapConnector(fakeConnector{d: fakeDriver{direct: direct}}, sypl.New("db", o), opts...))
+
+	t.Cleanup(func() { db.Close() })
+
+	return db, rec
+}
+
+//////
+// Tests.
+//////
+
+func TestExec(t *testing.T) {
+	for _, direct := range []bool{true, false} {
+		db, rec := openDB(t, direct)
+
+		if _, err := db.Exec("UPDATE t SET a = ? WHERE b = ?", 1, "x"); err != nil {
+			t.Fatal(err)
+		}
+
+		records := rec.Messages()
+
+		if len(records) != 1 {
+			t.Fatalf("direct=%v: expected 1 record, got %d", direct, len(records))
+		}
+
+		r := records[0]
+
+		if r.Level != level.Debug ||
+			r.OriginalContent != "UPDATE t SET a = ? WHERE b = ?\n" ||
+			r.Fields[FieldOperation] != OperationExec ||
+			r.Fields[FieldRowsAffected] != int64(3) ||
+			!reflect.DeepEqual(r.Fields[FieldArgs], []any{int64(1), "x"}) {
+			t.Errorf("direct=%v: record = %+v", direct, r)
+		}
+
+		if _, ok := r.Fields[FieldDuration].(time.Duration); !ok {
+			t.Errorf("direct=%v: duration = %T, want time.Duration", direct, r.Fields[FieldDuration])
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	for _, direct := range []bool{true, false} {
+		db, rec := openDB(t, direct)
+
+		var n int
+
+		if err := db.QueryRow("SELECT 1").Scan(&n); err != nil || n != 1 {
+			t.Fatalf("direct=%v: n = %d, err = %v", direct, n, err)
+		}
+
+		records := rec.Messages()
+
+		if len(records) != 1 || records[0].Fields[FieldOperation] != OperationQuery {
+			t.Errorf("direct=%v: records = %+v", direct, records)
+		}
+	}
+}
+
+func TestSlowAndFailed(t *testing.T) {
+	db, rec := openDB(t, true, WithSlowThreshold(10*time.Millisecond))
+
+	if _, err := db.Exec("SLOW UPDATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec("FAIL UPDATE"); !errors.Is(err, errFakeFailure) {
+		t.Fatalf("err = %v, want errFakeFailure", err)
+	}
+
+	records := rec.Messages()
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	if slow := records[0]; slow.Level != level.Warn || len(slow.Tags) != 1 || slow.Tags[0] != SlowTag {
+		t.Errorf("slow record = %+v", slow)
+	}
+
+	if failed := records[1]; failed.Level != level.Error || failed.Fields[FieldError] != errFakeFailure.Error() {
+		t.Errorf("failed record = %+v", failed)
+	}
+}
+
+func TestArgsRedactor(t *testing.T) {
+	db, rec := openDB(t, true, WithArgsRedactor(RedactAll))
+
+	if _, err := db.Exec("UPDATE users SET password = ?", "s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+
+	if records := rec.Messages(); len(records) != 1 ||
+		!reflect.DeepEqual(records[0].Fields[FieldArgs], []any{RedactedValue}) {
+		t.Errorf("records = %+v", records)
+	}
+}
+
+// Statements below the logger's level aren't built: the args redactor
+// doesn't even run.
+func TestDisabledLevelSkipsWork(t *testing.T) {
+	rec, o := output.Recorder(level.Info)
+
+	redacted := 0
+
+	redactor := func(query string, args []driver.NamedValue) []any {
+		redacted++
+
+		return RedactAll(query, args)
+	}
+
+	db := sql.OpenDB(WrapConnector(
+		fakeConnector{d: fakeDriver{direct: true}},
+		sypl.New("db", o),
+		WithArgsRedactor(redactor),
+	))
+
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("UPDATE t SET a = ?", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if redacted != 0 || rec.Len() != 0 {
+		t.Errorf("redactor calls = %d, records = %d - want 0, 0", redacted, rec.Len())
+	}
+
+	// Failures - @ Error - are still logged.
+	_, _ = db.Exec("FAIL UPDATE")
+
+	if redacted != 1 || rec.Len() != 1 {
+		t.Errorf("redactor calls = %d, records = %d - want 1, 1", redacted, rec.Len())
+	}
+}
+
+func TestSampling(t *testing.T) {
+	db, rec := openDB(t, true,
+		WithSampling(processor.SampleConfig{First: 1}),
+		WithSlowThreshold(10*time.Millisecond),
+	)
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.Exec("UPDATE t"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Exec("SLOW UPDATE"); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = db.Exec("FAIL UPDATE")
+	}
+
+	// 1 sampled, 3 slow, and 3 failed.
+	if n := rec.Len(); n != 7 {
+		t.Errorf("records = %d, want 7", n)
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	db, fallbackRec := openDB(t, true)
+
+	rec, o := output.Recorder(level.Trace)
+
+	ctx := sypl.NewContext(context.Background(), sypl.New("ctx", o))
+
+	if _, err := db.ExecContext(ctx, "UPDATE t"); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Len() != 1 || fallbackRec.Len() != 0 {
+		t.Errorf("context logger records = %d, fallback records = %d", rec.Len(), fallbackRec.Len())
+	}
+}
+
+func TestWrap_Register(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	name := fmt.Sprintf("sypldb-fake-%d", registrations.Add(1))
+
+	sql.Register(name, Wrap(fakeDriver{direct: true}, sypl.New("db", o)))
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.Exec("UPDATE t"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Len() != 1 {
+		t.Errorf("records = %d, want 1", rec.Len())
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+//////
+// Helpers.
+//////
+
+// clientFields returns the fields of a call - `p` is filled by gRPC via the
+// `grpc.Peer` call option, falling back to the connection's target.
+func (cfg *config) clientFields(ctx context.Context, p *peer.Peer, target string) fields.Fields {
+	md, _ := metadata.FromOutgoingContext(ctx)
+
+	f := cfg.metadataFields(md)
+
+	f[FieldPeer] = target
+
+	if p.Addr != nil {
+		f[FieldPeer] = p.Addr.String()
+	}
+
+	return f
+}
+
+// clientStream counts messages, and logs once the stream ends.
+type clientStream struct {
+	grpc.ClientStream
+
+	// finish logs the call - once.
+	finish func(err error)
+
+	// once guards `finish`.
+	once sync.Once
+
+	// serverStreams is true for server, and bidi streams: the stream only
+	// ends on a `RecvMsg` error - `io.EOF` included.
+	serverStreams bool
+
+	mu           sync.Mutex
+	msgsReceived int
+	msgsSent     int
+}
+
+// end logs the call outcome - once.
+func (s *clientStream) end(err error) {
+	s.once.Do(func() { s.finish(err) })
+}
+
+// counts returns the messages counts.
+func (s *clientStream) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.msgsReceived, s.msgsSent
+}
+
+// SendMsg counts successfully sent messages. A send error ends the stream -
+// the actual status is retrieved via `RecvMsg`, as gRPC mandates.
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+
+	if err == nil {
+		s.mu.Lock()
+		s.msgsSent++
+		s.mu.Unlock()
+	}
+
+	return err
+}
+
+// RecvMsg counts successfully received messages, and detects the stream's
+// end: `io.EOF` (success), any other error, or - for client-streaming calls -
+// the single response.
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	switch {
+	case err == nil:
+		s.mu.Lock()
+		s.msgsReceived++
+		s.mu.Unlock()
+
+		if !s.serverStreams {
+			s.end(nil)
+		}
+	case errors.Is(err, io.EOF):
+		s.end(nil)
+	default:
+		s.end(err)
+	}
+
+	return err
+}
+
+//////
+// Factory.
+//////
+
+// UnaryClientInterceptor returns a client interceptor logging every unary
+// call through the call context's logger - see `sypl.NewContext` - falling
+// back to `l`; with neither, nothing is logged.
+func UnaryClientInterceptor(l *sypl.Sypl, opts ...Option) grpc.UnaryClientInterceptor {
+	cfg := newConfig(opts...)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		start := time.Now()
+
+		p := &peer.Peer{}
+
+		err := invoker(ctx, method, req, reply, cc, append(callOpts, grpc.Peer(p))...)
+
+		if logger := sypl.FromContextOrDefault(ctx, l); logger != nil {
+			result := callResult{err: err, method: method, msgsSent: 1, start: start}
+
+			if err == nil {
+				result.msgsReceived = 1
+			}
+
+			cfg.log(logger, cfg.clientFields(ctx, p, cc.Target()), result)
+		}
+
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a client interceptor logging every
+// streaming call through the call context's logger - see `sypl.NewContext` -
+// falling back to `l`; with neither, nothing is logged.
+//
+// NOTE: The call is logged when the stream ends, i.e. when `RecvMsg` returns
+// `io.EOF`, or an error - for client-streaming calls, the response. A stream
+// abandoned before that - without reading its end - isn't logged; that's
+// also how gRPC itself leaks such streams, until their context is canceled.
+func StreamClientInterceptor(l *sypl.Sypl, opts ...Option) grpc.StreamClientInterceptor {
+	cfg := newConfig(opts...)
+
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		start := time.Now()
+
+		p := &peer.Peer{}
+
+		logger := sypl.FromContextOrDefault(ctx, l)
+
+		cs, err := streamer(ctx, desc, cc, method, append(callOpts, grpc.Peer(p))...)
+		if err != nil {
+			if logger != nil {
+				cfg.log(logger, cfg.clientFields(ctx, p, cc.Target()), callResult{
+					err:    err,
+					method: method,
+					start:  start,
+				})
+			}
+
+			return nil, err
+		}
+
+		wrapped := &clientStream{ClientStream: cs, serverStreams: desc.ServerStreams}
+
+		wrapped.finish = func(err error) {
+			if logger == nil {
+				return
+			}
+
+			received, sent := wrapped.counts()
+
+			cfg.log(logger, cfg.clientFields(ctx, p, cc.Target()), callResult{
+				err:          err,
+				method:       method,
+				msgsReceived: received,
+				msgsSent:     sent,
+				start:        start,
+			})
+		}
+
+		return wrapped, nil
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package syplgrpc provides Sypl's gRPC support: server, and client unary,
+// and stream interceptors logging every call. It lives in its own Go module
+// (github.com/thalesfsp/sypl/syplgrpc/v2) so the core sypl module carries no
+// gRPC dependency - import this module only if you use gRPC.
+//
+// Every finished call is logged with the following structured fields:
+//
+//	field          value
+//	-------------  ---------------------------------------------------
+//	method         full method name, e.g. "/pkg.Service/Method"
+//	peer           remote address - the target, client-side, if unknown
+//	code           gRPC status code, e.g. "NotFound"
+//	duration       time.Duration from the call start, to its end
+//	msgs_received  messages received - 1 for successful unary calls
+//	msgs_sent      messages sent - 1 for successful unary calls
+//	error          the status message - only for failed calls
+//
+// The level is chosen by status code - see `CodeToLevel`, and
+// `WithLevelFunc`. Incoming (server), or outgoing (client) metadata keys can
+// be extracted into fields - see `WithMetadataKeys`.
+//
+// Server-side, each call gets a request-scoped child logger - `l.With`
+// carrying the method, peer, and extracted metadata - injected via
+// `sypl.NewContext`, so handlers retrieve it with `sypl.FromContext`, or
+// `sypl.FromContextOrDefault`. Client-side, entries go through the call
+// context's logger, when present, falling back to the interceptor's one.
+//
+// Usage:
+//
+//	srv := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(syplgrpc.UnaryServerInterceptor(l)),
+//		grpc.ChainStreamInterceptor(syplgrpc.StreamServerInterceptor(l)),
+//	)
+//
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithChainUnaryInterceptor(syplgrpc.UnaryClientInterceptor(l)),
+//		grpc.WithChainStreamInterceptor(syplgrpc.StreamClientInterceptor(l)),
+//	)
+package syplgrpc
@@ -0,0 +1,23 @@
+module github.com/thalesfsp/sypl/syplgrpc/v2
+
+go 1.23
+
+replace github.com/thalesfsp/sypl/v2 => ../
+
+require (
+	github.com/thalesfsp/sypl/v2 v2.0.0
+	google.golang.org/grpc v1.72.0
+)
+
+require (
+	github.com/acarl005/stripansi v0.0.0-20180116102854-5a71ef0e047d // indirect
+	github.com/fatih/color v1.18.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+	google.golang.org/protobuf v1.36.5 // indirect
+)
@@ -0,0 +1,145 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+//////
+// Helpers.
+//////
+
+// serverFields returns the request-scoped fields of a call.
+func (cfg *config) serverFields(ctx context.Context, method string) fields.Fields {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	f := cfg.metadataFields(md)
+
+	f[FieldMethod] = method
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		f[FieldPeer] = p.Addr.String()
+	}
+
+	return f
+}
+
+// serverStream counts messages, and carries the request-scoped context.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+
+	msgsReceived int
+	msgsSent     int
+}
+
+// Context returns the context carrying the request-scoped logger.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg counts successfully sent messages.
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+
+	if err == nil {
+		s.msgsSent++
+	}
+
+	return err
+}
+
+// RecvMsg counts successfully received messages.
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+
+	if err == nil {
+		s.msgsReceived++
+	}
+
+	return err
+}
+
+//////
+// Factory.
+//////
+
+// UnaryServerInterceptor returns a server interceptor logging every unary
+// call through `l`, and injecting the request-scoped logger into the
+// handler's context.
+func UnaryServerInterceptor(l *sypl.Sypl, opts ...Option) grpc.UnaryServerInterceptor {
+	cfg := newConfig(opts...)
+
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		start := time.Now()
+
+		f := cfg.serverFields(ctx, info.FullMethod)
+
+		reqLogger := l.With(f)
+
+		resp, err := handler(sypl.NewContext(ctx, reqLogger), req)
+
+		result := callResult{err: err, method: info.FullMethod, msgsReceived: 1, start: start}
+
+		if err == nil {
+			result.msgsSent = 1
+		}
+
+		cfg.log(reqLogger, f, result)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor logging every
+// streaming call - once the handler returns - through `l`, and injecting the
+// request-scoped logger into the stream's context.
+func StreamServerInterceptor(l *sypl.Sypl, opts ...Option) grpc.StreamServerInterceptor {
+	cfg := newConfig(opts...)
+
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+
+		f := cfg.serverFields(ss.Context(), info.FullMethod)
+
+		reqLogger := l.With(f)
+
+		wrapped := &serverStream{
+			ServerStream: ss,
+			ctx:          sypl.NewContext(ss.Context(), reqLogger),
+		}
+
+		err := handler(srv, wrapped)
+
+		cfg.log(reqLogger, f, callResult{
+			err:          err,
+			method:       info.FullMethod,
+			msgsReceived: wrapped.msgsReceived,
+			msgsSent:     wrapped.msgsSent,
+			start:        start,
+		})
+
+		return err
+	}
+}
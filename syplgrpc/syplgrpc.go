@@ -0,0 +1,164 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplgrpc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// Call log field names.
+const (
+	FieldCode         = "code"
+	FieldDuration     = "duration"
+	FieldError        = "error"
+	FieldMethod       = "method"
+	FieldMsgsReceived = "msgs_received"
+	FieldMsgsSent     = "msgs_sent"
+	FieldPeer         = "peer"
+)
+
+// config is the interceptors optional configuration.
+type config struct {
+	// levelFn chooses the level from the status code.
+	levelFn func(code codes.Code) level.Level
+
+	// metadataKeys are the metadata keys extracted into fields.
+	metadataKeys []string
+}
+
+// Option allows to specify optional interceptors configuration.
+type Option func(*config)
+
+// WithLevelFunc sets the function choosing the level from the status code.
+// Default: `CodeToLevel`.
+//
+// NOTE: Returning `level.Fatal` exits the process - sypl's Fatal contract.
+func WithLevelFunc(fn func(code codes.Code) level.Level) Option {
+	return func(cfg *config) {
+		if fn != nil {
+			cfg.levelFn = fn
+		}
+	}
+}
+
+// WithMetadataKeys extracts the given metadata keys - incoming server-side,
+// outgoing client-side - into fields named after the (lower-cased) key.
+// Multiple values are comma-joined; absent keys are omitted.
+//
+// NOTE: Metadata is client-controlled: don't extract credentials, e.g.
+// "authorization".
+func WithMetadataKeys(keys ...string) Option {
+	return func(cfg *config) {
+		for _, k := range keys {
+			cfg.metadataKeys = append(cfg.metadataKeys, strings.ToLower(k))
+		}
+	}
+}
+
+// newConfig is the `config` factory. It applies defaults.
+func newConfig(opts ...Option) *config {
+	cfg := &config{levelFn: CodeToLevel}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+//////
+// Helpers.
+//////
+
+// CodeToLevel is the default status code-to-level mapping - the one widely
+// used by gRPC logging middlewares:
+//   - Info: OK, Canceled, InvalidArgument, NotFound, AlreadyExists,
+//     Unauthenticated - expected, client-caused outcomes;
+//   - Warn: DeadlineExceeded, PermissionDenied, ResourceExhausted,
+//     FailedPrecondition, Aborted, OutOfRange, Unavailable;
+//   - Error: Unknown, Unimplemented, Internal, DataLoss - and any
+//     unrecognized code.
+func CodeToLevel(code codes.Code) level.Level {
+	switch code {
+	case codes.OK,
+		codes.Canceled,
+		codes.InvalidArgument,
+		codes.NotFound,
+		codes.AlreadyExists,
+		codes.Unauthenticated:
+		return level.Info
+	case codes.DeadlineExceeded,
+		codes.PermissionDenied,
+		codes.ResourceExhausted,
+		codes.FailedPrecondition,
+		codes.Aborted,
+		codes.OutOfRange,
+		codes.Unavailable:
+		return level.Warn
+	case codes.Unknown,
+		codes.Unimplemented,
+		codes.Internal,
+		codes.DataLoss:
+		return level.Error
+	default:
+		return level.Error
+	}
+}
+
+// metadataFields extracts the configured keys from `md`.
+func (cfg *config) metadataFields(md metadata.MD) fields.Fields {
+	f := fields.Fields{}
+
+	for _, k := range cfg.metadataKeys {
+		if values := md.Get(k); len(values) > 0 {
+			f[k] = strings.Join(values, ", ")
+		}
+	}
+
+	return f
+}
+
+// callResult is a finished call outcome.
+type callResult struct {
+	err          error
+	method       string
+	msgsReceived int
+	msgsSent     int
+	start        time.Time
+}
+
+// log emits the call entry through `l`, merged with `f` - mutated.
+func (cfg *config) log(l *sypl.Sypl, f fields.Fields, r callResult) {
+	st := status.Convert(r.err)
+
+	f[FieldCode] = st.Code().String()
+	f[FieldDuration] = time.Since(r.start)
+	f[FieldMethod] = r.method
+	f[FieldMsgsReceived] = r.msgsReceived
+	f[FieldMsgsSent] = r.msgsSent
+
+	if r.err != nil {
+		f[FieldError] = st.Message()
+	}
+
+	l.PrintlnWithOptions(
+		cfg.levelFn(st.Code()),
+		fmt.Sprintf("%s %s", r.method, st.Code()),
+		sypl.WithFields(f),
+	)
+}
@@ -0,0 +1,248 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	checkMethod = "/grpc.health.v1.Health/Check"
+	watchMethod = "/grpc.health.v1.Health/Watch"
+)
+
+// healthServer answers "ok" as SERVING, anything else NotFound - logging
+// through the request-scoped logger - and streams two updates on Watch.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+// Check implements the health service.
+func (healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if l, ok := sypl.FromContext(ctx); ok {
+		l.Infoln("inside handler")
+	}
+
+	if req.GetService() != "ok" {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// Watch implements the health service.
+func (healthServer) Watch(_ *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	if _, ok := sypl.FromContext(stream.Context()); !ok {
+		return status.Error(codes.Internal, "no request-scoped logger")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setup starts an in-process server, and returns a connected client, and the
+// server, and client loggers' recorders.
+func setup(t *testing.T, opts ...Option) (healthpb.HealthClient, *output.RecorderOutput, *output.RecorderOutput) {
+	t.Helper()
+
+	serverRec, serverOutput := output.Recorder(level.Trace)
+	clientRec, clientOutput := output.Recorder(level.Trace)
+
+	serverLogger := sypl.New("server", serverOutput)
+	clientLogger := sypl.New("client", clientOutput)
+
+	lis := bufconn.Listen(1 << 20)
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(serverLogger, opts...)),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(serverLogger, opts...)),
+	)
+
+	healthpb.RegisterHealthServer(srv, healthServer{})
+
+	go func() { _ = srv.Serve(lis) }()
+
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(clientLogger, opts...)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(clientLogger, opts...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn), serverRec, clientRec
+}
+
+// waitForRecords waits until `rec` has `n` records.
+func waitForRecords(t *testing.T, rec *output.RecorderOutput, n int) []output.Record {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for rec.Len() < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	records := rec.Messages()
+
+	if len(records) != n {
+		t.Fatalf("expected %d records, got %d", n, len(records))
+	}
+
+	return records
+}
+
+func TestUnary(t *testing.T) {
+	client, serverRec, clientRec := setup(t, WithMetadataKeys("X-Request-Id"))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Handler entry, and the call entry - both carrying the request-scoped
+	// fields.
+	serverRecords := waitForRecords(t, serverRec, 2)
+
+	for _, r := range serverRecords {
+		if r.Fields[FieldMethod] != checkMethod || r.Fields["x-request-id"] != "req-1" || r.Fields[FieldPeer] == nil {
+			t.Errorf("%q: fields = %v", r.OriginalContent, r.Fields)
+		}
+	}
+
+	call := serverRecords[1]
+
+	if call.Level != level.Info || call.Fields[FieldCode] != "OK" ||
+		call.Fields[FieldMsgsReceived] != 1 || call.Fields[FieldMsgsSent] != 1 {
+		t.Errorf("server call record = %+v", call)
+	}
+
+	if _, ok := call.Fields[FieldDuration].(time.Duration); !ok {
+		t.Errorf("duration = %T, want time.Duration", call.Fields[FieldDuration])
+	}
+
+	c := waitForRecords(t, clientRec, 1)[0]
+
+	if c.Fields[FieldCode] != "OK" || c.Fields["x-request-id"] != "req-1" || c.Fields[FieldPeer] == nil {
+		t.Errorf("client record = %+v", c)
+	}
+}
+
+func TestUnary_ErrorCode(t *testing.T) {
+	client, serverRec, clientRec := setup(t, WithLevelFunc(func(code codes.Code) level.Level {
+		if code == codes.NotFound {
+			return level.Warn
+		}
+
+		return CodeToLevel(code)
+	}))
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "nope"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("err = %v, want NotFound", err)
+	}
+
+	for _, r := range []output.Record{waitForRecords(t, serverRec, 2)[1], waitForRecords(t, clientRec, 1)[0]} {
+		if r.Level != level.Warn ||
+			r.Fields[FieldCode] != "NotFound" ||
+			r.Fields[FieldError] != "unknown service" ||
+			r.Fields[FieldMsgsSent] == r.Fields[FieldMsgsReceived] {
+			t.Errorf("record = %+v", r)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	client, serverRec, clientRec := setup(t)
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "ok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+
+	s := waitForRecords(t, serverRec, 1)[0]
+
+	if s.Fields[FieldMethod] != watchMethod ||
+		s.Fields[FieldCode] != "OK" ||
+		s.Fields[FieldMsgsReceived] != 1 ||
+		s.Fields[FieldMsgsSent] != 2 {
+		t.Errorf("server record = %+v", s)
+	}
+
+	c := waitForRecords(t, clientRec, 1)[0]
+
+	if c.Fields[FieldCode] != "OK" || c.Fields[FieldMsgsReceived] != 2 || c.Fields[FieldMsgsSent] != 1 {
+		t.Errorf("client record = %+v", c)
+	}
+}
+
+func TestClient_ContextLogger(t *testing.T) {
+	client, _, clientRec := setup(t)
+
+	rec, o := output.Recorder(level.Trace)
+
+	ctx := sypl.NewContext(context.Background(), sypl.New("ctx", o))
+
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForRecords(t, rec, 1)
+
+	if clientRec.Len() != 0 {
+		t.Errorf("fallback logger got %d records, want 0", clientRec.Len())
+	}
+}
+
+func TestCodeToLevel(t *testing.T) {
+	tests := map[codes.Code]level.Level{
+		codes.OK:               level.Info,
+		codes.NotFound:         level.Info,
+		codes.DeadlineExceeded: level.Warn,
+		codes.Unavailable:      level.Warn,
+		codes.Internal:         level.Error,
+		codes.Code(999):        level.Error,
+	}
+
+	for code, want := range tests {
+		if got := CodeToLevel(code); got != want {
+			t.Errorf("CodeToLevel(%s) = %s, want %s", code, got, want)
+		}
+	}
+}
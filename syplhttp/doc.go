@@ -0,0 +1,56 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package syplhttp provides net/http integrations built on sypl, using only
+// the standard library.
+//
+// # Server access log
+//
+// `Middleware` (and `Handler`) log every served request through a sypl
+// logger, with the following structured fields:
+//
+//	field       value
+//	----------  --------------------------------------------------------
+//	method      request method
+//	path        request URL path
+//	status      response status code - 200 when never written explicitly
+//	bytes       response body bytes written
+//	latency     time.Duration from the request start, to handler return
+//	remote_ip   client IP - see `WithTrustProxyHeaders`
+//	user_agent  request User-Agent
+//	request_id  the inbound request ID header, or a freshly generated one
+//
+// Entries are tagged with `formatter.TagHTTPAccessLog`, so `formatter.GCP`
+// promotes them to Cloud Logging's `httpRequest`.
+//
+// Each request gets a request-scoped child logger - `l.With` carrying the
+// request ID - injected via `sypl.NewContextWith`, so handlers retrieve it
+// with `sypl.FromContext`, or `sypl.FromContextOrDefault`. It's derived on
+// first retrieval: requests never logging don't pay for it. The request ID
+// is echoed back in the response header.
+//
+// A panicking handler - its panic resuming afterwards - is logged as a 500
+// @ Error, never sampled out. Pair with `sypl.RecoverHandler` (inside) for a
+// 500 answer, and a logged panic. Deliberate aborts - `http.ErrAbortHandler`
+// panics - aren't logged.
+//
+// The level is chosen by status class (5xx: Error, 4xx: Warn, others:
+// Info) - see `WithLevelFunc`. Successful (< 400) requests can be sampled -
+// see `WithSuccessSampling` - failures are always logged. The message
+// content defaults to a short "METHOD path status" summary; `WithFormat`
+// switches it to the Common, or Combined Log Format line - the fields are
+// attached regardless.
+//
+// # Client transport
+//
+// `Transport` is the outbound mirror: an `http.RoundTripper` logging the
+// method, URL - query values redacted, but allow-listed ones - status,
+// latency, and error of every request. It doesn't retry: outer retriers tag
+// each attempt via `WithAttempt`, and the attempt number is logged. Headers
+// are only logged when allow-listed, and bodies only when capture is
+// enabled - truncated to N bytes, the server, and the caller still reading
+// them whole. Entries go
+// through the request context's logger, when present - e.g. the one injected
+// by `Middleware` - so outbound calls carry the inbound request ID.
+package syplhttp
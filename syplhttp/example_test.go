@@ -0,0 +1,38 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplhttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/syplhttp"
+)
+
+// ExampleMiddleware demonstrates access logging, and the request-scoped
+// logger available to handlers.
+func ExampleMiddleware() {
+	l := sypl.New("api", output.Console(level.Info))
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		// Carries the request ID.
+		sypl.FromContextOrDefault(r.Context(), l).Infoln("saying hello")
+
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	h := syplhttp.Middleware(l)(mux)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	// Output:
+	// saying hello
+	// GET /hello 200
+}
@@ -0,0 +1,382 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplhttp
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/shared"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// DefaultRequestIDHeader is the default request ID header - read from the
+// request, and echoed back in the response.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// Access log field names.
+const (
+	FieldBytes     = "bytes"
+	FieldLatency   = "latency"
+	FieldMethod    = "method"
+	FieldPath      = "path"
+	FieldRemoteIP  = "remote_ip"
+	FieldRequestID = "request_id"
+	FieldStatus    = "status"
+	FieldUserAgent = "user_agent"
+)
+
+// clfTimeFormat is the Common Log Format timestamp layout.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// Format determines the access log message content.
+type Format int
+
+// Available formats.
+const (
+	// FormatStructured is a short "METHOD path status" summary - details
+	// live in the fields. It's the default.
+	FormatStructured Format = iota
+
+	// FormatCommon is the Common Log Format (CLF) line:
+	// `host ident authuser [date] "request" status bytes`.
+	FormatCommon
+
+	// FormatCombined is the Combined Log Format line: CLF plus the quoted
+	// referer, and user agent.
+	FormatCombined
+)
+
+// String interface implementation.
+func (f Format) String() string {
+	switch f {
+	case FormatStructured:
+		return "Structured"
+	case FormatCommon:
+		return "Common"
+	case FormatCombined:
+		return "Combined"
+	default:
+		return "Unknown"
+	}
+}
+
+// serverConfig is the access log middleware optional configuration.
+type serverConfig struct {
+	// format of the message content.
+	format Format
+
+	// levelFn chooses the level from the response status.
+	levelFn func(status int) level.Level
+
+	// requestIDGen generates request IDs for requests carrying none.
+	requestIDGen func() string
+
+	// requestIDHeader is the request ID header name.
+	requestIDHeader string
+
+	// sampleEvery logs one in every `sampleEvery` successful requests.
+	// `<= 1` logs all.
+	sampleEvery uint64
+
+	// trustProxyHeaders derives the remote IP from proxy headers.
+	trustProxyHeaders bool
+}
+
+// Option allows to specify optional access log middleware configuration.
+type Option func(*serverConfig)
+
+// WithFormat sets the message content format. Default: `FormatStructured`.
+func WithFormat(f Format) Option {
+	return func(cfg *serverConfig) {
+		cfg.format = f
+	}
+}
+
+// WithLevelFunc sets the function choosing the level from the response
+// status. Default: `LevelByStatus`.
+//
+// NOTE: Returning `level.Fatal` exits the process - sypl's Fatal contract.
+func WithLevelFunc(fn func(status int) level.Level) Option {
+	return func(cfg *serverConfig) {
+		if fn != nil {
+			cfg.levelFn = fn
+		}
+	}
+}
+
+// WithRequestIDHeader sets the request ID header name. Default:
+// `DefaultRequestIDHeader`.
+func WithRequestIDHeader(name string) Option {
+	return func(cfg *serverConfig) {
+		if name != "" {
+			cfg.requestIDHeader = name
+		}
+	}
+}
+
+// WithRequestIDGenerator sets the generator used for requests carrying no
+// request ID. Default: UUIDv4.
+func WithRequestIDGenerator(gen func() string) Option {
+	return func(cfg *serverConfig) {
+		if gen != nil {
+			cfg.requestIDGen = gen
+		}
+	}
+}
+
+// WithSuccessSampling logs only one in every `every` successful (status
+// below 400) requests - the first one included. Failures are always
+// logged. `every <= 1` logs all - the default.
+func WithSuccessSampling(every uint64) Option {
+	return func(cfg *serverConfig) {
+		cfg.sampleEvery = every
+	}
+}
+
+// WithTrustProxyHeaders derives the remote IP from the first
+// `X-Forwarded-For` entry, or `X-Real-IP` - in this order - falling back to
+// the connection's address. Only enable it behind a proxy you control: the
+// headers are client-controlled otherwise.
+func WithTrustProxyHeaders() Option {
+	return func(cfg *serverConfig) {
+		cfg.trustProxyHeaders = true
+	}
+}
+
+//////
+// Helpers.
+//////
+
+// LevelByStatus is the default status-to-level mapping: 5xx is Error, 4xx is
+// Warn, anything else is Info.
+func LevelByStatus(status int) level.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return level.Error
+	case status >= http.StatusBadRequest:
+		return level.Warn
+	default:
+		return level.Info
+	}
+}
+
+// remoteIP returns the client IP - see `WithTrustProxyHeaders`.
+func remoteIP(r *http.Request, trustProxyHeaders bool) string {
+	if trustProxyHeaders {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+
+			return strings.TrimSpace(first)
+		}
+
+		if xri := r.Header.Get("X-Real-IP"); xri != "" {
+			return strings.TrimSpace(xri)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// dashIfEmpty returns "-" - CLF's placeholder - for empty values.
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// clfLine renders the Common, or - when `combined` - Combined Log Format
+// line.
+func clfLine(r *http.Request, ip string, start time.Time, status, bytes int, combined bool) string {
+	user := ""
+
+	if r.URL.User != nil {
+		user = r.URL.User.Username()
+	} else if u, _, ok := r.BasicAuth(); ok {
+		user = u
+	}
+
+	size := "-"
+
+	if bytes > 0 {
+		size = fmt.Sprint(bytes)
+	}
+
+	line := fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+		dashIfEmpty(ip),
+		dashIfEmpty(user),
+		start.Format(clfTimeFormat),
+		r.Method,
+		r.RequestURI,
+		r.Proto,
+		status,
+		size,
+	)
+
+	if combined {
+		line = fmt.Sprintf(`%s "%s" "%s"`, line, dashIfEmpty(r.Referer()), dashIfEmpty(r.UserAgent()))
+	}
+
+	return line
+}
+
+//////
+// Middleware.
+//////
+
+// accessLogger is the access log middleware state.
+type accessLogger struct {
+	cfg serverConfig
+
+	logger *sypl.Sypl
+
+	// successes counts successful requests - sampling.
+	successes atomic.Uint64
+}
+
+// sampledOut determines whether a request with the given status is dropped
+// by success sampling.
+func (a *accessLogger) sampledOut(status int) bool {
+	if a.cfg.sampleEvery <= 1 || status >= http.StatusBadRequest {
+		return false
+	}
+
+	return (a.successes.Add(1)-1)%a.cfg.sampleEvery != 0
+}
+
+// wrap returns `next` wrapped with access logging.
+func (a *accessLogger) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(a.cfg.requestIDHeader)
+
+		if requestID == "" {
+			requestID = a.cfg.requestIDGen()
+		}
+
+		w.Header().Set(a.cfg.requestIDHeader, requestID)
+
+		rw := &responseWriter{ResponseWriter: w}
+
+		// Logs even if `next` panics - the panic then resumes. Pair with
+		// `sypl.RecoverHandler` (inside) for a 500 answer, and a logged
+		// panic.
+		defer func() {
+			if p := recover(); p != nil {
+				// A deliberate abort - e.g. `httputil.ReverseProxy` on a client
+				// disconnection - isn't a server error: not logged.
+				if p == http.ErrAbortHandler { //nolint:errorlint // Identity check - exactly as net/http does.
+					panic(p)
+				}
+
+				// Unanswered: net/http aborts the response. Logged as a 500 @
+				// Error - never sampled out.
+				a.log(r, rw, start, requestID, http.StatusInternalServerError, level.Error)
+
+				panic(p)
+			}
+
+			status := rw.statusCode()
+
+			if a.sampledOut(status) {
+				return
+			}
+
+			a.log(r, rw, start, requestID, status, a.cfg.levelFn(status))
+		}()
+
+		// The request-scoped logger is only derived if retrieved.
+		ctx := sypl.NewContextWith(r.Context(), a.logger, fields.Fields{FieldRequestID: requestID})
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
+
+// log emits the access log entry.
+func (a *accessLogger) log(
+	r *http.Request,
+	rw *responseWriter,
+	start time.Time,
+	requestID string,
+	status int,
+	lvl level.Level,
+) {
+	ip := remoteIP(r, a.cfg.trustProxyHeaders)
+
+	f := fields.Fields{
+		FieldBytes:     rw.bytes,
+		FieldLatency:   time.Since(start),
+		FieldMethod:    r.Method,
+		FieldPath:      r.URL.Path,
+		FieldRemoteIP:  ip,
+		FieldRequestID: requestID,
+		FieldStatus:    status,
+		FieldUserAgent: r.UserAgent(),
+	}
+
+	var content string
+
+	switch a.cfg.format {
+	case FormatCommon:
+		content = clfLine(r, ip, start, status, rw.bytes, false)
+	case FormatCombined:
+		content = clfLine(r, ip, start, status, rw.bytes, true)
+	case FormatStructured:
+		fallthrough
+	default:
+		content = fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, status)
+	}
+
+	a.logger.PrintlnWithOptions(lvl, content, sypl.WithFields(f), sypl.WithTags(formatter.TagHTTPAccessLog))
+}
+
+//////
+// Factory.
+//////
+
+// Middleware returns an access log middleware logging every request served
+// by the wrapped handler through `l`. See the package documentation for the
+// fields, and the options for levels, sampling, and formats.
+func Middleware(l *sypl.Sypl, opts ...Option) func(http.Handler) http.Handler {
+	a := &accessLogger{
+		cfg: serverConfig{
+			format:          FormatStructured,
+			levelFn:         LevelByStatus,
+			requestIDGen:    shared.GenerateUUID,
+			requestIDHeader: DefaultRequestIDHeader,
+		},
+		logger: l,
+	}
+
+	for _, opt := range opts {
+		opt(&a.cfg)
+	}
+
+	return a.wrap
+}
+
+// Handler wraps `next` with the access log middleware - see `Middleware`.
+func Handler(l *sypl.Sypl, next http.Handler, opts ...Option) http.Handler {
+	return Middleware(l, opts...)(next)
+}
@@ -0,0 +1,310 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/formatter"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+)
+
+// serve runs `req` through `h` wrapped with the middleware, returning the
+// recorded response, and the recorder output.
+func serve(
+	t *testing.T,
+	h http.Handler,
+	req *http.Request,
+	opts ...Option,
+) (*httptest.ResponseRecorder, *output.RecorderOutput) {
+	t.Helper()
+
+	rec, o := output.Recorder(level.Trace)
+
+	l := sypl.New("http", o)
+
+	w := httptest.NewRecorder()
+
+	Handler(l, h, opts...).ServeHTTP(w, req)
+
+	return w, rec
+}
+
+func TestMiddleware_Fields(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders?id=1", nil)
+
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set(DefaultRequestIDHeader, "req-1")
+
+	w, rec := serve(t, h, req)
+
+	if w.Header().Get(DefaultRequestIDHeader) != "req-1" {
+		t.Errorf("response request ID = %q, want req-1", w.Header().Get(DefaultRequestIDHeader))
+	}
+
+	records := rec.Messages()
+
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	r := records[0]
+
+	if r.Level != level.Info {
+		t.Errorf("Level = %s, want info", r.Level)
+	}
+
+	if r.OriginalContent != "POST /orders 201\n" {
+		t.Errorf("OriginalContent = %q", r.OriginalContent)
+	}
+
+	want := map[string]interface{}{
+		FieldBytes:     5,
+		FieldMethod:    http.MethodPost,
+		FieldPath:      "/orders",
+		FieldRemoteIP:  "192.0.2.1",
+		FieldRequestID: "req-1",
+		FieldStatus:    http.StatusCreated,
+		FieldUserAgent: "test-agent",
+	}
+
+	for k, v := range want {
+		if r.Fields[k] != v {
+			t.Errorf("field %s = %v, want %v", k, r.Fields[k], v)
+		}
+	}
+
+	if _, ok := r.Fields[FieldLatency].(time.Duration); !ok {
+		t.Errorf("latency field = %T, want time.Duration", r.Fields[FieldLatency])
+	}
+
+	if !slices.Contains(r.Tags, formatter.TagHTTPAccessLog) {
+		t.Errorf("Tags = %v, want %q", r.Tags, formatter.TagHTTPAccessLog)
+	}
+}
+
+func TestMiddleware_ContextLogger(t *testing.T) {
+	h := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		l, ok := sypl.FromContext(r.Context())
+		if !ok {
+			t.Fatal("expected a request-scoped logger in the context")
+		}
+
+		l.Infoln("inside handler")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	_, rec := serve(t, h, req, WithRequestIDGenerator(func() string { return "generated" }))
+
+	records := rec.Messages()
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	for _, r := range records {
+		if r.Fields[FieldRequestID] != "generated" {
+			t.Errorf("%q: request_id = %v, want generated", r.OriginalContent, r.Fields[FieldRequestID])
+		}
+	}
+}
+
+func TestMiddleware_LevelByStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   level.Level
+	}{
+		{http.StatusOK, level.Info},
+		{http.StatusFound, level.Info},
+		{http.StatusNotFound, level.Warn},
+		{http.StatusServiceUnavailable, level.Error},
+	}
+
+	for _, tt := range tests {
+		h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(tt.status)
+		})
+
+		_, rec := serve(t, h, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if records := rec.Messages(); len(records) != 1 || records[0].Level != tt.want {
+			t.Errorf("status %d: records = %+v, want one @ %s", tt.status, records, tt.want)
+		}
+	}
+}
+
+func TestMiddleware_SuccessSampling(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	status := http.StatusOK
+
+	h := Handler(sypl.New("http", o), http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}), WithSuccessSampling(3))
+
+	for i := 0; i < 6; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	if rec.Len() != 2 {
+		t.Errorf("sampled successes = %d, want 2", rec.Len())
+	}
+
+	status = http.StatusInternalServerError
+
+	for i := 0; i < 3; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	if rec.Len() != 5 {
+		t.Errorf("records = %d, want 5 - failures are never sampled", rec.Len())
+	}
+}
+
+// A panicking handler is logged as a 500 @ Error - even when sampling would
+// drop its success status - and the panic resumes.
+func TestMiddleware_Panic(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	h := Handler(sypl.New("http", o), http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler boom")
+	}), WithSuccessSampling(100))
+
+	for i := 0; i < 2; i++ {
+		var recovered any
+
+		func() {
+			defer func() { recovered = recover() }()
+
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+
+		if recovered != "handler boom" {
+			t.Errorf("recovered = %v, want the handler's panic", recovered)
+		}
+	}
+
+	records := rec.Messages()
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	for _, r := range records {
+		if r.Level != level.Error || r.Fields[FieldStatus] != http.StatusInternalServerError {
+			t.Errorf("got %s, status %v - want error, 500", r.Level, r.Fields[FieldStatus])
+		}
+	}
+}
+
+// A deliberate abort - `http.ErrAbortHandler` - resumes unlogged: it's no
+// server error.
+func TestMiddleware_ErrAbortHandler(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	h := Handler(sypl.New("http", o), http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	var recovered any
+
+	func() {
+		defer func() { recovered = recover() }()
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if recovered != http.ErrAbortHandler { //nolint:errorlint // Identity check.
+		t.Errorf("recovered = %v, want http.ErrAbortHandler", recovered)
+	}
+
+	if rec.Len() != 0 {
+		t.Errorf("records = %d, want 0", rec.Len())
+	}
+}
+
+func TestMiddleware_Formats(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("abc"))
+	})
+
+	tests := []struct {
+		format Format
+		want   *regexp.Regexp
+	}{
+		{
+			FormatCommon,
+			regexp.MustCompile(`^10\.0\.0\.1 - bob \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /a\?b=c HTTP/1\.1" 200 3\n$`),
+		},
+		{
+			FormatCombined,
+			regexp.MustCompile(`^10\.0\.0\.1 - bob \[.+\] "GET /a\?b=c HTTP/1\.1" 200 3 "http://ref" "agent"\n$`),
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/a?b=c", nil)
+
+		req.SetBasicAuth("bob", "secret")
+		req.Header.Set("Referer", "http://ref")
+		req.Header.Set("User-Agent", "agent")
+		req.Header.Set("X-Forwarded-For", "10.0.0.1, 172.16.0.1")
+
+		_, rec := serve(t, h, req, WithFormat(tt.format), WithTrustProxyHeaders())
+
+		records := rec.Messages()
+
+		if len(records) != 1 || !tt.want.MatchString(records[0].OriginalContent) {
+			t.Errorf("%s: records = %+v", tt.format, records)
+		}
+	}
+}
+
+func TestMiddleware_ProxyHeadersIgnoredByDefault(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+
+	_, rec := serve(t, http.NotFoundHandler(), req)
+
+	if records := rec.Messages(); len(records) != 1 || records[0].Fields[FieldRemoteIP] != "192.0.2.1" {
+		t.Errorf("records = %+v, want remote_ip 192.0.2.1", records)
+	}
+}
+
+func TestResponseWriter_Flusher(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("wrapped writer doesn't implement http.Flusher")
+		}
+
+		f.Flush()
+	})
+
+	w, rec := serve(t, h, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !w.Flushed {
+		t.Error("expected the underlying writer to be flushed")
+	}
+
+	if records := rec.Messages(); len(records) != 1 || records[0].Fields[FieldStatus] != http.StatusOK {
+		t.Errorf("records = %+v", records)
+	}
+}
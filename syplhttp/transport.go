@@ -0,0 +1,357 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplhttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// RedactedValue replaces redacted query parameter values.
+const RedactedValue = "REDACTED"
+
+// Outbound request log field names - `FieldMethod`, `FieldStatus`, and
+// `FieldLatency` are shared with the access log.
+const (
+	FieldAttempt         = "attempt"
+	FieldError           = "error"
+	FieldRequestBody     = "request_body"
+	FieldRequestHeaders  = "request_headers"
+	FieldResponseBody    = "response_body"
+	FieldResponseHeaders = "response_headers"
+	FieldURL             = "url"
+)
+
+// transportConfig is the client transport optional configuration.
+type transportConfig struct {
+	// allowedHeaders are the canonical names of the logged headers.
+	allowedHeaders map[string]struct{}
+
+	// allowedQueryParams are the query parameters logged in clear.
+	allowedQueryParams map[string]struct{}
+
+	// bodyLimit is the body capture size. `<= 0` disables it.
+	bodyLimit int
+
+	// levelFn chooses the level from the outcome.
+	levelFn func(status int, err error) level.Level
+}
+
+// TransportOption allows to specify optional client transport configuration.
+type TransportOption func(*transportConfig)
+
+// TransportWithAllowedHeaders logs the given request, and response headers -
+// multiple values comma-joined. Default: no header is logged, they often
+// carry credentials.
+func TransportWithAllowedHeaders(names ...string) TransportOption {
+	return func(cfg *transportConfig) {
+		for _, name := range names {
+			cfg.allowedHeaders[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+	}
+}
+
+// TransportWithAllowedQueryParams logs the given query parameters in clear.
+// Default: every query value is replaced by `RedactedValue` - keys are kept.
+//
+// NOTE: The URL password - if any - is always redacted.
+func TransportWithAllowedQueryParams(names ...string) TransportOption {
+	return func(cfg *transportConfig) {
+		for _, name := range names {
+			cfg.allowedQueryParams[name] = struct{}{}
+		}
+	}
+}
+
+// TransportWithBodyCapture logs up to `limit` bytes of the request, and
+// response bodies. The bodies are rebuilt, so the server, and the caller
+// still read them whole.
+//
+// NOTE: The response body capture reads - up to `limit` bytes - BEFORE
+// `RoundTrip` returns, so streaming responses are delayed until `limit`
+// bytes, or EOF arrive.
+func TransportWithBodyCapture(limit int) TransportOption {
+	return func(cfg *transportConfig) {
+		cfg.bodyLimit = limit
+	}
+}
+
+// TransportWithLevelFunc sets the function choosing the level from the
+// outcome - `status` is 0 when `err` is set. Default: `err` is Error,
+// otherwise `LevelByStatus`.
+//
+// NOTE: Returning `level.Fatal` exits the process - sypl's Fatal contract.
+func TransportWithLevelFunc(fn func(status int, err error) level.Level) TransportOption {
+	return func(cfg *transportConfig) {
+		if fn != nil {
+			cfg.levelFn = fn
+		}
+	}
+}
+
+//////
+// Attempts.
+//
+// `Transport` logs - it doesn't retry. Retries belong to an outer retrier
+// (e.g. a retrying client wrapper), which tags each attempt's request
+// context via `WithAttempt`: every attempt is then logged with its number.
+//////
+
+// attemptKey is the context key of the attempt number - see `WithAttempt`.
+type attemptKey struct{}
+
+// WithAttempt returns a copy of `ctx` carrying the attempt number of the
+// request - 1 for the first attempt, 2 for the first retry, and so on.
+// `Transport` doesn't retry: outer retriers set it on each attempt's request
+// context, and the transport logs it.
+func WithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns the attempt number carried by `ctx` - see
+// `WithAttempt` - and whether one was found.
+func AttemptFromContext(ctx context.Context) (int, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+
+	attempt, ok := ctx.Value(attemptKey{}).(int)
+
+	return attempt, ok
+}
+
+//////
+// Helpers.
+//////
+
+// defaultTransportLevel is the default outcome-to-level mapping.
+func defaultTransportLevel(status int, err error) level.Level {
+	if err != nil {
+		return level.Error
+	}
+
+	return LevelByStatus(status)
+}
+
+// redactURL returns `u` as a string, with its password, and the query values
+// not in `allowed` redacted.
+func redactURL(u *url.URL, allowed map[string]struct{}) string {
+	redacted := *u
+
+	if redacted.RawQuery != "" {
+		q := redacted.Query()
+
+		for k, vs := range q {
+			if _, ok := allowed[k]; ok {
+				continue
+			}
+
+			for i := range vs {
+				vs[i] = RedactedValue
+			}
+		}
+
+		redacted.RawQuery = q.Encode()
+	}
+
+	return redacted.Redacted()
+}
+
+// allowedHeaders returns the allow-listed headers of `h`.
+func allowedHeaders(h http.Header, allowed map[string]struct{}) map[string]string {
+	filtered := map[string]string{}
+
+	for name, values := range h {
+		if _, ok := allowed[name]; ok {
+			filtered[name] = strings.Join(values, ", ")
+		}
+	}
+
+	return filtered
+}
+
+// errReader always fails with `err`.
+type errReader struct{ err error }
+
+// Read implements `io.Reader`.
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// readCloser combines a reader, and the original body's closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureBody reads up to `limit` bytes of `body`, returning them, and a
+// replacement body yielding the full original content - a read error is
+// deferred to the replacement's reader.
+func captureBody(body io.ReadCloser, limit int) ([]byte, io.ReadCloser) {
+	captured, err := io.ReadAll(io.LimitReader(body, int64(limit)))
+
+	var rest io.Reader = body
+
+	if err != nil {
+		rest = errReader{err: err}
+	}
+
+	return captured, readCloser{
+		Reader: io.MultiReader(bytes.NewReader(captured), rest),
+		Closer: body,
+	}
+}
+
+//////
+// RoundTripper.
+//////
+
+// transport is the logging `http.RoundTripper`.
+type transport struct {
+	base http.RoundTripper
+
+	cfg transportConfig
+
+	logger *sypl.Sypl
+}
+
+// RoundTrip implements `http.RoundTripper`.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	var reqBody []byte
+
+	sent := req
+
+	// The caller's request is never modified - a clone carries the rebuilt
+	// body.
+	if t.cfg.bodyLimit > 0 && req.Body != nil && req.Body != http.NoBody {
+		sent = req.Clone(req.Context())
+
+		reqBody, sent.Body = captureBody(req.Body, t.cfg.bodyLimit)
+	}
+
+	resp, err := t.base.RoundTrip(sent)
+
+	var respBody []byte
+
+	if err == nil && t.cfg.bodyLimit > 0 {
+		respBody, resp.Body = captureBody(resp.Body, t.cfg.bodyLimit)
+	}
+
+	t.log(req, resp, err, start, reqBody, respBody)
+
+	return resp, err
+}
+
+// log emits the outbound request entry through the context logger, if any,
+// otherwise the transport's one.
+func (t *transport) log(
+	req *http.Request,
+	resp *http.Response,
+	err error,
+	start time.Time,
+	reqBody, respBody []byte,
+) {
+	l := sypl.FromContextOrDefault(req.Context(), t.logger)
+
+	if l == nil {
+		return
+	}
+
+	u := redactURL(req.URL, t.cfg.allowedQueryParams)
+
+	f := fields.Fields{
+		FieldLatency: time.Since(start),
+		FieldMethod:  req.Method,
+		FieldURL:     u,
+	}
+
+	if attempt, ok := AttemptFromContext(req.Context()); ok {
+		f[FieldAttempt] = attempt
+	}
+
+	if len(t.cfg.allowedHeaders) > 0 {
+		f[FieldRequestHeaders] = allowedHeaders(req.Header, t.cfg.allowedHeaders)
+	}
+
+	if t.cfg.bodyLimit > 0 {
+		f[FieldRequestBody] = string(reqBody)
+	}
+
+	status := 0
+
+	var content string
+
+	if err != nil {
+		f[FieldError] = err.Error()
+
+		content = fmt.Sprintf("%s %s: %s", req.Method, u, err)
+	} else {
+		status = resp.StatusCode
+
+		f[FieldStatus] = status
+
+		if len(t.cfg.allowedHeaders) > 0 {
+			f[FieldResponseHeaders] = allowedHeaders(resp.Header, t.cfg.allowedHeaders)
+		}
+
+		if t.cfg.bodyLimit > 0 {
+			f[FieldResponseBody] = string(respBody)
+		}
+
+		content = fmt.Sprintf("%s %s %d", req.Method, u, status)
+	}
+
+	l.PrintlnWithOptions(t.cfg.levelFn(status, err), content, sypl.WithFields(f))
+}
+
+//////
+// Factory.
+//////
+
+// Transport returns an `http.RoundTripper` logging every outbound request
+// sent through `base` - `http.DefaultTransport` if nil - with the method,
+// URL (query values redacted), status, latency, error, and - when carried
+// by the request context, see `WithAttempt` - attempt number as fields.
+// Entries are emitted through the request context's logger - see
+// `sypl.NewContext` - falling back to `l`; with neither, nothing is logged.
+//
+// Usage:
+//
+//	client := &http.Client{Transport: syplhttp.Transport(nil, l)}
+func Transport(base http.RoundTripper, l *sypl.Sypl, opts ...TransportOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &transport{
+		base: base,
+		cfg: transportConfig{
+			allowedHeaders:     map[string]struct{}{},
+			allowedQueryParams: map[string]struct{}{},
+			levelFn:            defaultTransportLevel,
+		},
+		logger: l,
+	}
+
+	for _, opt := range opts {
+		opt(&t.cfg)
+	}
+
+	return t
+}
@@ -0,0 +1,237 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+)
+
+var errDialBoom = errors.New("dial boom")
+
+// roundTripFunc adapts a function to `http.RoundTripper`.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements `http.RoundTripper`.
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// singleRecord returns the single record `rec` captured, failing the test
+// otherwise.
+func singleRecord(t *testing.T, rec *output.RecorderOutput) output.Record {
+	t.Helper()
+
+	records := rec.Messages()
+
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	return records[0]
+}
+
+func TestTransport_FieldsAndRedaction(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	rec, o := output.Recorder(level.Trace)
+
+	client := &http.Client{
+		Transport: Transport(nil, sypl.New("client", o), TransportWithAllowedQueryParams("page")),
+	}
+
+	resp, err := client.Get(srv.URL + "/items?page=2&token=s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	r := singleRecord(t, rec)
+
+	if r.Level != level.Warn {
+		t.Errorf("Level = %s, want warn", r.Level)
+	}
+
+	wantURL := srv.URL + "/items?page=2&token=" + RedactedValue
+
+	if r.Fields[FieldURL] != wantURL {
+		t.Errorf("url = %v, want %s", r.Fields[FieldURL], wantURL)
+	}
+
+	if strings.Contains(r.OriginalContent, "s3cr3t") {
+		t.Errorf("content leaks the token: %q", r.OriginalContent)
+	}
+
+	if r.Fields[FieldStatus] != http.StatusNotFound {
+		t.Errorf("fields = %v", r.Fields)
+	}
+
+	if _, ok := r.Fields[FieldAttempt]; ok {
+		t.Error("attempt must only be logged when set by an outer retrier")
+	}
+
+	if _, ok := r.Fields[FieldRequestHeaders]; ok {
+		t.Error("headers must not be logged by default")
+	}
+}
+
+func TestTransport_HeadersAndBodyCapture(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Set-Cookie", "session=secret")
+
+		_, _ = w.Write([]byte("echo:" + string(body)))
+	}))
+	defer srv.Close()
+
+	rec, o := output.Recorder(level.Trace)
+
+	client := &http.Client{
+		Transport: Transport(nil, sypl.New("client", o),
+			TransportWithAllowedHeaders("content-type"),
+			TransportWithBodyCapture(4),
+		),
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("0123456789"))
+
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+
+	resp.Body.Close()
+
+	// The capture is transparent - both ends got the whole body.
+	if string(body) != "echo:0123456789" {
+		t.Errorf("response body = %q", body)
+	}
+
+	r := singleRecord(t, rec)
+
+	if r.Fields[FieldRequestBody] != "0123" || r.Fields[FieldResponseBody] != "echo" {
+		t.Errorf("captured bodies = %q, %q", r.Fields[FieldRequestBody], r.Fields[FieldResponseBody])
+	}
+
+	reqHeaders, _ := r.Fields[FieldRequestHeaders].(map[string]string)
+
+	if len(reqHeaders) != 1 || reqHeaders["Content-Type"] != "text/plain" {
+		t.Errorf("request headers = %v", reqHeaders)
+	}
+
+	respHeaders, _ := r.Fields[FieldResponseHeaders].(map[string]string)
+
+	if len(respHeaders) != 1 || respHeaders["Content-Type"] != "text/plain" {
+		t.Errorf("response headers = %v", respHeaders)
+	}
+}
+
+// The transport never retries: an outer retrier's attempts are each logged,
+// with their number.
+func TestTransport_Attempts(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rec, o := output.Recorder(level.Trace)
+
+	client := &http.Client{Transport: Transport(nil, sypl.New("client", o))}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(WithAttempt(context.Background(), attempt), http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
+	}
+
+	if n := calls.Load(); n != 3 {
+		t.Errorf("requests sent = %d, want 3", n)
+	}
+
+	records := rec.Messages()
+
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+
+	for i, r := range records {
+		if r.Fields[FieldAttempt] != i+1 {
+			t.Errorf("record %d: attempt = %v, want %d", i, r.Fields[FieldAttempt], i+1)
+		}
+	}
+
+	if records[2].Fields[FieldStatus] != http.StatusOK || records[2].Level != level.Info {
+		t.Errorf("last record = %+v", records[2])
+	}
+}
+
+func TestTransport_ErrorAndContextLogger(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	fallbackRec, fallback := output.Recorder(level.Trace)
+
+	base := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errDialBoom
+	})
+
+	client := &http.Client{Transport: Transport(base, sypl.New("fallback", fallback))}
+
+	ctx := sypl.NewContext(context.Background(), sypl.New("ctx", o))
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.invalid/", nil)
+
+	if _, err := client.Do(req); !errors.Is(err, errDialBoom) {
+		t.Fatalf("err = %v, want errDialBoom", err)
+	}
+
+	r := singleRecord(t, rec)
+
+	if r.Level != level.Error || r.Fields[FieldError] != errDialBoom.Error() {
+		t.Errorf("record = %+v", r)
+	}
+
+	if fallbackRec.Len() != 0 {
+		t.Errorf("fallback logger got %d records, want 0", fallbackRec.Len())
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syplhttp
+
+import "net/http"
+
+// responseWriter records the response status, and size.
+//
+// NOTE: Optional interfaces (e.g. `http.Hijacker`) are reachable through
+// `http.ResponseController`, via `Unwrap`. `http.Flusher` is implemented
+// directly - streaming handlers commonly type-assert it.
+type responseWriter struct {
+	http.ResponseWriter
+
+	// bytes written to the body.
+	bytes int
+
+	// status is the final status code - 0 while not written.
+	status int
+}
+
+// statusCode returns the response status - 200 when the handler never
+// wrote a header, as net/http does.
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+// WriteHeader implements `http.ResponseWriter`. Informational (1xx) headers
+// - but 101 Switching Protocols - aren't final, and don't set the status.
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 &&
+		(statusCode >= http.StatusOK || statusCode == http.StatusSwitchingProtocols) {
+		w.status = statusCode
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements `http.ResponseWriter`.
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+
+	w.bytes += n
+
+	return n, err
+}
+
+// Flush implements `http.Flusher` - a no-op when the underlying writer
+// can't flush.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to `http.ResponseController`.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -11,8 +11,6 @@ import (
 
 	"github.com/thalesfsp/sypl/v2"
 	"github.com/thalesfsp/sypl/v2/fields"
-	"github.com/thalesfsp/sypl/v2/message"
-	"github.com/thalesfsp/sypl/v2/status"
 )
 
 // groupKeySeparator joins group names, and attr keys into flattened field
@@ -59,25 +57,15 @@ func HandlerWithLevel(leveler slog.Leveler) HandlerOption {
 
 // Enabled reports whether the handler handles records at the given level:
 // the level must be at, or above the optional floor - see
-// `HandlerWithLevel` - and at least one ENABLED sypl output must accept the
-// mapped sypl level.
-//
-// NOTE: The `SYPL_LEVEL` env var - which overrides output max levels at
-// write time - isn't consulted here.
+// `HandlerWithLevel` - and the sypl logger must be enabled for the mapped
+// sypl level - see `sypl.Sypl.Enabled`: the `SYPL_LEVEL`, and `SYPL_FILTER`
+// env vars are honored - so slog callers skip the work too.
 func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
 	if h.leveler != nil && l < h.leveler.Level() {
 		return false
 	}
 
-	syplLevel := ToSyplLevel(l)
-
-	for _, o := range h.logger.GetOutputs() {
-		if o.GetStatus() == status.Enabled && syplLevel <= o.GetMaxLevel() {
-			return true
-		}
-	}
-
-	return false
+	return h.logger.Enabled(ToSyplLevel(l))
 }
 
 // Handle converts the record into a sypl message, and prints it through the
@@ -100,7 +88,7 @@ func (h *Handler) Handle(_ context.Context, r slog.Record) error {
 		content += "\n"
 	}
 
-	m := message.New(ToSyplLevel(r.Level), content)
+	m := h.logger.NewMessage(ToSyplLevel(r.Level), content)
 
 	m.SetTimestamp(r.Time)
 
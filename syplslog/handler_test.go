@@ -19,6 +19,7 @@ import (
 	"github.com/thalesfsp/sypl/v2/output"
 	"github.com/thalesfsp/sypl/v2/processor"
 	"github.com/thalesfsp/sypl/v2/safebuffer"
+	"github.com/thalesfsp/sypl/v2/shared"
 	"github.com/thalesfsp/sypl/v2/status"
 )
 
@@ -157,6 +158,31 @@ func TestHandler_EnabledFloor(t *testing.T) {
 	}
 }
 
+// The `SYPL_LEVEL`, and `SYPL_FILTER` env vars are honored - slog callers
+// skip the work too.
+func TestHandler_EnabledHonorsEnvVars(t *testing.T) {
+	t.Setenv(shared.LevelEnvVar, "")
+	t.Setenv(shared.FilterEnvVar, "")
+
+	l, _, _ := newRecorderLogger(level.Info)
+
+	h := NewHandler(l)
+
+	ctx := context.Background()
+
+	t.Setenv(shared.LevelEnvVar, "debug")
+
+	if !h.Enabled(ctx, slog.LevelDebug) {
+		t.Fatal("Debug must be enabled - SYPL_LEVEL raises the output's level")
+	}
+
+	t.Setenv(shared.FilterEnvVar, "other-component")
+
+	if h.Enabled(ctx, slog.LevelError) {
+		t.Fatal("Error must be disabled - SYPL_FILTER filters the component out")
+	}
+}
+
 //////
 // Handle tests.
 //////
@@ -29,7 +29,15 @@ const (
 // documentation for the mapping table. In-between levels map conservatively -
 // down to the nearest standard level - so they are MORE likely to be printed.
 // It never yields `level.Fatal` - sypl exits the process on `Fatal`.
+//
+// NOTE: Custom levels - see `level.Register` - take part: a slog level maps
+// to the level - built-in, or custom - with the highest slog counterpart at,
+// or below it.
 func ToSyplLevel(l slog.Level) level.Level {
+	if level.Generation() > 0 {
+		return toSyplLevelCustom(l)
+	}
+
 	switch {
 	case l < slog.LevelDebug:
 		return level.Trace
@@ -44,9 +52,35 @@ func ToSyplLevel(l slog.Level) level.Level {
 	}
 }
 
+// toSyplLevelCustom is `ToSyplLevel` when custom levels are registered.
+func toSyplLevelCustom(l slog.Level) level.Level {
+	// Sorted by verbosity - i.e. by descending slog counterpart: the first
+	// eligible level at, or below `l` is the one with the highest.
+	levels := level.Levels()
+
+	// Falls back to the most verbose level.
+	found := levels[len(levels)-1]
+
+	for _, candidate := range levels {
+		if candidate == level.None || candidate == level.Fatal {
+			continue
+		}
+
+		if ToSlogLevel(candidate) <= l {
+			return candidate
+		}
+	}
+
+	return found
+}
+
 // ToSlogLevel maps a sypl level to a slog level. See the package
 // documentation for the mapping table. `None` - which sypl never prints,
 // unless forced - and unknown levels map to `slog.LevelInfo`.
+//
+// NOTE: Custom levels - see `level.Register` - map linearly by verbosity,
+// following the built-in ones: 4 slog units per `level.Scale`, e.g.: a level
+// at 35 - between Warn, and Info - maps to Info+2.
 func ToSlogLevel(l level.Level) slog.Level {
 	switch l {
 	case level.Trace:
@@ -64,6 +98,10 @@ func ToSlogLevel(l level.Level) slog.Level {
 	case level.None:
 		return slog.LevelInfo
 	default:
+		if l.IsValid() {
+			return slog.Level((level.Info.Verbosity() - l.Verbosity()) * 4 / level.Scale)
+		}
+
 		return slog.LevelInfo
 	}
 }
@@ -73,3 +73,41 @@ func TestLevelRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+// Custom levels take part in both mappings. Registered above Trace, so the
+// standard mapping - see above - is unaffected.
+func TestLevelMapping_CustomLevel(t *testing.T) {
+	verbose2, err := level.Register("verbose2", 70)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := level.Unregister(verbose2); err != nil {
+			t.Errorf("Unregister() error = %v", err)
+		}
+	})
+
+	if got := ToSlogLevel(verbose2); got != LevelTrace-4 {
+		t.Errorf("ToSlogLevel(verbose2) = %v, expected %v", got, LevelTrace-4)
+	}
+
+	tests := []struct {
+		in       slog.Level
+		expected level.Level
+	}{
+		{LevelTrace, level.Trace},
+		{LevelTrace + 2, level.Trace},
+		{LevelTrace - 2, verbose2},
+		{LevelTrace - 4, verbose2},
+		{LevelTrace - 10, verbose2},
+		{slog.LevelInfo, level.Info},
+		{LevelFatal, level.Error},
+	}
+
+	for _, tc := range tests {
+		if got := ToSyplLevel(tc.in); got != tc.expected {
+			t.Fatalf("ToSyplLevel(%v) = %v, expected %v", tc.in, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package sypltest provides test helpers for Sypl consumers, on top of
+// `output.Recorder`:
+//   - Assertions: `AssertLogged`, `AssertNotLogged`, `AssertSequence`
+//     (ordered, other records may be interleaved), and `WaitFor` (for
+//     asynchronous outputs).
+//   - Matchers, combined with AND semantics: `Contains`, `Message`,
+//     `MatchesRegexp`, `HasField`, `HasFieldKey`, `HasTag`, `FromOutput`,
+//     `Not`, and custom ones via `MatcherFunc`.
+//   - `Output`: routes logs to `t.Log`, so they're shown only on failure.
+//
+// Usage:
+//
+//	rec, o := output.Recorder(level.Trace)
+//
+//	l := sypl.New("api", o, sypltest.Output(t, level.Trace))
+//
+//	l.PrintlnWithOptions(level.Error, "request timeout",
+//		sypl.WithFields(fields.Fields{"user": 42}),
+//		sypl.WithTags("audit"),
+//	)
+//
+//	sypltest.AssertLogged(t, rec, level.Error,
+//		sypltest.Contains("timeout"),
+//		sypltest.HasField("user", 42),
+//		sypltest.HasTag("audit"),
+//	)
+package sypltest
@@ -0,0 +1,168 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package golden provides golden-file snapshot testing for formatters:
+// messages with a frozen clock, and a deterministic ID are rendered through
+// a formatter, and compared against `testdata/<name>.golden`. Run the tests
+// with `-update` - when the test package defines that flag - or with
+// `SYPL_UPDATE_GOLDEN=1` to (re)write the golden files - then review the
+// diff.
+//
+// NOTE: It registers no flag, so importers may define their own `-update`:
+//
+//	var _ = flag.Bool("update", false, "update the golden files")
+//
+// Usage:
+//
+//	func TestJSON_Golden(t *testing.T) {
+//		m := golden.Message(level.Info, "hello")
+//
+//		m.SetComponentName("api")
+//
+//		golden.AssertFormatter(t, formatter.JSON(), "json", m)
+//	}
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// ID is the ID of messages created by `Message`.
+const ID = "00000000-0000-4000-8000-000000000000"
+
+// Dir is the golden files directory, relative to the test's package.
+const Dir = "testdata"
+
+// FrozenTime is the timestamp of messages created by `Message`. It's UTC, so
+// renderings don't depend on the machine's time zone.
+var FrozenTime = time.Date(2021, time.January, 2, 3, 4, 5, 6000000, time.UTC)
+
+// UpdateEnvVar is the env var which - set to a true value, see
+// `strconv.ParseBool` - rewrites the golden files instead of comparing
+// against them. An alternative to `UpdateFlag`.
+const UpdateEnvVar = "SYPL_UPDATE_GOLDEN"
+
+// UpdateFlag is the test flag which - when defined by the test binary, and
+// set - rewrites the golden files instead of comparing against them.
+const UpdateFlag = "update"
+
+//////
+// Helpers.
+//////
+
+// Clock always returns `FrozenTime` - see `message.WithClock`.
+func Clock() time.Time {
+	return FrozenTime
+}
+
+// IDGenerator always returns `ID` - see `message.WithIDGenerator`.
+func IDGenerator() string {
+	return ID
+}
+
+// Message returns a message with the frozen clock, and the deterministic
+// ID.
+func Message(l level.Level, ct string) message.IMessage {
+	return message.New(l, ct, message.WithClock(Clock), message.WithIDGenerator(IDGenerator))
+}
+
+// Render renders the messages through `f` - a formatter, or any processor -
+// one per line, in order.
+func Render(f processor.IProcessor, messages ...message.IMessage) (string, error) {
+	var b strings.Builder
+
+	for _, m := range messages {
+		if err := f.Run(m); err != nil {
+			return "", err
+		}
+
+		b.WriteString(strings.TrimRight(m.GetContent().GetProcessed(), "\r\n"))
+		b.WriteString("\n")
+	}
+
+	return b.String(), nil
+}
+
+// updating determines whether golden files are rewritten - see
+// `UpdateFlag`, and `UpdateEnvVar`.
+func updating() bool {
+	if f := flag.Lookup(UpdateFlag); f != nil {
+		if update, err := strconv.ParseBool(f.Value.String()); err == nil && update {
+			return true
+		}
+	}
+
+	update, err := strconv.ParseBool(os.Getenv(UpdateEnvVar))
+
+	return err == nil && update
+}
+
+// Path returns the golden file path of `name`.
+func Path(name string) string {
+	return filepath.Join(Dir, name+".golden")
+}
+
+//////
+// Assertions.
+//////
+
+// Assert compares `got` against the `name` golden file. With `UpdateFlag`,
+// or `UpdateEnvVar` set, it (re)writes the file instead.
+func Assert(t testing.TB, name string, got []byte) {
+	t.Helper()
+
+	path := Path(name)
+
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("golden: %v - run with -%s, or %s=1 to create it", err, UpdateFlag, UpdateEnvVar)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf(
+			"golden: %s mismatch - run with -%s, or %s=1 if intended.\ngot:\n%s\nwant:\n%s",
+			path, UpdateFlag, UpdateEnvVar, got, want,
+		)
+	}
+}
+
+// AssertFormatter renders the messages through `f` - see `Render` - and
+// compares the result against the `name` golden file - see `Assert`.
+func AssertFormatter(t testing.TB, f processor.IProcessor, name string, messages ...message.IMessage) {
+	t.Helper()
+
+	got, err := Render(f, messages...)
+	if err != nil {
+		t.Fatalf("golden: rendering %s: %v", name, err)
+	}
+
+	Assert(t, name, []byte(got))
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/message"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+// upper is a formatter upper-casing the content.
+func upper() processor.IProcessor {
+	return processor.New("Upper", func(m message.IMessage) error {
+		m.GetContent().SetProcessed(strings.ToUpper(m.GetContent().GetProcessed()))
+
+		return nil
+	})
+}
+
+// inTempDir runs the test from a temporary directory - golden files are
+// relative to it.
+func inTempDir(t *testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestMessage_IsDeterministic(t *testing.T) {
+	m := Message(level.Info, "hello")
+
+	if !m.GetTimestamp().Equal(FrozenTime) || m.GetID() != ID {
+		t.Errorf("Message() timestamp = %v, ID = %q", m.GetTimestamp(), m.GetID())
+	}
+}
+
+func TestRender(t *testing.T) {
+	got, err := Render(upper(), Message(level.Info, "one\n"), Message(level.Info, "two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != "ONE\nTWO\n" {
+		t.Errorf("Render() = %q", got)
+	}
+}
+
+func TestAssert_UpdateThenCompare(t *testing.T) {
+	inTempDir(t)
+
+	t.Setenv(UpdateEnvVar, "true")
+
+	AssertFormatter(t, upper(), "upper", Message(level.Info, "hello"))
+
+	t.Setenv(UpdateEnvVar, "false")
+
+	written, err := os.ReadFile(filepath.Join(Dir, "upper.golden"))
+	if err != nil || string(written) != "HELLO\n" {
+		t.Fatalf("golden file = %q, %v", written, err)
+	}
+
+	// Matches.
+	AssertFormatter(t, upper(), "upper", Message(level.Info, "hello"))
+
+	// Mismatches.
+	tb := &fakeTB{TB: t}
+
+	Assert(tb, "upper", []byte("changed\n"))
+
+	if !tb.failed || !strings.Contains(tb.msg, "upper.golden mismatch") {
+		t.Errorf("Assert() failure = %v, %q", tb.failed, tb.msg)
+	}
+}
+
+// Importing the package registers no flag - importers may define their own
+// `-update`.
+func TestNoFlagRegistered(t *testing.T) {
+	if f := flag.Lookup("update"); f != nil {
+		t.Errorf("flag %q registered", f.Name)
+	}
+}
+
+// An `-update` flag defined by the test binary is honored.
+func TestAssert_UpdateFlag(t *testing.T) {
+	inTempDir(t)
+
+	commandLine := flag.CommandLine
+
+	t.Cleanup(func() { flag.CommandLine = commandLine })
+
+	flag.CommandLine = flag.NewFlagSet("golden", flag.ContinueOnError)
+
+	update := flag.Bool(UpdateFlag, false, "update the golden files")
+
+	*update = true
+
+	AssertFormatter(t, upper(), "upper", Message(level.Info, "hello"))
+
+	*update = false
+
+	written, err := os.ReadFile(filepath.Join(Dir, "upper.golden"))
+	if err != nil || string(written) != "HELLO\n" {
+		t.Fatalf("golden file = %q, %v", written, err)
+	}
+
+	// Not set anymore: compares.
+	tb := &fakeTB{TB: t}
+
+	Assert(tb, "upper", []byte("changed\n"))
+
+	if !tb.failed {
+		t.Error("Assert() rewrote the golden file with -update unset")
+	}
+}
+
+// fakeTB records a failure, instead of failing the test.
+type fakeTB struct {
+	testing.TB
+
+	failed bool
+	msg    string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.failed = true
+	f.msg = strings.TrimSpace(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypltest
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/thalesfsp/sypl/v2/output"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// Matcher matches a record. Matchers are combined with AND semantics by the
+// assertions.
+type Matcher struct {
+	description string
+	match       func(r output.Record) bool
+}
+
+//////
+// Methods.
+//////
+
+// Match returns true if `r` matches.
+func (m Matcher) Match(r output.Record) bool {
+	return m.match(r)
+}
+
+// String interface implementation. It describes the matcher - e.g.
+// `contains "timeout"` - in assertion failures.
+func (m Matcher) String() string {
+	return m.description
+}
+
+//////
+// Helpers.
+//////
+
+// matchAll returns true if `r` matches every matcher.
+func matchAll(r output.Record, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m.Match(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// toFloat converts numeric values to float64, reporting whether `v` is a
+// number.
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// equal compares field values: deeply, or - for numbers of different types,
+// e.g. `42`, and `int64(42)` - by value.
+func equal(got, want interface{}) bool {
+	if reflect.DeepEqual(got, want) {
+		return true
+	}
+
+	g, gotNumber := toFloat(got)
+	w, wantNumber := toFloat(want)
+
+	return gotNumber && wantNumber && g == w
+}
+
+//////
+// Factory.
+//////
+
+// MatcherFunc returns a custom matcher, described - in assertion failures -
+// by `description`.
+func MatcherFunc(description string, fn func(r output.Record) bool) Matcher {
+	return Matcher{description: description, match: fn}
+}
+
+// Contains matches records whose content - original, or processed -
+// contains `substr`.
+func Contains(substr string) Matcher {
+	return MatcherFunc(fmt.Sprintf("contains %q", substr), func(r output.Record) bool {
+		return strings.Contains(r.OriginalContent, substr) || strings.Contains(r.ProcessedContent, substr)
+	})
+}
+
+// Message matches records whose original content - trailing linebreaks
+// trimmed, as `Println` adds one - is `content`.
+func Message(content string) Matcher {
+	return MatcherFunc(fmt.Sprintf("message is %q", content), func(r output.Record) bool {
+		return strings.TrimRight(r.OriginalContent, "\r\n") == content
+	})
+}
+
+// MatchesRegexp matches records whose content - original, or processed -
+// matches `pattern`. It panics if `pattern` doesn't compile.
+func MatchesRegexp(pattern string) Matcher {
+	re := regexp.MustCompile(pattern)
+
+	return MatcherFunc(fmt.Sprintf("matches /%s/", pattern), func(r output.Record) bool {
+		return re.MatchString(r.OriginalContent) || re.MatchString(r.ProcessedContent)
+	})
+}
+
+// HasField matches records with the `key` field equal to `value`. Numbers
+// compare by value, regardless of their type - `HasField("user", 42)`
+// matches an `int64(42)`.
+func HasField(key string, value interface{}) Matcher {
+	return MatcherFunc(fmt.Sprintf("has field %s=%v", key, value), func(r output.Record) bool {
+		v, ok := r.Fields[key]
+
+		return ok && equal(v, value)
+	})
+}
+
+// HasFieldKey matches records with the `key` field, whatever its value.
+func HasFieldKey(key string) Matcher {
+	return MatcherFunc(fmt.Sprintf("has field %s", key), func(r output.Record) bool {
+		_, ok := r.Fields[key]
+
+		return ok
+	})
+}
+
+// HasTag matches records tagged with `tag`.
+func HasTag(tag string) Matcher {
+	return MatcherFunc(fmt.Sprintf("has tag %q", tag), func(r output.Record) bool {
+		return slices.Contains(r.Tags, tag)
+	})
+}
+
+// FromOutput matches records written by the `name` output.
+func FromOutput(name string) Matcher {
+	return MatcherFunc(fmt.Sprintf("from output %q", name), func(r output.Record) bool {
+		return r.OutputName == name
+	})
+}
+
+// Not negates `m`.
+func Not(m Matcher) Matcher {
+	return MatcherFunc("not "+m.String(), func(r output.Record) bool {
+		return !m.Match(r)
+	})
+}
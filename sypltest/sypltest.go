@@ -0,0 +1,264 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypltest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+	"github.com/thalesfsp/sypl/v2/processor"
+)
+
+//////
+// Consts, vars, and types.
+//////
+
+// waitForInterval is how often `WaitFor` polls the recorder.
+const waitForInterval = 5 * time.Millisecond
+
+// Step is an expected record of a sequence - see `AssertSequence`.
+type Step struct {
+	// Level is the expected level.
+	Level level.Level
+
+	// Matchers the record must match.
+	Matchers []Matcher
+}
+
+// String interface implementation.
+func (s Step) String() string {
+	return describe(s.Level, s.Matchers)
+}
+
+//////
+// Helpers.
+//////
+
+// describe describes an expectation - e.g. `error, contains "timeout"`.
+func describe(l level.Level, matchers []Matcher) string {
+	parts := []string{l.String()}
+
+	for _, m := range matchers {
+		parts = append(parts, m.String())
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// dump lists the records, one per line, for assertion failures.
+func dump(records []output.Record) string {
+	if len(records) == 0 {
+		return "  (no records)"
+	}
+
+	lines := make([]string, 0, len(records))
+
+	for i, r := range records {
+		line := fmt.Sprintf("  %d. [%s] %q", i, r.Level, strings.TrimRight(r.OriginalContent, "\r\n"))
+
+		if len(r.Fields) > 0 {
+			keys := make([]string, 0, len(r.Fields))
+
+			for k := range r.Fields {
+				keys = append(keys, k)
+			}
+
+			slices.Sort(keys)
+
+			pairs := make([]string, 0, len(keys))
+
+			for _, k := range keys {
+				pairs = append(pairs, fmt.Sprintf("%s=%v", k, r.Fields[k]))
+			}
+
+			line += " fields: " + strings.Join(pairs, " ")
+		}
+
+		if len(r.Tags) > 0 {
+			line += " tags: " + strings.Join(r.Tags, ",")
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Find returns the records at level `l` matching every matcher, in order.
+func Find(rec *output.RecorderOutput, l level.Level, matchers ...Matcher) []output.Record {
+	found := []output.Record{}
+
+	for _, r := range rec.Messages() {
+		if r.Level == l && matchAll(r, matchers) {
+			found = append(found, r)
+		}
+	}
+
+	return found
+}
+
+// Expect returns a sequence step - see `AssertSequence`.
+func Expect(l level.Level, matchers ...Matcher) Step {
+	return Step{Level: l, Matchers: matchers}
+}
+
+//////
+// Assertions.
+//////
+
+// AssertLogged asserts `rec` captured a record at level `l` matching every
+// matcher, returning the first one. On failure, the captured records are
+// listed.
+//
+// Example:
+//
+//	sypltest.AssertLogged(t, rec, level.Error,
+//		sypltest.Contains("timeout"),
+//		sypltest.HasField("user", 42),
+//		sypltest.HasTag("audit"),
+//	)
+func AssertLogged(t testing.TB, rec *output.RecorderOutput, l level.Level, matchers ...Matcher) output.Record {
+	t.Helper()
+
+	if found := Find(rec, l, matchers...); len(found) > 0 {
+		return found[0]
+	}
+
+	t.Errorf("expected a record matching: %s\ncaptured:\n%s", describe(l, matchers), dump(rec.Messages()))
+
+	return output.Record{}
+}
+
+// AssertNotLogged asserts `rec` captured NO record at level `l` matching
+// every matcher.
+func AssertNotLogged(t testing.TB, rec *output.RecorderOutput, l level.Level, matchers ...Matcher) {
+	t.Helper()
+
+	if found := Find(rec, l, matchers...); len(found) > 0 {
+		t.Errorf("expected no record matching: %s\nfound:\n%s", describe(l, matchers), dump(found))
+	}
+}
+
+// AssertSequence asserts `rec` captured records matching the steps, in
+// order - other records may be interleaved.
+//
+// Example:
+//
+//	sypltest.AssertSequence(t, rec,
+//		sypltest.Expect(level.Info, sypltest.Contains("connecting")),
+//		sypltest.Expect(level.Warn, sypltest.Contains("retrying")),
+//		sypltest.Expect(level.Info, sypltest.Contains("connected")),
+//	)
+func AssertSequence(t testing.TB, rec *output.RecorderOutput, steps ...Step) {
+	t.Helper()
+
+	records := rec.Messages()
+
+	next := 0
+
+	for _, r := range records {
+		if next == len(steps) {
+			break
+		}
+
+		if r.Level == steps[next].Level && matchAll(r, steps[next].Matchers) {
+			next++
+		}
+	}
+
+	if next == len(steps) {
+		return
+	}
+
+	t.Errorf(
+		"expected sequence step %d: %s - not found after the previous steps\ncaptured:\n%s",
+		next, steps[next], dump(records),
+	)
+}
+
+// WaitFor waits - up to `timeout` - for `rec` to capture a record at level
+// `l` matching every matcher, returning it. Use it with asynchronous
+// outputs, e.g. `output.Async`. On timeout, it fails like `AssertLogged`.
+func WaitFor(
+	t testing.TB,
+	rec *output.RecorderOutput,
+	timeout time.Duration,
+	l level.Level,
+	matchers ...Matcher,
+) output.Record {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if found := Find(rec, l, matchers...); len(found) > 0 {
+			return found[0]
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+
+		time.Sleep(waitForInterval)
+	}
+
+	t.Errorf(
+		"timed out after %s waiting for a record matching: %s\ncaptured:\n%s",
+		timeout, describe(l, matchers), dump(rec.Messages()),
+	)
+
+	return output.Record{}
+}
+
+//////
+// Output.
+//////
+
+// tbWriter routes writes to `t.Log`. Writes after the test finished - e.g.
+// from a leaked goroutine - are dropped: `t.Log` would panic.
+type tbWriter struct {
+	t testing.TB
+
+	mu   sync.Mutex
+	done bool
+}
+
+// Write conforms to the `io.Writer` interface.
+func (w *tbWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.done {
+		w.t.Log(strings.TrimRight(string(p), "\r\n"))
+	}
+
+	return len(p), nil
+}
+
+// Output is an `output` - named `Test` - that routes messages to `t.Log`,
+// so they're shown only for failing tests, or with `go test -v`, next to
+// the test's own output.
+//
+// Example:
+//
+//	l := sypl.New("api", sypltest.Output(t, level.Trace))
+func Output(t testing.TB, maxLevel level.Level, processors ...processor.IProcessor) output.IOutput {
+	w := &tbWriter{t: t}
+
+	t.Cleanup(func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		w.done = true
+	})
+
+	return output.New("Test", maxLevel, w, processors...)
+}
@@ -0,0 +1,228 @@
+// Copyright 2021 The sypl Authors. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sypltest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalesfsp/sypl/v2"
+	"github.com/thalesfsp/sypl/v2/fields"
+	"github.com/thalesfsp/sypl/v2/level"
+	"github.com/thalesfsp/sypl/v2/output"
+)
+
+//////
+// Test helpers.
+//////
+
+// fakeTB records failures, and logs - instead of failing the test running
+// the assertion.
+type fakeTB struct {
+	testing.TB
+
+	mu       sync.Mutex
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Log(args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeTB) failures() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.errors
+}
+
+// newLogger returns a logger writing to a recorder.
+func newLogger() (*sypl.Sypl, *output.RecorderOutput) {
+	rec, o := output.Recorder(level.Trace)
+
+	return sypl.New("test", o), rec
+}
+
+//////
+// Assertions.
+//////
+
+func TestAssertLogged(t *testing.T) {
+	l, rec := newLogger()
+
+	l.PrintlnWithOptions(level.Error, "request timeout",
+		sypl.WithFields(fields.Fields{"user": 42, "path": "/users"}),
+		sypl.WithTags("audit"),
+	)
+
+	got := AssertLogged(t, rec, level.Error,
+		Contains("timeout"),
+		Message("request timeout"),
+		MatchesRegexp(`^request \w+`),
+		HasField("user", int64(42)),
+		HasFieldKey("path"),
+		HasTag("audit"),
+		FromOutput("Recorder"),
+		Not(HasTag("other")),
+	)
+
+	if got.Fields["path"] != "/users" {
+		t.Errorf("AssertLogged() = %+v, want the matching record", got)
+	}
+
+	tests := []struct {
+		name     string
+		level    level.Level
+		matchers []Matcher
+		want     string
+	}{
+		{name: "level", level: level.Warn, want: "warn"},
+		{name: "content", level: level.Error, matchers: []Matcher{Contains("refused")}, want: `contains "refused"`},
+		{name: "field value", level: level.Error, matchers: []Matcher{HasField("user", 43)}, want: "has field user=43"},
+		{name: "tag", level: level.Error, matchers: []Matcher{HasTag("billing")}, want: `has tag "billing"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &fakeTB{}
+
+			AssertLogged(tb, rec, tt.level, tt.matchers...)
+
+			failures := tb.failures()
+
+			if len(failures) != 1 {
+				t.Fatalf("failures = %q, want 1", failures)
+			}
+
+			// The failure describes the expectation, and lists the records.
+			if !strings.Contains(failures[0], tt.want) || !strings.Contains(failures[0], `[error] "request timeout" fields: path=/users user=42 tags: audit`) {
+				t.Errorf("failure = %q", failures[0])
+			}
+		})
+	}
+}
+
+func TestAssertNotLogged(t *testing.T) {
+	l, rec := newLogger()
+
+	l.Infoln("started")
+
+	AssertNotLogged(t, rec, level.Error)
+	AssertNotLogged(t, rec, level.Info, Contains("stopped"))
+
+	tb := &fakeTB{}
+
+	AssertNotLogged(tb, rec, level.Info, Contains("start"))
+
+	if failures := tb.failures(); len(failures) != 1 || !strings.Contains(failures[0], `"started"`) {
+		t.Errorf("failures = %q, want the offending record", failures)
+	}
+}
+
+func TestAssertSequence(t *testing.T) {
+	l, rec := newLogger()
+
+	l.Infoln("connecting")
+	l.Debugln("noise")
+	l.Warnln("retrying")
+	l.Infoln("connected")
+
+	AssertSequence(t, rec,
+		Expect(level.Info, Contains("connecting")),
+		Expect(level.Warn, Contains("retrying")),
+		Expect(level.Info, Contains("connected")),
+	)
+
+	tb := &fakeTB{}
+
+	// Out of order.
+	AssertSequence(tb, rec,
+		Expect(level.Info, Contains("connected")),
+		Expect(level.Warn, Contains("retrying")),
+	)
+
+	if failures := tb.failures(); len(failures) != 1 || !strings.Contains(failures[0], `step 1: warn, contains "retrying"`) {
+		t.Errorf("failures = %q, want step 1", failures)
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	rec, o := output.Recorder(level.Trace)
+
+	async := output.Async(o)
+
+	l := sypl.New("test", async)
+
+	defer l.Close()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+
+		l.Errorln("late failure")
+	}()
+
+	if got := WaitFor(t, rec, 5*time.Second, level.Error, Contains("late")); got.Level != level.Error {
+		t.Errorf("WaitFor() = %+v", got)
+	}
+
+	tb := &fakeTB{}
+
+	WaitFor(tb, rec, 10*time.Millisecond, level.Error, Contains("never"))
+
+	if failures := tb.failures(); len(failures) != 1 || !strings.Contains(failures[0], "timed out after 10ms") {
+		t.Errorf("failures = %q, want the timeout", failures)
+	}
+}
+
+//////
+// Output.
+//////
+
+func TestOutput(t *testing.T) {
+	tb := &fakeTB{}
+
+	o := Output(tb, level.Info)
+
+	if o.GetName() != "Test" {
+		t.Errorf("GetName() = %q, want Test", o.GetName())
+	}
+
+	l := sypl.New("test", o)
+
+	l.Infoln("shown")
+	l.Debugln("filtered")
+
+	// After the test finished, logs are dropped - t.Log would panic.
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+
+	l.Infoln("late")
+
+	if len(tb.logs) != 1 || tb.logs[0] != "shown" {
+		t.Errorf("logs = %q, want [shown]", tb.logs)
+	}
+}
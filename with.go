@@ -18,8 +18,10 @@ import (
 // commit 25dfacc).
 //
 // The derived logger inherits Name, the default io.Writer level, status, the
-// error handler, the context extractor, and the fast-gate setting. `f` may
-// be nil, or empty - the child then simply inherits the parent's fields.
+// error handler, the context extractor, the fast-gate, message pooling, and
+// dispatch mode settings - sharing the output workers -, the clock, the ID
+// generator, the routing rules, and the verbosity. `f` may be nil, or
+// empty - the child then simply inherits the parent's fields.
 func (sypl *Sypl) With(f fields.Fields) *Sypl {
 	sypl.rLock()
 	defer sypl.rUnlock()
@@ -40,13 +42,20 @@ func (sypl *Sypl) With(f fields.Fields) *Sypl {
 	// ELEMENTS stay shared by design.
 	s := New(sypl.Name, sypl.outputs...)
 
+	s.clock = sypl.clock
 	s.contextExtractor = sypl.contextExtractor
 	s.defaultIoWriterLevel = sypl.defaultIoWriterLevel
+	s.dispatchMode = sypl.dispatchMode
+	s.dispatcher = sypl.dispatcher
 	s.errorHandler = sypl.errorHandler
 	s.fastGate = sypl.fastGate
 	s.fields = merged
+	s.idGenerator = sypl.idGenerator
+	s.pooling = sypl.pooling
+	s.router = sypl.router
 	s.status = sypl.status
 	s.tags = slices.Clone(sypl.tags)
+	s.verbosity = sypl.verbosity
 
 	return s
 }